@@ -68,6 +68,10 @@ type Command struct {
 
 	// DisablePersistentFlags skip persistent flags from parent
 	DisablePersistentFlags bool
+
+	// EnableDoubleDash ends the flags of the command at a `--` argument, the
+	// `--` and everything after it reach Run untouched
+	EnableDoubleDash bool
 }
 
 func (c *Command) AddSubCommand(cmd *Command) {
@@ -251,6 +255,7 @@ func (c *Command) executeInner(ctx *Context, args []string) error {
 	parser := NewParser(args, ctx)
 	// allow unknown flags
 	parser.SetAllowUnknown(c.EnableUnknownFlag)
+	parser.SetStopAtDoubleDash(c.EnableDoubleDash)
 
 	var current = parser.GetCurrent()
 	// get next arg
@@ -311,7 +316,11 @@ func (c *Command) executeInner(ctx *Context, args []string) error {
 		}
 	}
 
-	for _, s := range remainArgs {
+	for i, s := range remainArgs {
+		if c.EnableDoubleDash && s == "--" {
+			callArgs = append(callArgs, remainArgs[i:]...)
+			break
+		}
 		if s != "help" {
 			callArgs = append(callArgs, s)
 		} else {
@@ -346,6 +355,10 @@ func (c *Command) executeInner(ctx *Context, args []string) error {
 
 func (c *Command) processError(ctx *Context, err error) {
 	Errorf(ctx.Stderr(), "ERROR: %s\n", err.Error())
+	if e, ok := err.(ErrorWithExitCode); ok {
+		Exit(e.GetExitCode())
+		return
+	}
 	if e, ok := err.(SuggestibleError); ok {
 		PrintSuggestions(ctx, i18n.GetLanguage(), e.GetSuggestions())
 		Exit(2)
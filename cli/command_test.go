@@ -244,3 +244,26 @@ func TestExecuteHelp(t *testing.T) {
 	cmd.executeHelp(ctx, nil)
 	assert.Equal(t, "\x1b[1;31mERROR: test help error\n\x1b[0m", buf2.String())
 }
+
+func TestExecuteDoubleDash(t *testing.T) {
+	var got []string
+	cmd := newAliyunCmd()
+	subCmd := newTestCmd()
+	subCmd.KeepArgs = true
+	subCmd.EnableDoubleDash = true
+	subCmd.Run = func(ctx *Context, args []string) error {
+		got = args
+		return nil
+	}
+	subCmd.Flags().Add(&Flag{Name: "profile", AssignedMode: AssignedOnce})
+	cmd.AddSubCommand(subCmd)
+
+	ctx := NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ctx.EnterCommand(cmd)
+	cmd.Execute(ctx, []string{"test", "--profile", "prod", "--", "git", "help", "--version"})
+	assert.Equal(t, []string{"--profile", "prod", "--", "git", "help", "--version"}, got)
+	assert.False(t, ctx.IsHelp())
+	v, ok := ctx.Flags().GetValue("profile")
+	assert.True(t, ok)
+	assert.Equal(t, "prod", v)
+}
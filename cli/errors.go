@@ -43,6 +43,35 @@ func (e *errorWithTip) GetTip(lang string) string {
 	return e.tip
 }
 
+// If command.Execute return error with exit code, exit the process with that code
+type ErrorWithExitCode interface {
+	GetExitCode() int
+}
+
+type errorWithExitCode struct {
+	err  error
+	code int
+}
+
+func NewErrorWithExitCode(err error, code int) error {
+	return &errorWithExitCode{
+		err:  err,
+		code: code,
+	}
+}
+
+func (e *errorWithExitCode) Error() string {
+	return e.err.Error()
+}
+
+func (e *errorWithExitCode) Unwrap() error {
+	return e.err
+}
+
+func (e *errorWithExitCode) GetExitCode() int {
+	return e.code
+}
+
 // OUTPUT:
 // Error: "'%s' is not a valid command
 //
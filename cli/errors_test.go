@@ -31,6 +31,16 @@ func TestErrorWithTip(t *testing.T) {
 	assert.Equal(t, "nicai-1", e.GetTip("ch"))
 }
 
+func TestErrorWithExitCode(t *testing.T) {
+	cause := errors.New("err test")
+	err := NewErrorWithExitCode(cause, 4)
+	e, ok := err.(ErrorWithExitCode)
+	assert.True(t, ok)
+	assert.Equal(t, 4, e.GetExitCode())
+	assert.Equal(t, "err test", err.Error())
+	assert.True(t, errors.Is(err, cause))
+}
+
 func TestInvalidCommandError(t *testing.T) {
 	w := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
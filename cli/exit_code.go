@@ -17,8 +17,14 @@ import "os"
 
 var (
 	withExitCode = true
+	exitHooks    []func(code int)
 )
 
+// OnExit registers fn to run with the exit code before the process exits.
+func OnExit(fn func(code int)) {
+	exitHooks = append(exitHooks, fn)
+}
+
 func EnableExitCode() {
 	withExitCode = true
 }
@@ -28,6 +34,9 @@ func DisableExitCode() {
 }
 
 func Exit(code int) {
+	for _, fn := range exitHooks {
+		fn(code)
+	}
 	if withExitCode {
 		os.Exit(code)
 	}
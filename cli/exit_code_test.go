@@ -26,3 +26,17 @@ func TestEnableExitCode(t *testing.T) {
 	EnableExitCode()
 	assert.True(t, withExitCode)
 }
+
+func TestOnExit(t *testing.T) {
+	oldHooks := exitHooks
+	defer func() {
+		exitHooks = oldHooks
+		EnableExitCode()
+	}()
+	DisableExitCode()
+
+	var got []int
+	OnExit(func(code int) { got = append(got, code) })
+	Exit(3)
+	assert.Equal(t, []int{3}, got)
+}
@@ -30,6 +30,9 @@ type Parser struct {
 	detector     flagDetector
 	currentFlag  *Flag
 	allowUnknown bool
+	// stopAtDoubleDash reads every arg after `--` as a plain arg
+	stopAtDoubleDash bool
+	afterDoubleDash  bool
 }
 
 func NewParser(args []string, detector flagDetector) *Parser {
@@ -46,6 +49,10 @@ func (p *Parser) SetAllowUnknown(allow bool) {
 	p.allowUnknown = allow
 }
 
+func (p *Parser) SetStopAtDoubleDash(stop bool) {
+	p.stopAtDoubleDash = stop
+}
+
 func (p *Parser) ReadNextArg() (arg string, more bool, err error) {
 	for {
 		arg, _, more, err = p.readNext()
@@ -94,6 +101,16 @@ func (p *Parser) readNext() (arg string, flag *Flag, more bool, err error) {
 	p.current++
 	more = true
 
+	if p.afterDoubleDash {
+		arg = s
+		return
+	}
+	if p.stopAtDoubleDash && s == "--" && p.currentFlag == nil {
+		p.afterDoubleDash = true
+		arg = s
+		return
+	}
+
 	value := ""
 	flag, value, err = p.parseCommandArg(s)
 	if err != nil {
@@ -14,6 +14,7 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -172,3 +173,20 @@ func TestParserSetAllowUnknown(t *testing.T) {
 // aliyun oss cp -r oss-url local-path
 // aliyun oss cp -r local-path oss-url
 // aliyun oss -e oss-cn-beijing.aliyuncs.com ls oss://bucket-name
+
+func TestParserStopAtDoubleDash(t *testing.T) {
+	ctx := NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	ctx.Flags().Add(&Flag{Name: "profile", AssignedMode: AssignedOnce})
+	parser := NewParser([]string{"--profile", "prod", "--", "ls", "--profile", "-l"}, ctx)
+	parser.SetStopAtDoubleDash(true)
+	args, err := parser.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"--", "ls", "--profile", "-l"}, args)
+	v, _ := ctx.Flags().GetValue("profile")
+	assert.Equal(t, "prod", v)
+
+	// `--` is still an error when not enabled
+	parser = NewParser([]string{"--"}, NewCommandContext(new(bytes.Buffer), new(bytes.Buffer)))
+	_, err = parser.ReadAll()
+	assert.EqualError(t, err, "not support '--' in command line")
+}
@@ -192,7 +192,7 @@ func runPluginCommand(binPath string, args []string, stdout io.Writer, stderr io
 
 	if err := cmd.Run(); err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitError.ExitCode())
+			cli.Exit(exitError.ExitCode())
 		}
 		return fmt.Errorf("plugin execution failed: %w", err)
 	}
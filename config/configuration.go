@@ -33,10 +33,14 @@ const (
 )
 
 type Configuration struct {
-	CurrentProfile string    `json:"current"`
-	Profiles       []Profile `json:"profiles"`
-	MetaPath       string    `json:"meta_path"`
+	CurrentProfile string      `json:"current"`
+	Profiles       []Profile   `json:"profiles"`
+	MetaPath       string      `json:"meta_path"`
+	Encryption     *Encryption `json:"encryption,omitempty"`
 	//Plugins 		[]Plugin `json:"plugin"`
+
+	// key seals the secrets of an encrypted config on save
+	key []byte
 }
 
 var hookGetHomePath = func(fn func() string) func() string {
@@ -138,16 +142,30 @@ func getProfileName(ctx *cli.Context) (name string) {
 }
 
 func LoadProfileWithContext(ctx *cli.Context) (profile Profile, err error) {
+	project, err := LoadProjectConfig()
+	if err != nil {
+		return
+	}
 	if util.GetFromEnv("ALIBABA_CLOUD_IGNORE_PROFILE", "ALIBABACLOUD_IGNORE_PROFILE") == "TRUE" {
 		profile = NewProfile("default")
 		profile.RegionId = "cn-hangzhou"
 	} else {
 		currentPath := getConfigurePath(ctx)
 		profileName := getProfileName(ctx)
+		selected := project.selects(profileName)
+		if profileName == "" && project != nil {
+			profileName = project.Profile
+		}
 		profile, err = LoadProfile(currentPath, profileName)
 		if err != nil {
+			if project != nil && project.Profile != "" && profileName == project.Profile {
+				err = fmt.Errorf("%v, pinned by %s", err, project.Path())
+			}
 			return
 		}
+		if selected {
+			project.applyTo(&profile)
+		}
 	}
 
 	// Load from flags
@@ -218,7 +236,7 @@ func LoadConfigurationWithContext(ctx *cli.Context) (conf *Configuration, err er
 }
 
 func SaveConfiguration(config *Configuration) (err error) {
-	bytes, err := json.MarshalIndent(config, "", "\t")
+	bytes, err := config.marshal()
 	if err != nil {
 		return
 	}
@@ -228,7 +246,7 @@ func SaveConfiguration(config *Configuration) (err error) {
 }
 
 func SaveConfigurationWithContext(ctx *cli.Context, config *Configuration) (err error) {
-	bytes, err := json.MarshalIndent(config, "", "\t")
+	bytes, err := config.marshal()
 	if err != nil {
 		return
 	}
@@ -318,7 +336,10 @@ func atomicWriteFileWithRename(path string, data []byte, perm os.FileMode, renam
 
 func NewConfigFromBytes(bytes []byte) (conf *Configuration, err error) {
 	conf = NewConfiguration()
-	err = json.Unmarshal(bytes, conf)
+	if err = json.Unmarshal(bytes, conf); err != nil {
+		return
+	}
+	err = conf.unlockSecretsFromEnv()
 	return
 }
 
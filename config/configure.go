@@ -122,6 +122,17 @@ func loadOrCreateConfiguration() (*Configuration, error) {
 	return hookLoadOrCreateConfiguration(LoadOrCreateConfiguration)(GetConfigPath() + "/" + configFile)
 }
 
+// loadConfigurationForUpdate loads the configuration the configure commands
+// write, the one of --config-path when it exists.
+func loadConfigurationForUpdate(ctx *cli.Context) (*Configuration, error) {
+	if customPath, ok := ConfigurePathFlag(ctx.Flags()).GetValue(); ok {
+		if _, err := hookFileStat(os.Stat)(customPath); !os.IsNotExist(err) {
+			return LoadConfigurationFromFile(customPath)
+		}
+	}
+	return loadOrCreateConfiguration()
+}
+
 func NewConfigureCommand() *cli.Command {
 	c := &cli.Command{
 		Name: "configure",
@@ -175,25 +186,20 @@ func NewConfigureCommand() *cli.Command {
 	c.AddSubCommand(NewConfigureSafetyPolicyCommand())
 	c.AddSubCommand(NewConfigureAiModeCommand())
 	c.AddSubCommand(NewConfigurePluginSettingsCommand())
+	c.AddSubCommand(NewConfigureAuditLogCommand())
+	c.AddSubCommand(NewConfigureLockCommand())
+	c.AddSubCommand(NewConfigureUnlockCommand())
+	c.AddSubCommand(NewConfigureExportCredentialsCommand())
+	c.AddSubCommand(NewConfigureServeCredentialsCommand())
+	c.AddSubCommand(NewConfigureSsoSyncCommand())
+	c.AddSubCommand(NewConfigureWhereCommand())
 	return c
 }
 
 func doConfigure(ctx *cli.Context, profileName string, mode string) error {
 	w := ctx.Stdout()
 
-	var err error
-	var conf *Configuration
-	if customPath, ok := ConfigurePathFlag(ctx.Flags()).GetValue(); ok {
-		if _, err := hookFileStat(os.Stat)(customPath); !os.IsNotExist(err) {
-			conf, err = LoadConfigurationFromFile(customPath)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	if conf == nil {
-		conf, err = loadOrCreateConfiguration()
-	}
+	conf, err := loadConfigurationForUpdate(ctx)
 	if err != nil {
 		return err
 	}
@@ -299,9 +305,14 @@ func doConfigure(ctx *cli.Context, profileName string, mode string) error {
 	}
 
 	if cp.Mode != CloudSSO || cp.OutputFormat == "" {
-		cli.Printf(w, "Default Output Format [%s]: json (Only support json)\n", cp.OutputFormat)
-		// cp.OutputFormat = ReadInput(cp.OutputFormat)
-		cp.OutputFormat = "json"
+		// not read from the input, scripts answer the prompts in order; keep
+		// a format chosen by `configure set --output-format`
+		current := cp.OutputFormat
+		if !IsSupportedOutputFormat(cp.OutputFormat) {
+			cp.OutputFormat = "json"
+		}
+		cli.Printf(w, "Default Output Format [%s]: %s (%s, change it with `aliyun configure set --output-format`)\n",
+			current, cp.OutputFormat, strings.Join(SupportedOutputFormats, "|"))
 	}
 
 	if cp.Mode != CloudSSO || cp.Language == "" {
@@ -0,0 +1,169 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+)
+
+func NewConfigureAuditLogCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name: "audit-log",
+		Short: i18n.T(
+			"manage the local audit log of API calls and plugin runs",
+			"管理 API 调用及插件执行的本地审计日志"),
+		Usage: "audit-log [command] [--config-path <configPath>]",
+		Long: i18n.T(
+			`Configure the audit log. When enabled, every API call (RPC, ROA and OpenAPI) and plugin run is appended as one JSON line to audit.log under the config dir, or to the file given by --path, as soon as it completes. Each line records time, OS user, host, profile, auth mode, product, API, method, path, region, endpoint, request ID, HTTP status and duration. Parameters and plugin flags whose name contains secret, password, token, private key, access key or credential are masked, except pagination tokens such as NextToken. When the CLI exits, a final line with "event": "exit" records the exit code of the run.
+Environment variables ALIBABA_CLOUD_CLI_AUDIT_LOG_ENABLED and ALIBABA_CLOUD_CLI_AUDIT_LOG_PATH override the saved settings.`,
+			`配置审计日志。启用后，每次 API 调用（RPC、ROA 和 OpenAPI）及插件执行都会以一行 JSON 追加到配置目录下的 audit.log，或 --path 指定的文件，调用完成后立即写入。每行记录时间、系统用户、主机、profile、认证模式、产品、API、方法、路径、地域、endpoint、请求 ID、HTTP 状态码和耗时。名称中包含 secret、password、token、private key、access key 或 credential 的参数和插件 flag 会被脱敏，NextToken 等分页标记除外。CLI 退出时会再追加一行 "event": "exit" 记录本次运行的退出码。
+环境变量 ALIBABA_CLOUD_CLI_AUDIT_LOG_ENABLED 和 ALIBABA_CLOUD_CLI_AUDIT_LOG_PATH 会覆盖已保存的设置。`),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			configDir, cfg, err := loadAuditLogConfig(ctx)
+			if err != nil {
+				return err
+			}
+			return doAuditLogShow(ctx, configDir, cfg)
+		},
+	}
+
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "audit-log",
+		Name:         "path",
+		AssignedMode: cli.AssignedOnce,
+		Persistent:   true,
+		Short: i18n.T(
+			"audit log file for enable (default: audit.log under the config dir)",
+			"enable 使用的审计日志文件（默认：配置目录下的 audit.log）"),
+	})
+	AddFlags(cmd.Flags())
+
+	cmd.AddSubCommand(newConfigureAuditLogShowCommand())
+	cmd.AddSubCommand(newConfigureAuditLogEnableCommand())
+	cmd.AddSubCommand(newConfigureAuditLogDisableCommand())
+	return cmd
+}
+
+func loadAuditLogConfig(ctx *cli.Context) (configDir string, cfg *audit.Config, err error) {
+	configDir = GetConfigDir(ctx)
+	cfg, err = audit.Load(configDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("load audit-log config failed: %w", err)
+	}
+	return configDir, cfg, nil
+}
+
+func newConfigureAuditLogShowCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "show",
+		Usage: "show [--config-path <configPath>]",
+		Short: i18n.T("display current audit log config", "显示当前审计日志配置"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			configDir, cfg, err := loadAuditLogConfig(ctx)
+			if err != nil {
+				return err
+			}
+			return doAuditLogShow(ctx, configDir, cfg)
+		},
+	}
+}
+
+func newConfigureAuditLogEnableCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "enable",
+		Usage: "enable [--path <file>] [--config-path <configPath>]",
+		Short: i18n.T("turn on the audit log", "开启审计日志"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			configDir, cfg, err := loadAuditLogConfig(ctx)
+			if err != nil {
+				return err
+			}
+			return doAuditLogEnable(ctx, configDir, cfg)
+		},
+	}
+}
+
+func newConfigureAuditLogDisableCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "disable",
+		Usage: "disable [--config-path <configPath>]",
+		Short: i18n.T("turn off the audit log", "关闭审计日志"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			configDir, cfg, err := loadAuditLogConfig(ctx)
+			if err != nil {
+				return err
+			}
+			cfg.Enabled = false
+			return audit.Save(configDir, cfg)
+		},
+	}
+}
+
+func doAuditLogShow(ctx *cli.Context, configDir string, cfg *audit.Config) error {
+	effective := audit.MergeFromEnv(cfg)
+	out := struct {
+		Enabled          bool   `json:"enabled"`
+		Path             string `json:"path,omitempty"`
+		EffectiveEnabled bool   `json:"effective_enabled"`
+		EffectivePath    string `json:"effective_path"`
+		ConfigFile       string `json:"config_file"`
+	}{
+		Enabled:          cfg.Enabled,
+		Path:             cfg.Path,
+		EffectiveEnabled: effective.Enabled,
+		EffectivePath:    effective.LogPath(configDir),
+		ConfigFile:       audit.GetConfigFilePath(configDir),
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	cli.Println(ctx.Stdout(), string(data))
+	return nil
+}
+
+func doAuditLogEnable(ctx *cli.Context, configDir string, cfg *audit.Config) error {
+	if v, ok := ctx.Flags().Get("path").GetValue(); ok {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return fmt.Errorf("--path must not be empty")
+		}
+		abs, err := filepath.Abs(v)
+		if err != nil {
+			return fmt.Errorf("invalid --path %s: %w", v, err)
+		}
+		cfg.Path = abs
+	}
+	cfg.Enabled = true
+	return audit.Save(configDir, cfg)
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enterAuditLogSub(t *testing.T, ctx *cli.Context, name string) *cli.Command {
+	t.Helper()
+	root := NewConfigureAuditLogCommand()
+	ctx.EnterCommand(root)
+	sub := root.GetSubCommand(name)
+	require.NotNil(t, sub, "subcommand %q", name)
+	ctx.EnterCommand(sub)
+	return sub
+}
+
+func TestConfigureAuditLog_Show_Default(t *testing.T) {
+	t.Setenv(audit.EnvEnabled, "")
+	t.Setenv(audit.EnvPath, "")
+	dir := t.TempDir()
+	ctx, w := testAiModeContext(t, dir)
+	sub := enterAuditLogSub(t, ctx, "show")
+	require.NoError(t, sub.Run(ctx, []string{}))
+	assert.Contains(t, w.String(), `"enabled": false`)
+	assert.Contains(t, w.String(), filepath.Join(dir, audit.DefaultLogFileName))
+}
+
+func TestConfigureAuditLog_EnableDisable(t *testing.T) {
+	dir := t.TempDir()
+	ctx, _ := testAiModeContext(t, dir)
+	enable := enterAuditLogSub(t, ctx, "enable")
+	ctx.Flags().Get("path").SetAssigned(true)
+	ctx.Flags().Get("path").SetValue(filepath.Join(dir, "logs", "cli.log"))
+	require.NoError(t, enable.Run(ctx, []string{}))
+
+	cfg, err := audit.Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, filepath.Join(dir, "logs", "cli.log"), cfg.Path)
+
+	ctx2, _ := testAiModeContext(t, dir)
+	disable := enterAuditLogSub(t, ctx2, "disable")
+	require.NoError(t, disable.Run(ctx2, []string{}))
+	cfg, err = audit.Load(dir)
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, filepath.Join(dir, "logs", "cli.log"), cfg.Path)
+}
+
+func TestConfigureAuditLog_EnableEmptyPath(t *testing.T) {
+	dir := t.TempDir()
+	ctx, _ := testAiModeContext(t, dir)
+	enable := enterAuditLogSub(t, ctx, "enable")
+	ctx.Flags().Get("path").SetAssigned(true)
+	ctx.Flags().Get("path").SetValue(" ")
+	assert.EqualError(t, enable.Run(ctx, []string{}), "--path must not be empty")
+}
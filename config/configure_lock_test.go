@@ -0,0 +1,114 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfigPathCtx is newCtx with --config-path set to path, the file itself
+// is not written.
+func newConfigPathCtx(path string) *cli.Context {
+	ctx := newCtx()
+	ConfigurePathFlag(ctx.Flags()).SetAssigned(true)
+	ConfigurePathFlag(ctx.Flags()).SetValue(path)
+	return ctx
+}
+
+func stubReadPassphrase(t *testing.T, answers ...string) {
+	origin := readPassphrase
+	t.Cleanup(func() { readPassphrase = origin })
+	readPassphrase = func(ctx *cli.Context, prompt string) (string, error) {
+		require.NotEmpty(t, answers, "unexpected prompt %s", prompt)
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+}
+
+func TestConfigureLockUnlock(t *testing.T) {
+	fastScrypt(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, err := newEncryptionTestConfiguration().marshal()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	ctx := newConfigPathCtx(path)
+	stdout := ctx.Stdout().(*bytes.Buffer)
+	stubReadPassphrase(t, "pass", "typo")
+	assert.EqualError(t, doConfigureLock(ctx), "passphrases do not match")
+
+	stubReadPassphrase(t, "pass", "pass")
+	require.NoError(t, doConfigureLock(ctx))
+	assert.Equal(t, "Encrypted the credentials of 2 profiles.\n", stdout.String())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"secret"`)
+
+	// the environment passphrase opens the secrets on load
+	t.Setenv(EnvConfigPassphrase, "pass")
+	ctx = newConfigPathCtx(path)
+	assert.EqualError(t, doConfigureLock(ctx), "the credentials in config.json are already encrypted")
+	profile, err := LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", profile.AccessKeySecret)
+
+	// without it, unlock prompts
+	t.Setenv(EnvConfigPassphrase, "")
+	ctx = newConfigPathCtx(path)
+	stdout = ctx.Stdout().(*bytes.Buffer)
+	stubReadPassphrase(t, "wrong")
+	assert.Equal(t, errWrongPassphrase, doConfigureUnlock(ctx))
+	stubReadPassphrase(t, "pass")
+	require.NoError(t, doConfigureUnlock(ctx))
+	assert.Equal(t, "Decrypted the credentials of 2 profiles.\n", stdout.String())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"access_key_secret": "secret"`)
+
+	assert.EqualError(t, doConfigureUnlock(ctx), "the credentials in config.json are not encrypted")
+}
+
+func TestConfigureLockWithKeyFile(t *testing.T) {
+	fastScrypt(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data, err := newEncryptionTestConfiguration().marshal()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("from-file\n"), 0600))
+	t.Setenv(EnvConfigKeyFile, keyFile)
+
+	ctx := newConfigPathCtx(path)
+	stubReadPassphrase(t)
+	require.NoError(t, doConfigureLock(ctx))
+
+	conf, err := LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	p, _ := conf.GetProfile("oauth")
+	assert.Equal(t, "refresh", p.OAuthRefreshToken)
+
+	require.NoError(t, doConfigureUnlock(ctx))
+	conf, err = LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	assert.False(t, conf.IsLocked())
+}
@@ -17,6 +17,8 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
@@ -63,6 +65,42 @@ func NewConfigureSafetyPolicyCommand() *cli.Command {
 			"action for rule: deny or confirm",
 			"规则动作: deny 或 confirm"),
 	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "safety",
+		Name:         "profiles",
+		AssignedMode: cli.AssignedOnce,
+		Persistent:   true,
+		Short: i18n.T(
+			"only apply the rule to these profiles, comma separated, wildcard allowed (e.g. prod,prod-*)",
+			"规则仅对这些配置生效，逗号分隔，支持通配符 (如 prod,prod-*)"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "safety",
+		Name:         "regions",
+		AssignedMode: cli.AssignedOnce,
+		Persistent:   true,
+		Short: i18n.T(
+			"only apply the rule in these regions, comma separated, wildcard allowed (e.g. cn-shanghai,cn-*)",
+			"规则仅在这些地域生效，逗号分隔，支持通配符 (如 cn-shanghai,cn-*)"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "safety",
+		Name:         "param",
+		AssignedMode: cli.AssignedRepeatable,
+		Persistent:   true,
+		Short: i18n.T(
+			"request parameter condition, repeatable: Name=Value, Name!=Value, Name~=glob, Name>N, Name>=N, Name<N, Name<=N or Name",
+			"请求参数条件，可重复: Name=Value, Name!=Value, Name~=glob, Name>N, Name>=N, Name<N, Name<=N 或 Name"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "safety",
+		Name:         "index",
+		AssignedMode: cli.AssignedOnce,
+		Persistent:   true,
+		Short: i18n.T(
+			"number of the rule as shown by list",
+			"规则的序号，与 list 的输出一致"),
+	})
 
 	AddFlags(cmd.Flags())
 
@@ -141,7 +179,7 @@ func newConfigureSafetyPolicyDisableCommand() *cli.Command {
 func newConfigureSafetyPolicyAddCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "add",
-		Usage: "add --pattern <pattern> --action <deny|confirm|forbid> [--config-path <configPath>]",
+		Usage: "add --pattern <pattern> --action <deny|confirm|forbid> [--profiles <p1,p2>] [--regions <r1,r2>] [--param <Name>N>]... [--config-path <configPath>]",
 		Short: i18n.T("add or update a safety rule", "添加或更新安全规则"),
 		Run: func(ctx *cli.Context, args []string) error {
 			if len(args) > 0 {
@@ -159,8 +197,10 @@ func newConfigureSafetyPolicyAddCommand() *cli.Command {
 func newConfigureSafetyPolicyRemoveCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "remove",
-		Usage: "remove --pattern <pattern> [--config-path <configPath>]",
-		Short: i18n.T("remove a safety rule by pattern", "按模式删除安全规则"),
+		Usage: "remove {--index <N> | --pattern <pattern> [--profiles <p1,p2>] [--regions <r1,r2>] [--param <Name>N>]...} [--config-path <configPath>]",
+		Short: i18n.T(
+			"remove a safety rule by its number in list, or by its pattern and conditions",
+			"按 list 中的序号，或按模式及条件删除一条安全规则"),
 		Run: func(ctx *cli.Context, args []string) error {
 			if len(args) > 0 {
 				return cli.NewInvalidCommandError(args[0], ctx)
@@ -226,34 +266,90 @@ func doSafetyPolicyAdd(ctx *cli.Context, configDir string, policy *safety.Policy
 		return fmt.Errorf("action must be deny, confirm, or forbid")
 	}
 
-	// Check if rule already exists, update it
+	rule, err := safetyRuleFromFlags(ctx, pattern)
+	if err != nil {
+		return err
+	}
+	rule.Action = action
+
+	// Check if rule already exists (same pattern and conditions), update it
 	for i := range policy.Rules {
-		if policy.Rules[i].Pattern == pattern {
+		if policy.Rules[i].Pattern == pattern && policy.Rules[i].DescribeConditions() == rule.DescribeConditions() {
 			policy.Rules[i].Action = action
 			return safety.SavePolicy(configDir, policy)
 		}
 	}
 
-	policy.Rules = append(policy.Rules, safety.Rule{
-		Pattern: pattern,
-		Action:  action,
-	})
+	policy.Rules = append(policy.Rules, rule)
 	return safety.SavePolicy(configDir, policy)
 }
 
-func doSafetyPolicyRemove(ctx *cli.Context, configDir string, policy *safety.Policy) error {
-	pattern, ok := ctx.Flags().Get("pattern").GetValue()
-	if !ok || pattern == "" {
-		return fmt.Errorf("--pattern is required for remove")
+// safetyRuleFromFlags reads the conditions of a rule given by --profiles,
+// --regions and --param.
+func safetyRuleFromFlags(ctx *cli.Context, pattern string) (safety.Rule, error) {
+	rule := safety.Rule{Pattern: pattern}
+	if v, ok := ctx.Flags().Get("profiles").GetValue(); ok {
+		rule.Profiles = splitSafetyList(v)
+	}
+	if v, ok := ctx.Flags().Get("regions").GetValue(); ok {
+		rule.Regions = splitSafetyList(v)
 	}
+	if f := ctx.Flags().Get("param"); f != nil {
+		for _, v := range f.GetValues() {
+			cond, err := safety.ParseParamCondition(v)
+			if err != nil {
+				return rule, fmt.Errorf("invalid --param: %w", err)
+			}
+			rule.Params = append(rule.Params, cond)
+		}
+	}
+	return rule, nil
+}
 
-	newRules := make([]safety.Rule, 0)
-	for _, r := range policy.Rules {
-		if r.Pattern != pattern {
-			newRules = append(newRules, r)
+func splitSafetyList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
 		}
 	}
-	policy.Rules = newRules
+	return out
+}
+
+// doSafetyPolicyRemove removes exactly one rule, rules of the same pattern
+// with other conditions are kept.
+func doSafetyPolicyRemove(ctx *cli.Context, configDir string, policy *safety.Policy) error {
+	index := -1
+	if v, ok := ctx.Flags().Get("index").GetValue(); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 || n > len(policy.Rules) {
+			return fmt.Errorf("invalid --index %s, use a number from 1 to %d as shown by `aliyun configure safety-policy list`", v, len(policy.Rules))
+		}
+		index = n - 1
+	} else {
+		pattern, ok := ctx.Flags().Get("pattern").GetValue()
+		if !ok || pattern == "" {
+			return fmt.Errorf("--index or --pattern is required for remove")
+		}
+		rule, err := safetyRuleFromFlags(ctx, pattern)
+		if err != nil {
+			return err
+		}
+		for i, r := range policy.Rules {
+			if r.Pattern == pattern && r.DescribeConditions() == rule.DescribeConditions() {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			if conditions := rule.DescribeConditions(); conditions != "" {
+				return fmt.Errorf("no rule %s [%s], use --index to remove a rule listed by `aliyun configure safety-policy list`", pattern, conditions)
+			}
+			return fmt.Errorf("no rule %s without conditions, use --index to remove a rule listed by `aliyun configure safety-policy list`", pattern)
+		}
+	}
+
+	policy.Rules = append(policy.Rules[:index:index], policy.Rules[index+1:]...)
 	return safety.SavePolicy(configDir, policy)
 }
 
@@ -268,6 +364,10 @@ func doSafetyPolicyList(ctx *cli.Context, configDir string, policy *safety.Polic
 	}
 	cli.Println(w, "Rules:")
 	for i, r := range policy.Rules {
+		if conditions := r.DescribeConditions(); conditions != "" {
+			cli.Printf(w, "  %d. %s -> %s [%s]\n", i+1, r.Pattern, r.Action, conditions)
+			continue
+		}
 		cli.Printf(w, "  %d. %s -> %s\n", i+1, r.Pattern, r.Action)
 	}
 	return nil
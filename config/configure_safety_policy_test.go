@@ -114,6 +114,46 @@ func TestConfigureSafetyPolicy_AddRemoveList(t *testing.T) {
 	assert.Empty(t, p3.Rules)
 }
 
+func TestConfigureSafetyPolicy_Add_WithConditions(t *testing.T) {
+	dir := t.TempDir()
+	ctx, _ := testAiModeContext(t, dir)
+	add := enterSafetyPolicySub(t, ctx, "add")
+	ctx.Flags().Get("pattern").SetAssigned(true)
+	ctx.Flags().Get("pattern").SetValue("ecs:RunInstances")
+	ctx.Flags().Get("action").SetAssigned(true)
+	ctx.Flags().Get("action").SetValue(string(safety.ActionConfirm))
+	ctx.Flags().Get("profiles").SetAssigned(true)
+	ctx.Flags().Get("profiles").SetValue("prod, prod-*")
+	ctx.Flags().Get("param").SetAssigned(true)
+	ctx.Flags().Get("param").SetValues([]string{"Amount>10", "RegionId=cn-shanghai"})
+	require.NoError(t, add.Run(ctx, []string{}))
+
+	p, err := safety.LoadPolicy(dir)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 1)
+	assert.Equal(t, []string{"prod", "prod-*"}, p.Rules[0].Profiles)
+	assert.Equal(t, []safety.ParamCondition{
+		{Name: "Amount", Op: safety.OpGt, Value: "10"},
+		{Name: "RegionId", Op: safety.OpEq, Value: "cn-shanghai"},
+	}, p.Rules[0].Params)
+
+	ctx2, w2 := testAiModeContext(t, dir)
+	list := enterSafetyPolicySub(t, ctx2, "list")
+	require.NoError(t, list.Run(ctx2, []string{}))
+	assert.Contains(t, w2.String(), "1. ecs:RunInstances -> confirm [profiles=prod,prod-* Amount>10 RegionId=cn-shanghai]")
+
+	ctx3, _ := testAiModeContext(t, dir)
+	add3 := enterSafetyPolicySub(t, ctx3, "add")
+	ctx3.Flags().Get("pattern").SetAssigned(true)
+	ctx3.Flags().Get("pattern").SetValue("ecs:RunInstances")
+	ctx3.Flags().Get("action").SetAssigned(true)
+	ctx3.Flags().Get("action").SetValue(string(safety.ActionDeny))
+	ctx3.Flags().Get("param").SetAssigned(true)
+	ctx3.Flags().Get("param").SetValues([]string{"Amount>ten"})
+	err = add3.Run(ctx3, []string{})
+	assert.EqualError(t, err, `invalid --param: parameter condition "Amount>ten" needs a numeric value`)
+}
+
 func TestConfigureSafetyPolicy_Add_UpdatesExistingPattern(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{
@@ -194,6 +234,45 @@ func TestConfigureSafetyPolicy_Remove_MissingPattern(t *testing.T) {
 	assert.Contains(t, err.Error(), "--pattern is required")
 }
 
+func TestConfigureSafetyPolicy_Remove_OneRule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, safety.SavePolicy(dir, &safety.Policy{Rules: []safety.Rule{
+		{Pattern: "ecs:Delete*", Action: safety.ActionDeny, Profiles: []string{"prod"}},
+		{Pattern: "ecs:Delete*", Action: safety.ActionConfirm},
+		{Pattern: "rds:*", Action: safety.ActionConfirm},
+	}}))
+
+	ctx, _ := testAiModeContext(t, dir)
+	rm := enterSafetyPolicySub(t, ctx, "remove")
+	ctx.Flags().Get("pattern").SetAssigned(true)
+	ctx.Flags().Get("pattern").SetValue("ecs:Delete*")
+	ctx.Flags().Get("profiles").SetAssigned(true)
+	ctx.Flags().Get("profiles").SetValue("prod")
+	require.NoError(t, rm.Run(ctx, []string{}))
+	p, err := safety.LoadPolicy(dir)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 2)
+	assert.Equal(t, safety.ActionConfirm, p.Rules[0].Action)
+	assert.Empty(t, p.Rules[0].Profiles)
+
+	err = rm.Run(ctx, []string{})
+	assert.EqualError(t, err, "no rule ecs:Delete* [profiles=prod], use --index to remove a rule listed by `aliyun configure safety-policy list`")
+
+	ctx2, _ := testAiModeContext(t, dir)
+	rm = enterSafetyPolicySub(t, ctx2, "remove")
+	ctx2.Flags().Get("index").SetAssigned(true)
+	ctx2.Flags().Get("index").SetValue("2")
+	require.NoError(t, rm.Run(ctx2, []string{}))
+	p, err = safety.LoadPolicy(dir)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 1)
+	assert.Equal(t, "ecs:Delete*", p.Rules[0].Pattern)
+
+	ctx2.Flags().Get("index").SetValue("2")
+	err = rm.Run(ctx2, []string{})
+	assert.EqualError(t, err, "invalid --index 2, use a number from 1 to 1 as shown by `aliyun configure safety-policy list`")
+}
+
 func TestConfigureSafetyPolicy_Subcommand_ExtraArgs(t *testing.T) {
 	dir := t.TempDir()
 	ctx, _ := testAiModeContext(t, dir)
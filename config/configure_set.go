@@ -34,6 +34,7 @@ func NewConfigureSetCommand() *cli.Command {
 	}
 
 	AddFlags(cmd.Flags())
+	cmd.Flags().Add(NewOutputFormatFlag())
 
 	return cmd
 }
@@ -124,8 +125,16 @@ func doConfigureSet(ctx *cli.Context) error {
 
 	profile.RegionId = RegionFlag(flags).GetStringOrDefault(profile.RegionId)
 	profile.Language = LanguageFlag(flags).GetStringOrDefault(profile.Language)
-	profile.OutputFormat = "json" // "output", profile.OutputFormat)
-	profile.Site = "china"        // "site", profile.Site)
+	if !IsSupportedOutputFormat(profile.OutputFormat) {
+		profile.OutputFormat = "json"
+	}
+	if v := OutputFormatFlag(flags).GetStringOrDefault(""); v != "" {
+		if !IsSupportedOutputFormat(v) {
+			return fmt.Errorf("invalid --output-format %s, supported: %s", v, strings.Join(SupportedOutputFormats, ", "))
+		}
+		profile.OutputFormat = v
+	}
+	profile.Site = "china" // "site", profile.Site)
 	profile.ReadTimeout = ReadTimeoutFlag(flags).GetIntegerOrDefault(profile.ReadTimeout)
 	profile.ConnectTimeout = ConnectTimeoutFlag(flags).GetIntegerOrDefault(profile.ConnectTimeout)
 	profile.RetryCount = RetryCountFlag(flags).GetIntegerOrDefault(profile.RetryCount)
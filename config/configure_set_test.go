@@ -306,6 +306,56 @@ func TestDoConfigureSet_ExternalAccountType(t *testing.T) {
 	assert.Equal(t, "buc", savedProfile.ExternalAccountType)
 }
 
+func TestDoConfigureSet_OutputFormat(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	ctx := cli.NewCommandContext(stdout, stderr)
+	AddFlags(ctx.Flags())
+	ctx.Flags().Add(NewOutputFormatFlag())
+
+	originhook := hookLoadOrCreateConfiguration
+	originhookSave := hookSaveConfigurationWithContext
+	defer func() {
+		hookLoadOrCreateConfiguration = originhook
+		hookSaveConfigurationWithContext = originhookSave
+	}()
+
+	var savedProfile Profile
+	hookSaveConfigurationWithContext = func(fn func(ctx *cli.Context, config *Configuration) error) func(ctx *cli.Context, config *Configuration) error {
+		return func(ctx *cli.Context, config *Configuration) error {
+			p, _ := config.GetProfile(config.CurrentProfile)
+			savedProfile = p
+			return nil
+		}
+	}
+
+	hookLoadOrCreateConfiguration = func(fn func(path string) (*Configuration, error)) func(path string) (*Configuration, error) {
+		return func(path string) (*Configuration, error) {
+			return &Configuration{
+				CurrentProfile: "default",
+				Profiles: []Profile{
+					{Name: "default", RegionId: "cn-hangzhou", Mode: AK, AccessKeyId: "ak", AccessKeySecret: "sk", OutputFormat: "yaml"},
+				},
+			}, nil
+		}
+	}
+
+	err := doConfigureSet(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", savedProfile.OutputFormat)
+
+	flag := OutputFormatFlag(ctx.Flags())
+	flag.SetAssigned(true)
+	flag.SetValue("csv")
+	err = doConfigureSet(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "csv", savedProfile.OutputFormat)
+
+	flag.SetValue("xml")
+	err = doConfigureSet(ctx)
+	assert.EqualError(t, err, "invalid --output-format xml, supported: json, csv, tsv, yaml, jsonl")
+}
+
 func TestDoConfigureSet_AutoPluginInstall(t *testing.T) {
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
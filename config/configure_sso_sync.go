@@ -0,0 +1,300 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/cloudsso"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+)
+
+const (
+	ProfileTemplateFlagName = "profile-template"
+	NoPruneFlagName         = "no-prune"
+
+	DefaultProfileTemplate = "{account}-{accessConfig}"
+	defaultSsoSyncRegion   = "cn-hangzhou"
+)
+
+// profileNameUnsafe matches the runs of characters that are replaced in the
+// names of accounts and access configurations, profile names are typed in
+// shells.
+var profileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ssoSyncTarget is a profile sso-sync generates for an account and an access
+// configuration.
+type ssoSyncTarget struct {
+	name         string
+	accountId    string
+	accessConfig string
+}
+
+func NewProfileTemplateFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         ProfileTemplateFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--profile-template <template>` to name the profiles, placeholders {account}, {accountId}, {accessConfig} and {accessConfigId}, default {account}-{accessConfig}",
+			"使用 `--profile-template <template>` 指定 profile 的命名模板，支持占位符 {account}、{accountId}、{accessConfig} 和 {accessConfigId}，默认 {account}-{accessConfig}"),
+	}
+}
+
+func NewNoPruneFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         NoPruneFlagName,
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"use `--no-prune` to keep the generated profiles whose account or access configuration is no longer accessible",
+			"使用 `--no-prune` 保留账号或访问配置已不可访问的已生成 profile"),
+	}
+}
+
+func NewConfigureSsoSyncCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "sso-sync",
+		Usage: "sso-sync [--cloud-sso-sign-in-url <url>] [--profile-template <template>] [--region <regionId>] [--no-prune]",
+		Short: i18n.T("generate CloudSSO profiles for all accounts and access configurations", "为所有账号和访问配置生成 CloudSSO profile"),
+		Long: i18n.T(
+			`Log in to CloudSSO once and generate a profile for every account and access configuration the user can access, named by --profile-template. Existing profiles of the same name are updated, and all CloudSSO profiles of the sign in url share the new access token.
+Profiles generated earlier whose account or access configuration is no longer accessible are deleted, unless --no-prune is given. Profiles configured by hand are never deleted. If the current profile is deleted, the default profile becomes current, without one nothing is changed and the sync fails.
+The sign in url defaults to the one of the current profile.`,
+			`登录 CloudSSO 一次，为用户可访问的每个账号和访问配置生成以 --profile-template 命名的 profile。已存在的同名 profile 会被更新，该登录链接的所有 CloudSSO profile 共享新的访问令牌。
+账号或访问配置已不可访问的已生成 profile 会被删除，指定 --no-prune 时保留。手动配置的 profile 不会被删除。当前 profile 被删除时切换到 default profile，若不存在则不做任何修改并报错。
+登录链接默认使用当前 profile 的登录链接。`),
+		Sample: "aliyun configure sso-sync --cloud-sso-sign-in-url https://signin-xxx.alibabacloudsso.com/device/login --profile-template '{accountId}-{accessConfig}'",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doConfigureSsoSync(ctx)
+		},
+	}
+	AddFlags(cmd.Flags())
+	cmd.Flags().Add(NewProfileTemplateFlag())
+	cmd.Flags().Add(NewNoPruneFlag())
+	return cmd
+}
+
+func sanitizeProfileNamePart(s string) string {
+	return strings.Trim(profileNameUnsafe.ReplaceAllString(strings.TrimSpace(s), "-"), "-")
+}
+
+func renderProfileName(template string, account cloudsso.AccountDetailResponse, ac cloudsso.AccessConfiguration) string {
+	accountName := account.DisplayName
+	if accountName == "" {
+		accountName = account.AccountId
+	}
+	acName := ac.AccessConfigurationName
+	if acName == "" {
+		acName = ac.AccessConfigurationId
+	}
+	return strings.NewReplacer(
+		"{account}", sanitizeProfileNamePart(accountName),
+		"{accountId}", sanitizeProfileNamePart(account.AccountId),
+		"{accessConfig}", sanitizeProfileNamePart(acName),
+		"{accessConfigId}", sanitizeProfileNamePart(ac.AccessConfigurationId),
+	).Replace(template)
+}
+
+func ssoSyncSignInUrl(ctx *cli.Context, conf *Configuration) (string, error) {
+	if v, ok := CloudSSOSignInUrlFlag(ctx.Flags()).GetValue(); ok && v != "" {
+		return v, nil
+	}
+	if current, ok := conf.GetProfile(conf.CurrentProfile); ok && current.Mode == CloudSSO && current.CloudSSOSignInUrl != "" {
+		return current.CloudSSOSignInUrl, nil
+	}
+	return "", fmt.Errorf("missing --cloud-sso-sign-in-url <url>, the current profile is not a CloudSSO profile")
+}
+
+// listSsoSyncTargets lists the profiles of every account and access
+// configuration, it fails on two of them with the same name.
+func listSsoSyncTargets(template string, prefix string, accessToken string, httpClient *http.Client) ([]ssoSyncTarget, error) {
+	accounts, err := cloudssoListAllUsers(&cloudsso.ListUserParameter{
+		AccessToken: accessToken,
+		BaseUrl:     prefix,
+		HttpClient:  httpClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list account failed: %s", err)
+	}
+	var targets []ssoSyncTarget
+	owners := map[string]string{}
+	for _, account := range accounts {
+		acs, err := cloudssoListAllAccessConfigurations(&cloudsso.AccessConfigurationsParameter{
+			AccessToken: accessToken,
+			UrlPrefix:   prefix,
+			HttpClient:  httpClient,
+			AccountId:   account.AccountId,
+		}, cloudsso.AccessConfigurationsRequest{
+			AccountId: account.AccountId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list access configuration of account %s failed: %s", account.AccountId, err)
+		}
+		for _, ac := range acs {
+			name := renderProfileName(template, account, ac)
+			owner := account.AccountId + "/" + ac.AccessConfigurationId
+			if name == "" {
+				return nil, fmt.Errorf("profile template %q yields an empty name for %s", template, owner)
+			}
+			if other, ok := owners[name]; ok {
+				return nil, fmt.Errorf("profile template %q yields the name %s for both %s and %s, add {accountId} or {accessConfigId} to it",
+					template, name, other, owner)
+			}
+			owners[name] = owner
+			targets = append(targets, ssoSyncTarget{name: name, accountId: account.AccountId, accessConfig: ac.AccessConfigurationId})
+		}
+	}
+	return targets, nil
+}
+
+func doConfigureSsoSync(ctx *cli.Context) error {
+	w := ctx.Stdout()
+	template := DefaultProfileTemplate
+	if v, ok := ctx.Flags().GetValue(ProfileTemplateFlagName); ok && v != "" {
+		template = v
+	}
+	conf, err := loadConfigurationForUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	signInUrl, err := ssoSyncSignInUrl(ctx, conf)
+	if err != nil {
+		return err
+	}
+	baseUrl, err := url.Parse(signInUrl)
+	if err != nil || baseUrl.Host == "" {
+		return fmt.Errorf("invalid CloudSSO sign in url: %s", signInUrl)
+	}
+	prefix := baseUrl.Scheme + "://" + baseUrl.Host
+
+	ssoLogin := cloudsso.SsoLogin{
+		SignInUrl: signInUrl,
+		// force login
+		ExpireTime: 0,
+		HttpClient: utilNewHttpClient(),
+	}
+	accessToken, err := cloudssoGetAccessToken(&ssoLogin)
+	if err != nil {
+		return fmt.Errorf("get access token failed: %s", err)
+	}
+	accessTokenExpire := utilGetCurrentUnixTime() + int64(accessToken.ExpiresIn)
+
+	targets, err := listSsoSyncTargets(template, prefix, accessToken.AccessToken, ssoLogin.HttpClient)
+	if err != nil {
+		return err
+	}
+	// check every name before the configuration changes
+	for _, target := range targets {
+		existing, ok := conf.GetProfile(target.name)
+		if !ok || existing.CloudSSOSynced {
+			continue
+		}
+		if existing.Mode != CloudSSO || existing.CloudSSOSignInUrl != signInUrl ||
+			existing.CloudSSOAccountId != target.accountId || existing.CloudSSOAccessConfig != target.accessConfig {
+			return fmt.Errorf("profile %s already exists and is not a CloudSSO profile of account %s and access configuration %s, use another --profile-template",
+				target.name, target.accountId, target.accessConfig)
+		}
+	}
+
+	region := defaultSsoSyncRegion
+	if v, ok := RegionFlag(ctx.Flags()).GetValue(); ok && v != "" {
+		region = v
+	}
+	wanted := map[string]bool{}
+	var created, updated, pruned []string
+	for _, target := range targets {
+		wanted[target.name] = true
+		p, ok := conf.GetProfile(target.name)
+		if !ok {
+			p = NewProfile(target.name)
+			p.RegionId = region
+			// only generated profiles are pruned, never ones configured by hand
+			p.CloudSSOSynced = true
+			created = append(created, target.name)
+		} else {
+			updated = append(updated, target.name)
+		}
+		if p.CloudSSOAccountId != target.accountId || p.CloudSSOAccessConfig != target.accessConfig || p.CloudSSOSignInUrl != signInUrl {
+			// the STS credentials belong to another role
+			p.AccessKeyId = ""
+			p.AccessKeySecret = ""
+			p.StsToken = ""
+			p.StsExpiration = 0
+		}
+		p.Mode = CloudSSO
+		p.CloudSSOSignInUrl = signInUrl
+		p.CloudSSOAccountId = target.accountId
+		p.CloudSSOAccessConfig = target.accessConfig
+		if p.RegionId == "" {
+			p.RegionId = region
+		}
+		conf.PutProfile(p)
+	}
+
+	profiles := make([]Profile, 0, len(conf.Profiles))
+	for _, p := range conf.Profiles {
+		if p.Mode == CloudSSO && p.CloudSSOSignInUrl == signInUrl {
+			if p.CloudSSOSynced && !wanted[p.Name] && !ctx.Flags().Get(NoPruneFlagName).IsAssigned() {
+				pruned = append(pruned, p.Name)
+				continue
+			}
+			// one login serves all profiles of the sign in url
+			p.AccessToken = accessToken.AccessToken
+			p.CloudSSOAccessTokenExpire = accessTokenExpire
+		}
+		profiles = append(profiles, p)
+	}
+	conf.Profiles = profiles
+	switchedFrom := ""
+	for _, name := range pruned {
+		if name != conf.CurrentProfile {
+			continue
+		}
+		// never switch to an arbitrary profile, commands would run with its credentials
+		if _, ok := conf.GetProfile(DefaultConfigProfileName); !ok {
+			return fmt.Errorf("the current profile %s is no longer accessible and there is no %s profile to switch to, switch to another profile with `aliyun configure switch` or pass --no-prune",
+				name, DefaultConfigProfileName)
+		}
+		switchedFrom = name
+		conf.CurrentProfile = DefaultConfigProfileName
+	}
+
+	if err := hookSaveConfigurationWithContext(SaveConfigurationWithContext)(ctx, conf); err != nil {
+		return fmt.Errorf("save configuration failed %v", err)
+	}
+	for _, name := range created {
+		cli.Printf(w, "created  %s\n", name)
+	}
+	for _, name := range updated {
+		cli.Printf(w, "updated  %s\n", name)
+	}
+	for _, name := range pruned {
+		cli.Printf(w, "pruned   %s\n", name)
+	}
+	cli.Printf(w, "%d profiles created, %d updated, %d pruned.\n", len(created), len(updated), len(pruned))
+	if switchedFrom != "" {
+		cli.Printf(w, "the current profile %s was pruned, switched to %s\n", switchedFrom, DefaultConfigProfileName)
+	}
+	return nil
+}
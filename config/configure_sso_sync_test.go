@@ -0,0 +1,219 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/cloudsso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSignInUrl = "https://signin-test.alibabacloudsso.com/device/login"
+
+// mockSso serves the accounts and their access configurations.
+func mockSso(t *testing.T, accounts []cloudsso.AccountDetailResponse, acs map[string][]cloudsso.AccessConfiguration) *int {
+	logins := 0
+	originGetAccessToken := cloudssoGetAccessToken
+	originListAllUsers := cloudssoListAllUsers
+	originListAllAccessConfigurations := cloudssoListAllAccessConfigurations
+	originGetCurrentUnixTime := utilGetCurrentUnixTime
+	originSave := hookSaveConfigurationWithContext
+	t.Cleanup(func() {
+		cloudssoGetAccessToken = originGetAccessToken
+		cloudssoListAllUsers = originListAllUsers
+		cloudssoListAllAccessConfigurations = originListAllAccessConfigurations
+		utilGetCurrentUnixTime = originGetCurrentUnixTime
+		hookSaveConfigurationWithContext = originSave
+	})
+	cloudssoGetAccessToken = func(ssoLogin *cloudsso.SsoLogin) (*cloudsso.AccessTokenResponse, error) {
+		logins++
+		assert.Equal(t, testSignInUrl, ssoLogin.SignInUrl)
+		return &cloudsso.AccessTokenResponse{AccessToken: "sso-token", ExpiresIn: 3600}, nil
+	}
+	cloudssoListAllUsers = func(userParam *cloudsso.ListUserParameter) ([]cloudsso.AccountDetailResponse, error) {
+		assert.Equal(t, "https://signin-test.alibabacloudsso.com", userParam.BaseUrl)
+		assert.Equal(t, "sso-token", userParam.AccessToken)
+		return accounts, nil
+	}
+	cloudssoListAllAccessConfigurations = func(accessParam *cloudsso.AccessConfigurationsParameter, req cloudsso.AccessConfigurationsRequest) ([]cloudsso.AccessConfiguration, error) {
+		return acs[req.AccountId], nil
+	}
+	utilGetCurrentUnixTime = func() int64 { return 1000 }
+	hookSaveConfigurationWithContext = func(fn func(ctx *cli.Context, config *Configuration) error) func(ctx *cli.Context, config *Configuration) error {
+		return fn
+	}
+	return &logins
+}
+
+func TestRenderProfileName(t *testing.T) {
+	account := cloudsso.AccountDetailResponse{AccountId: "1234", DisplayName: "Prod Account (CN)"}
+	ac := cloudsso.AccessConfiguration{AccessConfigurationId: "ac-1", AccessConfigurationName: "Admin"}
+	assert.Equal(t, "Prod-Account-CN-Admin", renderProfileName(DefaultProfileTemplate, account, ac))
+	assert.Equal(t, "sso.1234.ac-1", renderProfileName("sso.{accountId}.{accessConfigId}", account, ac))
+	assert.Equal(t, "1234-ac-1", renderProfileName(DefaultProfileTemplate, cloudsso.AccountDetailResponse{AccountId: "1234"},
+		cloudsso.AccessConfiguration{AccessConfigurationId: "ac-1"}))
+}
+
+func TestConfigureSsoSync(t *testing.T) {
+	logins := mockSso(t, []cloudsso.AccountDetailResponse{
+		{AccountId: "111", DisplayName: "prod"},
+		{AccountId: "222", DisplayName: "dev"},
+	}, map[string][]cloudsso.AccessConfiguration{
+		"111": {{AccessConfigurationId: "ac-admin", AccessConfigurationName: "Admin"}},
+		"222": {
+			{AccessConfigurationId: "ac-admin", AccessConfigurationName: "Admin"},
+			{AccessConfigurationId: "ac-ro", AccessConfigurationName: "ReadOnly"},
+		},
+	})
+
+	manual := Profile{Name: "manual", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "111",
+		CloudSSOAccessConfig: "ac-ro", AccessToken: "old", RegionId: "cn-beijing"}
+	existing := Profile{Name: "prod-Admin", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "111",
+		CloudSSOAccessConfig: "ac-admin", AccessKeyId: "STS.keep", StsToken: "keep", StsExpiration: 5000, RegionId: "cn-shanghai"}
+	gone := Profile{Name: "old-Admin", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "333",
+		CloudSSOAccessConfig: "ac-admin", CloudSSOSynced: true}
+	ctx := newConfigFileCtx(t, newStsProfile(), manual, existing, gone)
+	ctx.Flags().Add(NewProfileTemplateFlag())
+	ctx.Flags().Add(NewNoPruneFlag())
+	CloudSSOSignInUrlFlag(ctx.Flags()).SetAssigned(true)
+	CloudSSOSignInUrlFlag(ctx.Flags()).SetValue(testSignInUrl)
+
+	require.NoError(t, doConfigureSsoSync(ctx))
+	assert.Equal(t, 1, *logins)
+	assert.Equal(t, "created  dev-Admin\ncreated  dev-ReadOnly\nupdated  prod-Admin\npruned   old-Admin\n"+
+		"2 profiles created, 1 updated, 1 pruned.\n", ctx.Stdout().(*bytes.Buffer).String())
+
+	conf, err := LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	_, ok := conf.GetProfile("old-Admin")
+	assert.False(t, ok)
+
+	p, ok := conf.GetProfile("dev-ReadOnly")
+	require.True(t, ok)
+	assert.Equal(t, CloudSSO, p.Mode)
+	assert.Equal(t, "222", p.CloudSSOAccountId)
+	assert.Equal(t, "ac-ro", p.CloudSSOAccessConfig)
+	assert.Equal(t, "sso-token", p.AccessToken)
+	assert.Equal(t, int64(4600), p.CloudSSOAccessTokenExpire)
+	assert.Equal(t, "cn-hangzhou", p.RegionId)
+	assert.True(t, p.CloudSSOSynced)
+
+	// the STS credentials of the same role are kept, a profile configured by
+	// hand is not taken over by the sync
+	p, _ = conf.GetProfile("prod-Admin")
+	assert.Equal(t, "keep", p.StsToken)
+	assert.Equal(t, "cn-shanghai", p.RegionId)
+	assert.False(t, p.CloudSSOSynced)
+
+	// profiles configured by hand share the token but are never pruned
+	p, _ = conf.GetProfile("manual")
+	assert.Equal(t, "sso-token", p.AccessToken)
+	assert.False(t, p.CloudSSOSynced)
+	assert.Equal(t, "default", conf.CurrentProfile)
+}
+
+func TestConfigureSsoSyncNoPrune(t *testing.T) {
+	mockSso(t, []cloudsso.AccountDetailResponse{{AccountId: "111", DisplayName: "prod"}},
+		map[string][]cloudsso.AccessConfiguration{"111": {{AccessConfigurationId: "ac-admin", AccessConfigurationName: "Admin"}}})
+	gone := Profile{Name: "old-Admin", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "333",
+		CloudSSOAccessConfig: "ac-admin", CloudSSOSynced: true}
+	current := Profile{Name: "current", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "111",
+		CloudSSOAccessConfig: "ac-admin", RegionId: "cn-hangzhou"}
+	ctx := newConfigFileCtx(t, current, gone)
+	ctx.Flags().Add(NewProfileTemplateFlag())
+	ctx.Flags().Add(NewNoPruneFlag())
+	ctx.Flags().Get(NoPruneFlagName).SetAssigned(true)
+	ctx.Flags().Get(ProfileTemplateFlagName).SetAssigned(true)
+	ctx.Flags().Get(ProfileTemplateFlagName).SetValue("sso-{accountId}")
+
+	// the sign in url of the current profile
+	require.NoError(t, doConfigureSsoSync(ctx))
+	conf, err := LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	p, ok := conf.GetProfile("old-Admin")
+	require.True(t, ok)
+	assert.Equal(t, "sso-token", p.AccessToken)
+	_, ok = conf.GetProfile("sso-111")
+	assert.True(t, ok)
+}
+
+func TestConfigureSsoSyncPrunesCurrentProfile(t *testing.T) {
+	mockSso(t, []cloudsso.AccountDetailResponse{{AccountId: "111", DisplayName: "prod"}},
+		map[string][]cloudsso.AccessConfiguration{"111": {{AccessConfigurationId: "ac-admin", AccessConfigurationName: "Admin"}}})
+	gone := Profile{Name: "old-Admin", Mode: CloudSSO, CloudSSOSignInUrl: testSignInUrl, CloudSSOAccountId: "333",
+		CloudSSOAccessConfig: "ac-admin", CloudSSOSynced: true, RegionId: "cn-hangzhou"}
+	other := Profile{Name: "other", Mode: AK, AccessKeyId: "akid", AccessKeySecret: "secret", RegionId: "cn-hangzhou"}
+	ctx := newConfigFileCtx(t, gone, other)
+	ctx.Flags().Add(NewProfileTemplateFlag())
+	ctx.Flags().Add(NewNoPruneFlag())
+	conf, err := LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	conf.Profiles = []Profile{gone, other}
+	require.NoError(t, SaveConfigurationWithContext(ctx, conf))
+
+	// no default profile to fall back to, nothing is written
+	assert.EqualError(t, doConfigureSsoSync(ctx), "the current profile old-Admin is no longer accessible and there is no default profile to switch to, "+
+		"switch to another profile with `aliyun configure switch` or pass --no-prune")
+	conf, err = LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "old-Admin", conf.CurrentProfile)
+	assert.Len(t, conf.Profiles, 2)
+
+	conf.PutProfile(newStsProfile())
+	require.NoError(t, SaveConfigurationWithContext(ctx, conf))
+	require.NoError(t, doConfigureSsoSync(ctx))
+	assert.Contains(t, ctx.Stdout().(*bytes.Buffer).String(), "the current profile old-Admin was pruned, switched to default\n")
+	conf, err = LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "default", conf.CurrentProfile)
+	_, ok := conf.GetProfile("old-Admin")
+	assert.False(t, ok)
+}
+
+func TestConfigureSsoSyncConflicts(t *testing.T) {
+	mockSso(t, []cloudsso.AccountDetailResponse{{AccountId: "111", DisplayName: "prod"}},
+		map[string][]cloudsso.AccessConfiguration{"111": {
+			{AccessConfigurationId: "ac-admin", AccessConfigurationName: "Admin"},
+			{AccessConfigurationId: "ac-ro", AccessConfigurationName: "ReadOnly"},
+		}})
+	ctx := newConfigFileCtx(t, newStsProfile(), Profile{Name: "prod-Admin", Mode: AK, AccessKeyId: "akid", AccessKeySecret: "secret"})
+	ctx.Flags().Add(NewProfileTemplateFlag())
+	ctx.Flags().Add(NewNoPruneFlag())
+
+	assert.EqualError(t, doConfigureSsoSync(ctx), "missing --cloud-sso-sign-in-url <url>, the current profile is not a CloudSSO profile")
+
+	CloudSSOSignInUrlFlag(ctx.Flags()).SetAssigned(true)
+	CloudSSOSignInUrlFlag(ctx.Flags()).SetValue(testSignInUrl)
+	assert.EqualError(t, doConfigureSsoSync(ctx), "profile prod-Admin already exists and is not a CloudSSO profile of account 111 and access configuration ac-admin, use another --profile-template")
+
+	ctx.Flags().Get(ProfileTemplateFlagName).SetAssigned(true)
+	ctx.Flags().Get(ProfileTemplateFlagName).SetValue("{account}")
+	assert.EqualError(t, doConfigureSsoSync(ctx), `profile template "{account}" yields the name prod for both 111/ac-admin and 111/ac-ro, add {accountId} or {accessConfigId} to it`)
+
+	// nothing is written on errors
+	conf, err := LoadConfigurationWithContext(ctx)
+	require.NoError(t, err)
+	assert.Len(t, conf.Profiles, 2)
+
+	cloudssoListAllUsers = func(userParam *cloudsso.ListUserParameter) ([]cloudsso.AccountDetailResponse, error) {
+		return nil, errors.New("forbidden")
+	}
+	assert.EqualError(t, doConfigureSsoSync(ctx), "list account failed: forbidden")
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/aliyun/aliyun-cli/v3/util"
+)
+
+var (
+	profileEnvNames      = []string{"ALIBABACLOUD_PROFILE", "ALIBABA_CLOUD_PROFILE", "ALICLOUD_PROFILE"}
+	regionEnvNames       = []string{"ALIBABA_CLOUD_REGION_ID", "ALIBABACLOUD_REGION_ID", "ALICLOUD_REGION_ID", "REGION_ID", "REGION"}
+	endpointTypeEnvNames = []string{"ALIBABA_CLOUD_ENDPOINT_TYPE", "ALIBABACLOUD_ENDPOINT_TYPE", "ALICLOUD_ENDPOINT_TYPE", "ENDPOINT_TYPE"}
+)
+
+// settingSource is an effective setting and where its value came from.
+type settingSource struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+func NewConfigureWhereCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "where",
+		Usage: "where [--profile <profileName>] [--region <regionId>] [--endpoint-type <type>]",
+		Short: i18n.T("show where the effective settings come from", "显示当前生效配置的来源"),
+		Long: i18n.T(
+			`Show the effective profile, region, endpoint type, output format and safety policy in the current directory, and where each of them comes from. The project config is the first .aliyun.json found walking up from the current directory, set ALIBABA_CLOUD_IGNORE_PROJECT_CONFIG=true to skip it.
+Precedence, highest first:
+  profile        --profile, ALIBABA_CLOUD_PROFILE, project config, current profile of the config file
+  region         --region, project config, profile, ALIBABA_CLOUD_REGION_ID
+  endpoint type  --endpoint-type, project config, profile, ALIBABA_CLOUD_ENDPOINT_TYPE
+  output format  project config, profile
+  safety policy  ALIBABA_CLOUD_SAFETY_POLICY_ENABLED and ALIBABA_CLOUD_SAFETY_POLICY_RULES, project config, safety-policy.json
+The region, endpoint type and output format of the project config only apply to the profile it pins, or to the current profile when it pins none. They are ignored when --profile or ALIBABA_CLOUD_PROFILE chooses another profile, or when ALIBABA_CLOUD_IGNORE_PROFILE is set.
+The project config can only make the safety policy stricter: it can enable the policy and add rules, which are checked after those of safety-policy.json. Its "enabled": false and allow rules are ignored.`,
+			`显示当前目录下生效的 profile、地域、Endpoint 类型、输出格式和安全策略及其来源。项目配置为从当前目录向上查找到的第一个 .aliyun.json，设置 ALIBABA_CLOUD_IGNORE_PROJECT_CONFIG=true 可忽略。
+优先级从高到低：
+  profile        --profile、ALIBABA_CLOUD_PROFILE、项目配置、配置文件的当前 profile
+  地域           --region、项目配置、profile、ALIBABA_CLOUD_REGION_ID
+  Endpoint 类型  --endpoint-type、项目配置、profile、ALIBABA_CLOUD_ENDPOINT_TYPE
+  输出格式       项目配置、profile
+  安全策略       ALIBABA_CLOUD_SAFETY_POLICY_ENABLED 和 ALIBABA_CLOUD_SAFETY_POLICY_RULES、项目配置、safety-policy.json
+项目配置中的地域、Endpoint 类型和输出格式只作用于其指定的 profile，未指定时作用于当前 profile；通过 --profile 或 ALIBABA_CLOUD_PROFILE 选择其他 profile，或设置了 ALIBABA_CLOUD_IGNORE_PROFILE 时会被忽略。
+项目配置只能让安全策略更严格：可以启用策略和添加规则，其规则在 safety-policy.json 的规则之后匹配；其中的 "enabled": false 和 allow 规则会被忽略。`),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doConfigureWhere(ctx)
+		},
+	}
+	AddFlags(cmd.Flags())
+	return cmd
+}
+
+// lookupEnv returns the first of names that is set, and its value.
+func lookupEnv(names ...string) (string, string) {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return name, v
+		}
+	}
+	return "", ""
+}
+
+func whereSettings(ctx *cli.Context) ([]settingSource, error) {
+	project, err := LoadProjectConfig()
+	if err != nil {
+		return nil, err
+	}
+	configPath := getConfigurePath(ctx)
+	projectSource := "project " + project.Path()
+	settings := []settingSource{
+		{Name: "project config", Value: project.Path()},
+		{Name: "config file", Value: configPath},
+	}
+	if project == nil {
+		settings[0].Value = "-"
+	}
+
+	profile := settingSource{Name: "profile"}
+	var p Profile
+	// the project settings only apply to the profile it selects
+	selected := false
+	if util.GetFromEnv("ALIBABA_CLOUD_IGNORE_PROFILE", "ALIBABACLOUD_IGNORE_PROFILE") == "TRUE" {
+		p = NewProfile("default")
+		p.RegionId = "cn-hangzhou"
+		profile.Value, profile.Source = p.Name, "env ALIBABA_CLOUD_IGNORE_PROFILE"
+	} else {
+		conf, err := hookLoadOrCreateConfiguration(LoadOrCreateConfiguration)(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("init config failed %v", err)
+		}
+		if v, ok := ProfileFlag(ctx.Flags()).GetValue(); ok {
+			profile.Value, profile.Source = v, "flag --profile"
+		} else if name, v := lookupEnv(profileEnvNames...); v != "" {
+			profile.Value, profile.Source = v, "env "+name
+		} else if project != nil && project.Profile != "" {
+			profile.Value, profile.Source = project.Profile, projectSource
+		} else {
+			profile.Value, profile.Source = conf.CurrentProfile, "current profile of "+configPath
+		}
+		selected = project.selects(getProfileName(ctx))
+		var ok bool
+		if p, ok = conf.GetProfile(profile.Value); !ok {
+			profile.Value += " (not found)"
+		}
+	}
+	settings = append(settings, profile)
+	profileSource := "profile " + p.Name
+
+	region := settingSource{Name: "region"}
+	if v, ok := RegionFlag(ctx.Flags()).GetValue(); ok && v != "" {
+		region.Value, region.Source = v, "flag --region"
+	} else if selected && project.RegionId != "" {
+		region.Value, region.Source = project.RegionId, projectSource
+	} else if p.RegionId != "" {
+		region.Value, region.Source = p.RegionId, profileSource
+	} else if name, v := lookupEnv(regionEnvNames...); v != "" {
+		region.Value, region.Source = v, "env "+name
+	} else {
+		region.Value, region.Source = "-", "not set"
+	}
+	settings = append(settings, region)
+
+	endpointType := settingSource{Name: "endpoint type"}
+	if v, ok := EndpointTypeFlag(ctx.Flags()).GetValue(); ok && v != "" {
+		endpointType.Value, endpointType.Source = v, "flag --endpoint-type"
+	} else if selected && project.EndpointType != "" {
+		endpointType.Value, endpointType.Source = project.EndpointType, projectSource
+	} else if p.EndpointType != "" {
+		endpointType.Value, endpointType.Source = p.EndpointType, profileSource
+	} else if name, v := lookupEnv(endpointTypeEnvNames...); v != "" {
+		endpointType.Value, endpointType.Source = v, "env "+name
+	} else {
+		endpointType.Value, endpointType.Source = "-", "not set, public endpoints"
+	}
+	settings = append(settings, endpointType)
+
+	output := settingSource{Name: "output format"}
+	if selected && project.OutputFormat != "" {
+		output.Value, output.Source = project.OutputFormat, projectSource
+	} else if p.OutputFormat != "" {
+		output.Value, output.Source = p.OutputFormat, profileSource
+	} else {
+		output.Value, output.Source = "json", "default"
+	}
+	settings = append(settings, output)
+
+	return append(settings, whereSafetyPolicy(GetConfigDir(ctx), project, projectSource)...), nil
+}
+
+func whereSafetyPolicy(configDir string, project *ProjectConfig, projectSource string) []settingSource {
+	policyPath := safety.GetPolicyFilePath(configDir)
+	filePolicy, err := safety.LoadPolicy(configDir)
+	if err != nil {
+		return []settingSource{{Name: "safety policy", Value: "-", Source: err.Error()}}
+	}
+	fileSource := "default"
+	if _, err := os.Stat(policyPath); err == nil {
+		fileSource = "file " + policyPath
+	}
+	overrides := project.safetyOverrides()
+	effective := safety.MergePolicyFromEnv(overrides.Apply(filePolicy))
+
+	enabled := settingSource{Name: "safety policy", Value: "disabled"}
+	if effective.Enabled {
+		enabled.Value = "enabled"
+	}
+	if v, ok := os.LookupEnv(safety.EnvSafetyPolicyEnabled); ok && isParsableBool(v) {
+		enabled.Source = "env " + safety.EnvSafetyPolicyEnabled
+	} else if overrides.Enables(filePolicy) {
+		enabled.Source = projectSource
+	} else {
+		enabled.Source = fileSource
+	}
+
+	rules := settingSource{Name: "safety rules", Value: strconv.Itoa(len(effective.Rules))}
+	projectRules := len(overrides.StricterRules())
+	if _, ok := os.LookupEnv(safety.EnvSafetyPolicyRules); ok && !sameRules(effective.Rules, overrides.Apply(filePolicy).Rules) {
+		rules.Source = "env " + safety.EnvSafetyPolicyRules
+	} else if projectRules > 0 {
+		rules.Source = fmt.Sprintf("%d from %s, %d from %s", len(filePolicy.Rules), fileSource, projectRules, projectSource)
+	} else {
+		rules.Source = fileSource
+	}
+	return []settingSource{enabled, rules}
+}
+
+func isParsableBool(s string) bool {
+	_, err := strconv.ParseBool(strings.TrimSpace(s))
+	return err == nil
+}
+
+// sameRules tells whether the environment left the rules as they were,
+// MergePolicyFromEnv ignores an unparsable ALIBABA_CLOUD_SAFETY_POLICY_RULES.
+func sameRules(a, b []safety.Rule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Pattern != b[i].Pattern || a[i].Action != b[i].Action {
+			return false
+		}
+	}
+	return true
+}
+
+func doConfigureWhere(ctx *cli.Context) error {
+	settings, err := whereSettings(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(ctx.Stdout(), 8, 0, 1, ' ', 0)
+	fmt.Fprint(tw, "Setting\t| Value\t| Source\n")
+	fmt.Fprint(tw, "---------\t| ---------\t| ---------\n")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t| %s\t| %s\n", s.Name, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
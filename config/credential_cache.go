@@ -0,0 +1,295 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	credentialsv2 "github.com/aliyun/credentials-go/credentials"
+)
+
+// The STS credentials of role-assuming profiles are kept on disk, so that
+// consecutive invocations of the cli do not assume the role again. An entry
+// is keyed by the whole role chain, any change of a profile in the chain
+// yields another key.
+const (
+	credentialCacheDirName = "sts_cache"
+	// credentialCacheMargin is the time before the expiration from which an
+	// entry is no longer handed out.
+	credentialCacheMargin = 5 * time.Minute
+	// defaultCredentialDuration is the session duration STS grants when the
+	// profile does not specify expired_seconds.
+	defaultCredentialDuration = 3600
+)
+
+var credentialCacheNow = time.Now
+
+// credentialCacheTypes maps the cacheable modes to the credential type
+// GetCredential builds for them.
+var credentialCacheTypes = map[AuthenticateMode]string{
+	RamRoleArn:          "ram_role_arn",
+	RamRoleArnWithEcs:   "ram_role_arn",
+	ChainableRamRoleArn: "ram_role_arn",
+	RsaKeyPair:          "rsa_key_pair",
+	OIDC:                "oidc_role_arn",
+}
+
+type credentialCacheEntry struct {
+	Profile         string `json:"profile"`
+	Mode            string `json:"mode"`
+	AccessKeyId     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	SecurityToken   string `json:"sts_token"`
+	Expiration      int64  `json:"expiration"`
+}
+
+func (e *credentialCacheEntry) valid() bool {
+	return e != nil && e.SecurityToken != "" &&
+		credentialCacheNow().Add(credentialCacheMargin).Unix() < e.Expiration
+}
+
+func getCredentialCacheDir(ctx *cli.Context) string {
+	if ctx == nil {
+		return filepath.Join(GetConfigPath(), credentialCacheDirName)
+	}
+	return filepath.Join(GetConfigDir(ctx), credentialCacheDirName)
+}
+
+func isCredentialCacheDisabled(ctx *cli.Context) bool {
+	return ctx != nil && NoCredentialCacheFlag(ctx.Flags()).IsAssigned()
+}
+
+// credentialCacheKey hashes the profile together with its source profiles,
+// it returns false for profiles that are not cached.
+func (cp *Profile) credentialCacheKey() (string, bool) {
+	var chain []interface{}
+	visited := map[string]bool{}
+	p := cp
+	for {
+		if _, ok := credentialCacheTypes[p.Mode]; !ok {
+			return "", false
+		}
+		chain = append(chain, p, resolveSTSEndpoint(p))
+		if p.Mode != ChainableRamRoleArn {
+			break
+		}
+		if cp.parent == nil || visited[p.SourceProfile] {
+			return "", false
+		}
+		visited[p.SourceProfile] = true
+		source, ok := cp.parent.GetProfile(p.SourceProfile)
+		if !ok {
+			return "", false
+		}
+		if _, ok := credentialCacheTypes[source.Mode]; !ok {
+			// AK, StsToken and the other modes end the chain
+			chain = append(chain, source)
+			break
+		}
+		p = &source
+	}
+	data, err := json.Marshal(chain)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+func (cp *Profile) credentialDuration() int64 {
+	if cp.Mode != OIDC && cp.ExpiredSeconds > 0 {
+		return int64(cp.ExpiredSeconds)
+	}
+	return defaultCredentialDuration
+}
+
+func readCredentialCache(path string) *credentialCacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry credentialCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func writeCredentialCache(path string, entry *credentialCacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// cachedCredential hands out the cached STS credentials of a profile and
+// assumes the role only when they are about to expire.
+type cachedCredential struct {
+	mu       sync.Mutex
+	typeName string
+	path     string
+	profile  string
+	mode     AuthenticateMode
+	duration int64
+	entry    *credentialCacheEntry
+	build    func() (credentialsv2.Credential, error)
+	provider credentialsv2.Credential
+}
+
+// getCachedCredential returns nil when the profile is not cached. A cache
+// miss builds the credential at once, so configuration errors surface as
+// they do without the cache.
+func (cp *Profile) getCachedCredential(ctx *cli.Context, proxyHost *string) (credentialsv2.Credential, error) {
+	if isCredentialCacheDisabled(ctx) {
+		return nil, nil
+	}
+	// a locked configuration keeps its secrets off the disk
+	if cp.parent != nil && cp.parent.Encryption != nil {
+		return nil, nil
+	}
+	key, ok := cp.credentialCacheKey()
+	if !ok {
+		return nil, nil
+	}
+	cc := &cachedCredential{
+		typeName: credentialCacheTypes[cp.Mode],
+		path:     filepath.Join(getCredentialCacheDir(ctx), key+".json"),
+		profile:  cp.Name,
+		mode:     cp.Mode,
+		duration: cp.credentialDuration(),
+		build: func() (credentialsv2.Credential, error) {
+			return cp.newCredential(ctx, proxyHost)
+		},
+	}
+	if entry := readCredentialCache(cc.path); entry.valid() {
+		cc.entry = entry
+		return cc, nil
+	}
+	provider, err := cc.build()
+	if err != nil {
+		return nil, err
+	}
+	cc.provider = provider
+	return cc, nil
+}
+
+func (cc *cachedCredential) GetCredential() (*credentialsv2.CredentialModel, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if !cc.entry.valid() {
+		// another process may have assumed the role meanwhile
+		if entry := readCredentialCache(cc.path); entry.valid() {
+			cc.entry = entry
+		} else if err := cc.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	model := new(credentialsv2.CredentialModel).
+		SetAccessKeyId(cc.entry.AccessKeyId).
+		SetAccessKeySecret(cc.entry.AccessKeySecret).
+		SetSecurityToken(cc.entry.SecurityToken).
+		SetType(cc.typeName)
+	return model, nil
+}
+
+func (cc *cachedCredential) refresh() error {
+	if cc.provider == nil {
+		provider, err := cc.build()
+		if err != nil {
+			return err
+		}
+		cc.provider = provider
+	}
+	start := credentialCacheNow()
+	model, err := cc.provider.GetCredential()
+	if err != nil {
+		return err
+	}
+	entry := &credentialCacheEntry{
+		Profile:         cc.profile,
+		Mode:            string(cc.mode),
+		AccessKeyId:     tea.StringValue(model.AccessKeyId),
+		AccessKeySecret: tea.StringValue(model.AccessKeySecret),
+		SecurityToken:   tea.StringValue(model.SecurityToken),
+		Expiration:      start.Unix() + cc.duration,
+	}
+	if cc.entry != nil && cc.entry.SecurityToken == entry.SecurityToken {
+		// the provider still holds the old session, it renews it on its own
+		cc.entry.Expiration = credentialCacheNow().Add(credentialCacheMargin).Unix() + 1
+		return nil
+	}
+	cc.entry = entry
+	// the cache only saves round trips, failing to write it is not fatal
+	_ = writeCredentialCache(cc.path, entry)
+	return nil
+}
+
+func (cc *cachedCredential) GetAccessKeyId() (*string, error) {
+	model, err := cc.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return model.AccessKeyId, nil
+}
+
+func (cc *cachedCredential) GetAccessKeySecret() (*string, error) {
+	model, err := cc.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return model.AccessKeySecret, nil
+}
+
+func (cc *cachedCredential) GetSecurityToken() (*string, error) {
+	model, err := cc.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return model.SecurityToken, nil
+}
+
+func (cc *cachedCredential) GetBearerToken() *string {
+	return nil
+}
+
+func (cc *cachedCredential) GetType() *string {
+	return &cc.typeName
+}
+
+// expiration returns the expiration of the credentials last handed out.
+func (cc *cachedCredential) expiration() int64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.entry == nil {
+		return 0
+	}
+	return cc.entry.Expiration
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	credentialsv2 "github.com/aliyun/credentials-go/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoleProfile() *Profile {
+	p := newProfile()
+	p.Mode = RamRoleArn
+	p.AccessKeyId = "akid"
+	p.AccessKeySecret = "secret"
+	p.RamRoleArn = "acs:ram::123:role/test"
+	p.RoleSessionName = "session"
+	p.ExpiredSeconds = 900
+	return p
+}
+
+// countingCredential builds STS credentials that change on every build.
+func countingCredential(count *int) func() (credentialsv2.Credential, error) {
+	return func() (credentialsv2.Credential, error) {
+		*count++
+		config := new(credentialsv2.Config).SetType("sts").
+			SetAccessKeyId(fmt.Sprintf("STS.id%d", *count)).
+			SetAccessKeySecret("secret").
+			SetSecurityToken(fmt.Sprintf("token%d", *count))
+		return credentialsv2.NewCredential(config)
+	}
+}
+
+func TestCredentialCacheKey(t *testing.T) {
+	p := newRoleProfile()
+	key, ok := p.credentialCacheKey()
+	assert.True(t, ok)
+	assert.Len(t, key, 64)
+
+	same := newRoleProfile()
+	sameKey, _ := same.credentialCacheKey()
+	assert.Equal(t, key, sameKey)
+
+	same.RoleSessionName = "other"
+	otherKey, _ := same.credentialCacheKey()
+	assert.NotEqual(t, key, otherKey)
+
+	ak := newProfile()
+	ak.Mode = AK
+	_, ok = ak.credentialCacheKey()
+	assert.False(t, ok)
+
+	// the key of a chained profile follows its source profiles
+	cf := NewConfiguration()
+	source := newRoleProfile()
+	source.Name = "source"
+	cf.PutProfile(*source)
+	chained := newRoleProfile()
+	chained.Name = "chained"
+	chained.Mode = ChainableRamRoleArn
+	chained.SourceProfile = "source"
+	chained.parent = cf
+	key, ok = chained.credentialCacheKey()
+	assert.True(t, ok)
+	source.AccessKeySecret = "rotated"
+	cf.PutProfile(*source)
+	otherKey, ok = chained.credentialCacheKey()
+	assert.True(t, ok)
+	assert.NotEqual(t, key, otherKey)
+
+	chained.SourceProfile = "missing"
+	_, ok = chained.credentialCacheKey()
+	assert.False(t, ok)
+
+	// a cycle is never cached
+	cf.PutProfile(Profile{Name: "loop", Mode: ChainableRamRoleArn, SourceProfile: "loop"})
+	chained.SourceProfile = "loop"
+	_, ok = chained.credentialCacheKey()
+	assert.False(t, ok)
+}
+
+func TestCachedCredential(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	origin := credentialCacheNow
+	credentialCacheNow = func() time.Time { return now }
+	defer func() { credentialCacheNow = origin }()
+
+	dir := t.TempDir()
+	count := 0
+	newCached := func() *cachedCredential {
+		return &cachedCredential{
+			typeName: "ram_role_arn",
+			path:     filepath.Join(dir, credentialCacheDirName, "key.json"),
+			profile:  "default",
+			mode:     RamRoleArn,
+			duration: 900,
+			build:    countingCredential(&count),
+		}
+	}
+
+	cc := newCached()
+	model, err := cc.GetCredential()
+	require.NoError(t, err)
+	assert.Equal(t, "STS.id1", *model.AccessKeyId)
+	assert.Equal(t, "token1", *model.SecurityToken)
+	assert.Equal(t, "ram_role_arn", *cc.GetType())
+
+	info, err := os.Stat(cc.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	entry := readCredentialCache(cc.path)
+	assert.Equal(t, now.Unix()+900, entry.Expiration)
+	assert.Equal(t, "RamRoleArn", entry.Mode)
+
+	// another invocation reads the file without assuming the role
+	model, err = newCached().GetCredential()
+	require.NoError(t, err)
+	assert.Equal(t, "token1", *model.SecurityToken)
+	assert.Equal(t, 1, count)
+
+	// shortly before the expiration the role is assumed again
+	now = now.Add(900*time.Second - credentialCacheMargin)
+	token, err := newCached().GetSecurityToken()
+	require.NoError(t, err)
+	assert.Equal(t, "token2", *token)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "token2", readCredentialCache(cc.path).SecurityToken)
+}
+
+func TestGetCachedCredential(t *testing.T) {
+	configDir := t.TempDir()
+	ctx := newConfigPathCtx(filepath.Join(configDir, "config.json"))
+	dir := filepath.Join(configDir, credentialCacheDirName)
+	p := newRoleProfile()
+	key, _ := p.credentialCacheKey()
+	path := filepath.Join(dir, key+".json")
+	require.NoError(t, writeCredentialCache(path, &credentialCacheEntry{
+		Profile:         "default",
+		Mode:            "RamRoleArn",
+		AccessKeyId:     "STS.cached",
+		AccessKeySecret: "cached-secret",
+		SecurityToken:   "cached-token",
+		Expiration:      time.Now().Add(time.Hour).Unix(),
+	}))
+
+	cred, err := p.GetCredential(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ram_role_arn", *cred.GetType())
+	model, err := cred.GetCredential()
+	require.NoError(t, err)
+	assert.Equal(t, "STS.cached", *model.AccessKeyId)
+	assert.Equal(t, "cached-secret", *model.AccessKeySecret)
+	assert.Equal(t, "cached-token", *model.SecurityToken)
+
+	// the cache is not used once the profile changes
+	changed := newRoleProfile()
+	changed.RamRoleArn = "acs:ram::123:role/other"
+	cred, err = changed.GetCredential(ctx, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &cachedCredential{}, cred)
+	assert.Nil(t, cred.(*cachedCredential).entry)
+	assert.NotNil(t, cred.(*cachedCredential).provider)
+
+	// an expired entry builds the credential at once
+	require.NoError(t, writeCredentialCache(path, &credentialCacheEntry{
+		SecurityToken: "expired",
+		Expiration:    time.Now().Add(time.Minute).Unix(),
+	}))
+	cred, err = p.GetCredential(ctx, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, cred.(*cachedCredential).provider)
+
+	// modes without an assumed role are not cached
+	ak := newProfile()
+	ak.Mode = StsToken
+	ak.AccessKeyId = "akid"
+	ak.AccessKeySecret = "secret"
+	ak.StsToken = "token"
+	cred, err = ak.GetCredential(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "sts", *cred.GetType())
+	_, isCached := cred.(*cachedCredential)
+	assert.False(t, isCached)
+}
+
+func TestGetCachedCredentialDisabled(t *testing.T) {
+	configDir := t.TempDir()
+	ctx := newConfigPathCtx(filepath.Join(configDir, "config.json"))
+	dir := filepath.Join(configDir, credentialCacheDirName)
+	p := newRoleProfile()
+	key, _ := p.credentialCacheKey()
+	require.NoError(t, writeCredentialCache(filepath.Join(dir, key+".json"), &credentialCacheEntry{
+		SecurityToken: "cached-token",
+		Expiration:    time.Now().Add(time.Hour).Unix(),
+	}))
+
+	NoCredentialCacheFlag(ctx.Flags()).SetAssigned(true)
+	cred, err := p.GetCredential(ctx, nil)
+	require.NoError(t, err)
+	_, isCached := cred.(*cachedCredential)
+	assert.False(t, isCached)
+	assert.Equal(t, "ram_role_arn", *cred.GetType())
+
+	// a locked configuration does not write secrets to the cache
+	ctx = newConfigPathCtx(filepath.Join(t.TempDir(), "config.json"))
+	p.parent = &Configuration{Encryption: &Encryption{}}
+	cred, err = p.getCachedCredential(ctx, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, cred)
+}
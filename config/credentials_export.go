@@ -0,0 +1,243 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+)
+
+const (
+	ExportFormatEnv               = "env"
+	ExportFormatJSON              = "json"
+	ExportFormatCredentialProcess = "credential-process"
+
+	ExportFormatFlagName = "format"
+)
+
+const credentialsTimeFormat = "2006-01-02T15:04:05Z"
+
+// exportedCredentials are the resolved credentials of a profile. Expiration
+// is zero when it is unknown, or when the credentials do not expire.
+type exportedCredentials struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	SecurityToken   string
+	Expiration      time.Time
+}
+
+// credentialsURIResponse is the body a CredentialsURI endpoint answers with.
+type credentialsURIResponse struct {
+	Code            string `json:"Code"`
+	AccessKeyId     string `json:"AccessKeyId"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	SecurityToken   string `json:"SecurityToken"`
+	Expiration      string `json:"Expiration"`
+}
+
+// credentialProcessOutput is the profile json printed for the process_command
+// of an External profile.
+type credentialProcessOutput struct {
+	Mode            AuthenticateMode `json:"mode"`
+	AccessKeyId     string           `json:"access_key_id"`
+	AccessKeySecret string           `json:"access_key_secret"`
+	StsToken        string           `json:"sts_token,omitempty"`
+	Expiration      string           `json:"expiration,omitempty"`
+}
+
+// resolveCredentials resolves the access key of any mode but Anonymous and
+// BearerToken.
+func (cp *Profile) resolveCredentials(ctx *cli.Context) (*exportedCredentials, error) {
+	if cp.Mode == Anonymous || cp.Mode == BearerToken {
+		return nil, fmt.Errorf("profile %s of mode %s has no access key credentials", cp.Name, cp.Mode)
+	}
+	cred, err := cp.GetCredential(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	model, err := cred.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	c := &exportedCredentials{
+		AccessKeyId:     tea.StringValue(model.AccessKeyId),
+		AccessKeySecret: tea.StringValue(model.AccessKeySecret),
+		SecurityToken:   tea.StringValue(model.SecurityToken),
+	}
+	if cached, ok := cred.(*cachedCredential); ok {
+		if expiration := cached.expiration(); expiration > 0 {
+			c.Expiration = time.Unix(expiration, 0)
+		}
+	} else if (cp.Mode == CloudSSO || cp.Mode == OAuth) && cp.StsExpiration > 0 {
+		c.Expiration = time.Unix(cp.StsExpiration, 0)
+	}
+	return c, nil
+}
+
+// expirationString is the expiration to hand out. Clients of a
+// CredentialsURI need one, when it is unknown, or the credentials do not
+// expire, they are told to come back after unknownExpirationTTL.
+func (c *exportedCredentials) expirationString() string {
+	expiration := c.Expiration
+	if expiration.IsZero() {
+		expiration = credentialCacheNow().Add(unknownExpirationTTL)
+	}
+	return expiration.UTC().Format(credentialsTimeFormat)
+}
+
+func (c *exportedCredentials) uriResponse() *credentialsURIResponse {
+	return &credentialsURIResponse{
+		Code:            "Success",
+		AccessKeyId:     c.AccessKeyId,
+		AccessKeySecret: c.AccessKeySecret,
+		SecurityToken:   c.SecurityToken,
+		Expiration:      c.expirationString(),
+	}
+}
+
+func (c *exportedCredentials) env(regionId string) map[string]string {
+	envs := map[string]string{
+		"ALIBABA_CLOUD_ACCESS_KEY_ID":     c.AccessKeyId,
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET": c.AccessKeySecret,
+	}
+	if c.SecurityToken != "" {
+		envs["ALIBABA_CLOUD_SECURITY_TOKEN"] = c.SecurityToken
+	}
+	if regionId != "" {
+		envs["ALIBABA_CLOUD_REGION_ID"] = regionId
+	}
+	return envs
+}
+
+func checkExportFormat(format string) error {
+	switch format {
+	case ExportFormatEnv, ExportFormatJSON, ExportFormatCredentialProcess:
+		return nil
+	}
+	return fmt.Errorf("invalid format %s, supported: %s, %s, %s", format,
+		ExportFormatEnv, ExportFormatJSON, ExportFormatCredentialProcess)
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeExportedCredentials(w io.Writer, format string, profile *Profile, c *exportedCredentials) error {
+	var v interface{}
+	switch format {
+	case ExportFormatEnv:
+		envs := c.env(profile.RegionId)
+		keys := make([]string, 0, len(envs))
+		for k := range envs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			cli.Printf(w, "export %s=%s\n", k, shellQuote(envs[k]))
+		}
+		return nil
+	case ExportFormatJSON:
+		v = c.uriResponse()
+	case ExportFormatCredentialProcess:
+		out := &credentialProcessOutput{
+			Mode:            AK,
+			AccessKeyId:     c.AccessKeyId,
+			AccessKeySecret: c.AccessKeySecret,
+		}
+		if c.SecurityToken != "" {
+			out.Mode = StsToken
+			out.StsToken = c.SecurityToken
+			out.Expiration = c.expirationString()
+		}
+		v = out
+	default:
+		return checkExportFormat(format)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	cli.Println(w, string(data))
+	return nil
+}
+
+func NewExportFormatFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         ExportFormatFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--format env|json|credential-process` to choose the output, default env",
+			"使用 `--format env|json|credential-process` 指定输出格式，默认 env"),
+	}
+}
+
+func NewConfigureExportCredentialsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "export-credentials",
+		Usage: "export-credentials [--profile <profileName>] [--format env|json|credential-process]",
+		Short: i18n.T("print the resolved credentials of a profile", "打印 profile 解析后的凭证"),
+		Long: i18n.T(
+			`Resolve the credentials of a profile of any mode, including CloudSSO, OAuth and role-assuming profiles, and print them for other tools:
+  env                 shell export lines of ALIBABA_CLOUD_ACCESS_KEY_ID, ALIBABA_CLOUD_ACCESS_KEY_SECRET, ALIBABA_CLOUD_SECURITY_TOKEN and ALIBABA_CLOUD_REGION_ID, e.g. eval "$(aliyun configure export-credentials --profile prod)"
+  json                the body of a CredentialsURI endpoint
+  credential-process  the json the process_command of an External profile prints
+The output contains secrets, do not write it to logs.`,
+			`解析任意模式 profile 的凭证（包括 CloudSSO、OAuth 和角色扮演的 profile），并按以下格式打印，供其他工具使用：
+  env                 ALIBABA_CLOUD_ACCESS_KEY_ID、ALIBABA_CLOUD_ACCESS_KEY_SECRET、ALIBABA_CLOUD_SECURITY_TOKEN 和 ALIBABA_CLOUD_REGION_ID 的 shell export 语句，例如 eval "$(aliyun configure export-credentials --profile prod)"
+  json                CredentialsURI 接口返回的内容
+  credential-process  External 模式 profile 的 process_command 应输出的 json
+输出包含敏感信息，请勿写入日志。`),
+		Sample: "aliyun configure export-credentials --profile prod --format credential-process",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doConfigureExportCredentials(ctx)
+		},
+	}
+	AddFlags(cmd.Flags())
+	cmd.Flags().Add(NewExportFormatFlag())
+	return cmd
+}
+
+func doConfigureExportCredentials(ctx *cli.Context) error {
+	format := ExportFormatEnv
+	if v, ok := ctx.Flags().GetValue(ExportFormatFlagName); ok {
+		format = v
+	}
+	// fail before any credentials are resolved
+	if err := checkExportFormat(format); err != nil {
+		return err
+	}
+	profile, err := LoadProfileWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("config failed: %s", err.Error())
+	}
+	c, err := profile.resolveCredentials(ctx)
+	if err != nil {
+		return err
+	}
+	return writeExportedCredentials(ctx.Stdout(), format, &profile, c)
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteExportedCredentials(t *testing.T) {
+	profile := &Profile{Name: "prod", RegionId: "cn-beijing"}
+	c := &exportedCredentials{
+		AccessKeyId:     "STS.id",
+		AccessKeySecret: "it's secret",
+		SecurityToken:   "token",
+		Expiration:      time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC),
+	}
+
+	w := new(bytes.Buffer)
+	require.NoError(t, writeExportedCredentials(w, ExportFormatEnv, profile, c))
+	assert.Equal(t, "export ALIBABA_CLOUD_ACCESS_KEY_ID='STS.id'\n"+
+		"export ALIBABA_CLOUD_ACCESS_KEY_SECRET='it'\\''s secret'\n"+
+		"export ALIBABA_CLOUD_REGION_ID='cn-beijing'\n"+
+		"export ALIBABA_CLOUD_SECURITY_TOKEN='token'\n", w.String())
+
+	w.Reset()
+	require.NoError(t, writeExportedCredentials(w, ExportFormatJSON, profile, c))
+	assert.JSONEq(t, `{"Code":"Success","AccessKeyId":"STS.id","AccessKeySecret":"it's secret",
+		"SecurityToken":"token","Expiration":"2026-01-01T08:00:00Z"}`, w.String())
+
+	w.Reset()
+	require.NoError(t, writeExportedCredentials(w, ExportFormatCredentialProcess, profile, c))
+	assert.JSONEq(t, `{"mode":"StsToken","access_key_id":"STS.id","access_key_secret":"it's secret",
+		"sts_token":"token","expiration":"2026-01-01T08:00:00Z"}`, w.String())
+
+	w.Reset()
+	ak := &exportedCredentials{AccessKeyId: "akid", AccessKeySecret: "secret"}
+	require.NoError(t, writeExportedCredentials(w, ExportFormatCredentialProcess, profile, ak))
+	assert.JSONEq(t, `{"mode":"AK","access_key_id":"akid","access_key_secret":"secret"}`, w.String())
+
+	// an access key does not expire, but a CredentialsURI body always has one
+	w.Reset()
+	require.NoError(t, writeExportedCredentials(w, ExportFormatJSON, profile, ak))
+	var resp credentialsURIResponse
+	require.NoError(t, json.Unmarshal(w.Bytes(), &resp))
+	_, err := time.Parse(credentialsTimeFormat, resp.Expiration)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, writeExportedCredentials(w, "yaml", profile, c),
+		"invalid format yaml, supported: env, json, credential-process")
+}
+
+func TestResolveCredentials(t *testing.T) {
+	ctx := newCtx()
+	p := newStsProfile()
+	c, err := p.resolveCredentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "STS.id", c.AccessKeyId)
+	assert.Equal(t, "token", c.SecurityToken)
+	assert.True(t, c.Expiration.IsZero())
+
+	p.Mode = CloudSSO
+	p.CloudSSOSignInUrl = "https://signin"
+	p.CloudSSOAccountId = "123"
+	p.CloudSSOAccessConfig = "ac-1"
+	p.AccessToken = "access-token"
+	p.CloudSSOAccessTokenExpire = time.Now().Add(time.Hour).Unix()
+	p.StsExpiration = time.Now().Add(time.Hour).Unix()
+	c, err = p.resolveCredentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, p.StsExpiration, c.Expiration.Unix())
+
+	p.Mode = Anonymous
+	_, err = p.resolveCredentials(ctx)
+	assert.EqualError(t, err, "profile default of mode Anonymous has no access key credentials")
+}
+
+func TestConfigureExportCredentials(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	origin := credentialCacheNow
+	credentialCacheNow = func() time.Time { return now }
+	defer func() { credentialCacheNow = origin }()
+
+	ctx := newConfigFileCtx(t, newStsProfile())
+	ctx.Flags().Add(NewExportFormatFlag())
+	stdout := ctx.Stdout().(*bytes.Buffer)
+
+	ctx.Flags().Get(ExportFormatFlagName).SetAssigned(true)
+	ctx.Flags().Get(ExportFormatFlagName).SetValue("json")
+	require.NoError(t, doConfigureExportCredentials(ctx))
+	// the expiration is unknown, clients are told to come back
+	assert.JSONEq(t, `{"Code":"Success","AccessKeyId":"STS.id","AccessKeySecret":"secret","SecurityToken":"token",
+		"Expiration":"2026-01-01T00:15:00Z"}`, stdout.String())
+
+	ctx.Flags().Get(ExportFormatFlagName).SetValue("xml")
+	assert.EqualError(t, doConfigureExportCredentials(ctx),
+		"invalid format xml, supported: env, json, credential-process")
+}
+
+func TestCredentialsSource(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	origin := credentialCacheNow
+	credentialCacheNow = func() time.Time { return now }
+	defer func() { credentialCacheNow = origin }()
+
+	count := 0
+	source := &credentialsSource{}
+	source.resolve = func() (*exportedCredentials, error) {
+		count++
+		return &exportedCredentials{AccessKeyId: "id", SecurityToken: "token", Expiration: now.Add(time.Hour)}, nil
+	}
+	_, err := source.get()
+	require.NoError(t, err)
+	_, err = source.get()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// shortly before the expiration the credentials are resolved again
+	now = now.Add(time.Hour - credentialCacheMargin)
+	_, err = source.get()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// STS credentials of unknown expiration are resolved on every request
+	source.resolve = func() (*exportedCredentials, error) {
+		count++
+		return &exportedCredentials{AccessKeyId: "id", SecurityToken: "token"}, nil
+	}
+	source.current = nil
+	source.get()
+	source.get()
+	assert.Equal(t, 4, count)
+}
+
+func TestCredentialsHandler(t *testing.T) {
+	source := &credentialsSource{}
+	source.resolve = func() (*exportedCredentials, error) {
+		return &exportedCredentials{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"}, nil
+	}
+	handler := credentialsHandler(source, "/secret", "")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/secret", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"SecurityToken":"token"`)
+	// the expiration is required by the clients
+	assert.Contains(t, w.Body.String(), `"Expiration":"`)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/secret", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	source = &credentialsSource{resolve: func() (*exportedCredentials, error) {
+		return nil, errors.New("assume role failed")
+	}}
+	w = httptest.NewRecorder()
+	credentialsHandler(source, "/secret", "").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/secret", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"Code":"Failed","Message":"assume role failed"}`, w.Body.String())
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+)
+
+const (
+	ListenFlagName    = "listen"
+	AuthTokenFlagName = "auth-token"
+
+	defaultCredentialsListen = "127.0.0.1:0"
+	unixSocketPrefix         = "unix:"
+)
+
+// unknownExpirationTTL is the expiration handed out for credentials whose
+// expiration is unknown or that do not expire, clients come back for them
+// after about this time.
+const unknownExpirationTTL = 15 * time.Minute
+
+// credentialsSource resolves the credentials of a profile again shortly
+// before they expire.
+type credentialsSource struct {
+	mu      sync.Mutex
+	ctx     *cli.Context
+	profile *Profile
+	current *exportedCredentials
+	resolve func() (*exportedCredentials, error)
+}
+
+func newCredentialsSource(ctx *cli.Context, profile *Profile) *credentialsSource {
+	s := &credentialsSource{ctx: ctx, profile: profile}
+	s.resolve = func() (*exportedCredentials, error) {
+		return s.profile.resolveCredentials(s.ctx)
+	}
+	return s
+}
+
+func (s *credentialsSource) fresh() bool {
+	c := s.current
+	if c == nil {
+		return false
+	}
+	if c.Expiration.IsZero() {
+		// an access key does not expire, STS credentials of unknown
+		// expiration are resolved on every request
+		return c.SecurityToken == ""
+	}
+	return credentialCacheNow().Add(credentialCacheMargin).Before(c.Expiration)
+}
+
+func (s *credentialsSource) get() (*exportedCredentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.fresh() {
+		c, err := s.resolve()
+		if err != nil {
+			return nil, err
+		}
+		s.current = c
+	}
+	return s.current, nil
+}
+
+// authorized tells whether r carries token, either as a bearer token or as
+// the token query parameter, the SDKs can only be given a uri.
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	got := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		got = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// credentialsHandler answers GET requests of path with the body of a
+// CredentialsURI endpoint, requests without token are rejected when token is
+// not empty.
+func credentialsHandler(source *credentialsSource, path string, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		c, err := source.get()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "Failed", "Message": err.Error()})
+			return
+		}
+		resp := c.uriResponse()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// loopbackHostOnly rejects requests whose Host header is not a loopback
+// address or localhost. Without a token, it keeps a web page that rebinds its
+// own domain to 127.0.0.1 from reading the credentials.
+func loopbackHostOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !isLoopbackHost(host) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// startLoopbackCredentialsServer serves the source on a random port of the
+// loopback interface under a random path, which only the child processes
+// given the uri know.
+func startLoopbackCredentialsServer(source *credentialsSource) (uri string, stop func(), err error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	path := "/" + token
+	server := &http.Server{Handler: credentialsHandler(source, path, "")}
+	go server.Serve(listener)
+	return "http://" + listener.Addr().String() + path, func() { server.Close() }, nil
+}
+
+func NewListenFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         ListenFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--listen <host:port>` or `--listen unix:<path>` to set the address to listen on, default a random port of 127.0.0.1",
+			"使用 `--listen <host:port>` 或 `--listen unix:<path>` 指定监听地址，默认为 127.0.0.1 的随机端口"),
+	}
+}
+
+func NewAuthTokenFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         AuthTokenFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--auth-token <token>` to require the token as bearer token or as token query parameter, required for addresses other than loopback",
+			"使用 `--auth-token <token>` 要求请求通过 Bearer Token 或 token 查询参数携带该令牌，监听非回环地址时必须指定"),
+	}
+}
+
+func NewConfigureServeCredentialsCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "serve-credentials",
+		Usage: "serve-credentials [--profile <profileName>] [--listen <host:port>|unix:<path>] [--auth-token <token>]",
+		Short: i18n.T("serve the credentials of a profile for the CredentialsURI mode", "为 CredentialsURI 模式提供 profile 的凭证"),
+		Long: i18n.T(
+			`Serve the credentials of a profile of any mode on an HTTP endpoint, in the json the CredentialsURI mode of the cli and the ALIBABA_CLOUD_CREDENTIALS_URI of the Alibaba Cloud SDKs expect. STS credentials are refreshed before they expire, so containers and local services can use the short-lived credentials of a CloudSSO or OAuth login without a copy of any secret.
+With --auth-token, a request must carry the token in the header "Authorization: Bearer <token>", or in the token query parameter, e.g. http://host.docker.internal:8780/?token=<token>. Listening on an address other than loopback requires --auth-token. Without --auth-token, requests whose Host header is not localhost or a loopback address are rejected, so a web page cannot read the credentials through DNS rebinding.
+The server runs until it is interrupted.`,
+			`通过 HTTP 接口提供任意模式 profile 的凭证，返回格式与 CLI 的 CredentialsURI 模式及阿里云 SDK 的 ALIBABA_CLOUD_CREDENTIALS_URI 一致。STS 凭证在过期前自动刷新，容器和本地服务无需复制任何密钥即可使用 CloudSSO 或 OAuth 登录获得的短期凭证。
+指定 --auth-token 后，请求须在请求头 "Authorization: Bearer <token>" 或 token 查询参数中携带该令牌，例如 http://host.docker.internal:8780/?token=<token>。监听非回环地址时必须指定 --auth-token。未指定 --auth-token 时，Host 请求头不是 localhost 或回环地址的请求会被拒绝，以防网页通过 DNS 重绑定读取凭证。
+服务持续运行直至被中断。`),
+		Sample: "aliyun configure serve-credentials --profile dev --listen 127.0.0.1:8780",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doConfigureServeCredentials(ctx)
+		},
+	}
+	AddFlags(cmd.Flags())
+	cmd.Flags().Add(NewListenFlag())
+	cmd.Flags().Add(NewAuthTokenFlag())
+	return cmd
+}
+
+// isLoopbackAddress tells whether the host of a tcp address is loopback,
+// unix sockets are guarded by the permissions of the file.
+func isLoopbackAddress(address string) bool {
+	if strings.HasPrefix(address, unixSocketPrefix) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	return isLoopbackHost(host)
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenCredentials listens on a tcp address or on unix:<path>, it returns
+// the uri clients fetch the credentials from.
+func listenCredentials(address string) (net.Listener, string, error) {
+	if path, ok := strings.CutPrefix(address, unixSocketPrefix); ok {
+		// a socket left by a server that did not shut down cleanly
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return nil, "", err
+		}
+		return listener, address, nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, "", err
+	}
+	return listener, "http://" + listener.Addr().String() + "/", nil
+}
+
+func doConfigureServeCredentials(ctx *cli.Context) error {
+	address := defaultCredentialsListen
+	if v, ok := ctx.Flags().GetValue(ListenFlagName); ok && v != "" {
+		address = v
+	}
+	token, _ := ctx.Flags().GetValue(AuthTokenFlagName)
+	if token == "" && !isLoopbackAddress(address) {
+		return fmt.Errorf("listening on %s requires --auth-token, the credentials would be readable by anyone who reaches it", address)
+	}
+	profile, err := LoadProfileWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("config failed: %s", err.Error())
+	}
+	source := newCredentialsSource(ctx, &profile)
+	// fail at once on a profile that cannot resolve credentials
+	if _, err := source.get(); err != nil {
+		return cli.NewErrorWithTip(err, fmt.Sprintf("profile %q: failed to resolve credentials", profile.Name))
+	}
+
+	listener, uri, err := listenCredentials(address)
+	if err != nil {
+		return err
+	}
+	handler := credentialsHandler(source, "/", token)
+	if token == "" && !strings.HasPrefix(address, unixSocketPrefix) {
+		handler = loopbackHostOnly(handler)
+	}
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	cli.Printf(ctx.Stderr(), "Serving the credentials of profile %s at %s, press Ctrl+C to stop\n", profile.Name, uri)
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
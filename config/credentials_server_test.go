@@ -0,0 +1,155 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLoopbackAddress(t *testing.T) {
+	assert.True(t, isLoopbackAddress("127.0.0.1:8780"))
+	assert.True(t, isLoopbackAddress("[::1]:8780"))
+	assert.True(t, isLoopbackAddress("localhost:8780"))
+	assert.True(t, isLoopbackAddress("unix:/tmp/credentials.sock"))
+	assert.False(t, isLoopbackAddress("0.0.0.0:8780"))
+	assert.False(t, isLoopbackAddress(":8780"))
+	assert.False(t, isLoopbackAddress("192.168.1.2:8780"))
+	assert.False(t, isLoopbackAddress("8780"))
+}
+
+func TestCredentialsHandlerAuthToken(t *testing.T) {
+	source := &credentialsSource{resolve: func() (*exportedCredentials, error) {
+		return &exportedCredentials{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"}, nil
+	}}
+	handler := credentialsHandler(source, "/", "s3cret")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token=s3cret", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body credentialsURIResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Success", body.Code)
+	assert.Equal(t, "token", body.SecurityToken)
+}
+
+func TestLoopbackHostOnly(t *testing.T) {
+	source := &credentialsSource{resolve: func() (*exportedCredentials, error) {
+		return &exportedCredentials{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"}, nil
+	}}
+	handler := loopbackHostOnly(credentialsHandler(source, "/", ""))
+
+	for _, host := range []string{"127.0.0.1:8780", "localhost:8780", "LOCALHOST", "[::1]:8780", "127.0.0.2"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, host)
+	}
+	for _, host := range []string{"attacker.example:8780", "attacker.example", "localhost.attacker.example:8780", "192.168.1.2:8780", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code, host)
+		assert.Empty(t, w.Body.String(), host)
+	}
+}
+
+func TestListenCredentials(t *testing.T) {
+	listener, uri, err := listenCredentials("127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	assert.Equal(t, "http://"+listener.Addr().String()+"/", uri)
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// the path of a socket is limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "cred")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.sock")
+
+	source := &credentialsSource{resolve: func() (*exportedCredentials, error) {
+		return &exportedCredentials{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"}, nil
+	}}
+	unixListener, uri, err := listenCredentials("unix:" + path)
+	require.NoError(t, err)
+	assert.Equal(t, "unix:"+path, uri)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	server := &http.Server{Handler: credentialsHandler(source, "/", "")}
+	go server.Serve(unixListener)
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	server.Close()
+
+	// a socket left behind is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	// closing a listener removes its socket, keep the file
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	unixListener, _, err = listenCredentials("unix:" + path)
+	require.NoError(t, err)
+	unixListener.Close()
+}
+
+func TestConfigureServeCredentialsRequiresToken(t *testing.T) {
+	ctx := newConfigFileCtx(t, newStsProfile())
+	ctx.Flags().Add(NewListenFlag())
+	ctx.Flags().Add(NewAuthTokenFlag())
+	ctx.Flags().Get(ListenFlagName).SetAssigned(true)
+	ctx.Flags().Get(ListenFlagName).SetValue("0.0.0.0:8780")
+	assert.EqualError(t, doConfigureServeCredentials(ctx),
+		"listening on 0.0.0.0:8780 requires --auth-token, the credentials would be readable by anyone who reaches it")
+}
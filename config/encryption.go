@@ -0,0 +1,290 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EnvConfigPassphrase and EnvConfigKeyFile supply the passphrase of an
+	// encrypted config.json, directly or as the content of a file.
+	EnvConfigPassphrase = "ALIBABA_CLOUD_CLI_CONFIG_PASSPHRASE"
+	EnvConfigKeyFile    = "ALIBABA_CLOUD_CLI_CONFIG_KEY_FILE"
+
+	encryptionVersion = 1
+	encryptionKDF     = "scrypt"
+	sealedPrefix      = "enc:v1:"
+	// encryptionCheck is sealed into Encryption.Check to tell a wrong
+	// passphrase from a damaged field.
+	encryptionCheck = "aliyun-cli"
+)
+
+// scrypt parameters recommended for interactive use in 2017, still the
+// x/crypto default; about 100ms per derivation.
+var (
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+var errWrongPassphrase = errors.New("wrong passphrase for the encrypted config")
+
+// Encryption records how the secret fields of config.json are sealed. Only
+// the secrets are encrypted, so `configure list` and friends keep working on
+// a locked config.
+type Encryption struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    string `json:"salt"`
+	Check   string `json:"check"`
+}
+
+// secretFields returns the profile fields sealed at rest.
+func secretFields(p *Profile) []*string {
+	return []*string{
+		&p.AccessKeySecret,
+		&p.StsToken,
+		&p.PrivateKey,
+		&p.AccessToken,
+		&p.OAuthAccessToken,
+		&p.OAuthRefreshToken,
+		&p.BearerTokenValue,
+	}
+}
+
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// lookupPassphrase reads the passphrase from the environment, or from the
+// file EnvConfigKeyFile points to.
+func lookupPassphrase() (string, bool, error) {
+	if v := os.Getenv(EnvConfigPassphrase); v != "" {
+		return v, true, nil
+	}
+	path := os.Getenv(EnvConfigKeyFile)
+	if path == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("read %s failed: %w", EnvConfigKeyFile, err)
+	}
+	v := strings.TrimRight(string(data), "\r\n")
+	if v == "" {
+		return "", false, fmt.Errorf("%s %s is empty", EnvConfigKeyFile, path)
+	}
+	return v, true, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+}
+
+func seal(key []byte, plaintext string) (string, error) {
+	if plaintext == "" || IsSealed(plaintext) {
+		return plaintext, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func unseal(key []byte, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed value too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsLocked reports whether the secrets of c are sealed at rest.
+func (c *Configuration) IsLocked() bool {
+	return c.Encryption != nil
+}
+
+// unlockSecretsFromEnv opens the sealed secrets of a loaded config with the
+// passphrase from the environment. Without one the secrets stay sealed; only
+// the profiles that need them fail, see Profile.checkSealed.
+func (c *Configuration) unlockSecretsFromEnv() error {
+	if c.Encryption == nil {
+		return nil
+	}
+	passphrase, ok, err := lookupPassphrase()
+	if err != nil || !ok {
+		return err
+	}
+	return c.OpenSecrets(passphrase)
+}
+
+// OpenSecrets decrypts the sealed secrets of every profile in memory and
+// keeps the key, so they are sealed again on save.
+func (c *Configuration) OpenSecrets(passphrase string) error {
+	if c.Encryption == nil {
+		return nil
+	}
+	if c.Encryption.Version != encryptionVersion || c.Encryption.KDF != encryptionKDF {
+		return fmt.Errorf("unsupported config encryption version %d (%s)", c.Encryption.Version, c.Encryption.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(c.Encryption.Salt)
+	if err != nil {
+		return fmt.Errorf("invalid config encryption salt: %w", err)
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if check, err := unseal(key, c.Encryption.Check); err != nil || check != encryptionCheck {
+		return errWrongPassphrase
+	}
+	for i := range c.Profiles {
+		for _, field := range secretFields(&c.Profiles[i]) {
+			v, err := unseal(key, *field)
+			if err != nil {
+				return fmt.Errorf("decrypt profile %s failed: %w", c.Profiles[i].Name, err)
+			}
+			*field = v
+		}
+	}
+	c.key = key
+	return nil
+}
+
+// Lock turns on encryption with passphrase. The secrets are sealed when c is
+// saved.
+func (c *Configuration) Lock(passphrase string) error {
+	if c.Encryption != nil {
+		return fmt.Errorf("config is already encrypted")
+	}
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	check, err := seal(key, encryptionCheck)
+	if err != nil {
+		return err
+	}
+	c.Encryption = &Encryption{
+		Version: encryptionVersion,
+		KDF:     encryptionKDF,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Check:   check,
+	}
+	c.key = key
+	return nil
+}
+
+// Unlock turns off encryption. The secrets must have been opened, and are
+// written in plaintext when c is saved.
+func (c *Configuration) Unlock() error {
+	if c.Encryption == nil {
+		return fmt.Errorf("config is not encrypted")
+	}
+	if c.key == nil {
+		return errConfigLocked()
+	}
+	c.Encryption = nil
+	c.key = nil
+	return nil
+}
+
+// marshal encodes c for config.json, sealing the secrets of a locked config.
+// Values still sealed, because no passphrase was given, are kept as they are.
+func (c *Configuration) marshal() ([]byte, error) {
+	if c.Encryption == nil {
+		return json.MarshalIndent(c, "", "\t")
+	}
+	out := *c
+	out.Profiles = make([]Profile, len(c.Profiles))
+	copy(out.Profiles, c.Profiles)
+	for i := range out.Profiles {
+		for _, field := range secretFields(&out.Profiles[i]) {
+			if *field == "" || IsSealed(*field) {
+				continue
+			}
+			if c.key == nil {
+				return nil, errConfigLocked()
+			}
+			v, err := seal(c.key, *field)
+			if err != nil {
+				return nil, err
+			}
+			*field = v
+		}
+	}
+	return json.MarshalIndent(&out, "", "\t")
+}
+
+func errConfigLocked() error {
+	return fmt.Errorf("the credentials in config.json are encrypted, set %s or %s to unlock them",
+		EnvConfigPassphrase, EnvConfigKeyFile)
+}
+
+// checkSealed fails when a secret of the profile could not be decrypted.
+func (cp *Profile) checkSealed() error {
+	for _, field := range secretFields(cp) {
+		if IsSealed(*field) {
+			return fmt.Errorf("profile %s: %w", cp.Name, errConfigLocked())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastScrypt keeps key derivation cheap in tests.
+func fastScrypt(t *testing.T) {
+	origin := scryptN
+	scryptN = 1024
+	t.Cleanup(func() { scryptN = origin })
+	t.Setenv(EnvConfigPassphrase, "")
+	t.Setenv(EnvConfigKeyFile, "")
+}
+
+func newEncryptionTestConfiguration() *Configuration {
+	return &Configuration{
+		CurrentProfile: "default",
+		Profiles: []Profile{
+			{Name: "default", Mode: AK, AccessKeyId: "id", AccessKeySecret: "secret", RegionId: "cn-hangzhou"},
+			{Name: "oauth", Mode: OAuth, OAuthSiteType: "CN", OAuthRefreshToken: "refresh", RegionId: "cn-hangzhou"},
+		},
+	}
+}
+
+func TestSealUnseal(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := seal(key, "secret")
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	again, _ := seal(key, "secret")
+	assert.NotEqual(t, sealed, again, "every seal uses a new nonce")
+
+	plain, err := unseal(key, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain)
+
+	plain, err = unseal(key, "not sealed")
+	require.NoError(t, err)
+	assert.Equal(t, "not sealed", plain)
+
+	empty, _ := seal(key, "")
+	assert.Equal(t, "", empty)
+
+	_, err = unseal(make([]byte, 32), sealedPrefix+"AAAA")
+	assert.Error(t, err)
+	other := make([]byte, 32)
+	other[0] = 1
+	_, err = unseal(other, sealed)
+	assert.Error(t, err)
+}
+
+func TestConfigurationLockRoundTrip(t *testing.T) {
+	fastScrypt(t)
+	conf := newEncryptionTestConfiguration()
+	require.NoError(t, conf.Lock("passphrase"))
+	assert.EqualError(t, conf.Lock("passphrase"), "config is already encrypted")
+
+	data, err := conf.marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"secret"`)
+	assert.NotContains(t, string(data), `"refresh"`)
+	assert.Contains(t, string(data), `"access_key_id": "id"`)
+	assert.Contains(t, string(data), `"kdf": "scrypt"`)
+	// the in-memory profiles keep the plaintext
+	assert.Equal(t, "secret", conf.Profiles[0].AccessKeySecret)
+
+	// no passphrase: the config loads, the secrets stay sealed
+	loaded, err := NewConfigFromBytes(data)
+	require.NoError(t, err)
+	assert.True(t, loaded.IsLocked())
+	p, _ := loaded.GetProfile("default")
+	assert.True(t, IsSealed(p.AccessKeySecret))
+	err = p.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile default: the credentials in config.json are encrypted")
+	_, err = p.GetCredential(nil, nil)
+	assert.Error(t, err)
+
+	// untouched sealed values are saved as they are, new secrets need the key
+	_, err = loaded.marshal()
+	require.NoError(t, err)
+	loaded.Profiles[0].StsToken = "token"
+	_, err = loaded.marshal()
+	assert.Error(t, err)
+
+	t.Setenv(EnvConfigPassphrase, "passphrase")
+	loaded, err = NewConfigFromBytes(data)
+	require.NoError(t, err)
+	p, _ = loaded.GetProfile("default")
+	assert.Equal(t, "secret", p.AccessKeySecret)
+	assert.NoError(t, p.Validate())
+	p, _ = loaded.GetProfile("oauth")
+	assert.Equal(t, "refresh", p.OAuthRefreshToken)
+
+	t.Setenv(EnvConfigPassphrase, "wrong")
+	_, err = NewConfigFromBytes(data)
+	assert.Equal(t, errWrongPassphrase, err)
+}
+
+func TestConfigurationUnlock(t *testing.T) {
+	fastScrypt(t)
+	conf := newEncryptionTestConfiguration()
+	assert.EqualError(t, conf.Unlock(), "config is not encrypted")
+	require.NoError(t, conf.Lock("passphrase"))
+	data, err := conf.marshal()
+	require.NoError(t, err)
+
+	loaded, err := NewConfigFromBytes(data)
+	require.NoError(t, err)
+	assert.Error(t, loaded.Unlock(), "secrets not opened yet")
+	assert.Equal(t, errWrongPassphrase, loaded.OpenSecrets("wrong"))
+	require.NoError(t, loaded.OpenSecrets("passphrase"))
+	require.NoError(t, loaded.Unlock())
+
+	data, err = loaded.marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"access_key_secret": "secret"`)
+	assert.NotContains(t, string(data), `"encryption"`)
+}
+
+func TestLookupPassphrase(t *testing.T) {
+	fastScrypt(t)
+	_, ok, err := lookupPassphrase()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0600))
+	t.Setenv(EnvConfigKeyFile, path)
+	v, ok, err := lookupPassphrase()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "from-file", v)
+
+	t.Setenv(EnvConfigPassphrase, "from-env")
+	v, _, _ = lookupPassphrase()
+	assert.Equal(t, "from-env", v)
+
+	t.Setenv(EnvConfigPassphrase, "")
+	t.Setenv(EnvConfigKeyFile, filepath.Join(t.TempDir(), "missing"))
+	_, _, err = lookupPassphrase()
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0600))
+	t.Setenv(EnvConfigKeyFile, path)
+	_, _, err = lookupPassphrase()
+	assert.True(t, strings.HasSuffix(err.Error(), "is empty"))
+}
+
+func TestOpenSecretsUnsupportedVersion(t *testing.T) {
+	conf := &Configuration{Encryption: &Encryption{Version: 2, KDF: "argon2"}}
+	assert.EqualError(t, conf.OpenSecrets("x"), "unsupported config encryption version 2 (argon2)")
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+)
+
+const RefreshCredentialsFlagName = "refresh-credentials"
+
+// credentialEnvNames are removed from the inherited environment, so that the
+// command never mixes the credentials of two sources.
+var credentialEnvNames = []string{
+	"ALIBABA_CLOUD_ACCESS_KEY_ID",
+	"ALIBABA_CLOUD_ACCESS_KEY_SECRET",
+	"ALIBABA_CLOUD_SECURITY_TOKEN",
+	"ALIBABA_CLOUD_CREDENTIALS_URI",
+}
+
+var execCommandFunc = exec.Command
+
+func NewRefreshCredentialsFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         RefreshCredentialsFlagName,
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"use `--refresh-credentials` to serve credentials that are refreshed before they expire through ALIBABA_CLOUD_CREDENTIALS_URI",
+			"使用 `--refresh-credentials` 通过 ALIBABA_CLOUD_CREDENTIALS_URI 提供在过期前自动刷新的凭证"),
+	}
+}
+
+func NewExecCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:  "exec",
+		Usage: "exec [--profile <profileName>] [--refresh-credentials] -- <command> [args...]",
+		Short: i18n.T("run a command with the credentials of a profile", "使用 profile 的凭证运行命令"),
+		Long: i18n.T(
+			`Resolve the credentials of a profile of any mode and run the command with ALIBABA_CLOUD_ACCESS_KEY_ID, ALIBABA_CLOUD_ACCESS_KEY_SECRET, ALIBABA_CLOUD_SECURITY_TOKEN, ALIBABA_CLOUD_REGION_ID and the other settings of the profile in its environment. The command inherits stdin, stdout and stderr, and its exit code becomes the exit code of aliyun.
+Environment variables cannot change while the command runs, so STS credentials expire in long-running commands. With --refresh-credentials the command gets ALIBABA_CLOUD_CREDENTIALS_URI instead, a loopback endpoint only it knows, from which the Alibaba Cloud SDKs fetch credentials that are refreshed before they expire. Profiles of access keys, which do not expire, always get the environment variables.`,
+			`解析任意模式 profile 的凭证，并在命令的环境变量中设置 ALIBABA_CLOUD_ACCESS_KEY_ID、ALIBABA_CLOUD_ACCESS_KEY_SECRET、ALIBABA_CLOUD_SECURITY_TOKEN、ALIBABA_CLOUD_REGION_ID 及 profile 的其他配置后运行命令。命令继承标准输入、标准输出和标准错误，其退出码即为 aliyun 的退出码。
+命令运行期间环境变量无法更新，长时间运行的命令中 STS 凭证会过期。使用 --refresh-credentials 时命令获得 ALIBABA_CLOUD_CREDENTIALS_URI，这是仅该命令知道的本地回环地址，阿里云 SDK 可从中获取在过期前自动刷新的凭证。AccessKey 不会过期，此类 profile 始终使用环境变量。`),
+		Sample:           "aliyun exec --profile prod -- terraform apply",
+		KeepArgs:         true,
+		EnableDoubleDash: true,
+		Run: func(ctx *cli.Context, args []string) error {
+			code, err := doExec(ctx, args)
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				// the command already reported its failure
+				cli.Exit(code)
+			}
+			return nil
+		},
+	}
+	AddFlags(cmd.Flags())
+	cmd.Flags().Add(NewRefreshCredentialsFlag())
+	return cmd
+}
+
+// execCommandArgs drops the flags of aliyun in front of the command, the
+// parser has read them already.
+func execCommandArgs(fs *cli.FlagSet, args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return args[i:]
+		}
+		if strings.ContainsAny(arg, "=:") {
+			continue
+		}
+		var f *cli.Flag
+		if strings.HasPrefix(arg, "--") {
+			f = fs.Get(arg[2:])
+		} else if len(arg) == 2 {
+			f = fs.GetByShorthand(rune(arg[1]))
+		}
+		if f != nil && f.AssignedMode != cli.AssignedNone && i+1 < len(args) {
+			i++
+		}
+	}
+	return nil
+}
+
+// execEnv overrides base with envs and drops the names of unset.
+func execEnv(base []string, envs map[string]string, unset []string) []string {
+	drop := map[string]bool{}
+	for _, name := range unset {
+		drop[envKey(name)] = true
+	}
+	for name := range envs {
+		drop[envKey(name)] = true
+	}
+	result := make([]string, 0, len(base)+len(envs))
+	for _, e := range base {
+		if name, _, ok := strings.Cut(e, "="); ok && drop[envKey(name)] {
+			continue
+		}
+		result = append(result, e)
+	}
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, name+"="+envs[name])
+	}
+	return result
+}
+
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// execRuntimeEnv builds the environment of the command, stop shuts the
+// credentials endpoint down after the command exited.
+func execRuntimeEnv(ctx *cli.Context, profile *Profile) (envs map[string]string, stop func(), err error) {
+	stop = func() {}
+	if !ctx.Flags().Get(RefreshCredentialsFlagName).IsAssigned() {
+		envs, err = profile.GetRuntimeEnv(ctx)
+		return
+	}
+	source := newCredentialsSource(ctx, profile)
+	c, err := source.get()
+	if err != nil {
+		return nil, stop, err
+	}
+	envs = map[string]string{
+		"ALIBABA_CLOUD_REGION_ID": profile.RegionId,
+	}
+	if c.SecurityToken == "" {
+		for k, v := range c.env("") {
+			envs[k] = v
+		}
+	} else {
+		uri, stopServer, err := startLoopbackCredentialsServer(source)
+		if err != nil {
+			return nil, stop, err
+		}
+		stop = stopServer
+		envs["ALIBABA_CLOUD_CREDENTIALS_URI"] = uri
+		// the SDKs look at the config of the cli and at the ECS metadata
+		// before the uri
+		envs["ALIBABA_CLOUD_CLI_PROFILE_DISABLED"] = "true"
+		envs["ALIBABA_CLOUD_ECS_METADATA_DISABLED"] = "true"
+	}
+	profile.addRuntimeSettingsEnv(envs)
+	return envs, stop, nil
+}
+
+func doExec(ctx *cli.Context, args []string) (int, error) {
+	command := execCommandArgs(ctx.Flags(), args)
+	if len(command) == 0 {
+		return 0, fmt.Errorf("no command given, usage: aliyun exec [--profile <profileName>] -- <command> [args...]")
+	}
+	profile, err := LoadProfileWithContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("config failed: %s", err.Error())
+	}
+	envs, stop, err := execRuntimeEnv(ctx, &profile)
+	if err != nil {
+		return 0, cli.NewErrorWithTip(err, fmt.Sprintf("profile %q: failed to resolve credentials", profile.Name))
+	}
+	defer stop()
+
+	cmd := execCommandFunc(command[0], command[1:]...)
+	cmd.Env = execEnv(os.Environ(), envs, credentialEnvNames)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = ctx.Stdout()
+	cmd.Stderr = ctx.Stderr()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to execute %s: %v", command[0], err)
+	}
+
+	// the command decides how to handle interrupts, aliyun waits for it
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if code := exitErr.ExitCode(); code > 0 {
+				return code, nil
+			}
+			// killed by a signal
+			return 1, nil
+		}
+		return 0, fmt.Errorf("failed to execute %s: %v", command[0], err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfigFileCtx saves profiles to config.json in a new temporary directory,
+// the first one current, and returns a context that loads it.
+func newConfigFileCtx(t *testing.T, profiles ...Profile) *cli.Context {
+	// other tests leave the hook mocked, load the real file
+	originhook := hookLoadOrCreateConfiguration
+	t.Cleanup(func() { hookLoadOrCreateConfiguration = originhook })
+	hookLoadOrCreateConfiguration = func(fn func(path string) (*Configuration, error)) func(path string) (*Configuration, error) {
+		return fn
+	}
+	conf := NewConfiguration()
+	for _, p := range profiles {
+		conf.PutProfile(p)
+	}
+	conf.CurrentProfile = profiles[0].Name
+	ctx := newConfigPathCtx(filepath.Join(t.TempDir(), "config.json"))
+	ctx.Flags().Add(NewRefreshCredentialsFlag())
+	require.NoError(t, SaveConfigurationWithContext(ctx, conf))
+	return ctx
+}
+
+func newStsProfile() Profile {
+	return Profile{
+		Name:            "default",
+		Mode:            StsToken,
+		AccessKeyId:     "STS.id",
+		AccessKeySecret: "secret",
+		StsToken:        "token",
+		RegionId:        "cn-hangzhou",
+		OutputFormat:    "json",
+		Language:        "en",
+	}
+}
+
+func TestExecCommandArgs(t *testing.T) {
+	fs := newCtx().Flags()
+	fs.Add(NewRefreshCredentialsFlag())
+	assert.Equal(t, []string{"ls", "-l"}, execCommandArgs(fs, []string{"--profile", "prod", "--", "ls", "-l"}))
+	assert.Equal(t, []string{"ls", "--profile", "x"}, execCommandArgs(fs, []string{"--refresh-credentials", "ls", "--profile", "x"}))
+	assert.Equal(t, []string{"--", "x"}, execCommandArgs(fs, []string{"-p", "prod", "--", "--", "x"}))
+	assert.Empty(t, execCommandArgs(fs, []string{"--profile", "prod", "--"}))
+	assert.Nil(t, execCommandArgs(fs, nil))
+}
+
+func TestExecEnv(t *testing.T) {
+	base := []string{"PATH=/bin", "ALIBABA_CLOUD_ACCESS_KEY_ID=old", "ALIBABA_CLOUD_CREDENTIALS_URI=http://x", "HOME=/root"}
+	envs := map[string]string{
+		"ALIBABA_CLOUD_REGION_ID":     "cn-beijing",
+		"ALIBABA_CLOUD_ACCESS_KEY_ID": "new",
+	}
+	assert.Equal(t, []string{
+		"PATH=/bin",
+		"HOME=/root",
+		"ALIBABA_CLOUD_ACCESS_KEY_ID=new",
+		"ALIBABA_CLOUD_REGION_ID=cn-beijing",
+	}, execEnv(base, envs, credentialEnvNames))
+}
+
+func TestExecRuntimeEnvRefresh(t *testing.T) {
+	ctx := newConfigFileCtx(t, newStsProfile())
+	profile, err := LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+
+	// without --refresh-credentials the credentials are in the environment
+	envs, stop, err := execRuntimeEnv(ctx, &profile)
+	require.NoError(t, err)
+	stop()
+	assert.Equal(t, "token", envs["ALIBABA_CLOUD_SECURITY_TOKEN"])
+	assert.Empty(t, envs["ALIBABA_CLOUD_CREDENTIALS_URI"])
+
+	ctx.Flags().Get(RefreshCredentialsFlagName).SetAssigned(true)
+	envs, stop, err = execRuntimeEnv(ctx, &profile)
+	require.NoError(t, err)
+	defer stop()
+	assert.Empty(t, envs["ALIBABA_CLOUD_SECURITY_TOKEN"])
+	assert.Equal(t, "true", envs["ALIBABA_CLOUD_CLI_PROFILE_DISABLED"])
+	assert.Equal(t, "true", envs["ALIBABA_CLOUD_ECS_METADATA_DISABLED"])
+	assert.Equal(t, "cn-hangzhou", envs["ALIBABA_CLOUD_REGION_ID"])
+
+	resp, err := http.Get(envs["ALIBABA_CLOUD_CREDENTIALS_URI"])
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body credentialsURIResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Success", body.Code)
+	assert.Equal(t, "STS.id", body.AccessKeyId)
+	assert.Equal(t, "token", body.SecurityToken)
+	assert.NotEmpty(t, body.Expiration)
+
+	// an access key needs no endpoint
+	ak := newStsProfile()
+	ak.Mode = AK
+	ak.StsToken = ""
+	envs, stop, err = execRuntimeEnv(ctx, &ak)
+	require.NoError(t, err)
+	stop()
+	assert.Equal(t, "STS.id", envs["ALIBABA_CLOUD_ACCESS_KEY_ID"])
+	assert.Empty(t, envs["ALIBABA_CLOUD_CREDENTIALS_URI"])
+}
+
+func TestDoExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	ctx := newConfigFileCtx(t, newStsProfile())
+	stdout := ctx.Stdout().(*bytes.Buffer)
+	t.Setenv("ALIBABA_CLOUD_CREDENTIALS_URI", "http://inherited")
+
+	code, err := doExec(ctx, []string{"--", "sh", "-c",
+		`echo "$ALIBABA_CLOUD_ACCESS_KEY_ID $ALIBABA_CLOUD_SECURITY_TOKEN $ALIBABA_CLOUD_REGION_ID [$ALIBABA_CLOUD_CREDENTIALS_URI]"; exit 3`})
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "STS.id token cn-hangzhou []\n", stdout.String())
+
+	code, err = doExec(ctx, []string{"--", "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	_, err = doExec(ctx, []string{"--"})
+	assert.ErrorContains(t, err, "no command given")
+
+	_, err = doExec(ctx, []string{"--", "aliyun-exec-no-such-command"})
+	assert.ErrorContains(t, err, "failed to execute aliyun-exec-no-such-command")
+}
@@ -57,6 +57,8 @@ const (
 	AutoPluginInstallEnablePreFlagName = "auto-plugin-install-enable-pre"
 	BearerTokenFlagName                = "bearer-token"
 	BearerTokenHeaderKeyFlagName       = "bearer-token-header-key"
+	OutputFormatFlagName               = "output-format"
+	NoCredentialCacheFlagName          = "no-credential-cache"
 )
 
 func AddFlags(fs *cli.FlagSet) {
@@ -97,6 +99,7 @@ func AddFlags(fs *cli.FlagSet) {
 	fs.Add(NewAutoPluginInstallEnablePreFlag())
 	fs.Add(NewBearerTokenFlag())
 	fs.Add(NewBearerTokenHeaderKeyFlag())
+	fs.Add(NewNoCredentialCacheFlag())
 }
 
 func ConnectTimeoutFlag(fs *cli.FlagSet) *cli.Flag {
@@ -670,3 +673,38 @@ func NewBearerTokenHeaderKeyFlag() *cli.Flag {
 			"使用 `--bearer-token-header-key <key>` 指定自定义认证 Header 名称，例如 x-custom-token"),
 	}
 }
+
+// NewNoCredentialCacheFlag bypasses the STS credential cache of
+// role-assuming profiles, see credential_cache.go.
+func NewNoCredentialCacheFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         NoCredentialCacheFlagName,
+		AssignedMode: cli.AssignedNone,
+		Persistent:   true,
+		Short: i18n.T(
+			"use `--no-credential-cache` to assume the role again instead of reusing the cached STS credentials",
+			"使用 `--no-credential-cache` 重新扮演角色，不使用缓存的 STS 凭证"),
+	}
+}
+
+func NoCredentialCacheFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(NoCredentialCacheFlagName)
+}
+
+func OutputFormatFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(OutputFormatFlagName)
+}
+
+// NewOutputFormatFlag is only registered on `configure set`; the root command
+// already owns `--output`.
+func NewOutputFormatFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "config",
+		Name:         OutputFormatFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--output-format {json|csv|tsv|yaml|jsonl}` to assign the default output format of the profile",
+			"使用 `--output-format {json|csv|tsv|yaml|jsonl}` 指定配置的默认输出格式"),
+	}
+}
@@ -124,6 +124,7 @@ type Profile struct {
 	StsExpiration              int64            `json:"sts_expiration,omitempty"`                // for CloudSSO or OAuth, read only
 	CloudSSOAccessConfig       string           `json:"cloud_sso_access_config,omitempty"`       // for CloudSSO
 	CloudSSOAccountId          string           `json:"cloud_sso_account_id,omitempty"`          // for CloudSSO, read only
+	CloudSSOSynced             bool             `json:"cloud_sso_synced,omitempty"`              // for CloudSSO, generated by configure sso-sync
 	OAuthAccessToken           string           `json:"oauth_access_token,omitempty"`
 	OAuthRefreshToken          string           `json:"oauth_refresh_token,omitempty"`
 	OAuthAccessTokenExpire     int64            `json:"oauth_access_token_expire,omitempty"`
@@ -148,6 +149,19 @@ func NewProfile(name string) Profile {
 	}
 }
 
+// SupportedOutputFormats lists the values accepted for output_format. json
+// prints the response as is; the others are rendered by the `--output` filters.
+var SupportedOutputFormats = []string{"json", "csv", "tsv", "yaml", "jsonl"}
+
+func IsSupportedOutputFormat(format string) bool {
+	for _, f := range SupportedOutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
 // UnmarshalJSON accepts both read_timeout (current) and legacy retry_timeout.
 // configure set --read-timeout historically persisted as retry_timeout; keep loading those configs.
 func (cp *Profile) UnmarshalJSON(data []byte) error {
@@ -168,6 +182,9 @@ func (cp *Profile) UnmarshalJSON(data []byte) error {
 }
 
 func (cp *Profile) Validate() error {
+	if err := cp.checkSealed(); err != nil {
+		return err
+	}
 	if cp.RegionId == "" {
 		return fmt.Errorf("region can't be empty")
 	}
@@ -473,6 +490,18 @@ func mergeProfileAfterCredentialRefresh(disk Profile, cp *Profile) Profile {
 }
 
 func (cp *Profile) GetCredential(ctx *cli.Context, proxyHost *string) (cred credentialsv2.Credential, err error) {
+	if err = cp.checkSealed(); err != nil {
+		return
+	}
+	// role-assuming profiles reuse the STS credentials of former runs, see
+	// credential_cache.go
+	if cred, err = cp.getCachedCredential(ctx, proxyHost); cred != nil || err != nil {
+		return
+	}
+	return cp.newCredential(ctx, proxyHost)
+}
+
+func (cp *Profile) newCredential(ctx *cli.Context, proxyHost *string) (cred credentialsv2.Credential, err error) {
 	config := new(credentialsv2.Config)
 	// The AK, StsToken are direct credential
 	// Others are indirect credential
@@ -701,6 +730,9 @@ func (cp *Profile) GetCredential(ctx *cli.Context, proxyHost *string) (cred cred
 			// not support refresh access token yet, need to re-login
 			var reLoginCommand string
 			reLoginCommand = fmt.Sprintf("aliyun configure --profile %s", cp.Name)
+			if cp.CloudSSOSynced {
+				reLoginCommand = fmt.Sprintf("aliyun configure sso-sync --cloud-sso-sign-in-url %s", cp.CloudSSOSignInUrl)
+			}
 			return nil, fmt.Errorf(i18n.T(
 				"CloudSSO access token is expired, please re-login with command: %s",
 				"CloudSSO访问令牌已过期，请通过命令：%s 重新登录").GetMessage(), reLoginCommand)
@@ -835,6 +867,13 @@ func (cp *Profile) GetRuntimeEnv(ctx *cli.Context) (map[string]string, error) {
 		}
 	}
 
+	cp.addRuntimeSettingsEnv(envs)
+	return envs, nil
+}
+
+// addRuntimeSettingsEnv adds the API call settings of the profile, shared by
+// GetRuntimeEnv and `aliyun exec`.
+func (cp *Profile) addRuntimeSettingsEnv(envs map[string]string) {
 	// 添加 API 调用配置
 	if cp.Language != "" {
 		envs["ALIBABA_CLOUD_LANGUAGE"] = cp.Language
@@ -865,8 +904,6 @@ func (cp *Profile) GetRuntimeEnv(ctx *cli.Context) (map[string]string, error) {
 	}
 
 	envs["ALIBABA_CLOUD_CLI_VERSION"] = cli.GetVersion()
-
-	return envs, nil
 }
 
 // Intentionally NOT included (compare with GetRuntimeEnv above):
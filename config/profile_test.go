@@ -653,11 +653,19 @@ func credentialSTSEndpoint(t *testing.T, cred credentialsv2.Credential) string {
 	if !providerField.IsValid() {
 		providerField = rv
 	}
-	for providerField.Kind() == reflect.Ptr || providerField.Kind() == reflect.Interface {
-		if providerField.IsNil() {
-			t.Fatal("credential provider is nil")
+	for {
+		for providerField.Kind() == reflect.Ptr || providerField.Kind() == reflect.Interface {
+			if providerField.IsNil() {
+				t.Fatal("credential provider is nil")
+			}
+			providerField = providerField.Elem()
+		}
+		// unwrap the credential cache and the provider wrap of credentials-go
+		inner := providerField.FieldByName("provider")
+		if !inner.IsValid() {
+			break
 		}
-		providerField = providerField.Elem()
+		providerField = inner
 	}
 	if stsField := providerField.FieldByName("stsEndpoint"); stsField.IsValid() {
 		return stsField.String()
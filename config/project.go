@@ -0,0 +1,152 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+const (
+	// ProjectConfigFileName is the project-local configuration, the first one
+	// found walking up from the working directory applies.
+	ProjectConfigFileName = ".aliyun.json"
+	// EnvIgnoreProjectConfig set to true skips the project-local configuration.
+	EnvIgnoreProjectConfig = "ALIBABA_CLOUD_IGNORE_PROJECT_CONFIG"
+)
+
+// ProjectConfig pins the settings of a repository. Its values override those
+// of the profile it selects and yield to the flags, the profile yields to
+// --profile and ALIBABA_CLOUD_PROFILE, which also drop the other values when
+// they choose another profile.
+type ProjectConfig struct {
+	Profile      string            `json:"profile,omitempty"`
+	RegionId     string            `json:"region_id,omitempty"`
+	EndpointType string            `json:"endpoint_type,omitempty"`
+	OutputFormat string            `json:"output_format,omitempty"`
+	SafetyPolicy *safety.Overrides `json:"safety_policy,omitempty"`
+	path         string
+}
+
+var projectWorkingDir = os.Getwd
+
+// findProjectConfig returns the path of the nearest project configuration in
+// dir or its parents.
+func findProjectConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig returns nil when no project configuration applies. A
+// broken one is an error rather than ignored, it may pin the account.
+func LoadProjectConfig() (*ProjectConfig, error) {
+	if strings.EqualFold(os.Getenv(EnvIgnoreProjectConfig), "true") {
+		return nil, nil
+	}
+	dir, err := projectWorkingDir()
+	if err != nil {
+		return nil, nil
+	}
+	path := findProjectConfig(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read project config %s failed: %v", path, err)
+	}
+	pc := &ProjectConfig{}
+	if err := json.Unmarshal(data, pc); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %v", path, err)
+	}
+	if pc.OutputFormat != "" && !IsSupportedOutputFormat(pc.OutputFormat) {
+		return nil, fmt.Errorf("invalid project config %s: unsupported output_format %s, use one of %s",
+			path, pc.OutputFormat, strings.Join(SupportedOutputFormats, ", "))
+	}
+	pc.path = path
+	return pc, nil
+}
+
+func (pc *ProjectConfig) Path() string {
+	if pc == nil {
+		return ""
+	}
+	return pc.path
+}
+
+// selects tells whether the project settings apply when name is the profile
+// chosen by --profile or ALIBABA_CLOUD_PROFILE: none is chosen, or the one the
+// project pins. Another profile keeps its own region, endpoint type and
+// output format.
+func (pc *ProjectConfig) selects(name string) bool {
+	if pc == nil {
+		return false
+	}
+	return name == "" || name == pc.Profile
+}
+
+// applyTo overrides the settings of the profile the project selects.
+func (pc *ProjectConfig) applyTo(p *Profile) {
+	if pc == nil {
+		return
+	}
+	if pc.RegionId != "" {
+		p.RegionId = pc.RegionId
+	}
+	if pc.EndpointType != "" {
+		p.EndpointType = pc.EndpointType
+	}
+	if pc.OutputFormat != "" {
+		p.OutputFormat = pc.OutputFormat
+	}
+}
+
+func (pc *ProjectConfig) safetyOverrides() *safety.Overrides {
+	if pc == nil {
+		return nil
+	}
+	return pc.SafetyPolicy
+}
+
+// LoadEffectiveSafetyPolicy is the safety policy of the config directory with
+// the overrides of the project and of the environment.
+func LoadEffectiveSafetyPolicy(configDir string) (*safety.Policy, error) {
+	pc, err := LoadProjectConfig()
+	if err != nil {
+		return nil, err
+	}
+	return safety.LoadEffectivePolicyWithOverrides(configDir, pc.safetyOverrides())
+}
+
+// MergeSafetyPolicyIntoEnvs passes the effective safety policy to plugins.
+func MergeSafetyPolicyIntoEnvs(configDir string, envs map[string]string) {
+	// a broken project config already failed the profile, skip its rules here
+	pc, _ := LoadProjectConfig()
+	safety.MergeSafetyPolicyPathIntoEnvsWithOverrides(configDir, pc.safetyOverrides(), envs)
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inProject writes content to the .aliyun.json of a new project and runs the
+// test in a directory below it, it returns the path of the file.
+func inProject(t *testing.T, content string) string {
+	root := t.TempDir()
+	path := filepath.Join(root, ProjectConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	dir := filepath.Join(root, "src", "pkg")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	origin := projectWorkingDir
+	t.Cleanup(func() { projectWorkingDir = origin })
+	projectWorkingDir = func() (string, error) { return dir, nil }
+	return path
+}
+
+func clearProfileEnv(t *testing.T) {
+	for _, name := range append(append(profileEnvNames, regionEnvNames...), endpointTypeEnvNames...) {
+		t.Setenv(name, "")
+	}
+	t.Setenv("ALIBABA_CLOUD_IGNORE_PROFILE", "")
+	t.Setenv(EnvIgnoreProjectConfig, "")
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	clearProfileEnv(t)
+	path := inProject(t, `{"profile": "prod", "region_id": "cn-shanghai", "output_format": "yaml"}`)
+	pc, err := LoadProjectConfig()
+	require.NoError(t, err)
+	assert.Equal(t, path, pc.Path())
+	assert.Equal(t, "prod", pc.Profile)
+	assert.Equal(t, "cn-shanghai", pc.RegionId)
+
+	t.Setenv(EnvIgnoreProjectConfig, "true")
+	pc, err = LoadProjectConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, pc)
+	assert.Equal(t, "", pc.Path())
+
+	t.Setenv(EnvIgnoreProjectConfig, "")
+	inProject(t, `{"profile": `)
+	_, err = LoadProjectConfig()
+	assert.ErrorContains(t, err, "invalid project config")
+
+	inProject(t, `{"output_format": "xml"}`)
+	_, err = LoadProjectConfig()
+	assert.ErrorContains(t, err, "unsupported output_format xml")
+
+	origin := projectWorkingDir
+	defer func() { projectWorkingDir = origin }()
+	projectWorkingDir = func() (string, error) { return t.TempDir(), nil }
+	pc, err = LoadProjectConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, pc)
+}
+
+func TestLoadProfileWithProjectConfig(t *testing.T) {
+	clearProfileEnv(t)
+	dev := newStsProfile()
+	dev.Name = "dev"
+	prod := newStsProfile()
+	prod.Name = "prod"
+	prod.RegionId = "cn-beijing"
+	ctx := newConfigFileCtx(t, dev, prod)
+	inProject(t, `{"profile": "prod", "region_id": "cn-shanghai", "endpoint_type": "vpc", "output_format": "yaml"}`)
+
+	p, err := LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", p.Name)
+	assert.Equal(t, "cn-shanghai", p.RegionId)
+	assert.Equal(t, "vpc", p.EndpointType)
+	assert.Equal(t, "yaml", p.OutputFormat)
+
+	// another profile keeps its own settings
+	t.Setenv("ALIBABA_CLOUD_PROFILE", "dev")
+	p, err = LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", p.Name)
+	assert.Equal(t, "cn-hangzhou", p.RegionId)
+	assert.Equal(t, "", p.EndpointType)
+	assert.Equal(t, "json", p.OutputFormat)
+
+	t.Setenv("ALIBABA_CLOUD_PROFILE", "prod")
+	p, err = LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cn-shanghai", p.RegionId)
+
+	// without a pinned profile the settings apply to the current one
+	t.Setenv("ALIBABA_CLOUD_PROFILE", "")
+	inProject(t, `{"region_id": "cn-shanghai"}`)
+	p, err = LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", p.Name)
+	assert.Equal(t, "cn-shanghai", p.RegionId)
+
+	path := inProject(t, `{"profile": "staging"}`)
+	_, err = LoadProfileWithContext(ctx)
+	assert.ErrorContains(t, err, "pinned by "+path)
+
+	ProfileFlag(ctx.Flags()).SetAssigned(true)
+	ProfileFlag(ctx.Flags()).SetValue("dev")
+	p, err = LoadProfileWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", p.Name)
+	assert.Equal(t, "cn-hangzhou", p.RegionId)
+}
+
+func TestLoadEffectiveSafetyPolicyWithProjectConfig(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(safety.EnvSafetyPolicyEnabled, "")
+	os.Unsetenv(safety.EnvSafetyPolicyEnabled)
+	t.Setenv(safety.EnvSafetyPolicyRules, "")
+	os.Unsetenv(safety.EnvSafetyPolicyRules)
+	configDir := t.TempDir()
+	require.NoError(t, os.WriteFile(safety.GetPolicyFilePath(configDir),
+		[]byte(`{"enabled": false, "rules": [{"pattern": "oss:*", "action": "allow"}]}`), 0600))
+	inProject(t, `{"safety_policy": {"enabled": true, "rules": [{"pattern": "ecs:Delete*", "action": "deny"}, {"pattern": "*", "action": "allow"}]}}`)
+
+	policy, err := LoadEffectiveSafetyPolicy(configDir)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	require.Len(t, policy.Rules, 2)
+	assert.Equal(t, "oss:*", policy.Rules[0].Pattern)
+	assert.Equal(t, "ecs:Delete*", policy.Rules[1].Pattern)
+
+	envs := map[string]string{}
+	MergeSafetyPolicyIntoEnvs(configDir, envs)
+	assert.Equal(t, "true", envs[safety.EnvSafetyPolicyEnabled])
+	assert.Equal(t, "oss:*=allow,ecs:Delete*=deny", envs[safety.EnvSafetyPolicyRules])
+
+	// a project cannot turn the policy of the user off
+	require.NoError(t, os.WriteFile(safety.GetPolicyFilePath(configDir),
+		[]byte(`{"enabled": true, "rules": [{"pattern": "ecs:Delete*", "action": "confirm"}]}`), 0600))
+	inProject(t, `{"safety_policy": {"enabled": false, "rules": [{"pattern": "ecs:*", "action": "allow"}]}}`)
+	policy, err = LoadEffectiveSafetyPolicy(configDir)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	assert.Equal(t, safety.ActionConfirm, policy.Check(safety.CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}).Action)
+}
+
+func TestConfigureWhere(t *testing.T) {
+	clearProfileEnv(t)
+	os.Unsetenv(safety.EnvSafetyPolicyEnabled)
+	os.Unsetenv(safety.EnvSafetyPolicyRules)
+	dev := newStsProfile()
+	dev.Name = "dev"
+	ctx := newConfigFileCtx(t, dev)
+	configPath := getConfigurePath(ctx)
+
+	origin := projectWorkingDir
+	defer func() { projectWorkingDir = origin }()
+	projectWorkingDir = func() (string, error) { return t.TempDir(), nil }
+	settings, err := whereSettings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, settingSource{Name: "project config", Value: "-"}, settings[0])
+	assert.Equal(t, settingSource{Name: "profile", Value: "dev", Source: "current profile of " + configPath}, settings[2])
+	assert.Equal(t, settingSource{Name: "region", Value: "cn-hangzhou", Source: "profile dev"}, settings[3])
+	assert.Equal(t, settingSource{Name: "endpoint type", Value: "-", Source: "not set, public endpoints"}, settings[4])
+	assert.Equal(t, settingSource{Name: "output format", Value: "json", Source: "profile dev"}, settings[5])
+	assert.Equal(t, settingSource{Name: "safety policy", Value: "disabled", Source: "default"}, settings[6])
+
+	path := inProject(t, `{"profile": "dev", "region_id": "cn-shanghai", "safety_policy": {"enabled": true, "rules": [{"pattern": "ecs:Delete*", "action": "deny"}]}}`)
+	t.Setenv("ALIBABA_CLOUD_ENDPOINT_TYPE", "vpc")
+	settings, err = whereSettings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, settingSource{Name: "project config", Value: path}, settings[0])
+	assert.Equal(t, settingSource{Name: "profile", Value: "dev", Source: "project " + path}, settings[2])
+	assert.Equal(t, settingSource{Name: "region", Value: "cn-shanghai", Source: "project " + path}, settings[3])
+	assert.Equal(t, settingSource{Name: "endpoint type", Value: "vpc", Source: "env ALIBABA_CLOUD_ENDPOINT_TYPE"}, settings[4])
+	assert.Equal(t, settingSource{Name: "safety policy", Value: "enabled", Source: "project " + path}, settings[6])
+	assert.Equal(t, settingSource{Name: "safety rules", Value: "1", Source: "0 from default, 1 from project " + path}, settings[7])
+
+	// the project region does not apply to another profile
+	t.Setenv("ALIBABA_CLOUD_PROFILE", "other")
+	settings, err = whereSettings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, settingSource{Name: "profile", Value: "other (not found)", Source: "env ALIBABA_CLOUD_PROFILE"}, settings[2])
+	assert.Equal(t, settingSource{Name: "region", Value: "-", Source: "not set"}, settings[3])
+	t.Setenv("ALIBABA_CLOUD_PROFILE", "")
+
+	t.Setenv(safety.EnvSafetyPolicyEnabled, "false")
+	RegionFlag(ctx.Flags()).SetAssigned(true)
+	RegionFlag(ctx.Flags()).SetValue("cn-beijing")
+	settings, err = whereSettings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, settingSource{Name: "region", Value: "cn-beijing", Source: "flag --region"}, settings[3])
+	assert.Equal(t, settingSource{Name: "safety policy", Value: "disabled", Source: "env " + safety.EnvSafetyPolicyEnabled}, settings[6])
+
+	require.NoError(t, doConfigureWhere(ctx))
+	out := ctx.Stdout().(*bytes.Buffer).String()
+	assert.Contains(t, out, "Setting")
+	assert.Contains(t, out, "| flag --region\n")
+}
@@ -53,6 +53,7 @@ import (
 	"github.com/aliyun/aliyun-cli/v3/mock"
 	"github.com/aliyun/aliyun-cli/v3/openapi"
 	"github.com/aliyun/aliyun-cli/v3/oss/lib"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
 	sysmock "github.com/aliyun/aliyun-cli/v3/sysconfig/mock"
 )
 
@@ -66,14 +67,28 @@ func Main(args []string) {
 	stdout := newStdoutWriter()
 	stderr := newStderrWriter()
 
+	// audit entries are written as each call completes, the exit code of the
+	// run follows as a separate record
+	finishAudit := finishAuditLog(stderr)
+	cli.OnExit(finishAudit)
+	// exit code of a run that returns instead of calling exit
+	exitCode := 0
+	defer func() { finishAudit(exitCode) }()
+
 	if sysmock.FirstCommandToken(args) != "mock" {
-		result := sysmock.Intercept(sysmock.Options{
+		opts := sysmock.Options{
 			Args:     args,
 			Stdout:   stdout,
 			Stderr:   stderr,
 			MockPath: sysmock.ResolvePath(config.GetConfigPath),
-		})
-		if result.Handled {
+		}
+		if sysmock.IsRecordMode() {
+			// record mode: run for real and capture the run as a mock record
+			recorder := sysmock.NewRecorder(opts)
+			stdout, stderr = recorder.Stdout(), recorder.Stderr()
+			cli.OnExit(recorder.Finish)
+			defer func() { recorder.Finish(exitCode) }()
+		} else if result := sysmock.Intercept(opts); result.Handled {
 			exit(result.ExitCode)
 			return
 		}
@@ -83,6 +98,8 @@ func Main(args []string) {
 	profile, err := config.LoadOrCreateDefaultProfile()
 	if err != nil {
 		cli.Errorf(stderr, "ERROR: load current configuration failed %s", err)
+		exitCode = 1
+		exit(exitCode)
 		return
 	}
 
@@ -127,6 +144,12 @@ func newRootCommand(profile config.Profile, stdout io.Writer) *cli.Command {
 	rootCmd.AddSubCommand(config.NewConfigureCommand())
 	// list-supported-pricing-apis: enumerate every OpenAPI that supports --estimate-cost
 	rootCmd.AddSubCommand(openapi.NewListSupportedPricingApisCommand())
+	// batch: run the calls of a JSONL/YAML file in one process
+	rootCmd.AddSubCommand(commando.NewBatchCommand())
+	// cache: manage the response cache of read-only API calls
+	rootCmd.AddSubCommand(openapi.NewCacheCommand())
+	// exec: run any program with the resolved credentials of a profile
+	rootCmd.AddSubCommand(config.NewExecCommand())
 	// oss old version, duplicate with ossutil, will remove in future
 	ossCmd := lib.NewOssCommand()
 	// `aliyun oss <ApiName> ... --estimate-cost` quotes via CloudControl; the
@@ -190,6 +213,14 @@ func newRootCommand(profile config.Profile, stdout io.Writer) *cli.Command {
 	return rootCmd
 }
 
+func finishAuditLog(stderr io.Writer) func(int) {
+	return func(code int) {
+		if err := audit.Finish(code); err != nil {
+			cli.Errorf(stderr, "WARNING: write audit log failed %s\n", err)
+		}
+	}
+}
+
 func ParseInSecure(args []string) (bool, interface{}) {
 	// check has insecure flag
 	for _, arg := range args {
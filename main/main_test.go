@@ -236,3 +236,59 @@ func TestParseInSecure(t *testing.T) {
 		})
 	}
 }
+
+func TestMainRecordModeCapturesRun(t *testing.T) {
+	mockPath := filepath.Join(t.TempDir(), "mocks.json")
+	t.Setenv(sysmock.EnvMockEnabled, sysmock.MockModeRecord)
+	t.Setenv(sysmock.EnvMockPath, mockPath)
+	t.Setenv("HOME", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	resetMainHooks(t, &stdout, &stderr, nil)
+
+	Main([]string{"version"})
+
+	records, err := sysmock.Load(mockPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	if records[0].Cmd != "version" || records[0].Stdout != stdout.String() || records[0].ExitCode != 0 {
+		t.Fatalf("record = %+v, want version run with stdout %q", records[0], stdout.String())
+	}
+}
+
+func TestMainRecordModeRecordsLoadConfigFailure(t *testing.T) {
+	mockPath := filepath.Join(t.TempDir(), "mocks.json")
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".aliyun"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aliyun", "config.json"), []byte("{invalid json"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv(sysmock.EnvMockEnabled, sysmock.MockModeRecord)
+	t.Setenv(sysmock.EnvMockPath, mockPath)
+	t.Setenv("HOME", home)
+
+	var stdout, stderr bytes.Buffer
+	var exitCode int
+	resetMainHooks(t, &stdout, &stderr, func(code int) {
+		exitCode = code
+	})
+
+	Main([]string{"ecs", "DescribeRegions"})
+
+	if exitCode != 1 {
+		t.Fatalf("exit code = %d, want 1", exitCode)
+	}
+	records, err := sysmock.Load(mockPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(records) != 1 || records[0].ExitCode != 1 {
+		t.Fatalf("records = %+v, want one record with exit code 1", records)
+	}
+}
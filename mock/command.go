@@ -52,7 +52,15 @@ Workflow:
   3. Run the target command, for example:
      aliyun ecs DescribeRegions
   4. Disable mocking when finished:
-     unset ALIBABA_CLOUD_CLI_MOCK`, `
+     unset ALIBABA_CLOUD_CLI_MOCK
+
+Record mode:
+  Set ALIBABA_CLOUD_CLI_MOCK=record to run commands for real and append each
+  run (command, stdout, stderr and exit code) to the mock file as a one-shot
+  record. The values of flags, JSON fields and NAME=value lines whose name
+  contains secret, password, token, private key, access key or credential are
+  redacted, pagination tokens such as NextToken are kept. Switch back to ALIBABA_CLOUD_CLI_MOCK=true to
+  replay the recorded runs offline.`, `
 环境变量:
   mock 默认不生效。运行需要被 mock 的命令前，先在当前 shell 配置:
 
@@ -65,7 +73,14 @@ Workflow:
   3. 执行需要被 mock 的目标命令，例如:
      aliyun ecs DescribeRegions
   4. 使用结束后关闭 mock:
-     unset ALIBABA_CLOUD_CLI_MOCK`).Text())
+     unset ALIBABA_CLOUD_CLI_MOCK
+
+录制模式:
+  设置 ALIBABA_CLOUD_CLI_MOCK=record 后命令会真实执行，并把每次运行的命令、
+  stdout、stderr 和退出码作为一次性记录追加到 mock 文件。名称中包含 secret、
+  password、token、private key、access key 或 credential 的参数、JSON 字段和
+  NAME=value 行的值会被脱敏，NextToken 等分页标记会保留。改回
+  ALIBABA_CLOUD_CLI_MOCK=true 即可离线回放录制的结果。`).Text())
 	cmd.PrintTail(ctx)
 }
 
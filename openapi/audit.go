@@ -0,0 +1,210 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/dara"
+	"github.com/alibabacloud-go/tea/tea"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/redact"
+)
+
+var requestIdPattern = regexp.MustCompile(`"RequestId"\s*:\s*"([^"]*)"`)
+
+// sanitizeParams merges request parameters for the audit log, masking secrets
+// the same way sanitizeHeaders does for --cli-dry-run.
+func sanitizeParams(params ...map[string]string) map[string]string {
+	var out map[string]string
+	for _, m := range params {
+		for k, v := range m {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			if redact.IsSensitiveKey(k) {
+				v = maskValue(v)
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// sanitizeArgs masks the values of secret flags in plugin arguments, both
+// `--flag value` and `--flag=value`.
+func sanitizeArgs(args []string) []string {
+	out := make([]string, len(args))
+	maskNext := false
+	for i, arg := range args {
+		if maskNext {
+			out[i] = maskValue(arg)
+			maskNext = false
+			continue
+		}
+		out[i] = arg
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		name, value, inline := strings.Cut(arg[2:], "=")
+		if !redact.IsSensitiveKey(name) {
+			continue
+		}
+		if inline {
+			out[i] = "--" + name + "=" + maskValue(value)
+		} else {
+			maskNext = true
+		}
+	}
+	return out
+}
+
+func openAuditLog(ctx *cli.Context) *audit.Log {
+	return audit.Open(config.GetConfigDir(ctx))
+}
+
+// recordAudit logs one call made through the SDK client: RPC, ROA and every
+// page or poll of --pager and --waiter. Throttling retries count as one call,
+// a response served from the cache is logged as cached.
+func (a *BasicInvoker) recordAudit(request *requests.CommonRequest, resp *responses.CommonResponse, err error, start time.Time, cached bool) {
+	if a == nil || a.auditLog == nil || request == nil {
+		return
+	}
+	entry := audit.Entry{
+		Time:       start,
+		Product:    request.Product,
+		Api:        request.ApiName,
+		Method:     request.Method,
+		Path:       request.PathPattern,
+		Region:     request.RegionId,
+		Endpoint:   request.Domain,
+		DurationMs: time.Since(start).Milliseconds(),
+		Cached:     cached,
+		Params:     sanitizeParams(request.QueryParams, request.FormParams, request.PathParams),
+	}
+	if a.profile != nil {
+		entry.Profile = a.profile.Name
+		entry.Mode = string(a.profile.Mode)
+	}
+	if resp != nil && resp.GetHttpStatus() != 0 {
+		entry.StatusCode = resp.GetHttpStatus()
+		entry.RequestId = responseRequestId(resp.GetHttpHeaders(), resp.GetHttpContentString())
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		var serverErr *sdkerrors.ServerError
+		if errors.As(err, &serverErr) {
+			entry.StatusCode = serverErr.HttpStatus()
+			entry.RequestId = serverErr.RequestId()
+		}
+	}
+	a.auditLog.Record(entry)
+}
+
+func responseRequestId(headers map[string][]string, body string) string {
+	for k, v := range headers {
+		if (strings.EqualFold(k, "x-acs-request-id") || strings.EqualFold(k, "x-log-requestid")) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	if m := requestIdPattern.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// recordAudit logs one call made through the darabonba OpenAPI client, or
+// served from the cache.
+func (a *HttpContext) recordAudit(err error, start time.Time, cached bool) {
+	if a == nil || a.auditLog == nil {
+		return
+	}
+	entry := audit.Entry{
+		Time:       start,
+		DurationMs: time.Since(start).Milliseconds(),
+		Cached:     cached,
+	}
+	if a.profile != nil {
+		entry.Profile = a.profile.Name
+		entry.Mode = string(a.profile.Mode)
+		entry.Region = a.profile.RegionId
+	}
+	if a.product != nil {
+		entry.Product = a.product.Code
+	}
+	if p := a.openapiParams; p != nil {
+		entry.Api = dara.StringValue(p.Action)
+		entry.Method = dara.StringValue(p.Method)
+		entry.Path = dara.StringValue(p.Pathname)
+	}
+	if r := a.openapiRequest; r != nil {
+		entry.Endpoint = dara.StringValue(r.EndpointOverride)
+		query := make(map[string]string, len(r.Query))
+		for k, v := range r.Query {
+			query[k] = dara.StringValue(v)
+		}
+		entry.Params = sanitizeParams(query)
+	}
+	if entry.Endpoint == "" && a.openapiClient != nil {
+		entry.Endpoint = dara.StringValue(a.openapiClient.Endpoint)
+	}
+	if a.openapiResponse != nil {
+		if code, ok := a.openapiResponse["statusCode"].(int); ok {
+			entry.StatusCode = code
+		}
+		if headers, ok := a.openapiResponse["headers"].(map[string]*string); ok {
+			flat := make(map[string][]string, len(headers))
+			for k, v := range headers {
+				flat[k] = []string{dara.StringValue(v)}
+			}
+			entry.RequestId = responseRequestId(flat, "")
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		var sdkErr *tea.SDKError
+		if errors.As(err, &sdkErr) {
+			entry.StatusCode = tea.IntValue(sdkErr.StatusCode)
+			entry.RequestId = responseRequestId(nil, tea.StringValue(sdkErr.Data))
+		}
+	}
+	a.auditLog.Record(entry)
+}
+
+// startPluginAudit logs a plugin run. The entry stays open until the plugin
+// returns; if the plugin exits non-zero the CLI exits right away and the entry
+// is timed and written by the exit hook.
+func (c *Commando) startPluginAudit(ctx *cli.Context, pluginName string, product string, command string, args []string) func() {
+	log := openAuditLog(ctx)
+	if log == nil {
+		return func() {}
+	}
+	return log.Start(audit.Entry{
+		Profile: c.profile.Name,
+		Mode:    string(c.profile.Mode),
+		Product: product,
+		Api:     command,
+		Plugin:  pluginName,
+		Region:  effectiveDryRunRegion(ctx, &c.profile),
+		Args:    sanitizeArgs(args),
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestAuditLog(t *testing.T) *audit.Log {
+	t.Helper()
+	t.Setenv(audit.EnvEnabled, "true")
+	t.Setenv(audit.EnvPath, "")
+	log := audit.Open(t.TempDir())
+	require.NotNil(t, log)
+	return log
+}
+
+func finishTestAuditLog(t *testing.T, log *audit.Log, exitCode int) []audit.Entry {
+	t.Helper()
+	require.NoError(t, audit.Finish(exitCode))
+	f, err := os.Open(log.Path())
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []audit.Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestSanitizeParams(t *testing.T) {
+	got := sanitizeParams(
+		map[string]string{"InstanceId": "i-1", "AccessKeyId": "LTAI1234567", "Signature": "abc"},
+		map[string]string{"Password": "P@ssw0rd!", "ClientSecret": "xyzw1"},
+		map[string]string{"AccountPassword": "hunter2", "oauth_access_token": "tokenvalue", "NextToken": "n1"},
+	)
+	assert.Equal(t, map[string]string{
+		"InstanceId":         "i-1",
+		"AccessKeyId":        "LTAI***",
+		"Signature":          "***",
+		"Password":           "P@ss***",
+		"ClientSecret":       "xyzw***",
+		"AccountPassword":    "hunt***",
+		"oauth_access_token": "toke***",
+		"NextToken":          "n1",
+	}, got)
+	assert.Nil(t, sanitizeParams(nil, map[string]string{}))
+}
+
+func TestSanitizeArgs(t *testing.T) {
+	got := sanitizeArgs([]string{"fc", "create", "--access-key-secret", "secretvalue", "--sts-token=tokenvalue", "--name", "fn"})
+	assert.Equal(t, []string{"fc", "create", "--access-key-secret", "secr***", "--sts-token=toke***", "--name", "fn"}, got)
+}
+
+func TestBasicInvoker_recordAudit(t *testing.T) {
+	log := openTestAuditLog(t)
+	invoker := &BasicInvoker{
+		profile:  &config.Profile{Name: "prod", Mode: config.AK},
+		auditLog: log,
+	}
+	request := requests.NewCommonRequest()
+	request.Product = "Ecs"
+	request.ApiName = "DeleteInstance"
+	request.RegionId = "cn-hangzhou"
+	request.Domain = "ecs.aliyuncs.com"
+	request.QueryParams["InstanceId"] = "i-1"
+	request.QueryParams["AccessKeyId"] = "LTAI1234567"
+
+	resp := responses.NewCommonResponse()
+	require.NoError(t, responses.Unmarshal(resp, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"RequestId":"req-1"}`)),
+	}, "JSON"))
+	start := time.Now()
+	invoker.recordAudit(request, resp, nil, start, false)
+	invoker.recordAudit(request, nil, sdkerrors.NewServerError(403, `{"Code":"Forbidden","RequestId":"req-2"}`, ""), start, false)
+
+	entries := finishTestAuditLog(t, log, 1)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "prod", entries[0].Profile)
+	assert.Equal(t, "AK", entries[0].Mode)
+	assert.Equal(t, "Ecs", entries[0].Product)
+	assert.Equal(t, "DeleteInstance", entries[0].Api)
+	assert.Equal(t, "cn-hangzhou", entries[0].Region)
+	assert.Equal(t, "ecs.aliyuncs.com", entries[0].Endpoint)
+	assert.Equal(t, 200, entries[0].StatusCode)
+	assert.Equal(t, "req-1", entries[0].RequestId)
+	assert.Equal(t, map[string]string{"InstanceId": "i-1", "AccessKeyId": "LTAI***"}, entries[0].Params)
+	assert.Nil(t, entries[0].ExitCode)
+
+	assert.Equal(t, 403, entries[1].StatusCode)
+	assert.Equal(t, "req-2", entries[1].RequestId)
+	assert.NotEmpty(t, entries[1].Error)
+
+	assert.Equal(t, audit.EventExit, entries[2].Event)
+	require.NotNil(t, entries[2].ExitCode)
+	assert.Equal(t, 1, *entries[2].ExitCode)
+}
+
+func TestCallWithThrottlingRetry_RecordsAudit(t *testing.T) {
+	log := openTestAuditLog(t)
+	invoker := &BasicInvoker{
+		profile:  &config.Profile{Name: "default"},
+		request:  requests.NewCommonRequest(),
+		auditLog: log,
+	}
+	invoker.request.Product = "Ecs"
+	invoker.request.ApiName = "DescribeRegions"
+	_, err := invoker.callWithThrottlingRetry(func() (*responses.CommonResponse, error) {
+		return nil, sdkerrors.NewServerError(500, `{"Code":"InternalError"}`, "")
+	})
+	require.Error(t, err)
+
+	entries := finishTestAuditLog(t, log, 0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DescribeRegions", entries[0].Api)
+	assert.Equal(t, 500, entries[0].StatusCode)
+}
@@ -0,0 +1,507 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	BatchFileFlagName        = "file"
+	BatchConcurrencyFlagName = "concurrency"
+)
+
+// BatchCall is one call of a batch file: an RPC API with its parameters, or a
+// ROA request given either by API name or by method, path and body.
+type BatchCall struct {
+	Product  string                 `json:"product" yaml:"product"`
+	Api      string                 `json:"api,omitempty" yaml:"api,omitempty"`
+	Version  string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	Region   string                 `json:"region,omitempty" yaml:"region,omitempty"`
+	Endpoint string                 `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Method   string                 `json:"method,omitempty" yaml:"method,omitempty"`
+	Path     string                 `json:"path,omitempty" yaml:"path,omitempty"`
+	Body     interface{}            `json:"body,omitempty" yaml:"body,omitempty"`
+	Params   map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	Headers  map[string]string      `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+// BatchResult is the output line of one call.
+type BatchResult struct {
+	Index      int             `json:"index"`
+	Product    string          `json:"product"`
+	Api        string          `json:"api,omitempty"`
+	Method     string          `json:"method,omitempty"`
+	Path       string          `json:"path,omitempty"`
+	Region     string          `json:"region,omitempty"`
+	Status     string          `json:"status"`
+	HttpStatus int             `json:"http_status,omitempty"`
+	RequestId  string          `json:"request_id,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+const (
+	BatchStatusSuccess = "success"
+	BatchStatusFailed  = "failed"
+)
+
+// batchJob is a call with its prepared invoker, or the error that stopped it.
+type batchJob struct {
+	index       int
+	call        *BatchCall
+	apiOrMethod string
+	path        string
+	invoker     Invoker
+	err         error
+}
+
+// sharedClient lets every invoker of a batch use the client built for the
+// first call, so credentials are resolved once per batch.
+type sharedClient struct {
+	once   sync.Once
+	client *sdk.Client
+	err    error
+}
+
+func (s *sharedClient) get(cp *config.Profile, ctx *cli.Context) (*sdk.Client, error) {
+	s.once.Do(func() {
+		s.client, s.err = GetClient(cp, ctx)
+	})
+	return s.client, s.err
+}
+
+func (c *Commando) NewBatchCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name: "batch",
+		Short: i18n.T(
+			"run many API calls described in a JSONL or YAML file",
+			"批量执行 JSONL 或 YAML 文件中描述的 API 调用"),
+		Usage: "batch --file <calls.jsonl|calls.yaml|-> [--concurrency N]",
+		Long: i18n.T(
+			`Run every call of the file in one process, sharing the loaded configuration, metadata, credentials and client.
+Each JSONL line, or each item of a YAML list, describes one call:
+  {"product":"ecs","api":"TagResources","region":"cn-hangzhou","params":{"ResourceType":"instance","ResourceId.1":"i-xxx","Tag.1.Key":"env","Tag.1.Value":"prod"}}
+  {"product":"cs","method":"GET","path":"/clusters","params":{"page_size":"50"}}
+Keys: product, api, version, region, endpoint, params and headers; ROA calls may give method, path and body instead of api.
+One JSON line is printed per call, in file order, with index, status, http_status, request_id and body or error. A summary goes to stderr and the exit code is non-zero if any call failed.`,
+			`在一个进程中执行文件中的全部调用，共享已加载的配置、元数据、凭证和客户端。
+JSONL 的每一行，或 YAML 列表的每一项，描述一次调用：
+  {"product":"ecs","api":"TagResources","region":"cn-hangzhou","params":{"ResourceType":"instance","ResourceId.1":"i-xxx","Tag.1.Key":"env","Tag.1.Value":"prod"}}
+  {"product":"cs","method":"GET","path":"/clusters","params":{"page_size":"50"}}
+可用字段：product、api、version、region、endpoint、params 和 headers；ROA 调用可用 method、path 和 body 代替 api。
+每次调用按文件顺序输出一行 JSON，包含 index、status、http_status、request_id 以及 body 或 error。汇总信息输出到 stderr，只要有调用失败，退出码即非零。`),
+		Sample: "aliyun batch --file calls.jsonl --concurrency 8",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return c.runBatch(ctx)
+		},
+	}
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "batch",
+		Name:         BatchFileFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Required:     true,
+		Short: i18n.T(
+			"JSONL file, or YAML file (.yaml/.yml), of calls; `-` reads JSONL from stdin",
+			"调用列表文件，JSONL 或 YAML（.yaml/.yml）；`-` 表示从标准输入读取 JSONL"),
+	})
+	cmd.Flags().Add(&cli.Flag{
+		Category:     "batch",
+		Name:         BatchConcurrencyFlagName,
+		AssignedMode: cli.AssignedOnce,
+		DefaultValue: "1",
+		Short: i18n.T(
+			"number of calls in flight at the same time (default: 1)",
+			"同时执行的调用数（默认：1）"),
+	})
+	cmd.Flags().Add(NewYesFlag())
+	return cmd
+}
+
+func (c *Commando) runBatch(ctx *cli.Context) error {
+	file, _ := ctx.Flags().Get(BatchFileFlagName).GetValue()
+	concurrency := 1
+	if v, ok := ctx.Flags().Get(BatchConcurrencyFlagName).GetValue(); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid --concurrency %s, need a positive integer", v)
+		}
+		concurrency = n
+	}
+
+	calls, err := LoadBatchCalls(file)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetInConfigureMode(DetectInConfigureMode(ctx.Flags()))
+	c.profile, err = config.LoadProfileWithContext(ctx)
+	if err != nil {
+		return cli.NewErrorWithTip(err, "Configuration failed, use `aliyun configure` to configure it")
+	}
+	if err = c.profile.Validate(); err != nil {
+		return cli.NewErrorWithTip(err, "Configuration failed, use `aliyun configure` to configure it.")
+	}
+	i18n.SetLanguage(c.profile.Language)
+	c.clients = &sharedClient{}
+
+	// prepare every call first: safety confirmations are asked one at a time
+	// and the shared client is built once, before anything runs
+	jobs := make([]*batchJob, len(calls))
+	for i := range calls {
+		jobs[i] = c.newBatchJob(ctx, i+1, &calls[i])
+	}
+
+	results := c.runBatchJobs(jobs, concurrency, ctx.Stdout())
+
+	failed := 0
+	for _, r := range results {
+		if r.Status != BatchStatusSuccess {
+			failed++
+		}
+	}
+	cli.Printf(ctx.Stderr(), "batch: %d calls, %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d batch calls failed", failed, len(results))
+	}
+	return nil
+}
+
+// LoadBatchCalls reads the calls of a batch file: a YAML list for .yaml and
+// .yml files, JSON lines otherwise. Blank lines and lines starting with `#`
+// are skipped.
+func LoadBatchCalls(file string) ([]BatchCall, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read batch file failed: %w", err)
+	}
+
+	var calls []BatchCall
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &calls); err != nil {
+			return nil, fmt.Errorf("parse batch file %s failed: %w", file, err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			var call BatchCall
+			dec := json.NewDecoder(strings.NewReader(text))
+			dec.UseNumber()
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&call); err != nil {
+				return nil, fmt.Errorf("parse batch file %s line %d failed: %w", file, line, err)
+			}
+			calls = append(calls, call)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read batch file failed: %w", err)
+		}
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("batch file %s has no calls", file)
+	}
+	return calls, nil
+}
+
+// newBatchJob resolves a call the way `aliyun <product> ...` does, runs the
+// safety policy check and prepares its invoker. Errors are kept in the job and
+// reported as its result.
+func (c *Commando) newBatchJob(parent *cli.Context, index int, call *BatchCall) *batchJob {
+	job := &batchJob{index: index, call: call}
+	if call.Product == "" {
+		job.err = fmt.Errorf("missing product")
+		return job
+	}
+	product, ok := c.library.GetProduct(call.Product)
+	if !ok {
+		job.err = &InvalidProductError{Code: call.Product, library: c.library}
+		return job
+	}
+	if ShouldUseOpenapi(parent, &product) {
+		job.err = fmt.Errorf("product %s is not supported by batch", product.GetLowerCode())
+		return job
+	}
+	if call.Version != "" {
+		style, ok := c.library.GetStyle(product.Code, call.Version)
+		if !ok {
+			job.err = fmt.Errorf("unchecked version %s", call.Version)
+			return job
+		}
+		product.ApiStyle = style
+	}
+
+	cmdName := call.Api
+	switch {
+	case call.Path != "":
+		if call.Method == "" {
+			job.err = fmt.Errorf("missing method for path %s", call.Path)
+			return job
+		}
+		job.apiOrMethod, job.path = strings.ToUpper(call.Method), call.Path
+		if api, ok := c.library.GetApiByPath(product.Code, product.Version, job.apiOrMethod, job.path); ok {
+			cmdName = api.Name
+		}
+	case call.Api == "":
+		job.err = fmt.Errorf("missing api, or method and path")
+		return job
+	case strings.ToLower(product.ApiStyle) == "restful":
+		api, ok := c.library.GetApi(product.Code, product.Version, call.Api)
+		if !ok {
+			job.err = &InvalidApiError{Name: call.Api, product: &product}
+			return job
+		}
+		job.apiOrMethod, job.path = api.Method, api.PathPattern
+	default:
+		if _, ok := c.library.GetApi(product.Code, product.Version, call.Api); !ok {
+			job.err = &InvalidApiError{Name: call.Api, product: &product}
+			return job
+		}
+		job.apiOrMethod = call.Api
+	}
+
+	ctx, err := newBatchCallContext(parent, call, cmdName)
+	if err != nil {
+		job.err = err
+		return job
+	}
+	safetyApi := call.Api
+	if safetyApi == "" {
+		safetyApi = job.apiOrMethod
+	}
+	job.invoker, job.err = c.createInvoker(ctx, call.Product, job.apiOrMethod, job.path)
+	if job.err == nil {
+		job.err = job.invoker.Prepare(ctx)
+	}
+	if job.err == nil {
+		target := safety.CommandInfo{Product: product.Code, ApiOrMethod: safetyApi, Path: call.Path}
+		params := mergeSafetyParams(safetyParams(ctx), commonRequestParams(job.invoker.getRequest()))
+		job.err = c.checkSafetyPolicy(ctx, target, params)
+	}
+	return job
+}
+
+// newBatchCallContext builds the context a single `aliyun` invocation of the
+// call would have: the flags of the batch command plus the call's own region,
+// endpoint, version, body, headers and parameters.
+func newBatchCallContext(parent *cli.Context, call *BatchCall, cmdName string) (*cli.Context, error) {
+	cmd := &cli.Command{Name: cmdName, EnableUnknownFlag: true}
+	config.AddFlags(cmd.Flags())
+	AddFlags(cmd.Flags())
+	ctx := cli.NewCommandContext(io.Discard, parent.Stderr())
+	ctx.SetCommand(cmd)
+	ctx.SetInConfigureMode(parent.InConfigureMode())
+	ctx.SetInsecure(parent.Insecure())
+
+	for _, f := range parent.Flags().Flags() {
+		if !f.IsAssigned() {
+			continue
+		}
+		if target := ctx.Flags().Get(f.Name); target != nil && target != f {
+			target.SetAssigned(true)
+			target.SetValue(f.GetStringOrDefault(""))
+			target.SetValues(f.GetValues())
+		}
+	}
+
+	set := func(f *cli.Flag, value string) {
+		f.SetAssigned(true)
+		f.SetValue(value)
+	}
+	if call.Region != "" {
+		set(config.RegionFlag(ctx.Flags()), call.Region)
+		config.RegionIdFlag(ctx.Flags()).SetAssigned(false)
+	}
+	if call.Endpoint != "" {
+		set(config.EndpointFlag(ctx.Flags()), call.Endpoint)
+	}
+	if call.Version != "" {
+		set(VersionFlag(ctx.Flags()), call.Version)
+	}
+	if call.Body != nil {
+		body, err := batchValue(call.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body: %w", err)
+		}
+		set(BodyFlag(ctx.Flags()), body)
+	}
+	if len(call.Headers) > 0 {
+		headers := HeaderFlag(ctx.Flags())
+		headers.SetAssigned(true)
+		values := append([]string{}, headers.GetValues()...)
+		for k, v := range call.Headers {
+			values = append(values, k+"="+v)
+		}
+		headers.SetValues(values)
+	}
+	for name, v := range call.Params {
+		value, err := batchValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %w", name, err)
+		}
+		f, err := ctx.UnknownFlags().AddByName(name)
+		if err != nil {
+			return nil, err
+		}
+		set(f, value)
+	}
+	return ctx, nil
+}
+
+// batchValue turns a parameter or body value into the string the CLI would
+// have been given: scalars as written, lists and objects as JSON.
+func batchValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case int:
+		return strconv.Itoa(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// runBatchJobs invokes the jobs with at most concurrency calls in flight and
+// prints each result as a JSON line, in job order, as soon as it is known.
+func (c *Commando) runBatchJobs(jobs []*batchJob, concurrency int, w io.Writer) []*BatchResult {
+	results := make([]*BatchResult, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < concurrency && n < len(jobs); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = c.invokeBatchJob(jobs[i])
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range jobs {
+			queue <- i
+		}
+		close(queue)
+	}()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for i := range jobs {
+		<-done[i]
+		encoder.Encode(results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Commando) invokeBatchJob(job *batchJob) *BatchResult {
+	result := &BatchResult{
+		Index:   job.index,
+		Product: job.call.Product,
+		Api:     job.call.Api,
+		Region:  job.call.Region,
+		Status:  BatchStatusFailed,
+	}
+	if job.path != "" {
+		result.Method, result.Path = job.apiOrMethod, job.path
+	}
+	if job.err != nil {
+		result.Error = job.err.Error()
+		return result
+	}
+	result.Region = job.invoker.getRequest().RegionId
+
+	resp, err := hookdo(job.invoker.Call)()
+	if resp != nil && resp.GetHttpStatus() != 0 {
+		result.HttpStatus = resp.GetHttpStatus()
+		result.RequestId = responseRequestId(resp.GetHttpHeaders(), resp.GetHttpContentString())
+		result.Body = batchBody(resp)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		var serverErr *sdkerrors.ServerError
+		if errors.As(err, &serverErr) {
+			result.HttpStatus = serverErr.HttpStatus()
+			result.RequestId = serverErr.RequestId()
+		}
+		return result
+	}
+	result.Status = BatchStatusSuccess
+	return result
+}
+
+// batchBody keeps a JSON response as is and quotes anything else, e.g. XML.
+func batchBody(resp *responses.CommonResponse) json.RawMessage {
+	body := resp.GetHttpContentBytes()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var buf bytes.Buffer
+		if json.Compact(&buf, body) == nil {
+			return buf.Bytes()
+		}
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
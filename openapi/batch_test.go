@@ -0,0 +1,288 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBatchFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadBatchCalls_JSONL(t *testing.T) {
+	path := writeBatchFile(t, "calls.jsonl", `
+# tag two instances
+{"product":"ecs","api":"TagResources","region":"cn-hangzhou","params":{"ResourceId.1":"i-1","Amount":12345678901234567890}}
+
+{"product":"cs","method":"GET","path":"/clusters","body":{"a":1},"headers":{"x-test":"v"}}
+`)
+	calls, err := LoadBatchCalls(path)
+	assert.Nil(t, err)
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "TagResources", calls[0].Api)
+	assert.Equal(t, "cn-hangzhou", calls[0].Region)
+	value, err := batchValue(calls[0].Params["Amount"])
+	assert.Nil(t, err)
+	assert.Equal(t, "12345678901234567890", value)
+	assert.Equal(t, "/clusters", calls[1].Path)
+	assert.Equal(t, "v", calls[1].Headers["x-test"])
+
+	path = writeBatchFile(t, "bad.jsonl", "{\"product\":\"ecs\",\"api\":\"DescribeRegions\"}\n{\"product\":\"ecs\",\"apis\":\"x\"}\n")
+	_, err = LoadBatchCalls(path)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 2 failed")
+
+	path = writeBatchFile(t, "empty.jsonl", "# nothing\n")
+	_, err = LoadBatchCalls(path)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "has no calls")
+
+	_, err = LoadBatchCalls(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "read batch file failed")
+}
+
+func TestLoadBatchCalls_YAML(t *testing.T) {
+	path := writeBatchFile(t, "calls.yaml", `
+- product: ecs
+  api: DescribeInstances
+  params:
+    PageSize: 50
+    DryRun: false
+- product: cs
+  method: POST
+  path: /clusters
+  body:
+    name: test
+`)
+	calls, err := LoadBatchCalls(path)
+	assert.Nil(t, err)
+	assert.Len(t, calls, 2)
+	value, _ := batchValue(calls[0].Params["PageSize"])
+	assert.Equal(t, "50", value)
+	value, _ = batchValue(calls[0].Params["DryRun"])
+	assert.Equal(t, "false", value)
+	body, _ := batchValue(calls[1].Body)
+	assert.Equal(t, `{"name":"test"}`, body)
+
+	path = writeBatchFile(t, "bad.yml", "product: ecs\n")
+	_, err = LoadBatchCalls(path)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "parse batch file")
+}
+
+func TestLoadBatchCalls_Stdin(t *testing.T) {
+	origin := stdin
+	defer func() { stdin = origin }()
+	stdin = strings.NewReader(`{"product":"ecs","api":"DescribeRegions"}`)
+	calls, err := LoadBatchCalls("-")
+	assert.Nil(t, err)
+	assert.Len(t, calls, 1)
+}
+
+func TestBatchValue(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{"abc", "abc"},
+		{json.Number("10"), "10"},
+		{true, "true"},
+		{7, "7"},
+		{1.5, "1.5"},
+		{float64(1000000), "1000000"},
+		{[]interface{}{"a", "b"}, `["a","b"]`},
+		{map[string]interface{}{"Key": "env"}, `{"Key":"env"}`},
+	} {
+		got, err := batchValue(tc.in)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestNewBatchCallContext(t *testing.T) {
+	parent := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	config.AddFlags(parent.Flags())
+	parent.Flags().Add(NewYesFlag())
+	config.RegionFlag(parent.Flags()).SetAssigned(true)
+	config.RegionFlag(parent.Flags()).SetValue("cn-beijing")
+	YesFlag(parent.Flags()).SetAssigned(true)
+
+	ctx, err := newBatchCallContext(parent, &BatchCall{
+		Product: "ecs",
+		Api:     "DescribeInstances",
+		Version: "2014-05-26",
+		Params:  map[string]interface{}{"PageSize": json.Number("50")},
+		Headers: map[string]string{"x-test": "v"},
+	}, "DescribeInstances")
+	assert.Nil(t, err)
+	assert.Equal(t, "DescribeInstances", ctx.Command().Name)
+	assert.True(t, YesFlag(ctx.Flags()).IsAssigned())
+	region, _ := config.RegionFlag(ctx.Flags()).GetValue()
+	assert.Equal(t, "cn-beijing", region)
+	version, _ := VersionFlag(ctx.Flags()).GetValue()
+	assert.Equal(t, "2014-05-26", version)
+	assert.Equal(t, []string{"x-test=v"}, HeaderFlag(ctx.Flags()).GetValues())
+	pageSize, _ := ctx.UnknownFlags().Get("PageSize").GetValue()
+	assert.Equal(t, "50", pageSize)
+	// the parent flags are copied, not shared
+	assert.NotSame(t, config.RegionFlag(parent.Flags()), config.RegionFlag(ctx.Flags()))
+
+	ctx, err = newBatchCallContext(parent, &BatchCall{Product: "ecs", Region: "cn-shanghai", Endpoint: "ecs.aliyuncs.com"}, "")
+	assert.Nil(t, err)
+	region, _ = config.RegionFlag(ctx.Flags()).GetValue()
+	assert.Equal(t, "cn-shanghai", region)
+	endpoint, _ := config.EndpointFlag(ctx.Flags()).GetValue()
+	assert.Equal(t, "ecs.aliyuncs.com", endpoint)
+}
+
+func newBatchTestCommando() *Commando {
+	c := NewCommando(new(bytes.Buffer), config.Profile{
+		Language:        "en",
+		Mode:            "AK",
+		AccessKeyId:     "accesskeyid",
+		AccessKeySecret: "accesskeysecret",
+		RegionId:        "cn-hangzhou",
+	})
+	c.library.builtinRepo, _ = meta.MockLoadRepository([]meta.Product{
+		{Code: "Ecs", Version: "2014-05-26", ApiStyle: "rpc", ApiNames: []string{"DescribeInstances"}},
+		{Code: "CS", Version: "2015-12-15", ApiStyle: "restful"},
+		{Code: "Sls", Version: "2020-12-30", ApiStyle: "restful"},
+	})
+	return c
+}
+
+func TestNewBatchJob(t *testing.T) {
+	c := newBatchTestCommando()
+	c.clients = &sharedClient{}
+	parent := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	config.AddFlags(parent.Flags())
+
+	first := c.newBatchJob(parent, 1, &BatchCall{Product: "cs", Method: "get", Path: "/clusters", Endpoint: "cs.aliyuncs.com"})
+	assert.Nil(t, first.err)
+	assert.Equal(t, "GET", first.apiOrMethod)
+	assert.Equal(t, "/clusters", first.path)
+	request := first.invoker.getRequest()
+	assert.Equal(t, "cs.aliyuncs.com", request.Domain)
+	assert.Equal(t, "cn-hangzhou", request.RegionId)
+
+	second := c.newBatchJob(parent, 2, &BatchCall{Product: "cs", Method: "GET", Path: "/clusters", Region: "cn-beijing",
+		Endpoint: "cs.cn-beijing.aliyuncs.com", Params: map[string]interface{}{"page_size": 10}})
+	assert.Nil(t, second.err)
+	request = second.invoker.getRequest()
+	assert.Equal(t, "cn-beijing", request.RegionId)
+	assert.Equal(t, "10", request.QueryParams["page_size"])
+	// every call of the batch shares the first client
+	assert.Same(t, first.invoker.getClient(), second.invoker.getClient())
+}
+
+func TestNewBatchJob_Errors(t *testing.T) {
+	c := newBatchTestCommando()
+	parent := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	config.AddFlags(parent.Flags())
+
+	for _, tc := range []struct {
+		call BatchCall
+		want string
+	}{
+		{BatchCall{Api: "DescribeRegions"}, "missing product"},
+		{BatchCall{Product: "ecs"}, "missing api, or method and path"},
+		{BatchCall{Product: "cs", Path: "/clusters"}, "missing method for path /clusters"},
+		{BatchCall{Product: "ecs", Api: "DescribeRegions", Version: "1999-01-01"}, "unchecked version 1999-01-01"},
+		{BatchCall{Product: "ecs", Api: "DescribeNothing"}, "DescribeNothing"},
+		{BatchCall{Product: "cs", Api: "DescribeClusters"}, "DescribeClusters"},
+		{BatchCall{Product: "foo", Api: "DescribeFoo"}, "'foo' is not a valid command or product"},
+		{BatchCall{Product: "sls", Api: "ListProject"}, "product sls is not supported by batch"},
+	} {
+		job := c.newBatchJob(parent, 1, &tc.call)
+		assert.NotNil(t, job.err, tc.want)
+		assert.Contains(t, job.err.Error(), tc.want)
+		assert.Nil(t, job.invoker)
+	}
+}
+
+func TestRunBatchJobs(t *testing.T) {
+	c := newBatchTestCommando()
+	failing := newFakeRegionInvoker()
+	failing.fail = "cn-hangzhou"
+	jobs := []*batchJob{
+		{index: 1, call: &BatchCall{Product: "ecs", Api: "DescribeInstances"}, invoker: newFakeRegionInvoker()},
+		{index: 2, call: &BatchCall{Product: "ecs", Api: "DeleteInstance"}, err: fmt.Errorf("operation blocked by safety policy")},
+		{index: 3, call: &BatchCall{Product: "ecs", Api: "DescribeInstances"}, invoker: failing},
+		{index: 4, call: &BatchCall{Product: "cs", Method: "GET", Path: "/clusters"}, apiOrMethod: "GET", path: "/clusters", invoker: newFakeRegionInvoker()},
+	}
+	out := new(bytes.Buffer)
+	results := c.runBatchJobs(jobs, 3, out)
+	assert.Len(t, results, 4)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	var first BatchResult
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, 1, first.Index)
+	assert.Equal(t, BatchStatusSuccess, first.Status)
+	assert.Equal(t, 200, first.HttpStatus)
+	assert.Equal(t, "cn-hangzhou-req", first.RequestId)
+	assert.Equal(t, "cn-hangzhou", first.Region)
+	assert.Contains(t, string(first.Body), `"InstanceId":"i-cn-hangzhou"`)
+
+	assert.Equal(t, `{"index":2,"product":"ecs","api":"DeleteInstance","status":"failed","error":"operation blocked by safety policy"}`, lines[1])
+	assert.Equal(t, BatchStatusFailed, results[2].Status)
+	assert.Equal(t, "region cn-hangzhou is down", results[2].Error)
+	assert.Equal(t, "GET", results[3].Method)
+	assert.Equal(t, "/clusters", results[3].Path)
+	assert.Equal(t, BatchStatusSuccess, results[3].Status)
+}
+
+func TestRunBatch(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	c := NewCommando(stdout, config.Profile{Language: "en", RegionId: "cn-hangzhou"})
+	cmd := c.NewBatchCommand()
+	ctx := cli.NewCommandContext(stdout, stderr)
+	config.AddFlags(ctx.Flags())
+	ctx.EnterCommand(cmd)
+
+	assert.Equal(t, "batch", cmd.Name)
+	assert.NotNil(t, ctx.Flags().Get(BatchFileFlagName))
+	assert.NotNil(t, ctx.Flags().Get(BatchConcurrencyFlagName))
+
+	ctx.Flags().Get(BatchConcurrencyFlagName).SetAssigned(true)
+	ctx.Flags().Get(BatchConcurrencyFlagName).SetValue("0")
+	err := c.runBatch(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, "invalid --concurrency 0, need a positive integer", err.Error())
+
+	ctx.Flags().Get(BatchConcurrencyFlagName).SetValue("2")
+	ctx.Flags().Get(BatchFileFlagName).SetAssigned(true)
+	ctx.Flags().Get(BatchFileFlagName).SetValue(filepath.Join(t.TempDir(), "missing.jsonl"))
+	err = c.runBatch(ctx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "read batch file failed")
+}
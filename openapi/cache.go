@@ -0,0 +1,238 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alibabacloud-go/tea/dara"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/cache"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/redact"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+)
+
+// volatileHeaders change on every run or retry without changing the response,
+// so they are left out of the cache key.
+var volatileHeaders = []string{"traceparent", "tracestate", "baggage", "x-acs-retry-attempts", "x-acs-retry-delay"}
+
+// openResponseCache returns the response cache for this call, and whether a
+// cached response must be skipped. --waiter polls for a change, so it never
+// uses the cache.
+func openResponseCache(ctx *cli.Context) (*cache.Store, bool) {
+	if NoCacheFlag(ctx.Flags()).IsAssigned() || ctx.Flags().Get(WaiterFlag.Name).IsAssigned() {
+		return nil, false
+	}
+	return cache.Open(config.GetConfigDir(ctx)), RefreshFlag(ctx.Flags()).IsAssigned()
+}
+
+// isReadOnlyCall classifies a call by its API name, or by its method when the
+// API is unknown, as for a ROA call by method and path.
+func isReadOnlyCall(apiName string, method string) bool {
+	if apiName != "" {
+		return safety.IsReadOnlyApiName(apiName)
+	}
+	return strings.EqualFold(method, "GET")
+}
+
+// isCacheableCall tells whether the response of a call may be written to the
+// cache, which is stored in plaintext. APIs whose name or ROA path looks like a
+// secret key, e.g. kms GetSecretValue or ram ListAccessKeys, are never cached
+// even when read-only.
+func isCacheableCall(apiName string, method string, path string) bool {
+	if !isReadOnlyCall(apiName, method) {
+		return false
+	}
+	return !redact.IsSensitiveKey(apiName) && !redact.IsSensitiveKey(path)
+}
+
+// hasSecretField tells whether a JSON response has a field that looks like a
+// credential, using the keys the audit log and the mock recorder mask. Such
+// responses are not cached whatever the API.
+func hasSecretField(body string) bool {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return false
+	}
+	var walk func(v interface{}) bool
+	walk = func(v interface{}) bool {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, child := range t {
+				if redact.IsSensitiveKey(k) || walk(child) {
+					return true
+				}
+			}
+		case []interface{}:
+			for _, child := range t {
+				if walk(child) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(v)
+}
+
+func profileCacheId(p *config.Profile) string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join([]string{p.Name, string(p.Mode), p.AccessKeyId, p.RamRoleArn}, "\n")
+}
+
+// canonicalJSON normalizes maps for the cache key; encoding/json sorts keys.
+func canonicalJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func cacheableHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[strings.ToLower(k)] = v
+	}
+	for _, k := range volatileHeaders {
+		delete(out, k)
+	}
+	return out
+}
+
+// responseCacheKey returns the cache key of request, or "" when the call is
+// not cached. It is computed before the call, as signing adds parameters.
+func (a *BasicInvoker) responseCacheKey(request *requests.CommonRequest) string {
+	if a == nil || a.cache == nil || request == nil || !isCacheableCall(request.ApiName, request.Method, request.PathPattern) {
+		return ""
+	}
+	return cache.Key(
+		profileCacheId(a.profile),
+		request.Product,
+		request.Version,
+		request.ApiName,
+		strings.ToUpper(request.Method),
+		request.PathPattern,
+		request.RegionId,
+		request.Domain,
+		canonicalJSON(request.QueryParams),
+		canonicalJSON(request.FormParams),
+		canonicalJSON(request.PathParams),
+		canonicalJSON(cacheableHeaders(request.Headers)),
+		string(request.Content),
+	)
+}
+
+func (a *BasicInvoker) cachedResponse(key string) (*responses.CommonResponse, bool) {
+	if key == "" || a.cacheRefresh {
+		return nil, false
+	}
+	e, ok := a.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	resp := responses.NewCommonResponse()
+	httpResponse := &http.Response{
+		StatusCode: e.Status,
+		Header:     http.Header(e.Headers),
+		Body:       io.NopCloser(strings.NewReader(e.Body)),
+	}
+	if err := responses.Unmarshal(resp, httpResponse, "JSON"); err != nil {
+		return nil, false
+	}
+	return resp, true
+}
+
+// cacheResponse saves a successful response. A cache that cannot be written
+// must not fail the call, so errors are dropped.
+func (a *BasicInvoker) cacheResponse(key string, resp *responses.CommonResponse) {
+	if key == "" || resp == nil || !resp.IsSuccess() || hasSecretField(resp.GetHttpContentString()) {
+		return
+	}
+	_ = a.cache.Put(key, &cache.Entry{
+		Status:  resp.GetHttpStatus(),
+		Headers: resp.GetHttpHeaders(),
+		Body:    resp.GetHttpContentString(),
+	})
+}
+
+func (a *OpenapiContext) responseCacheKey() string {
+	if a.cache == nil || a.api == nil || a.product == nil || !isCacheableCall(a.api.Name, a.method, dara.StringValue(a.openapiParams.Pathname)) {
+		return ""
+	}
+	r := a.openapiRequest
+	endpoint := dara.StringValue(r.EndpointOverride)
+	region := ""
+	if a.openapiClient != nil {
+		if endpoint == "" {
+			endpoint = dara.StringValue(a.openapiClient.Endpoint)
+		}
+		region = dara.StringValue(a.openapiClient.RegionId)
+	}
+	headers := make(map[string]string, len(r.Headers))
+	for k, v := range r.Headers {
+		headers[k] = dara.StringValue(v)
+	}
+	return cache.Key(
+		profileCacheId(a.profile),
+		a.product.GetLowerCode(),
+		dara.StringValue(a.openapiParams.Version),
+		a.api.Name,
+		strings.ToUpper(a.method),
+		dara.StringValue(a.openapiParams.Pathname),
+		region,
+		endpoint,
+		canonicalJSON(r.Query),
+		canonicalJSON(r.HostMap),
+		canonicalJSON(cacheableHeaders(headers)),
+		canonicalJSON(r.Body),
+	)
+}
+
+// Call serves read-only calls from the response cache when it is enabled. A
+// cached response is audited like a call, marked as cached.
+func (a *OpenapiContext) Call() error {
+	key := a.responseCacheKey()
+	if key != "" && !a.cacheRefresh {
+		if e, ok := a.cache.Get(key); ok {
+			a.openapiResponse = map[string]any{
+				"statusCode": e.Status,
+				"headers":    map[string]*string{},
+				"body":       e.Body,
+			}
+			a.recordAudit(nil, time.Now(), true)
+			return nil
+		}
+	}
+	if err := a.HttpContext.Call(); err != nil {
+		return err
+	}
+	if key != "" {
+		status, _ := a.openapiResponse["statusCode"].(int)
+		body := GetContentFromApiResponse(a.openapiResponse)
+		if (status == 0 || (status >= 200 && status < 300)) && !hasSecretField(body) {
+			_ = a.cache.Put(key, &cache.Entry{Status: status, Body: body})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/i18n"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/cache"
+)
+
+const CacheTTLFlagName = "ttl"
+
+func NewCacheCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name: "cache",
+		Short: i18n.T(
+			"manage the local cache of read-only API responses",
+			"管理只读 API 响应的本地缓存"),
+		Usage: "cache [show|enable|disable|clear] [--config-path <configPath>]",
+		Long: i18n.T(
+			`Configure the response cache. When enabled, the responses of read-only APIs (Describe*, List*, Get*, Query*, Search*, or ROA GET requests without a known API name) are cached under the cache dir of the config dir, keyed by profile, endpoint, API and parameters, and reused until the TTL expires (default 60 seconds). APIs that return credentials (names with Secret, AccessKey, Password, Token, Credential, Kubeconfig or PrivateKey) and responses with secret fields are never cached. Cached responses are still written to the audit log, marked as cached.
+Use --no-cache to bypass the cache for one call, or --refresh to skip the cached response and cache a fresh one. --waiter never uses the cache.
+Environment variables ALIBABA_CLOUD_CLI_CACHE_ENABLED and ALIBABA_CLOUD_CLI_CACHE_TTL override the saved settings.`,
+			`配置响应缓存。启用后，只读 API（Describe*、List*、Get*、Query*、Search*，或无已知 API 名称的 ROA GET 请求）的响应会缓存在配置目录下的 cache 目录中，按 profile、endpoint、API 和参数区分，在 TTL 过期前重复使用（默认 60 秒）。返回凭证的 API（名称含 Secret、AccessKey、Password、Token、Credential、Kubeconfig 或 PrivateKey）及含敏感字段的响应不会被缓存。命中缓存的调用仍会写入审计日志，并标记为 cached。
+使用 --no-cache 对单次调用绕过缓存，或使用 --refresh 跳过已缓存的响应并缓存新的响应。--waiter 从不使用缓存。
+环境变量 ALIBABA_CLOUD_CLI_CACHE_ENABLED 和 ALIBABA_CLOUD_CLI_CACHE_TTL 会覆盖已保存的设置。`),
+		Sample: "aliyun cache enable --ttl 300\n  aliyun cache clear",
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doCacheShow(ctx)
+		},
+	}
+
+	cmd.AddSubCommand(&cli.Command{
+		Name:  "show",
+		Usage: "show [--config-path <configPath>]",
+		Short: i18n.T("display current response cache config", "显示当前响应缓存配置"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doCacheShow(ctx)
+		},
+	})
+
+	enable := &cli.Command{
+		Name:  "enable",
+		Usage: "enable [--ttl <seconds>] [--config-path <configPath>]",
+		Short: i18n.T("turn on the response cache", "开启响应缓存"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			return doCacheEnable(ctx)
+		},
+	}
+	enable.Flags().Add(&cli.Flag{
+		Category:     "cache",
+		Name:         CacheTTLFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"seconds a cached response stays valid (default: 60)",
+			"缓存响应的有效秒数（默认：60）"),
+	})
+	cmd.AddSubCommand(enable)
+
+	cmd.AddSubCommand(&cli.Command{
+		Name:  "disable",
+		Usage: "disable [--config-path <configPath>]",
+		Short: i18n.T("turn off the response cache", "关闭响应缓存"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			configDir := config.GetConfigDir(ctx)
+			cfg, err := cache.Load(configDir)
+			if err != nil {
+				return fmt.Errorf("load cache config failed: %w", err)
+			}
+			cfg.Enabled = false
+			return cache.Save(configDir, cfg)
+		},
+	})
+
+	cmd.AddSubCommand(&cli.Command{
+		Name:  "clear",
+		Usage: "clear [--config-path <configPath>]",
+		Short: i18n.T("remove every cached response", "删除所有已缓存的响应"),
+		Run: func(ctx *cli.Context, args []string) error {
+			if len(args) > 0 {
+				return cli.NewInvalidCommandError(args[0], ctx)
+			}
+			n, err := cache.Clear(config.GetConfigDir(ctx))
+			if err != nil {
+				return fmt.Errorf("clear cache failed: %w", err)
+			}
+			cli.Printf(ctx.Stdout(), "removed %d cached responses\n", n)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func doCacheShow(ctx *cli.Context) error {
+	configDir := config.GetConfigDir(ctx)
+	cfg, err := cache.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load cache config failed: %w", err)
+	}
+	effective := cache.MergeFromEnv(cfg)
+	out := struct {
+		Enabled             bool   `json:"enabled"`
+		TTLSeconds          int    `json:"ttl_seconds,omitempty"`
+		EffectiveEnabled    bool   `json:"effective_enabled"`
+		EffectiveTTLSeconds int    `json:"effective_ttl_seconds"`
+		CacheDir            string `json:"cache_dir"`
+		ConfigFile          string `json:"config_file"`
+	}{
+		Enabled:             cfg.Enabled,
+		TTLSeconds:          cfg.TTLSeconds,
+		EffectiveEnabled:    effective.Enabled,
+		EffectiveTTLSeconds: int(effective.TTL().Seconds()),
+		CacheDir:            cache.GetDir(configDir),
+		ConfigFile:          cache.GetConfigFilePath(configDir),
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	cli.Println(ctx.Stdout(), string(data))
+	return nil
+}
+
+func doCacheEnable(ctx *cli.Context) error {
+	configDir := config.GetConfigDir(ctx)
+	cfg, err := cache.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load cache config failed: %w", err)
+	}
+	if v, ok := ctx.Flags().Get(CacheTTLFlagName).GetValue(); ok {
+		ttl, err := strconv.Atoi(v)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid --ttl %s, need a positive number of seconds", v)
+		}
+		cfg.TTLSeconds = ttl
+	}
+	cfg.Enabled = true
+	return cache.Save(configDir, cfg)
+}
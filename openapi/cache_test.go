@@ -0,0 +1,295 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	openapiClient "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCacheTestResponse(status int, body string) *responses.CommonResponse {
+	resp := responses.NewCommonResponse()
+	_ = responses.Unmarshal(resp, &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, "JSON")
+	return resp
+}
+
+func newCacheTestInvoker(t *testing.T, apiName string) *BasicInvoker {
+	request := requests.NewCommonRequest()
+	request.Product = "Ecs"
+	request.Version = "2014-05-26"
+	request.ApiName = apiName
+	request.RegionId = "cn-hangzhou"
+	request.Domain = "ecs.cn-hangzhou.aliyuncs.com"
+	request.QueryParams["VpcId"] = "vpc-1"
+	return &BasicInvoker{
+		profile: &config.Profile{Name: "default", Mode: config.AK, AccessKeyId: "id"},
+		request: request,
+		cache:   cache.NewStore(filepath.Join(t.TempDir(), cache.DirName), time.Minute),
+	}
+}
+
+func TestIsReadOnlyCall(t *testing.T) {
+	assert.True(t, isReadOnlyCall("DescribeVpcs", "POST"))
+	assert.False(t, isReadOnlyCall("DeleteVpc", "GET"))
+	assert.True(t, isReadOnlyCall("", "get"))
+	assert.False(t, isReadOnlyCall("", "POST"))
+}
+
+func TestIsCacheableCall(t *testing.T) {
+	assert.True(t, isCacheableCall("DescribeVpcs", "POST", ""))
+	assert.False(t, isCacheableCall("DeleteVpc", "GET", ""))
+	assert.False(t, isCacheableCall("GetSecretValue", "POST", ""))
+	assert.False(t, isCacheableCall("ListAccessKeys", "POST", ""))
+	assert.False(t, isCacheableCall("DescribeClusterUserKubeconfig", "GET", "/k8s/[ClusterId]/user_config"))
+	assert.False(t, isCacheableCall("", "GET", "/api/v1/credentials"))
+	assert.True(t, isCacheableCall("", "GET", "/clusters"))
+}
+
+func TestHasSecretField(t *testing.T) {
+	assert.False(t, hasSecretField(`{"RequestId":"r1","Vpcs":{"Vpc":[{"VpcId":"vpc-1"}]}}`))
+	assert.True(t, hasSecretField(`{"SecretData":"s3cr3t"}`))
+	assert.True(t, hasSecretField(`{"Credentials":[{"AccessKeySecret":"x","SecurityToken":"y"}]}`))
+	assert.True(t, hasSecretField(`{"Result":{"oauth_access_token":"x"}}`))
+	assert.True(t, hasSecretField(`{"AccountPassword":"x"}`))
+	assert.False(t, hasSecretField(`{"NextToken":"n1","Items":[{"ClientToken":"c1"}]}`))
+	assert.False(t, hasSecretField(`not json`))
+}
+
+func TestBasicInvokerResponseCache(t *testing.T) {
+	invoker := newCacheTestInvoker(t, "DescribeVpcs")
+	calls := 0
+	call := func() (*responses.CommonResponse, error) {
+		calls++
+		return newCacheTestResponse(200, `{"RequestId":"r1"}`), nil
+	}
+
+	resp, err := invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	assert.Equal(t, `{"RequestId":"r1"}`, resp.GetHttpContentString())
+	resp, err = invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 200, resp.GetHttpStatus())
+	assert.Equal(t, `{"RequestId":"r1"}`, resp.GetHttpContentString())
+
+	// another parameter value is another entry
+	invoker.request.QueryParams["VpcId"] = "vpc-2"
+	_, err = invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// retry and trace headers do not change the key
+	invoker.request.Headers["traceparent"] = "00-abc-def-01"
+	invoker.request.Headers["x-acs-retry-attempts"] = "1"
+	_, err = invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// --refresh calls the API and caches the fresh response
+	invoker.cacheRefresh = true
+	_, err = invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestBasicInvokerResponseCacheSkipsWritesAndErrors(t *testing.T) {
+	invoker := newCacheTestInvoker(t, "DeleteVpc")
+	calls := 0
+	call := func() (*responses.CommonResponse, error) {
+		calls++
+		return newCacheTestResponse(200, `{}`), nil
+	}
+	_, _ = invoker.callWithThrottlingRetry(call)
+	_, _ = invoker.callWithThrottlingRetry(call)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "", invoker.responseCacheKey(invoker.request))
+
+	invoker = newCacheTestInvoker(t, "DescribeVpcs")
+	key := invoker.responseCacheKey(invoker.request)
+	invoker.cacheResponse(key, newCacheTestResponse(404, `{"Code":"NotFound"}`))
+	_, ok := invoker.cachedResponse(key)
+	assert.False(t, ok)
+
+	// responses with credentials are not written to the plaintext cache
+	invoker.cacheResponse(key, newCacheTestResponse(200, `{"Credentials":{"AccessKeySecret":"x"}}`))
+	_, ok = invoker.cachedResponse(key)
+	assert.False(t, ok)
+
+	// a disabled cache has no key
+	invoker.cache = nil
+	assert.Equal(t, "", invoker.responseCacheKey(invoker.request))
+}
+
+func TestOpenapiContextResponseCache(t *testing.T) {
+	origExecute := httpContextExecuteFunc
+	defer func() { httpContextExecuteFunc = origExecute }()
+	calls := 0
+	httpContextExecuteFunc = func(a *HttpContext) (map[string]interface{}, error) {
+		calls++
+		return map[string]interface{}{"statusCode": 200, "body": map[string]interface{}{"RequestId": "r1"}}, nil
+	}
+
+	newContext := func(apiName string) *OpenapiContext {
+		return &OpenapiContext{
+			HttpContext: &HttpContext{
+				profile:        &config.Profile{Name: "default"},
+				product:        &meta.Product{Code: "Sls"},
+				openapiRequest: &openapiutil.OpenApiRequest{Headers: map[string]*string{}, Query: map[string]*string{"offset": tea.String("0")}},
+				openapiParams:  &openapiClient.Params{Pathname: tea.String("/logstores"), Version: tea.String("2020-12-30")},
+				cache:          cache.NewStore(filepath.Join(t.TempDir(), cache.DirName), time.Minute),
+			},
+			method: "GET",
+			api:    &meta.Api{Name: apiName},
+		}
+	}
+
+	a := newContext("ListLogStores")
+	require.NoError(t, a.Call())
+	first, _ := a.GetResponse()
+	a.openapiResponse = nil
+	require.NoError(t, a.Call())
+	second, _ := a.GetResponse()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, `{"RequestId":"r1"}`, first)
+	assert.Equal(t, first, second)
+
+	a = newContext("DeleteLogStore")
+	require.NoError(t, a.Call())
+	require.NoError(t, a.Call())
+	assert.Equal(t, 3, calls)
+
+	a = newContext("GetSecretValue")
+	require.NoError(t, a.Call())
+	require.NoError(t, a.Call())
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCacheHitIsAudited(t *testing.T) {
+	invoker := newCacheTestInvoker(t, "DescribeVpcs")
+	invoker.auditLog = openTestAuditLog(t)
+	call := func() (*responses.CommonResponse, error) {
+		return newCacheTestResponse(200, `{"RequestId":"r1"}`), nil
+	}
+	_, err := invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+	_, err = invoker.callWithThrottlingRetry(call)
+	require.NoError(t, err)
+
+	entries := finishTestAuditLog(t, invoker.auditLog, 0)
+	require.Len(t, entries, 3)
+	assert.False(t, entries[0].Cached)
+	assert.True(t, entries[1].Cached)
+	assert.Equal(t, "DescribeVpcs", entries[1].Api)
+	assert.Equal(t, 200, entries[1].StatusCode)
+	assert.Equal(t, "r1", entries[1].RequestId)
+}
+
+func TestOpenResponseCache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, cache.Save(dir, &cache.Config{Enabled: true}))
+	newContext := func() *cli.Context {
+		ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+		config.AddFlags(ctx.Flags())
+		AddFlags(ctx.Flags())
+		config.ConfigurePathFlag(ctx.Flags()).SetAssigned(true)
+		config.ConfigurePathFlag(ctx.Flags()).SetValue(filepath.Join(dir, "config.json"))
+		return ctx
+	}
+
+	store, refresh := openResponseCache(newContext())
+	assert.NotNil(t, store)
+	assert.False(t, refresh)
+
+	ctx := newContext()
+	RefreshFlag(ctx.Flags()).SetAssigned(true)
+	store, refresh = openResponseCache(ctx)
+	assert.NotNil(t, store)
+	assert.True(t, refresh)
+
+	ctx = newContext()
+	NoCacheFlag(ctx.Flags()).SetAssigned(true)
+	store, _ = openResponseCache(ctx)
+	assert.Nil(t, store)
+
+	ctx = newContext()
+	ctx.Flags().Get(WaiterFlag.Name).SetAssigned(true)
+	defer WaiterFlag.SetAssigned(false)
+	store, _ = openResponseCache(ctx)
+	assert.Nil(t, store)
+}
+
+func TestCacheCommand(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		stdout := new(bytes.Buffer)
+		ctx := cli.NewCommandContext(stdout, new(bytes.Buffer))
+		root := &cli.Command{Name: "aliyun"}
+		config.AddFlags(root.Flags())
+		root.AddSubCommand(NewCacheCommand())
+		ctx.EnterCommand(root)
+		root.Execute(ctx, append(args, "--config-path", filepath.Join(dir, "config.json")))
+		return stdout.String()
+	}
+	run("cache", "enable", "--ttl", "300")
+	cfg, err := cache.Load(dir)
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 300, cfg.TTLSeconds)
+
+	out := run("cache", "show")
+	assert.Contains(t, out, `"effective_ttl_seconds": 300`)
+
+	require.NoError(t, cache.NewStore(cache.GetDir(dir), time.Minute).Put("k", &cache.Entry{Status: 200}))
+	out = run("cache", "clear")
+	assert.Equal(t, "removed 1 cached responses\n", out)
+
+	run("cache", "disable")
+	cfg, err = cache.Load(dir)
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 300, cfg.TTLSeconds)
+}
+
+func TestDoCacheEnableInvalidTTL(t *testing.T) {
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	config.AddFlags(ctx.Flags())
+	config.ConfigurePathFlag(ctx.Flags()).SetAssigned(true)
+	config.ConfigurePathFlag(ctx.Flags()).SetValue(filepath.Join(t.TempDir(), "config.json"))
+	ctx.Flags().Add(&cli.Flag{Name: CacheTTLFlagName, AssignedMode: cli.AssignedOnce})
+	ctx.Flags().Get(CacheTTLFlagName).SetAssigned(true)
+	ctx.Flags().Get(CacheTTLFlagName).SetValue("0")
+	err := doCacheEnable(ctx)
+	assert.EqualError(t, err, "invalid --ttl 0, need a positive number of seconds")
+}
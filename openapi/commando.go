@@ -17,6 +17,8 @@ import (
 	"bufio"
 	"bytes"
 
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/aliyun/aliyun-cli/v3/cli/plugin"
@@ -33,6 +35,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	jmespath "github.com/jmespath/go-jmespath"
@@ -46,6 +49,11 @@ type Commando struct {
 	pluginIndexErr error // set when remote plugin index could not be loaded
 	localManifest  *plugin.LocalManifest
 	pluginLoaded   bool
+	// clients is set by `aliyun batch` to share one client between its calls
+	clients *sharedClient
+	// safetyTarget is the command as typed, processInvoke and processApiInvoke
+	// check it against the safety policy once the request is prepared
+	safetyTarget *safety.CommandInfo
 }
 
 var hookdo = func(fn func() (*responses.CommonResponse, error)) func() (*responses.CommonResponse, error) {
@@ -181,6 +189,10 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 			"add --estimate-cost, e.g. aliyun ecs RunInstances ... --estimate-cost --estimate-cost-context EstimatedInternetTrafficOutGB=100")
 	}
 
+	if err := checkPagerFlag(ctx); err != nil {
+		return err
+	}
+
 	// aliyun
 	if len(args) == 0 {
 		c.printUsage(ctx)
@@ -352,7 +364,7 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 				forceOn, forceOff := CliAIOverrides(ctx.Flags())
 				aimode.MergeUserAgentIntoPluginEnvs(configDir, envs, forceOn, forceOff)
 				util.MergeAgentSegmentIntoPluginEnvs(envs)
-				safety.MergeSafetyPolicyPathIntoEnvs(configDir, envs)
+				config.MergeSafetyPolicyIntoEnvs(configDir, envs)
 				throttlingretry.MergeIntoPluginEnvs(configDir, envs)
 				headers.MergeIntoPluginEnvs(envs)
 				ctx.SetRuntimeEnvs(envs)
@@ -368,14 +380,17 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 			//   aliyun fc function create       -> fc:function:create
 			//   aliyun fc invoke my-fn          -> fc:invoke:my-fn
 			// Users can still match coarsely with wildcards like `fc:function:*` or `fc:function*`.
+			endAudit := func() {}
 			if !isHelp && !isVersion {
 				cmdName := strings.Join(args[1:], ":")
-				if err := c.checkSafetyPolicy(ctx, args[0], cmdName, ""); err != nil {
+				if err := c.checkSafetyPolicy(ctx, safety.CommandInfo{Product: args[0], ApiOrMethod: cmdName}, safetyParams(ctx)); err != nil {
 					return err
 				}
+				endAudit = c.startPluginAudit(ctx, pluginName, args[0], cmdName, pluginArgs)
 			}
 
 			ok, err := plugin.ExecutePlugin(args[0], pluginArgs, ctx)
+			endAudit()
 			if err != nil {
 				return err
 			}
@@ -437,9 +452,7 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 		}
 		// Safety policy is keyed on what the user actually typed, so a rule like `sls:ListProject` matches `aliyun sls ListProject`
 		// regardless of how the cli later dispatches it (REST GET /, RPC, etc.).
-		if err := c.checkSafetyPolicy(ctx, product.Code, args[1], ""); err != nil {
-			return err
-		}
+		c.safetyTarget = &safety.CommandInfo{Product: product.Code, ApiOrMethod: args[1]}
 		if product.ApiStyle == "restful" {
 			api, found := meta.HookGetApi(c.library.GetApi)(product.Code, product.Version, args[1])
 			// For restful products, the 2-arg form `aliyun <product> <ApiName>` requires a valid ApiName so we can resolve the underlying Method + PathPattern from metadata.
@@ -455,6 +468,9 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 				// invoker resolve a method/path the metadata doesn't have,
 				// so the quote intent always wins here.
 				if EstimateCostFlag(ctx.Flags()).IsAssigned() {
+					if err := c.checkSafetyPolicy(ctx, *c.safetyTarget, safetyParams(ctx)); err != nil {
+						return err
+					}
 					return c.processEstimateCostByTriple(ctx, &product, product.Version, args[1])
 				}
 				if !force {
@@ -488,9 +504,7 @@ func (c *Commando) main(ctx *cli.Context, args []string) error {
 		if find {
 			c.CheckApiParamWithBuildInArgs(ctx, api)
 		}
-		if err := c.checkSafetyPolicy(ctx, product.Code, args[1], args[2]); err != nil {
-			return err
-		}
+		c.safetyTarget = &safety.CommandInfo{Product: product.Code, ApiOrMethod: args[1], Path: args[2]}
 		if ShouldUseOpenapi(ctx, &product) {
 			if !find {
 				return cli.NewErrorWithTip(fmt.Errorf("can not find api by path %s", args[2]),
@@ -514,6 +528,15 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 		return fmt.Errorf("invalid product, please check product code")
 	}
 
+	if RegionsFlag(ctx.Flags()).IsAssigned() {
+		return cli.NewErrorWithTip(
+			fmt.Errorf("--regions is not supported for product %s on this invoke path", product.Code),
+			"run the command once per region with --region instead")
+	}
+
+	if err := c.checkUnpreparedSafetyPolicy(ctx); err != nil {
+		return err
+	}
 	apiContext, err := c.createHttpContext(ctx, product, api, method, path)
 	if err != nil {
 		return err
@@ -522,6 +545,9 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 	if err != nil {
 		return err
 	}
+	if err := c.checkPreparedSafetyPolicy(ctx, openapiRequestParams(apiContext.getRequest())); err != nil {
+		return err
+	}
 
 	if CliDryRunFlag(ctx.Flags()).IsAssigned() {
 		oc, ok := apiContext.(*OpenapiContext)
@@ -577,18 +603,22 @@ func (c *Commando) processApiInvoke(ctx *cli.Context, product *meta.Product, api
 		}
 	}
 
-	if filter := GetOutputFilter(ctx); filter != nil {
+	if filter := GetOutputFilterWithDefault(ctx, c.profile.OutputFormat); filter != nil {
 		out, err = filter.FilterOutput(out)
 		if err != nil {
 			return err
 		}
+	} else {
+		out = sortJSON(out)
 	}
-	out = sortJSON(out)
 	cli.Println(ctx.Stdout(), out)
 	return nil
 }
 
 func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMethod string, path string) error {
+	if err := c.checkUnpreparedSafetyPolicy(ctx); err != nil {
+		return err
+	}
 	// create specific invoker
 	invoker, err := c.createInvoker(ctx, productCode, apiOrMethod, path)
 	if err != nil {
@@ -598,6 +628,9 @@ func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMeth
 	if err != nil {
 		return err
 	}
+	if err := c.checkPreparedSafetyPolicy(ctx, commonRequestParams(invoker.getRequest())); err != nil {
+		return err
+	}
 
 	if CliDryRunFlag(ctx.Flags()).IsAssigned() {
 		return processCliDryRun(ctx, invoker)
@@ -621,13 +654,23 @@ func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMeth
 	}
 
 	// if invoke with helper
-	out, err, ok := c.invokeWithHelper(invoker)
+	out, err, ok := c.invokeWithHelper(ctx, invoker)
+
+	// `--regions` prints what the other regions returned before failing
+	var failedRegions error
+	if _, partial := err.(*regionsError); partial && out != "" {
+		failedRegions, err = err, nil
+	}
 
 	// cli.Printf("invoker %v %v \n", invoker, reflect.TypeOf(invoker))
 	if ok {
 		if err != nil { // call with helper failed
 			return err
 		}
+		// `--pager stream=true` has already written every item
+		if out == "" {
+			return nil
+		}
 	} else {
 		resp, err := hookdo(invoker.Call)()
 		if err != nil {
@@ -641,7 +684,7 @@ func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMeth
 
 	// if `--quiet` assigned. do not print anything
 	if QuietFlag(ctx.Flags()).IsAssigned() {
-		return nil
+		return failedRegions
 	}
 
 	if QueryFlag(ctx.Flags()).IsAssigned() {
@@ -652,17 +695,17 @@ func (c *Commando) processInvoke(ctx *cli.Context, productCode string, apiOrMeth
 	}
 
 	// process `--output ...`
-	if filter := GetOutputFilter(ctx); filter != nil {
+	if filter := GetOutputFilterWithDefault(ctx, c.profile.OutputFormat); filter != nil {
 		out, err = filter.FilterOutput(out)
 		if err != nil {
 			return err
 		}
+	} else {
+		out = sortJSON(out)
 	}
 
-	out = sortJSON(out)
-
 	cli.Println(ctx.Stdout(), out)
-	return nil
+	return failedRegions
 }
 
 func sortJSON(content string) string {
@@ -788,9 +831,21 @@ func marshalDryRunInvokeMeta(library *Library, inv Invoker) (string, error) {
 }
 
 // invoke with helper
-func (c *Commando) invokeWithHelper(invoker Invoker) (resp string, err error, ok bool) {
+func (c *Commando) invokeWithHelper(ctx *cli.Context, invoker Invoker) (resp string, err error, ok bool) {
+	if RegionsFlag(ctx.Flags()).IsAssigned() {
+		resp, err = c.invokeInRegions(ctx, invoker)
+		ok = true
+		return
+	}
+
 	if pager := GetPager(); pager != nil {
 		// cli.Printf("call with pager")
+		if pager.Stream {
+			pager.Writer = ctx.Stdout()
+			if QuietFlag(ctx.Flags()).IsAssigned() {
+				pager.Writer = io.Discard
+			}
+		}
 		resp, err = pager.CallWith(invoker)
 		ok = true
 		return
@@ -813,6 +868,7 @@ func (c *Commando) invokeWithHelper(invoker Invoker) (resp string, err error, ok
 func (c *Commando) createInvoker(ctx *cli.Context, productCode string, apiOrMethod string, path string) (Invoker, error) {
 	force := ForceFlag(ctx.Flags()).IsAssigned()
 	basicInvoker := NewBasicInvoker(&c.profile)
+	basicInvoker.clients = c.clients
 
 	//
 	// get product info
@@ -1232,18 +1288,18 @@ func (c *Commando) handleInstallError(ctx *cli.Context, err error, pluginName st
 	}
 }
 
-func (c *Commando) checkSafetyPolicy(ctx *cli.Context, productCode string, apiOrMethod string, path string) error {
+// checkSafetyPolicy checks cmd, the command as typed, with the request
+// parameters its rule conditions are matched against.
+func (c *Commando) checkSafetyPolicy(ctx *cli.Context, cmd safety.CommandInfo, params map[string]string) error {
 	configDir := config.GetConfigDir(ctx)
-	policy, err := safety.LoadEffectivePolicy(configDir)
+	policy, err := config.LoadEffectiveSafetyPolicy(configDir)
 	if err != nil {
 		// Failed to load - skip policy check (fail open)
 		return nil
 	}
-	cmd := safety.CommandInfo{
-		Product:     productCode,
-		ApiOrMethod: apiOrMethod,
-		Path:        path,
-	}
+	cmd.Profile = c.profile.Name
+	cmd.Region = effectiveDryRunRegion(ctx, &c.profile)
+	cmd.Params = params
 	// --yes / -y or ALIBABA_CLOUD_SAFETY_SKIP_CONFIRM=1: skip confirm prompt for agent/non-interactive
 	skipConfirm := YesFlag(ctx.Flags()).IsAssigned() ||
 		os.Getenv("ALIBABA_CLOUD_SAFETY_SKIP_CONFIRM") == "1" ||
@@ -1251,6 +1307,147 @@ func (c *Commando) checkSafetyPolicy(ctx *cli.Context, productCode string, apiOr
 	return safety.CheckAndConfirm(ctx, policy, cmd, skipConfirm)
 }
 
+// checkUnpreparedSafetyPolicy rejects the command main dispatched before its
+// invoker is created, when a rule denies it whatever the parameters. The
+// parameter conditions are checked by checkPreparedSafetyPolicy.
+func (c *Commando) checkUnpreparedSafetyPolicy(ctx *cli.Context) error {
+	if c.safetyTarget == nil {
+		return nil
+	}
+	policy, err := config.LoadEffectiveSafetyPolicy(config.GetConfigDir(ctx))
+	if err != nil {
+		return nil
+	}
+	cmd := *c.safetyTarget
+	cmd.Profile = c.profile.Name
+	cmd.Region = effectiveDryRunRegion(ctx, &c.profile)
+	return safety.CheckDenied(policy, cmd)
+}
+
+// checkPreparedSafetyPolicy checks the command main dispatched with the
+// parameters of the prepared request, the values --body, list expansion and
+// the defaults of the cli put in it are the ones sent.
+func (c *Commando) checkPreparedSafetyPolicy(ctx *cli.Context, requestParams map[string]string) error {
+	if c.safetyTarget == nil {
+		return nil
+	}
+	target := *c.safetyTarget
+	c.safetyTarget = nil
+	return c.checkSafetyPolicy(ctx, target, mergeSafetyParams(safetyParams(ctx), requestParams))
+}
+
+// safetyParams collects the request parameters typed on the command line, for
+// commands whose request the cli does not build, e.g. plugins.
+func safetyParams(ctx *cli.Context) map[string]string {
+	params := make(map[string]string)
+	if ctx.UnknownFlags() != nil {
+		for _, f := range ctx.UnknownFlags().Flags() {
+			if v, ok := f.GetValue(); ok {
+				params[f.Name] = v
+			}
+		}
+	}
+	if _, ok := params["RegionId"]; !ok {
+		if v, ok := config.RegionIdFlag(ctx.Flags()).GetValue(); ok && v != "" {
+			params["RegionId"] = v
+		} else if v, ok := config.RegionFlag(ctx.Flags()).GetValue(); ok && v != "" {
+			params["RegionId"] = v
+		}
+	}
+	return params
+}
+
+// mergeSafetyParams overrides typed with the parameters of the request, names
+// differing only in case are the same parameter.
+func mergeSafetyParams(typed, request map[string]string) map[string]string {
+	params := make(map[string]string, len(typed)+len(request))
+	for k, v := range typed {
+		params[k] = v
+	}
+	for k, v := range request {
+		for existing := range params {
+			if strings.EqualFold(existing, k) {
+				delete(params, existing)
+			}
+		}
+		params[k] = v
+	}
+	return params
+}
+
+func commonRequestParams(request *requests.CommonRequest) map[string]string {
+	params := make(map[string]string)
+	for _, m := range []map[string]string{request.PathParams, request.FormParams, request.QueryParams} {
+		for k, v := range m {
+			params[k] = v
+		}
+	}
+	addBodyParams(params, request.Content)
+	return params
+}
+
+func openapiRequestParams(request *openapiutil.OpenApiRequest) map[string]string {
+	params := make(map[string]string)
+	if request == nil {
+		return params
+	}
+	switch body := request.Body.(type) {
+	case map[string]interface{}:
+		flattenSafetyParams(params, "", body)
+	case []byte:
+		addBodyParams(params, body)
+	case string:
+		addBodyParams(params, []byte(body))
+	}
+	for k, v := range request.Query {
+		if v != nil {
+			params[k] = *v
+		}
+	}
+	return params
+}
+
+// addBodyParams adds the fields of a json body, a body of another format
+// carries no parameters to match.
+func addBodyParams(params map[string]string, content []byte) {
+	if len(content) == 0 {
+		return
+	}
+	var body interface{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return
+	}
+	flattenSafetyParams(params, "", body)
+}
+
+// flattenSafetyParams names nested fields the way RPC flattens them, e.g.
+// Tag.1.Key.
+func flattenSafetyParams(params map[string]string, prefix string, v interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			flattenSafetyParams(params, join(k), item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			flattenSafetyParams(params, join(strconv.Itoa(i+1)), item)
+		}
+	case nil:
+	default:
+		if prefix != "" {
+			params[prefix] = fmt.Sprint(value)
+		}
+	}
+}
+
 func (c *Commando) setLangEnv(ctx *cli.Context) {
 	if ctx == nil {
 		return
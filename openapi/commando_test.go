@@ -26,6 +26,8 @@ import (
 	"strings"
 	"testing"
 
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	"github.com/stretchr/testify/assert"
 
@@ -3756,6 +3758,91 @@ func TestMain_SafetyPolicyEnforcement(t *testing.T) {
 		assert.Contains(t, err.Error(), "blocked by safety policy")
 		assert.Contains(t, err.Error(), "fc:create-function")
 	})
+
+	t.Run("2-arg RPC: a denied call is rejected before its invoker is created", func(t *testing.T) {
+		testHome := t.TempDir()
+		cleanup := setTestHomeDir(t, testHome)
+		defer cleanup()
+		writeSafetyPolicy(t, testHome, []safety.Rule{
+			{Pattern: "ecs:DeleteInstance", Action: safety.ActionDeny},
+		})
+		// createInvoker fails for a BearerToken profile, the error tells
+		// whether the call got that far
+		content := `{"current": "default", "profiles": [{"name": "default", "mode": "BearerToken", "bearer_token": "t", "region_id": "cn-hangzhou", "language": "en"}]}`
+		if err := os.WriteFile(filepath.Join(testHome, ".aliyun", "config.json"), []byte(content), 0644); err != nil {
+			t.Fatalf("write config.json: %v", err)
+		}
+
+		repo, err := meta.MockLoadRepository([]meta.Product{{Code: "Ecs", Version: "2014-05-26", ApiStyle: "rpc"}})
+		if err != nil {
+			t.Fatalf("mock repository: %v", err)
+		}
+		newCommando := func() (*cli.Context, *Commando) {
+			ctx, command := newSafetyCommandoTestCtx(t)
+			command.library = &Library{builtinRepo: repo, writer: new(bytes.Buffer)}
+			return ctx, command
+		}
+
+		ctx, command := newCommando()
+		os.Args = []string{"aliyun", "ecs", "DescribeRegions"}
+		err = command.main(ctx, []string{"ecs", "DescribeRegions"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "BearerToken")
+
+		ctx, command = newCommando()
+		os.Args = []string{"aliyun", "ecs", "DeleteInstance"}
+		err = command.main(ctx, []string{"ecs", "DeleteInstance"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "blocked by safety policy")
+		assert.NotContains(t, err.Error(), "BearerToken")
+	})
+
+	t.Run("3-arg REST: conditions match the parameters of the body", func(t *testing.T) {
+		testHome := t.TempDir()
+		cleanup := setTestHomeDir(t, testHome)
+		defer cleanup()
+		writeMinimalConfigJSON(t, testHome)
+		writeSafetyPolicy(t, testHome, []safety.Rule{
+			{Pattern: "cs:POST/*", Action: safety.ActionDeny,
+				Params: []safety.ParamCondition{{Name: "nodepool_info.size", Op: safety.OpGt, Value: "10"}}},
+		})
+
+		ctx, command := newSafetyCommandoTestCtx(t)
+		ForceFlag(ctx.Flags()).SetAssigned(true)
+		BodyFlag(ctx.Flags()).SetAssigned(true)
+		BodyFlag(ctx.Flags()).SetValue(`{"nodepool_info": {"size": 50}}`)
+		os.Args = []string{"aliyun", "cs", "POST", "/clusters/abc/nodepools"}
+		args := []string{"cs", "POST", "/clusters/abc/nodepools"}
+
+		err := command.main(ctx, args)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "blocked by safety policy")
+		assert.Contains(t, err.Error(), "cs:POST/*")
+	})
+}
+
+func TestRequestSafetyParams(t *testing.T) {
+	request := requests.NewCommonRequest()
+	request.QueryParams["RegionId"] = "cn-shanghai"
+	request.QueryParams["Tag.1.Key"] = "env"
+	request.FormParams["Amount"] = "3"
+	request.Content = []byte(`{"Name": "x", "Disks": [{"Size": 40}], "Ignored": null}`)
+	assert.Equal(t, map[string]string{
+		"RegionId":     "cn-shanghai",
+		"Tag.1.Key":    "env",
+		"Amount":       "3",
+		"Name":         "x",
+		"Disks.1.Size": "40",
+	}, commonRequestParams(request))
+
+	region := "cn-beijing"
+	assert.Equal(t, map[string]string{"RegionId": "cn-beijing", "size": "2"}, openapiRequestParams(&openapiutil.OpenApiRequest{
+		Query: map[string]*string{"RegionId": &region},
+		Body:  map[string]interface{}{"size": 2},
+	}))
+
+	merged := mergeSafetyParams(map[string]string{"regionid": "cn-hangzhou", "Force": "true"}, map[string]string{"RegionId": "cn-beijing"})
+	assert.Equal(t, map[string]string{"RegionId": "cn-beijing", "Force": "true"}, merged)
 }
 
 func TestEstimateCostContextRequiresEstimateCost(t *testing.T) {
@@ -506,8 +506,9 @@ func printEstimateCostResult(ctx *cli.Context, out string) error {
 		if err != nil {
 			return err
 		}
+	} else {
+		out = sortJSON(out)
 	}
-	out = sortJSON(out)
 	cli.Println(ctx.Stdout(), out)
 	return nil
 }
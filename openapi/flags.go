@@ -27,6 +27,9 @@ func AddFlags(fs *cli.FlagSet) {
 	fs.Add(NewBodyFlag())
 	fs.Add(NewBodyFileFlag())
 	fs.Add(PagerFlag)
+	fs.Add(NewRegionsFlag())
+	fs.Add(NewNoCacheFlag())
+	fs.Add(NewRefreshFlag())
 	fs.Add(NewAcceptFlag())
 	fs.Add(NewOutputFlag())
 	fs.Add(WaiterFlag)
@@ -70,6 +73,9 @@ const (
 	UserAgentFlagName           = "user-agent"
 	CliAIModeFlagName           = "cli-ai-mode"
 	CliNoAIModeFlagName         = "no-cli-ai-mode"
+	RegionsFlagName             = "regions"
+	NoCacheFlagName             = "no-cache"
+	RefreshFlagName             = "refresh"
 )
 
 func OutputFlag(fs *cli.FlagSet) *cli.Flag {
@@ -144,6 +150,18 @@ func YesFlag(fs *cli.FlagSet) *cli.Flag {
 	return fs.Get(YesFlagName)
 }
 
+func RegionsFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(RegionsFlagName)
+}
+
+func NoCacheFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(NoCacheFlagName)
+}
+
+func RefreshFlag(fs *cli.FlagSet) *cli.Flag {
+	return fs.Get(RefreshFlagName)
+}
+
 func NewYesFlag() *cli.Flag {
 	return &cli.Flag{
 		Category:     "caller",
@@ -358,6 +376,49 @@ func NewQuietFlag() *cli.Flag {
 	}
 }
 
+// NewRegionsFlag registers `--regions`. See regions.go for how the call is
+// fanned out and merged.
+func NewRegionsFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "caller",
+		Name:         RegionsFlagName,
+		AssignedMode: cli.AssignedOnce,
+		Short: i18n.T(
+			"use `--regions cn-hangzhou,cn-beijing` or `--regions all` to run a read API in several regions concurrently and merge the results, each item tagged with its RegionId",
+			"使用 `--regions cn-hangzhou,cn-beijing` 或 `--regions all` 在多个地域并发调用只读 API 并合并结果，每个条目标注其 RegionId",
+		),
+		ExcludeWith: []string{WaiterFlag.Name, DryRunFlagName, CliDryRunFlagName, CliDryRunJsonFlagName, EstimateCostFlagName},
+	}
+}
+
+// NewNoCacheFlag and NewRefreshFlag override the response cache, see
+// cache.go. Neither has an effect while the cache is disabled.
+func NewNoCacheFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "caller",
+		Name:         NoCacheFlagName,
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"use `--no-cache` to neither read nor write the response cache for this call",
+			"使用 `--no-cache` 本次调用不读取也不写入响应缓存",
+		),
+		ExcludeWith: []string{RefreshFlagName},
+	}
+}
+
+func NewRefreshFlag() *cli.Flag {
+	return &cli.Flag{
+		Category:     "caller",
+		Name:         RefreshFlagName,
+		AssignedMode: cli.AssignedNone,
+		Short: i18n.T(
+			"use `--refresh` to skip the cached response and cache the fresh one",
+			"使用 `--refresh` 跳过已缓存的响应并缓存新的响应",
+		),
+		ExcludeWith: []string{NoCacheFlagName},
+	}
+}
+
 func NewLogLevelFlag() *cli.Flag {
 	return &cli.Flag{
 		Category:     "caller",
@@ -33,6 +33,8 @@ import (
 	"github.com/aliyun/aliyun-cli/v3/config"
 	"github.com/aliyun/aliyun-cli/v3/meta"
 	slsUtils "github.com/aliyun/aliyun-cli/v3/sls"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/cache"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/otel"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/throttlingretry"
 	"github.com/aliyun/aliyun-cli/v3/util"
@@ -196,6 +198,9 @@ type HttpContext struct {
 	product         *meta.Product
 
 	throttlingRetryConfig *throttlingretry.Config
+	auditLog              *audit.Log
+	cache                 *cache.Store
+	cacheRefresh          bool
 }
 
 func NewHttpContext(cp *config.Profile) *HttpContext {
@@ -227,6 +232,8 @@ func (a *HttpContext) Init(ctx *cli.Context, product *meta.Product) error {
 		a.openapiRuntime.SetIgnoreSSL(true)
 	}
 	a.throttlingRetryConfig = openapiThrottlingRetryConfig(ctx)
+	a.auditLog = openAuditLog(ctx)
+	a.cache, a.cacheRefresh = openResponseCache(ctx)
 
 	if v, ok := config.EndpointFlag(ctx.Flags()).GetValue(); ok {
 		a.openapiRequest.EndpointOverride = tea.String(v)
@@ -254,7 +261,11 @@ func (a *HttpContext) Init(ctx *cli.Context, product *meta.Product) error {
 	return nil
 }
 
-func (a *HttpContext) Call() error {
+func (a *HttpContext) Call() (err error) {
+	start := time.Now()
+	defer func() {
+		a.recordAudit(err, start, false)
+	}()
 	cfg := a.throttlingRetryConfig
 	if cfg == nil {
 		cfg = throttlingretry.Default()
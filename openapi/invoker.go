@@ -29,6 +29,8 @@ import (
 	"github.com/aliyun/aliyun-cli/v3/config"
 	"github.com/aliyun/aliyun-cli/v3/meta"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/aimode"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/audit"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/cache"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/otel"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/throttlingretry"
 	"github.com/aliyun/aliyun-cli/v3/util"
@@ -118,6 +120,10 @@ type BasicInvoker struct {
 	product *meta.Product
 
 	throttlingRetryConfig *throttlingretry.Config
+	auditLog              *audit.Log
+	clients               *sharedClient
+	cache                 *cache.Store
+	cacheRefresh          bool
 }
 
 func NewBasicInvoker(cp *config.Profile) *BasicInvoker {
@@ -132,6 +138,14 @@ func (a *BasicInvoker) getRequest() *requests.CommonRequest {
 	return a.request
 }
 
+// callRequest sends a copy of the prepared request through the same client and
+// throttling retry policy as Call. The pager uses it to fetch pages in parallel.
+func (a *BasicInvoker) callRequest(request *requests.CommonRequest) (*responses.CommonResponse, error) {
+	return a.callRequestWithThrottlingRetry(request, func() (*responses.CommonResponse, error) {
+		return a.client.ProcessCommonRequest(request)
+	})
+}
+
 func (a *BasicInvoker) productCode() string {
 	if a.product == nil {
 		return ""
@@ -180,6 +194,8 @@ func (a *BasicInvoker) Init(ctx *cli.Context, product *meta.Product) error {
 	if cfg, cfgErr := throttlingretry.LoadEffective(config.GetConfigDir(ctx)); cfgErr == nil {
 		a.throttlingRetryConfig = cfg
 	}
+	a.auditLog = openAuditLog(ctx)
+	a.cache, a.cacheRefresh = openResponseCache(ctx)
 
 	a.request.RegionId = a.profile.RegionId
 	if v, ok := config.RegionFlag(ctx.Flags()).GetValue(); ok {
@@ -232,7 +248,11 @@ func (a *BasicInvoker) Init(ctx *cli.Context, product *meta.Product) error {
 			"Use flag --region <regionId> to assign region, "+hint)
 	}
 
-	a.client, err = GetClient(a.profile, ctx)
+	if a.clients != nil {
+		a.client, err = a.clients.get(a.profile, ctx)
+	} else {
+		a.client, err = GetClient(a.profile, ctx)
+	}
 	if err != nil {
 		return fmt.Errorf("init client failed %s", err)
 	}
@@ -38,13 +38,16 @@ func NewOutputFlag() *cli.Flag {
 			"使用 `--output cols=Field1,Field1 [rows=jmesPath]` 使用表格方式打印输出",
 		),
 		Long: i18n.T(
-			"",
-			"",
+			"use `--output format={table|csv|tsv|yaml|jsonl} [cols=Field1,Field2] [rows=jmesPath]` to choose the output format;"+
+				" cols is required for table, other formats print every (flattened) field when cols is omitted",
+			"使用 `--output format={table|csv|tsv|yaml|jsonl} [cols=Field1,Field2] [rows=jmesPath]` 选择输出格式；"+
+				"table 格式必须指定 cols，其他格式未指定 cols 时输出全部（展开后的）字段",
 		),
 		Fields: []cli.Field{
-			{Key: "cols", Repeatable: false, Required: true},
+			{Key: "cols", Repeatable: false, Required: false},
 			{Key: "rows", Repeatable: false, Required: false},
 			{Key: "num", Repeatable: false, Required: false},
+			{Key: "format", Repeatable: false, Required: false},
 		},
 	}
 }
@@ -54,10 +57,44 @@ type OutputFilter interface {
 }
 
 func GetOutputFilter(ctx *cli.Context) OutputFilter {
-	if !OutputFlag(ctx.Flags()).IsAssigned() {
+	return GetOutputFilterWithDefault(ctx, "")
+}
+
+// GetOutputFilterWithDefault is GetOutputFilter with a fallback format, usually
+// the profile's output_format, used when `--output` is not assigned.
+func GetOutputFilterWithDefault(ctx *cli.Context, defaultFormat string) OutputFilter {
+	format := defaultFormat
+	if OutputFlag(ctx.Flags()).IsAssigned() {
+		format, _ = OutputFlag(ctx.Flags()).GetFieldValue("format")
+		if format == "" {
+			format = OutputFormatTable
+		}
+	} else if !IsProfileOutputFormat(format) {
 		return nil
 	}
-	return NewTableOutputFilter(ctx)
+
+	switch strings.ToLower(format) {
+	case OutputFormatCSV:
+		return NewDelimitedOutputFilter(ctx, OutputFormatCSV)
+	case OutputFormatTSV:
+		return NewDelimitedOutputFilter(ctx, OutputFormatTSV)
+	case OutputFormatYAML:
+		return NewYamlOutputFilter(ctx)
+	case OutputFormatJSONL:
+		return NewJsonLinesOutputFilter(ctx)
+	case OutputFormatTable:
+		return NewTableOutputFilter(ctx)
+	default:
+		return &invalidOutputFilter{format: format}
+	}
+}
+
+type invalidOutputFilter struct {
+	format string
+}
+
+func (a *invalidOutputFilter) FilterOutput(s string) (string, error) {
+	return s, fmt.Errorf("unsupported output format '%s', use one of table, csv, tsv, yaml, jsonl", a.format)
 }
 
 type TableOutputFilter struct {
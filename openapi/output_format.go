@@ -0,0 +1,347 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	jmespath "github.com/jmespath/go-jmespath"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputFormatTable = "table"
+	OutputFormatCSV   = "csv"
+	OutputFormatTSV   = "tsv"
+	OutputFormatYAML  = "yaml"
+	OutputFormatJSONL = "jsonl"
+)
+
+// IsProfileOutputFormat reports whether format can be used as a profile's
+// default output format, i.e. it works without `cols=`.
+func IsProfileOutputFormat(format string) bool {
+	switch format {
+	case OutputFormatCSV, OutputFormatTSV, OutputFormatYAML, OutputFormatJSONL:
+		return true
+	}
+	return false
+}
+
+// outputSelection holds the `rows=`, `cols=` and `num=` fields shared by the
+// csv, tsv, yaml and jsonl filters.
+type outputSelection struct {
+	rows string
+	cols []string
+	num  bool
+}
+
+func newOutputSelection(ctx *cli.Context) outputSelection {
+	var sel outputSelection
+	flag := OutputFlag(ctx.Flags())
+	if flag == nil || !flag.IsAssigned() {
+		return sel
+	}
+	sel.rows, _ = flag.GetFieldValue("rows")
+	if v, ok := flag.GetFieldValue("cols"); ok && v != "" {
+		sel.cols = strings.Split(UnquoteString(v), ",")
+	}
+	if v, ok := flag.GetFieldValue("num"); ok {
+		sel.num = v == "true"
+	}
+	return sel
+}
+
+func decodeOutput(s string) (interface{}, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewBufferString(s))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("unmarshal output failed %s", err)
+	}
+	return v, nil
+}
+
+// selectRows resolves the rows to print: the `rows=` expression if given,
+// otherwise a top level array, otherwise the first array found by the same
+// detection `--pager` uses, otherwise the whole document as a single row.
+func (sel outputSelection) selectRows(v interface{}) ([]interface{}, error) {
+	if sel.rows != "" {
+		rows, err := jmespath.Search(sel.rows, v)
+		if err != nil {
+			return nil, fmt.Errorf("jmespath: '%s' failed %s", sel.rows, err)
+		}
+		rowsArray, ok := rows.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("jmespath: '%s' failed Need Array Expr", sel.rows)
+		}
+		return rowsArray, nil
+	}
+	if rowsArray, ok := v.([]interface{}); ok {
+		return rowsArray, nil
+	}
+	if path := detectArrayPath(v); path != "" {
+		if rows, err := jmespath.Search(path, v); err == nil {
+			if rowsArray, ok := rows.([]interface{}); ok {
+				return rowsArray, nil
+			}
+		}
+	}
+	return []interface{}{v}, nil
+}
+
+// project keeps only the `cols=` of every row, keyed by the column expression.
+func (sel outputSelection) project(rows []interface{}) []interface{} {
+	if len(sel.cols) == 0 {
+		return rows
+	}
+	projected := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		m := make(map[string]interface{}, len(sel.cols))
+		for _, col := range sel.cols {
+			m[col], _ = jmespath.Search(col, row)
+		}
+		projected = append(projected, m)
+	}
+	return projected
+}
+
+// flattenRow turns nested objects into dotted keys, e.g. `Placement.ZoneId`.
+// Arrays are kept as a single value.
+func flattenRow(prefix string, v interface{}, out map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok || (len(m) == 0 && prefix != "") {
+		if prefix == "" {
+			prefix = "Value"
+		}
+		out[prefix] = v
+		return
+	}
+	for k, child := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		flattenRow(key, child, out)
+	}
+}
+
+func formatCell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(b)
+	}
+}
+
+// DelimitedOutputFilter prints rows as csv or tsv. Columns come from `cols=`
+// (jmespath expressions, so `Placement.ZoneId` reaches nested fields) or,
+// without it, from the union of the flattened keys of all rows.
+type DelimitedOutputFilter struct {
+	ctx    *cli.Context
+	format string
+}
+
+func NewDelimitedOutputFilter(ctx *cli.Context, format string) OutputFilter {
+	return &DelimitedOutputFilter{ctx: ctx, format: format}
+}
+
+func (a *DelimitedOutputFilter) FilterOutput(s string) (string, error) {
+	v, err := decodeOutput(s)
+	if err != nil {
+		return s, err
+	}
+	sel := newOutputSelection(a.ctx)
+	rows, err := sel.selectRows(v)
+	if err != nil {
+		return "", err
+	}
+
+	header, records := a.tabulate(sel, rows)
+	var buf bytes.Buffer
+	if a.format == OutputFormatTSV {
+		writeTSV(&buf, header, records)
+	} else {
+		w := csv.NewWriter(&buf)
+		w.Write(header)
+		w.WriteAll(records)
+		if err := w.Error(); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func (a *DelimitedOutputFilter) tabulate(sel outputSelection, rows []interface{}) ([]string, [][]string) {
+	var header []string
+	records := make([][]string, 0, len(rows))
+	if len(sel.cols) > 0 {
+		header = append(header, sel.cols...)
+		for _, row := range rows {
+			record := make([]string, 0, len(sel.cols))
+			for _, col := range sel.cols {
+				cell, _ := jmespath.Search(col, row)
+				record = append(record, formatCell(cell))
+			}
+			records = append(records, record)
+		}
+	} else {
+		flattened := make([]map[string]interface{}, 0, len(rows))
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			m := make(map[string]interface{})
+			flattenRow("", row, m)
+			for k := range m {
+				if !seen[k] {
+					seen[k] = true
+					header = append(header, k)
+				}
+			}
+			flattened = append(flattened, m)
+		}
+		sort.Strings(header)
+		for _, m := range flattened {
+			record := make([]string, 0, len(header))
+			for _, col := range header {
+				record = append(record, formatCell(m[col]))
+			}
+			records = append(records, record)
+		}
+	}
+
+	if sel.num {
+		header = append([]string{"Num"}, header...)
+		for i := range records {
+			records[i] = append([]string{strconv.Itoa(i)}, records[i]...)
+		}
+	}
+	return header, records
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// writeTSV writes tab separated lines; backslash, tab and newlines inside a
+// value are escaped so each record stays on one line for awk and cut.
+func writeTSV(buf *bytes.Buffer, header []string, records [][]string) {
+	for _, record := range append([][]string{header}, records...) {
+		for i, field := range record {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(tsvEscaper.Replace(field))
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// YamlOutputFilter prints the response as yaml. Without `rows=` or `cols=`
+// the whole document is printed.
+type YamlOutputFilter struct {
+	ctx *cli.Context
+}
+
+func NewYamlOutputFilter(ctx *cli.Context) OutputFilter {
+	return &YamlOutputFilter{ctx: ctx}
+}
+
+func (a *YamlOutputFilter) FilterOutput(s string) (string, error) {
+	v, err := decodeOutput(s)
+	if err != nil {
+		return s, err
+	}
+	sel := newOutputSelection(a.ctx)
+	if sel.rows != "" || len(sel.cols) > 0 {
+		rows, err := sel.selectRows(v)
+		if err != nil {
+			return "", err
+		}
+		v = sel.project(rows)
+	}
+	b, err := yaml.Marshal(yamlNumbers(v))
+	if err != nil {
+		return "", fmt.Errorf("marshal yaml failed %s", err)
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// yamlNumbers replaces json.Number with int64 or float64, otherwise yaml would
+// quote them as strings.
+func yamlNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = yamlNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = yamlNumbers(child)
+		}
+	}
+	return v
+}
+
+// JsonLinesOutputFilter prints one compact JSON document per row.
+type JsonLinesOutputFilter struct {
+	ctx *cli.Context
+}
+
+func NewJsonLinesOutputFilter(ctx *cli.Context) OutputFilter {
+	return &JsonLinesOutputFilter{ctx: ctx}
+}
+
+func (a *JsonLinesOutputFilter) FilterOutput(s string) (string, error) {
+	v, err := decodeOutput(s)
+	if err != nil {
+		return s, err
+	}
+	sel := newOutputSelection(a.ctx)
+	rows, err := sel.selectRows(v)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, row := range sel.project(rows) {
+		if err := encoder.Encode(row); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
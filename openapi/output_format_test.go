@@ -0,0 +1,121 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+const outputFormatTestJson = `{
+	"RequestId": "r-1",
+	"Instances": {
+		"Instance": [
+			{"InstanceId": "i-1", "Name": "web, \"a\"", "Cpu": 2, "Placement": {"ZoneId": "cn-hangzhou-h"}},
+			{"InstanceId": "i-2", "Name": "db\tmain", "Cpu": 16, "Placement": {"ZoneId": "cn-hangzhou-i"}, "Tags": ["a"]}
+		]
+	}
+}`
+
+func newOutputFormatContext(fields map[string]string) *cli.Context {
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	flag := NewOutputFlag()
+	ctx.Flags().Add(flag)
+	if fields != nil {
+		flag.SetAssigned(true)
+		for i := range flag.Fields {
+			if v, ok := fields[flag.Fields[i].Key]; ok {
+				flag.Fields[i].SetAssigned(true)
+				flag.Fields[i].SetValue(v)
+			}
+		}
+	}
+	return ctx
+}
+
+func TestGetOutputFilterWithDefault(t *testing.T) {
+	ctx := newOutputFormatContext(nil)
+	assert.Nil(t, GetOutputFilterWithDefault(ctx, ""))
+	assert.Nil(t, GetOutputFilterWithDefault(ctx, "json"))
+	assert.IsType(t, &YamlOutputFilter{}, GetOutputFilterWithDefault(ctx, "yaml"))
+	assert.IsType(t, &JsonLinesOutputFilter{}, GetOutputFilterWithDefault(ctx, "jsonl"))
+	assert.IsType(t, &DelimitedOutputFilter{}, GetOutputFilterWithDefault(ctx, "csv"))
+
+	ctx = newOutputFormatContext(map[string]string{"cols": "InstanceId"})
+	assert.IsType(t, &TableOutputFilter{}, GetOutputFilterWithDefault(ctx, "yaml"))
+
+	ctx = newOutputFormatContext(map[string]string{"format": "TSV"})
+	assert.IsType(t, &DelimitedOutputFilter{}, GetOutputFilter(ctx))
+
+	ctx = newOutputFormatContext(map[string]string{"format": "xml"})
+	_, err := GetOutputFilter(ctx).FilterOutput("{}")
+	assert.EqualError(t, err, "unsupported output format 'xml', use one of table, csv, tsv, yaml, jsonl")
+}
+
+func TestDelimitedOutputFilter_CSV(t *testing.T) {
+	ctx := newOutputFormatContext(map[string]string{
+		"format": "csv",
+		"rows":   "Instances.Instance[]",
+		"cols":   "InstanceId,Name,Placement.ZoneId,Tags",
+	})
+	out, err := GetOutputFilter(ctx).FilterOutput(outputFormatTestJson)
+	assert.Nil(t, err)
+	assert.Equal(t, "InstanceId,Name,Placement.ZoneId,Tags\n"+
+		"i-1,\"web, \"\"a\"\"\",cn-hangzhou-h,\n"+
+		"i-2,db\tmain,cn-hangzhou-i,\"[\"\"a\"\"]\"", out)
+}
+
+func TestDelimitedOutputFilter_TSVFlattened(t *testing.T) {
+	ctx := newOutputFormatContext(map[string]string{"format": "tsv", "num": "true"})
+	out, err := GetOutputFilter(ctx).FilterOutput(outputFormatTestJson)
+	assert.Nil(t, err)
+	assert.Equal(t, "Num\tCpu\tInstanceId\tName\tPlacement.ZoneId\tTags\n"+
+		"0\t2\ti-1\tweb, \"a\"\tcn-hangzhou-h\t\n"+
+		"1\t16\ti-2\tdb\\tmain\tcn-hangzhou-i\t[\"a\"]", out)
+
+	_, err = GetOutputFilter(ctx).FilterOutput("test")
+	assert.EqualError(t, err, "unmarshal output failed invalid character 'e' in literal true (expecting 'r')")
+}
+
+func TestYamlOutputFilter(t *testing.T) {
+	ctx := newOutputFormatContext(map[string]string{"format": "yaml"})
+	out, err := GetOutputFilter(ctx).FilterOutput(`{"TotalCount": 12345678901, "Ratio": 0.5, "Name": "a"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Name: a\nRatio: 0.5\nTotalCount: 12345678901", out)
+
+	ctx = newOutputFormatContext(map[string]string{"format": "yaml", "cols": "InstanceId,Cpu"})
+	out, err = GetOutputFilter(ctx).FilterOutput(outputFormatTestJson)
+	assert.Nil(t, err)
+	assert.Equal(t, "- Cpu: 2\n  InstanceId: i-1\n- Cpu: 16\n  InstanceId: i-2", out)
+
+	ctx = newOutputFormatContext(map[string]string{"format": "yaml", "rows": "RequestId"})
+	_, err = GetOutputFilter(ctx).FilterOutput(outputFormatTestJson)
+	assert.EqualError(t, err, "jmespath: 'RequestId' failed Need Array Expr")
+}
+
+func TestJsonLinesOutputFilter(t *testing.T) {
+	ctx := newOutputFormatContext(map[string]string{"format": "jsonl", "cols": "InstanceId,Placement.ZoneId"})
+	out, err := GetOutputFilter(ctx).FilterOutput(outputFormatTestJson)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"InstanceId":"i-1","Placement.ZoneId":"cn-hangzhou-h"}`+"\n"+
+		`{"InstanceId":"i-2","Placement.ZoneId":"cn-hangzhou-i"}`, out)
+
+	ctx = newOutputFormatContext(map[string]string{"format": "jsonl"})
+	out, err = GetOutputFilter(ctx).FilterOutput(`[{"a":1},{"a":2}]`)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":2}", out)
+}
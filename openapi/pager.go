@@ -16,11 +16,14 @@ package openapi
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
 	jmespath "github.com/jmespath/go-jmespath"
@@ -40,6 +43,12 @@ var PagerFlag = &cli.Flag{Category: "caller",
 		{Key: "PageSize", DefaultValue: "PageSize", Short: i18n.T("PageSize", "")},
 		{Key: "TotalCount", DefaultValue: "TotalCount", Short: i18n.T("TotalCount", "")},
 		{Key: "NextToken", DefaultValue: "NextToken", Short: i18n.T("NextToken", "")},
+		{Key: "concurrency", DefaultValue: "1", Short: i18n.T(
+			"number of pages fetched in parallel once TotalCount is known (PageNumber style APIs only)",
+			"获取 TotalCount 后并发拉取分页的数量（仅适用于 PageNumber 分页的API）")},
+		{Key: "stream", Short: i18n.T(
+			"use `stream=true` to print each merged item as a JSON line as soon as its page arrives",
+			"使用 `stream=true` 在分页返回后立即将每个条目按 JSON Lines 逐行输出")},
 	},
 	ExcludeWith: []string{WaiterFlag.Name},
 }
@@ -56,6 +65,16 @@ type Pager struct {
 
 	PageSize int
 
+	// Concurrency bounds how many pages are in flight after the first response
+	// of a PageNumber style API; values below 2 keep the sequential loop.
+	Concurrency int
+	// Stream writes every merged item to Writer as one JSON line instead of
+	// buffering them for GetResponseCollection.
+	Stream bool
+	Writer io.Writer
+	// Region, set by `--regions`, is added as RegionId to items lacking one.
+	Region string
+
 	totalCount        int
 	currentPageNumber int
 	nextTokenMode     bool
@@ -86,30 +105,281 @@ func GetPager() *Pager {
 	pager.NextTokenExpr = nextTokenFlagTemp
 
 	pager.collectionPath, _ = PagerFlag.GetFieldValue("path")
+
+	concurrency, _ := PagerFlag.GetFieldValue("concurrency")
+	pager.Concurrency, _ = strconv.Atoi(concurrency)
+	stream, _ := PagerFlag.GetFieldValue("stream")
+	pager.Stream = strings.EqualFold(stream, "true")
 	return pager
 }
 
+// checkPagerFlag rejects a concurrency that is not a positive integer, and the
+// flags a streamed result cannot honor, every item is printed as it arrives.
+func checkPagerFlag(ctx *cli.Context) error {
+	if !PagerFlag.IsAssigned() {
+		return nil
+	}
+	if concurrency, ok := PagerFlag.GetFieldValue("concurrency"); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(concurrency)); err != nil || n < 1 {
+			return fmt.Errorf("invalid --pager concurrency=%s, it must be a positive integer", concurrency)
+		}
+	}
+	if stream, _ := PagerFlag.GetFieldValue("stream"); !strings.EqualFold(stream, "true") {
+		return nil
+	}
+	for _, f := range []*cli.Flag{OutputFlag(ctx.Flags()), QueryFlag(ctx.Flags())} {
+		if f != nil && f.IsAssigned() {
+			return cli.NewErrorWithTip(fmt.Errorf("--pager stream=true cannot be used with --%s", f.Name),
+				"stream=true prints every item as a JSON line, filter them with tools like jq instead")
+		}
+	}
+	return nil
+}
+
+// requestCaller is implemented by the invokers embedding *BasicInvoker. It lets
+// the pager send copies of the prepared request for different pages at once.
+type requestCaller interface {
+	callRequest(request *requests.CommonRequest) (*responses.CommonResponse, error)
+}
+
 func (a *Pager) CallWith(invoker Invoker) (string, error) {
-	for {
+	for first := true; ; first = false {
 
 		resp, err := invoker.Call()
 		if err != nil {
 			return "", err
 		}
 
-		err = a.FeedResponse(resp.GetHttpContentString())
+		body := resp.GetHttpContentString()
+		err = a.FeedResponse(body)
 		if err != nil {
 			return "", fmt.Errorf("call failed %s", err)
 		}
 
+		if first && a.Concurrency > 1 {
+			if caller, ok := invoker.(requestCaller); ok {
+				if pages, ok := a.remainingPages(body); ok {
+					if err := a.fetchPages(caller, invoker.getRequest(), pages); err != nil {
+						return "", err
+					}
+					break
+				}
+			}
+		}
+
 		if !a.HasMore() {
 			break
 		}
 		a.MoveNextPage(invoker.getRequest())
 	}
+	if a.streaming() {
+		return "", nil
+	}
 	return a.GetResponseCollection(), nil
 }
 
+// remainingPages reports the page numbers left after the first response of a
+// PageNumber style API. NextToken style responses cannot be fetched ahead.
+func (a *Pager) remainingPages(body string) ([]int, bool) {
+	if a.nextToken != "" {
+		return nil, false
+	}
+	var j interface{}
+	if err := json.Unmarshal([]byte(body), &j); err != nil {
+		return nil, false
+	}
+	total, ok := searchNumber(a.TotalCountExpr, j)
+	if !ok {
+		return nil, false
+	}
+	current, ok := searchNumber(a.PageNumberExpr, j)
+	if !ok {
+		return nil, false
+	}
+	size, ok := searchNumber(a.PageSizeExpr, j)
+	if !ok || size <= 0 {
+		return nil, false
+	}
+	last := int(math.Ceil(float64(total) / float64(size)))
+	var pages []int
+	for page := current + 1; page <= last; page++ {
+		pages = append(pages, page)
+	}
+	return pages, true
+}
+
+func searchNumber(expr string, j interface{}) (int, bool) {
+	if expr == "" {
+		return 0, false
+	}
+	val, err := jmespath.Search(expr, j)
+	if err != nil {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case float64:
+		return int(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return int(f), true
+	}
+	return 0, false
+}
+
+type pageResult struct {
+	page  int
+	items []interface{}
+	err   error
+}
+
+// fetchPages requests pages with at most a.Concurrency calls in flight and
+// merges them in page order, so the output matches the sequential loop.
+func (a *Pager) fetchPages(caller requestCaller, template *requests.CommonRequest, pages []int) error {
+	if len(pages) == 0 {
+		return nil
+	}
+	workers := a.Concurrency
+	if workers > len(pages) {
+		workers = len(pages)
+	}
+
+	jobs := make(chan int)
+	results := make(chan pageResult)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range jobs {
+				items, err := a.fetchPage(caller, template, page)
+				select {
+				case results <- pageResult{page: page, items: items, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, page := range pages {
+			select {
+			case jobs <- page:
+			case <-done:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	pending := make(map[int][]interface{})
+	next := 0
+	for r := range results {
+		if r.err != nil {
+			err = r.err
+			close(done)
+			break
+		}
+		pending[r.page] = r.items
+		for next < len(pages) {
+			items, ok := pending[pages[next]]
+			if !ok {
+				break
+			}
+			delete(pending, pages[next])
+			if err = a.emit(items); err != nil {
+				break
+			}
+			next++
+		}
+		if err != nil {
+			close(done)
+			break
+		}
+	}
+	return err
+}
+
+func (a *Pager) fetchPage(caller requestCaller, template *requests.CommonRequest, page int) ([]interface{}, error) {
+	request := cloneCommonRequest(template)
+	request.QueryParams[a.PageNumberFlag] = strconv.Itoa(page)
+	resp, err := caller.callRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	var j interface{}
+	if err := json.Unmarshal([]byte(resp.GetHttpContentString()), &j); err != nil {
+		return nil, fmt.Errorf("call failed unmarshal %s", err.Error())
+	}
+	return a.searchCollection(j)
+}
+
+// cloneCommonRequest copies the exported state of a prepared request, enough
+// to send it again with different parameters.
+func cloneCommonRequest(src *requests.CommonRequest) *requests.CommonRequest {
+	dst := requests.NewCommonRequest()
+	dst.Scheme = src.Scheme
+	dst.Method = src.Method
+	dst.Domain = src.Domain
+	dst.Port = src.Port
+	dst.RegionId = src.RegionId
+	dst.ReadTimeout = src.ReadTimeout
+	dst.ConnectTimeout = src.ConnectTimeout
+	dst.AcceptFormat = src.AcceptFormat
+	dst.Version = src.Version
+	dst.ApiName = src.ApiName
+	dst.Product = src.Product
+	dst.ServiceCode = src.ServiceCode
+	dst.EndpointType = src.EndpointType
+	dst.PathPattern = src.PathPattern
+	dst.Content = src.Content
+	for k, v := range src.QueryParams {
+		dst.QueryParams[k] = v
+	}
+	for k, v := range src.Headers {
+		dst.Headers[k] = v
+	}
+	for k, v := range src.FormParams {
+		dst.FormParams[k] = v
+	}
+	for k, v := range src.PathParams {
+		dst.PathParams[k] = v
+	}
+	return dst
+}
+
+func (a *Pager) streaming() bool {
+	return a.Stream && a.Writer != nil
+}
+
+// emit appends items to the merged collection, or prints them as JSON lines
+// in stream mode.
+func (a *Pager) emit(items []interface{}) error {
+	if a.Region != "" {
+		tagRegion(items, a.Region)
+	}
+	if !a.streaming() {
+		a.results = append(a.results, items...)
+		return nil
+	}
+	encoder := json.NewEncoder(a.Writer)
+	encoder.SetEscapeHTML(false)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (a *Pager) HasMore() bool {
 	if a.nextTokenMode {
 		return a.nextToken != ""
@@ -222,14 +492,25 @@ func (a *Pager) MoveNextPage(request *requests.CommonRequest) {
 }
 
 func (a *Pager) mergeCollections(body interface{}) error {
+	items, err := a.searchCollection(body)
+	if err != nil {
+		return err
+	}
+	return a.emit(items)
+}
+
+func (a *Pager) searchCollection(body interface{}) ([]interface{}, error) {
 	ar, err := jmespath.Search(a.collectionPath, body)
 	if err != nil {
-		return fmt.Errorf("jmespath search failed: %s", err.Error())
+		return nil, fmt.Errorf("jmespath search failed: %s", err.Error())
 	} else if ar == nil {
-		return fmt.Errorf("jmespath result empty: %s", a.collectionPath)
+		return nil, fmt.Errorf("jmespath result empty: %s", a.collectionPath)
 	}
-	a.results = append(a.results, ar.([]interface{})...)
-	return nil
+	items, ok := ar.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jmespath result is not a list: %s", a.collectionPath)
+	}
+	return items, nil
 }
 
 func (a *Pager) detectArrayPath(d interface{}) string {
@@ -16,12 +16,18 @@ package openapi
 import (
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	jmespath "github.com/jmespath/go-jmespath"
 	"github.com/stretchr/testify/assert"
 
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -259,6 +265,113 @@ func Test_mergeCollections(t *testing.T) {
 	assert.Equal(t, "jmespath result empty: test", err.Error())
 }
 
+type fakePageCaller struct {
+	mu       sync.Mutex
+	pages    []string
+	failPage string
+}
+
+func (f *fakePageCaller) callRequest(request *requests.CommonRequest) (*responses.CommonResponse, error) {
+	page := request.QueryParams["PageNumber"]
+	f.mu.Lock()
+	f.pages = append(f.pages, page)
+	f.mu.Unlock()
+	if page == f.failPage {
+		return nil, fmt.Errorf("page %s failed", page)
+	}
+	body := fmt.Sprintf(`{"TotalCount":6,"PageNumber":%s,"PageSize":2,"Items":{"Item":[{"Id":"%s-a"},{"Id":"%s-b"}]}}`, page, page, page)
+	resp := responses.NewCommonResponse()
+	err := responses.Unmarshal(resp, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, "JSON")
+	return resp, err
+}
+
+func TestPager_remainingPages(t *testing.T) {
+	pager := &Pager{
+		PageNumberExpr: "PageNumber",
+		PageSizeExpr:   "PageSize",
+		TotalCountExpr: "TotalCount",
+	}
+	pages, ok := pager.remainingPages(string(pagerTestJson))
+	assert.True(t, ok)
+	assert.Equal(t, []int{6, 7, 8}, pages)
+
+	pages, ok = pager.remainingPages(`{"TotalCount":"3","PageNumber":"1","PageSize":"10"}`)
+	assert.True(t, ok)
+	assert.Nil(t, pages)
+
+	_, ok = pager.remainingPages(`{"PageNumber":1,"PageSize":10}`)
+	assert.False(t, ok)
+
+	pager.nextToken = "token"
+	_, ok = pager.remainingPages(string(pagerTestJson))
+	assert.False(t, ok)
+}
+
+func TestPager_fetchPages(t *testing.T) {
+	pager := &Pager{
+		PageNumberFlag: "PageNumber",
+		Concurrency:    3,
+		collectionPath: "Items.Item[]",
+	}
+	caller := &fakePageCaller{}
+	template := requests.NewCommonRequest()
+	template.QueryParams["PageNumber"] = "1"
+	template.QueryParams["RegionId"] = "cn-hangzhou"
+
+	err := pager.fetchPages(caller, template, []int{2, 3, 4, 5})
+	assert.Nil(t, err)
+	assert.Len(t, caller.pages, 4)
+	assert.Equal(t, "1", template.QueryParams["PageNumber"])
+
+	var ids []string
+	for _, item := range pager.results {
+		ids = append(ids, item.(map[string]interface{})["Id"].(string))
+	}
+	assert.Equal(t, []string{"2-a", "2-b", "3-a", "3-b", "4-a", "4-b", "5-a", "5-b"}, ids)
+
+	pager.results = nil
+	caller = &fakePageCaller{failPage: "3"}
+	err = pager.fetchPages(caller, template, []int{2, 3, 4, 5})
+	assert.NotNil(t, err)
+	assert.Equal(t, "page 3 failed", err.Error())
+}
+
+func TestPager_Stream(t *testing.T) {
+	w := new(bytes.Buffer)
+	pager := &Pager{
+		PageNumberFlag: "PageNumber",
+		Concurrency:    2,
+		Stream:         true,
+		Writer:         w,
+		collectionPath: "Items.Item[]",
+	}
+	err := pager.fetchPages(&fakePageCaller{}, requests.NewCommonRequest(), []int{2, 3})
+	assert.Nil(t, err)
+	assert.Nil(t, pager.results)
+	assert.Equal(t, "{\"Id\":\"2-a\"}\n{\"Id\":\"2-b\"}\n{\"Id\":\"3-a\"}\n{\"Id\":\"3-b\"}\n", w.String())
+}
+
+func TestCloneCommonRequest(t *testing.T) {
+	src := requests.NewCommonRequest()
+	src.Product = "Ecs"
+	src.ApiName = "DescribeDisks"
+	src.Domain = "ecs.aliyuncs.com"
+	src.QueryParams["PageNumber"] = "1"
+	src.Headers["x-test"] = "v"
+
+	dst := cloneCommonRequest(src)
+	dst.QueryParams["PageNumber"] = "2"
+	assert.Equal(t, "Ecs", dst.Product)
+	assert.Equal(t, "DescribeDisks", dst.ApiName)
+	assert.Equal(t, "ecs.aliyuncs.com", dst.Domain)
+	assert.Equal(t, "v", dst.Headers["x-test"])
+	assert.Equal(t, "1", src.QueryParams["PageNumber"])
+}
+
 var pagerTestJson = []byte(`{
 	"PageNumber": 5,
 	"TotalCount": "37",
@@ -630,3 +743,40 @@ const pagerTestJsonNextToken2 = `{
 		]
 	}
 }`
+
+func TestCheckPagerFlag(t *testing.T) {
+	setField := func(key, value string) {
+		for i := range PagerFlag.Fields {
+			if PagerFlag.Fields[i].Key == key {
+				PagerFlag.Fields[i].SetAssigned(value != "")
+				PagerFlag.Fields[i].SetValue(value)
+			}
+		}
+	}
+	defer func() {
+		PagerFlag.SetAssigned(false)
+		setField("concurrency", "")
+		setField("stream", "")
+	}()
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	AddFlags(ctx.Flags())
+	assert.Nil(t, checkPagerFlag(ctx))
+
+	PagerFlag.SetAssigned(true)
+	assert.Nil(t, checkPagerFlag(ctx))
+	setField("concurrency", "4")
+	assert.Nil(t, checkPagerFlag(ctx))
+	setField("concurrency", "four")
+	assert.EqualError(t, checkPagerFlag(ctx), "invalid --pager concurrency=four, it must be a positive integer")
+	setField("concurrency", "0")
+	assert.NotNil(t, checkPagerFlag(ctx))
+	setField("concurrency", "")
+
+	setField("stream", "true")
+	assert.Nil(t, checkPagerFlag(ctx))
+	OutputFlag(ctx.Flags()).SetAssigned(true)
+	assert.EqualError(t, checkPagerFlag(ctx), "--pager stream=true cannot be used with --output")
+	OutputFlag(ctx.Flags()).SetAssigned(false)
+	QueryFlag(ctx.Flags()).SetAssigned(true)
+	assert.EqualError(t, checkPagerFlag(ctx), "--pager stream=true cannot be used with --cli-query")
+}
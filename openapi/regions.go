@@ -0,0 +1,395 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/safety"
+	jmespath "github.com/jmespath/go-jmespath"
+)
+
+// regionsInFlight bounds how many regions are called at the same time.
+const regionsInFlight = 10
+
+// regionResponseFields only describe a single call, so they are dropped from
+// the merged response.
+var regionResponseFields = []string{"RequestId", "NextToken", "PageNumber", "PageSize", "MaxResults"}
+
+type regionResult struct {
+	region string
+	body   string
+	err    error
+}
+
+// regionsError lists the regions whose call failed. When other regions
+// succeeded, their merged output is printed before it is reported.
+type regionsError struct {
+	failed []regionResult
+	total  int
+}
+
+func (e *regionsError) Error() string {
+	lines := []string{fmt.Sprintf("call failed in %d of %d regions", len(e.failed), e.total)}
+	for _, r := range e.failed {
+		lines = append(lines, fmt.Sprintf("%s: %s", r.region, r.err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// regionInvoker sends a copy of the prepared request to another region. It
+// keeps the client of the wrapped invoker, so `--pager` works unchanged.
+type regionInvoker struct {
+	Invoker
+	caller  requestCaller
+	request *requests.CommonRequest
+}
+
+func (r *regionInvoker) getRequest() *requests.CommonRequest {
+	return r.request
+}
+
+func (r *regionInvoker) Call() (*responses.CommonResponse, error) {
+	return r.caller.callRequest(r.request)
+}
+
+func (r *regionInvoker) callRequest(request *requests.CommonRequest) (*responses.CommonResponse, error) {
+	return r.caller.callRequest(request)
+}
+
+// invokeInRegions runs the prepared call in every region of `--regions` and
+// merges the responses. Regions are resolved against the product's regional
+// endpoints; `--endpoint` or a profile endpoint is used for all of them.
+func (c *Commando) invokeInRegions(ctx *cli.Context, invoker Invoker) (string, error) {
+	template := invoker.getRequest()
+	if err := checkRegionsReadApi(template); err != nil {
+		return "", err
+	}
+	caller, ok := invoker.(requestCaller)
+	if !ok {
+		return "", fmt.Errorf("--regions is not supported for this call")
+	}
+
+	product, ok := c.library.GetProduct(template.Product)
+	if !ok {
+		product = meta.Product{Code: template.Product}
+	}
+	value, _ := RegionsFlag(ctx.Flags()).GetValue()
+	regions, err := resolveRegions(value, &product)
+	if err != nil {
+		return "", err
+	}
+
+	fixedDomain := ""
+	if _, ok := config.EndpointFlag(ctx.Flags()).GetValue(); ok || c.profile.Endpoint != "" {
+		fixedDomain = template.Domain
+	}
+	endpoint := func(region string) (string, error) {
+		if fixedDomain != "" {
+			return fixedDomain, nil
+		}
+		domain, err := product.GetEndpointWithType(region, invoker.getClient(), c.profile.EndpointType)
+		if err != nil {
+			return "", fmt.Errorf("unknown endpoint for %s/%s! failed %s", product.GetLowerCode(), region, err)
+		}
+		return domain, nil
+	}
+
+	// in stream mode every region prints its items as they arrive
+	streaming := false
+	var writer io.Writer
+	if pager := GetPager(); pager != nil && pager.Stream {
+		streaming = true
+		writer = &syncWriter{w: ctx.Stdout()}
+		if QuietFlag(ctx.Flags()).IsAssigned() {
+			writer = io.Discard
+		}
+	}
+	call := func(ri Invoker, region string) (string, error) {
+		if pager := GetPager(); pager != nil {
+			pager.Region = region
+			pager.Writer = writer
+			return pager.CallWith(ri)
+		}
+		resp, err := hookdo(ri.Call)()
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "unmarshal") {
+			return "", err
+		}
+		return resp.GetHttpContentString(), nil
+	}
+
+	results := callRegions(invoker, caller, regions, endpoint, call)
+
+	out := ""
+	var failed []regionResult
+	if streaming {
+		for _, r := range results {
+			if r.err != nil {
+				failed = append(failed, r)
+			}
+		}
+	} else {
+		out, failed = mergeRegionResults(results)
+	}
+	if len(failed) == 0 {
+		return out, nil
+	}
+	if len(regions) == 1 {
+		return "", failed[0].err
+	}
+	if len(failed) == len(regions) {
+		out = ""
+	}
+	return out, &regionsError{failed: failed, total: len(regions)}
+}
+
+// checkRegionsReadApi accepts read-only RPC APIs and ROA GET requests.
+// Fanning out writes across regions is never what the user wants.
+func checkRegionsReadApi(request *requests.CommonRequest) error {
+	if request.PathPattern != "" {
+		if strings.EqualFold(request.Method, "GET") {
+			return nil
+		}
+		return fmt.Errorf("--regions only supports GET requests, not %s %s", request.Method, request.PathPattern)
+	}
+	if safety.IsReadOnlyApiName(request.ApiName) {
+		return nil
+	}
+	return fmt.Errorf("--regions only supports read APIs (Describe*, List*, Get*, Query*, Search*), not %s", request.ApiName)
+}
+
+// resolveRegions expands the `--regions` value, a comma separated list where
+// `all` stands for every region in the product's regional endpoints.
+func resolveRegions(value string, product *meta.Product) ([]string, error) {
+	var regions []string
+	seen := make(map[string]bool)
+	add := func(region string) {
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	for _, region := range strings.Split(value, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		if !strings.EqualFold(region, "all") {
+			add(region)
+			continue
+		}
+		if len(product.RegionalEndpoints) == 0 {
+			return nil, cli.NewErrorWithTip(fmt.Errorf("no regional endpoints known for product %s", product.GetLowerCode()),
+				"Use `--regions <regionId>,<regionId>` to list the regions")
+		}
+		all := make([]string, 0, len(product.RegionalEndpoints))
+		for r := range product.RegionalEndpoints {
+			all = append(all, r)
+		}
+		sort.Strings(all)
+		for _, r := range all {
+			add(r)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("--regions needs a comma separated list of region ids, or all")
+	}
+	return regions, nil
+}
+
+// newRegionRequest copies the prepared request and points it at region.
+func newRegionRequest(template *requests.CommonRequest, region string, domain string) *requests.CommonRequest {
+	request := cloneCommonRequest(template)
+	request.RegionId = region
+	request.Domain = domain
+	if _, ok := request.QueryParams["RegionId"]; ok {
+		request.QueryParams["RegionId"] = region
+	}
+	if _, ok := request.PathParams["RegionId"]; ok {
+		request.PathParams["RegionId"] = region
+	}
+	if _, ok := request.Headers["x-acs-region-id"]; ok {
+		request.Headers["x-acs-region-id"] = region
+	}
+	return request
+}
+
+// callRegions runs call once per region, at most regionsInFlight at a time,
+// and returns the results in the order of regions.
+func callRegions(invoker Invoker, caller requestCaller, regions []string,
+	endpoint func(region string) (string, error), call func(invoker Invoker, region string) (string, error)) []regionResult {
+	results := make([]regionResult, len(regions))
+	slots := make(chan struct{}, regionsInFlight)
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i].region = region
+			domain, err := endpoint(region)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			ri := &regionInvoker{
+				Invoker: invoker,
+				caller:  caller,
+				request: newRegionRequest(invoker.getRequest(), region, domain),
+			}
+			results[i].body, results[i].err = call(ri, region)
+		}(i, region)
+	}
+	wg.Wait()
+	return results
+}
+
+// mergeRegionResults concatenates the collection of every successful region,
+// in region order, into the first successful response. A response without a
+// collection is merged as one item, so the output is then a list of responses.
+func mergeRegionResults(results []regionResult) (string, []regionResult) {
+	var failed []regionResult
+	var skeleton interface{}
+	path := ""
+	items := []interface{}{}
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r)
+			continue
+		}
+		var body interface{}
+		dec := json.NewDecoder(strings.NewReader(r.body))
+		dec.UseNumber()
+		if err := dec.Decode(&body); err != nil {
+			r.err = fmt.Errorf("unmarshal %s", err)
+			failed = append(failed, r)
+			continue
+		}
+		if skeleton == nil {
+			skeleton = body
+			path = regionsCollectionPath(body)
+		}
+		items = append(items, tagRegion(regionItems(body, path), r.region)...)
+	}
+	if skeleton == nil {
+		return "", failed
+	}
+
+	var merged interface{} = items
+	if m, ok := skeleton.(map[string]interface{}); ok && path != "" && path != "@" {
+		for _, field := range regionResponseFields {
+			delete(m, field)
+		}
+		if _, ok := m["TotalCount"]; ok {
+			m["TotalCount"] = len(items)
+		}
+		setCollection(m, path, items)
+		merged = m
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return "", append(failed, regionResult{err: err})
+	}
+	return string(data), failed
+}
+
+// regionsCollectionPath finds the list to merge: the response itself, the
+// nested list `--pager` detects, or a top level list such as ROA responses
+// return. An empty path means the response has no list.
+func regionsCollectionPath(body interface{}) string {
+	if _, ok := body.([]interface{}); ok {
+		return "@"
+	}
+	if path := detectArrayPath(body); path != "" {
+		return path
+	}
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := m[k].([]interface{}); ok {
+			return k + "[]"
+		}
+	}
+	return ""
+}
+
+func regionItems(body interface{}, path string) []interface{} {
+	if path == "" {
+		return []interface{}{body}
+	}
+	v, err := jmespath.Search(path, body)
+	if err != nil {
+		return nil
+	}
+	items, _ := v.([]interface{})
+	return items
+}
+
+// setCollection replaces the list at path, one of the forms returned by
+// regionsCollectionPath.
+func setCollection(m map[string]interface{}, path string, items []interface{}) {
+	keys := strings.Split(strings.TrimSuffix(path, "[]"), ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = items
+}
+
+// tagRegion adds RegionId to the object items that do not have one.
+func tagRegion(items []interface{}, region string) []interface{} {
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if _, ok := m["RegionId"]; !ok {
+				m["RegionId"] = region
+			}
+		}
+	}
+	return items
+}
+
+// syncWriter serializes the JSON lines the regions stream concurrently.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
+	"github.com/aliyun/aliyun-cli/v3/config"
+	"github.com/aliyun/aliyun-cli/v3/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRegionInvoker struct {
+	request *requests.CommonRequest
+	mu      sync.Mutex
+	domains map[string]string
+	fail    string
+}
+
+func newFakeRegionInvoker() *fakeRegionInvoker {
+	request := requests.NewCommonRequest()
+	request.Product = "Ecs"
+	request.ApiName = "DescribeInstances"
+	request.RegionId = "cn-hangzhou"
+	request.Domain = "ecs.aliyuncs.com"
+	return &fakeRegionInvoker{request: request, domains: make(map[string]string)}
+}
+
+func (f *fakeRegionInvoker) getClient() *sdk.Client              { return nil }
+func (f *fakeRegionInvoker) getRequest() *requests.CommonRequest { return f.request }
+func (f *fakeRegionInvoker) Prepare(ctx *cli.Context) error      { return nil }
+
+func (f *fakeRegionInvoker) Call() (*responses.CommonResponse, error) {
+	return f.callRequest(f.request)
+}
+
+func (f *fakeRegionInvoker) callRequest(request *requests.CommonRequest) (*responses.CommonResponse, error) {
+	region := request.RegionId
+	f.mu.Lock()
+	f.domains[region] = request.Domain
+	f.mu.Unlock()
+	if region == f.fail {
+		return nil, fmt.Errorf("region %s is down", region)
+	}
+	body := fmt.Sprintf(`{"RequestId":"%s-req","TotalCount":1,"PageNumber":1,"PageSize":10,`+
+		`"Instances":{"Instance":[{"InstanceId":"i-%s"}]}}`, region, region)
+	resp := responses.NewCommonResponse()
+	err := responses.Unmarshal(resp, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, "JSON")
+	return resp, err
+}
+
+func newRegionsTestContext(stdout *bytes.Buffer, regions string) *cli.Context {
+	ctx := cli.NewCommandContext(stdout, new(bytes.Buffer))
+	AddFlags(ctx.Flags())
+	RegionsFlag(ctx.Flags()).SetAssigned(true)
+	RegionsFlag(ctx.Flags()).SetValue(regions)
+	return ctx
+}
+
+func TestResolveRegions(t *testing.T) {
+	product := &meta.Product{
+		Code: "Ecs",
+		RegionalEndpoints: map[string]string{
+			"cn-shanghai": "ecs.cn-shanghai.aliyuncs.com",
+			"cn-beijing":  "ecs.cn-beijing.aliyuncs.com",
+		},
+	}
+	regions, err := resolveRegions(" cn-hangzhou, cn-beijing,cn-hangzhou,", product)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cn-hangzhou", "cn-beijing"}, regions)
+
+	regions, err = resolveRegions("all", product)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cn-beijing", "cn-shanghai"}, regions)
+
+	regions, err = resolveRegions("cn-shanghai,ALL", product)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cn-shanghai", "cn-beijing"}, regions)
+
+	_, err = resolveRegions("all", &meta.Product{Code: "Foo"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "no regional endpoints known for product foo", err.Error())
+
+	_, err = resolveRegions(" , ", product)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--regions needs a comma separated list of region ids, or all", err.Error())
+}
+
+func TestCheckRegionsReadApi(t *testing.T) {
+	request := requests.NewCommonRequest()
+	request.ApiName = "DescribeInstances"
+	assert.Nil(t, checkRegionsReadApi(request))
+
+	request.ApiName = "ListTagResources"
+	assert.Nil(t, checkRegionsReadApi(request))
+
+	request.ApiName = "DeleteInstance"
+	err := checkRegionsReadApi(request)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--regions only supports read APIs (Describe*, List*, Get*, Query*, Search*), not DeleteInstance", err.Error())
+
+	request.PathPattern = "/clusters"
+	request.Method = "GET"
+	assert.Nil(t, checkRegionsReadApi(request))
+
+	request.Method = "POST"
+	err = checkRegionsReadApi(request)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--regions only supports GET requests, not POST /clusters", err.Error())
+}
+
+func TestNewRegionRequest(t *testing.T) {
+	template := requests.NewCommonRequest()
+	template.RegionId = "cn-hangzhou"
+	template.Domain = "ecs.cn-hangzhou.aliyuncs.com"
+	template.QueryParams["RegionId"] = "cn-hangzhou"
+	template.QueryParams["PageSize"] = "10"
+	template.Headers["x-acs-region-id"] = "cn-hangzhou"
+
+	request := newRegionRequest(template, "cn-beijing", "ecs.cn-beijing.aliyuncs.com")
+	assert.Equal(t, "cn-beijing", request.RegionId)
+	assert.Equal(t, "ecs.cn-beijing.aliyuncs.com", request.Domain)
+	assert.Equal(t, "cn-beijing", request.QueryParams["RegionId"])
+	assert.Equal(t, "10", request.QueryParams["PageSize"])
+	assert.Equal(t, "cn-beijing", request.Headers["x-acs-region-id"])
+	_, ok := request.PathParams["RegionId"]
+	assert.False(t, ok)
+
+	assert.Equal(t, "cn-hangzhou", template.RegionId)
+	assert.Equal(t, "cn-hangzhou", template.QueryParams["RegionId"])
+}
+
+func TestMergeRegionResults(t *testing.T) {
+	out, failed := mergeRegionResults([]regionResult{
+		{region: "cn-hangzhou", body: `{"RequestId":"a","TotalCount":2,"PageNumber":1,"Instances":{"Instance":[{"InstanceId":"i-1"},{"InstanceId":"i-2","RegionId":"cn-hz"}]}}`},
+		{region: "cn-beijing", err: fmt.Errorf("timeout")},
+		{region: "cn-shanghai", body: `{"RequestId":"b","TotalCount":1,"PageNumber":1,"Instances":{"Instance":[{"InstanceId":"i-3","Size":12345678901234567890}]}}`},
+		{region: "cn-shenzhen", body: `<xml/>`},
+	})
+	assert.Equal(t, `{"Instances":{"Instance":[{"InstanceId":"i-1","RegionId":"cn-hangzhou"},{"InstanceId":"i-2","RegionId":"cn-hz"},`+
+		`{"InstanceId":"i-3","RegionId":"cn-shanghai","Size":12345678901234567890}]},"TotalCount":3}`, out)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, "cn-beijing", failed[0].region)
+	assert.Equal(t, "cn-shenzhen", failed[1].region)
+	assert.Contains(t, failed[1].err.Error(), "unmarshal")
+
+	// ROA style top level list
+	out, failed = mergeRegionResults([]regionResult{
+		{region: "cn-hangzhou", body: `{"clusters":[{"name":"a"}],"page_info":{"total_count":1}}`},
+		{region: "cn-beijing", body: `{"clusters":[{"name":"b"}],"page_info":{"total_count":1}}`},
+	})
+	assert.Nil(t, failed)
+	assert.Equal(t, `{"clusters":[{"RegionId":"cn-hangzhou","name":"a"},{"RegionId":"cn-beijing","name":"b"}],"page_info":{"total_count":1}}`, out)
+
+	// responses without a list become a list of responses
+	out, _ = mergeRegionResults([]regionResult{
+		{region: "cn-hangzhou", body: `{"Status":"ok"}`},
+		{region: "cn-beijing", body: `{"Status":"ok","RegionId":"cn-beijing"}`},
+	})
+	assert.Equal(t, `[{"RegionId":"cn-hangzhou","Status":"ok"},{"RegionId":"cn-beijing","Status":"ok"}]`, out)
+
+	out, failed = mergeRegionResults([]regionResult{{region: "cn-hangzhou", err: fmt.Errorf("down")}})
+	assert.Equal(t, "", out)
+	assert.Len(t, failed, 1)
+}
+
+func TestRegionsError(t *testing.T) {
+	err := &regionsError{
+		failed: []regionResult{
+			{region: "cn-beijing", err: fmt.Errorf("timeout")},
+			{region: "cn-shenzhen", err: fmt.Errorf("denied")},
+		},
+		total: 3,
+	}
+	assert.Equal(t, "call failed in 2 of 3 regions\ncn-beijing: timeout\ncn-shenzhen: denied", err.Error())
+}
+
+func TestCallRegions(t *testing.T) {
+	invoker := newFakeRegionInvoker()
+	endpoint := func(region string) (string, error) {
+		if region == "cn-nowhere" {
+			return "", fmt.Errorf("unknown endpoint")
+		}
+		return "ecs." + region + ".aliyuncs.com", nil
+	}
+	call := func(ri Invoker, region string) (string, error) {
+		resp, err := ri.Call()
+		if err != nil {
+			return "", err
+		}
+		return resp.GetHttpContentString(), nil
+	}
+	results := callRegions(invoker, invoker, []string{"cn-hangzhou", "cn-nowhere", "cn-beijing"}, endpoint, call)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "cn-hangzhou", results[0].region)
+	assert.Contains(t, results[0].body, "i-cn-hangzhou")
+	assert.Equal(t, "cn-nowhere", results[1].region)
+	assert.Equal(t, "unknown endpoint", results[1].err.Error())
+	assert.Contains(t, results[2].body, "i-cn-beijing")
+	assert.Equal(t, "ecs.cn-beijing.aliyuncs.com", invoker.domains["cn-beijing"])
+	assert.Equal(t, "cn-hangzhou", invoker.request.RegionId)
+}
+
+func TestInvokeInRegions(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	profile := config.Profile{Language: "en", RegionId: "cn-hangzhou", Endpoint: "ecs.aliyuncs.com"}
+	c := NewCommando(stdout, profile)
+
+	invoker := newFakeRegionInvoker()
+	ctx := newRegionsTestContext(stdout, "cn-hangzhou,cn-beijing")
+	out, err := c.invokeInRegions(ctx, invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Instances":{"Instance":[{"InstanceId":"i-cn-hangzhou","RegionId":"cn-hangzhou"},`+
+		`{"InstanceId":"i-cn-beijing","RegionId":"cn-beijing"}]},"TotalCount":2}`, out)
+	// the profile endpoint is kept for every region
+	assert.Equal(t, "ecs.aliyuncs.com", invoker.domains["cn-beijing"])
+
+	invoker.fail = "cn-beijing"
+	out, err = c.invokeInRegions(ctx, invoker)
+	assert.Contains(t, out, "i-cn-hangzhou")
+	assert.NotContains(t, out, "i-cn-beijing")
+	assert.Equal(t, "call failed in 1 of 2 regions\ncn-beijing: region cn-beijing is down", err.Error())
+
+	// a single region keeps its own error
+	ctx = newRegionsTestContext(stdout, "cn-beijing")
+	out, err = c.invokeInRegions(ctx, invoker)
+	assert.Equal(t, "", out)
+	assert.Equal(t, "region cn-beijing is down", err.Error())
+
+	invoker.request.ApiName = "DeleteInstance"
+	_, err = c.invokeInRegions(ctx, invoker)
+	assert.Contains(t, err.Error(), "--regions only supports read APIs")
+}
+
+func TestInvokeInRegions_Pager(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	profile := config.Profile{Language: "en", RegionId: "cn-hangzhou", Endpoint: "ecs.aliyuncs.com"}
+	c := NewCommando(stdout, profile)
+	ctx := newRegionsTestContext(stdout, "cn-hangzhou,cn-beijing")
+
+	PagerFlag.SetAssigned(true)
+	defer PagerFlag.SetAssigned(false)
+
+	out, err := c.invokeInRegions(ctx, newFakeRegionInvoker())
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Instances":{"Instance":[{"InstanceId":"i-cn-hangzhou","RegionId":"cn-hangzhou"},`+
+		`{"InstanceId":"i-cn-beijing","RegionId":"cn-beijing"}]}}`, out)
+
+	stream := &PagerFlag.Fields[6]
+	stream.SetAssigned(true)
+	stream.SetValue("true")
+	defer stream.SetAssigned(false)
+	out, err = c.invokeInRegions(ctx, newFakeRegionInvoker())
+	assert.Nil(t, err)
+	assert.Equal(t, "", out)
+	assert.Contains(t, stdout.String(), "{\"InstanceId\":\"i-cn-hangzhou\",\"RegionId\":\"cn-hangzhou\"}\n")
+	assert.Contains(t, stdout.String(), "{\"InstanceId\":\"i-cn-beijing\",\"RegionId\":\"cn-beijing\"}\n")
+}
+
+func TestProcessApiInvoke_RejectsRegions(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	c := NewCommando(stdout, config.Profile{Language: "en", RegionId: "cn-hangzhou"})
+	ctx := newRegionsTestContext(stdout, "all")
+	err := c.processApiInvoke(ctx, &meta.Product{Code: "Sls"}, &meta.Api{}, "GET", "/logstores")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "--regions is not supported for product Sls")
+}
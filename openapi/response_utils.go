@@ -16,6 +16,7 @@ package openapi
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	jmespath "github.com/jmespath/go-jmespath"
 )
@@ -51,9 +52,16 @@ func evaluateExpr(body []byte, expr string) (string, error) {
 		return "", fmt.Errorf("jmes search failed %s", err)
 	}
 
-	if s, ok := obj.(string); ok {
-		return s, nil
-	} else {
+	switch v := obj.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
 		return "", fmt.Errorf("object %v isn't string", obj)
 	}
 }
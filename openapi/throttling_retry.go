@@ -22,6 +22,7 @@ import (
 	"time"
 
 	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	"github.com/aliyun/aliyun-cli/v3/sysconfig/throttlingretry"
 )
@@ -34,15 +35,36 @@ const (
 var throttlingRetrySleep = time.Sleep
 
 func (a *BasicInvoker) callWithThrottlingRetry(call func() (*responses.CommonResponse, error)) (*responses.CommonResponse, error) {
+	var request *requests.CommonRequest
+	if a != nil {
+		request = a.request
+	}
+	return a.callRequestWithThrottlingRetry(request, call)
+}
+
+// callRequestWithThrottlingRetry retries call like callWithThrottlingRetry but
+// stamps the retry headers on request instead of the invoker's own request, so
+// copies sent concurrently (e.g. pages fetched by the pager) do not race.
+func (a *BasicInvoker) callRequestWithThrottlingRetry(request *requests.CommonRequest, call func() (*responses.CommonResponse, error)) (resp *responses.CommonResponse, err error) {
+	cacheKey := a.responseCacheKey(request)
+	start := time.Now()
+	cached := false
+	defer func() {
+		a.recordAudit(request, resp, err, start, cached)
+	}()
+	if resp, cached = a.cachedResponse(cacheKey); cached {
+		return resp, nil
+	}
 	retried := false
 	retryDelayMS := int64(0)
 	maxAttempts := a.throttlingRetryMaxAttempts()
 	for retryAttempt := 0; ; retryAttempt++ {
 		if retryAttempt > 0 {
-			a.applyRetryRequestHeaders(retryAttempt, retryDelayMS)
+			setRetryRequestHeaders(request, retryAttempt, retryDelayMS)
 		}
 		resp, err := call()
 		if err == nil {
+			a.cacheResponse(cacheKey, resp)
 			return resp, nil
 		}
 
@@ -124,12 +146,19 @@ func (a *BasicInvoker) throttlingRetryConfigOrDefault() *throttlingretry.Config
 }
 
 func (a *BasicInvoker) applyRetryRequestHeaders(retryAttempt int, delayMS int64) {
-	if a == nil || a.request == nil || retryAttempt <= 0 {
+	if a == nil {
+		return
+	}
+	setRetryRequestHeaders(a.request, retryAttempt, delayMS)
+}
+
+func setRetryRequestHeaders(request *requests.CommonRequest, retryAttempt int, delayMS int64) {
+	if request == nil || retryAttempt <= 0 {
 		return
 	}
-	if a.request.Headers == nil {
-		a.request.Headers = make(map[string]string)
+	if request.Headers == nil {
+		request.Headers = make(map[string]string)
 	}
-	a.request.Headers["x-acs-retry-attempts"] = fmt.Sprintf("%d", retryAttempt)
-	a.request.Headers["x-acs-retry-delay"] = fmt.Sprintf("%d", delayMS)
+	request.Headers["x-acs-retry-attempts"] = fmt.Sprintf("%d", retryAttempt)
+	request.Headers["x-acs-retry-delay"] = fmt.Sprintf("%d", delayMS)
 }
@@ -14,10 +14,14 @@
 package openapi
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/aliyun/aliyun-cli/v3/i18n"
 )
@@ -33,18 +37,47 @@ var WaiterFlag = &cli.Flag{Category: "helper",
 		""),
 	Fields: []cli.Field{
 		{Key: "expr", Required: true, Short: i18n.T("", "")},
-		{Key: "to", Required: true, Short: i18n.T("", "")},
+		{Key: "to", Required: true, Short: i18n.T(
+			"expected value, repeat it as `to=Running to=Starting` to accept any of several values",
+			"期望的值，重复指定如 `to=Running to=Starting` 可接受多个值中的任意一个")},
 		{Key: "timeout", DefaultValue: "180", Short: i18n.T("", "")},
 		{Key: "interval", DefaultValue: "5", Short: i18n.T("", "")},
+		{Key: "op", DefaultValue: "==", Short: i18n.T(
+			"comparison between result and `to`: ==, !=, >, >=, <, <= (numeric for the last four)",
+			"返回值与 `to` 的比较方式：==, !=, >, >=, <, <=（后四种按数值比较）")},
+		{Key: "fail", Short: i18n.T(
+			"stop at once with exit code 4 when the result is this value, repeatable, e.g. `fail=Stopped fail=CreateFailed`",
+			"返回值为该值时立即停止并以退出码 4 退出，可重复指定，例如 `fail=Stopped fail=CreateFailed`")},
+		{Key: "backoff", DefaultValue: "fixed", Short: i18n.T(
+			"polling policy: `fixed` or `exponential`; exponential allows timeout up to 86400 seconds",
+			"轮询策略：`fixed` 或 `exponential`，exponential 允许 timeout 最长 86400 秒")},
+		{Key: "max-interval", DefaultValue: "60", Short: i18n.T(
+			"upper bound of the interval in seconds for `backoff=exponential`",
+			"`backoff=exponential` 时轮询间隔的上限（秒）")},
+		{Key: "ignore-errors", Short: i18n.T(
+			"error codes to keep polling on, glob allowed, e.g. `ignore-errors=InvalidInstanceId.NotFound`",
+			"遇到这些错误码时继续轮询，支持通配符，例如 `ignore-errors=InvalidInstanceId.NotFound`")},
 	},
 	ExcludeWith: []string{"pager"},
 }
 
+// WaiterFailureExitCode is the process exit code when `--waiter fail=...`
+// matches, so scripts can tell a failed resource from a timeout.
+const WaiterFailureExitCode = 4
+
+var waiterSleep = time.Sleep
+
 type Waiter struct {
 	expr string
 	to   string
 	//	timeout  time.Duration	TODO use Flag.Field to validate
 	//	interval time.Duration  TODO use Flag.Field to validate
+
+	op           string
+	accepts      []string
+	fails        []string
+	backoff      string
+	ignoreErrors []string
 }
 
 func GetWaiter() *Waiter {
@@ -57,18 +90,56 @@ func GetWaiter() *Waiter {
 	waiter.to, _ = WaiterFlag.GetFieldValue("to")
 	//waiter.timeout = time.Duration(time.Second * 180)
 	//waiter.interval = time.Duration(time.Second * 5)
+	waiter.op, _ = WaiterFlag.GetFieldValue("op")
+	// values may contain commas, several of them are given by repeating the field
+	if accepts := WaiterFlag.GetFieldValues("to"); len(accepts) > 1 {
+		waiter.accepts = accepts
+		waiter.to = strings.Join(accepts, "' or '")
+	}
+	waiter.fails = WaiterFlag.GetFieldValues("fail")
+	waiter.backoff, _ = WaiterFlag.GetFieldValue("backoff")
+	ignoreErrors, _ := WaiterFlag.GetFieldValue("ignore-errors")
+	waiter.ignoreErrors = splitWaiterValues(ignoreErrors)
 
 	return waiter
 }
 
+func splitWaiterValues(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func (a *Waiter) CallWith(invoker Invoker) (string, error) {
+	exponential := false
+	switch a.backoff {
+	case "", "fixed":
+	case "exponential":
+		exponential = true
+	default:
+		return "", fmt.Errorf("--waiter backoff=%s must be fixed or exponential", a.backoff)
+	}
+	switch a.op {
+	case "", "==", "!=", ">", ">=", "<", "<=":
+	default:
+		return "", fmt.Errorf("--waiter op=%s must be one of ==, !=, >, >=, <, <=", a.op)
+	}
+
 	//
-	// timeout is 1-600 seconds, default is 180
+	// timeout is 1-600 seconds (1-86400 with exponential backoff), default is 180
+	maxTimeout := 600
+	if exponential {
+		maxTimeout = 86400
+	}
 	timeout := time.Duration(time.Second * 180)
 	if s, ok := WaiterFlag.GetFieldValue("timeout"); ok {
 		if n, err := strconv.Atoi(s); err == nil {
-			if n <= 0 || n > 600 {
-				return "", fmt.Errorf("--waiter timeout=%s must between 1-600 (seconds)", s)
+			if n <= 0 || n > maxTimeout {
+				return "", fmt.Errorf("--waiter timeout=%s must between 1-%d (seconds)", s, maxTimeout)
 			}
 			timeout = time.Duration(time.Second * time.Duration(n))
 		} else {
@@ -88,27 +159,150 @@ func (a *Waiter) CallWith(invoker Invoker) (string, error) {
 			return "", fmt.Errorf("--waiter interval=%s must be integer", s)
 		}
 	}
+	//
+	// max-interval is 2-600 seconds, default is 60, only used by exponential backoff
+	maxInterval := time.Duration(time.Second * 60)
+	if s, ok := WaiterFlag.GetFieldValue("max-interval"); ok && exponential {
+		if n, err := strconv.Atoi(s); err == nil {
+			if n <= 1 || n > 600 {
+				return "", fmt.Errorf("--waiter max-interval=%s must between 2-600 (seconds)", s)
+			}
+			maxInterval = time.Duration(time.Second * time.Duration(n))
+		} else {
+			return "", fmt.Errorf("--waiter max-interval=%s must be integer", s)
+		}
+	}
 
 	begin := time.Now()
+	last := ""
 	for {
 		resp, err := invoker.Call()
-		if err != nil {
+		if err != nil && !a.isIgnoredError(err) {
 			return "", err
 		}
 
-		v, err := evaluateExpr(resp.GetHttpContentBytes(), a.expr)
-		if err != nil {
-			return "", err
-		}
+		if err == nil {
+			v, err := evaluateExpr(resp.GetHttpContentBytes(), a.expr)
+			if err != nil {
+				return "", err
+			}
+			last = v
 
-		if v == a.to {
-			return resp.GetHttpContentString(), nil
+			if a.isFailure(v) {
+				return "", cli.NewErrorWithExitCode(
+					fmt.Errorf("wait '%s' to '%s' failed, reached failure state '%s'", a.expr, a.to, v),
+					WaiterFailureExitCode)
+			}
+
+			ok, err := a.isAccepted(v)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return resp.GetHttpContentString(), nil
+			}
 		}
 		duration := time.Since(begin)
 		if duration > timeout {
 			return "", fmt.Errorf("wait '%s' to '%s' timeout(%dseconds), last='%s'",
-				a.expr, a.to, timeout/time.Second, v)
+				a.expr, a.to, timeout/time.Second, last)
+		}
+		waiterSleep(interval)
+		if exponential {
+			interval = interval * 2
+			if interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+	}
+}
+
+func (a *Waiter) accepted() []string {
+	if a.accepts == nil {
+		return []string{a.to}
+	}
+	return a.accepts
+}
+
+// isAccepted compares the evaluated value with `to`. For == the value must be
+// one of the accepted values, for != none of them; numeric operators succeed
+// when the comparison holds for any accepted value.
+func (a *Waiter) isAccepted(v string) (bool, error) {
+	switch a.op {
+	case "", "==":
+		for _, to := range a.accepted() {
+			if v == to {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "!=":
+		for _, to := range a.accepted() {
+			if v == to {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	if v == "" {
+		// the resource may not be visible yet
+		return false, nil
+	}
+	actual, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return false, fmt.Errorf("--waiter op=%s needs a numeric result, got '%s'", a.op, v)
+	}
+	for _, to := range a.accepted() {
+		expected, err := strconv.ParseFloat(to, 64)
+		if err != nil {
+			return false, fmt.Errorf("--waiter op=%s needs a numeric to=, got '%s'", a.op, to)
+		}
+		if compareWaiterNumber(a.op, actual, expected) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func compareWaiterNumber(op string, actual, expected float64) bool {
+	switch op {
+	case ">":
+		return actual > expected
+	case ">=":
+		return actual >= expected
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	}
+	return false
+}
+
+func (a *Waiter) isFailure(v string) bool {
+	for _, fail := range a.fails {
+		if v == fail {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnoredError reports whether err is a server error whose code matches
+// `ignore-errors`, e.g. a NotFound returned right after the resource was created.
+func (a *Waiter) isIgnoredError(err error) bool {
+	if len(a.ignoreErrors) == 0 {
+		return false
+	}
+	var serverErr *sdkerrors.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	code := serverErr.ErrorCode()
+	for _, pattern := range a.ignoreErrors {
+		if ok, _ := filepath.Match(pattern, code); ok {
+			return true
 		}
-		time.Sleep(interval)
 	}
+	return false
 }
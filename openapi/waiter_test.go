@@ -14,10 +14,19 @@
 package openapi
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
+	"github.com/aliyun/aliyun-cli/v3/cli"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 func TestWaiter_CallWith(t *testing.T) {
@@ -119,3 +128,172 @@ func TestWaiter_CallWith_IntervalRange(t *testing.T) {
 		assert.Equal(t, "--waiter interval="+interval+" must between 2-10 (seconds)", err.Error())
 	}
 }
+
+type sequenceInvoker struct {
+	*BasicInvoker
+	bodies []string
+	errs   []error
+	calls  int
+}
+
+func (s *sequenceInvoker) Prepare(ctx *cli.Context) error {
+	return nil
+}
+
+func (s *sequenceInvoker) Call() (*responses.CommonResponse, error) {
+	i := s.calls
+	if i >= len(s.bodies) {
+		i = len(s.bodies) - 1
+	}
+	s.calls++
+	if s.errs != nil && s.errs[i] != nil {
+		return nil, s.errs[i]
+	}
+	resp := responses.NewCommonResponse()
+	err := responses.Unmarshal(resp, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(s.bodies[i])),
+	}, "JSON")
+	return resp, err
+}
+
+func withWaiterFields(t *testing.T, timeout, interval string) {
+	originSleep := waiterSleep
+	waiterSleep = func(time.Duration) {}
+	WaiterFlag.Fields[2].SetAssigned(true)
+	WaiterFlag.Fields[2].SetValue(timeout)
+	WaiterFlag.Fields[3].SetAssigned(true)
+	WaiterFlag.Fields[3].SetValue(interval)
+	t.Cleanup(func() {
+		waiterSleep = originSleep
+		WaiterFlag.Fields[2].SetAssigned(false)
+		WaiterFlag.Fields[3].SetAssigned(false)
+	})
+}
+
+func TestWaiter_CallWith_AcceptedValues(t *testing.T) {
+	withWaiterFields(t, "180", "5")
+	invoker := &sequenceInvoker{bodies: []string{
+		`{"Status":"Pending"}`,
+		`{"Status":"Starting"}`,
+	}}
+	waiter := &Waiter{expr: "Status", to: "Running,Starting", accepts: []string{"Running", "Starting"}}
+	str, err := waiter.CallWith(invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Status":"Starting"}`, str)
+	assert.Equal(t, 2, invoker.calls)
+}
+
+func TestWaiter_CallWith_FailureState(t *testing.T) {
+	withWaiterFields(t, "180", "5")
+	invoker := &sequenceInvoker{bodies: []string{
+		`{"Status":"Pending"}`,
+		`{"Status":"CreateFailed"}`,
+	}}
+	waiter := &Waiter{expr: "Status", to: "Running", fails: []string{"Stopped", "CreateFailed"}}
+	str, err := waiter.CallWith(invoker)
+	assert.Equal(t, "", str)
+	assert.NotNil(t, err)
+	assert.Equal(t, "wait 'Status' to 'Running' failed, reached failure state 'CreateFailed'", err.Error())
+	e, ok := err.(cli.ErrorWithExitCode)
+	assert.True(t, ok)
+	assert.Equal(t, WaiterFailureExitCode, e.GetExitCode())
+}
+
+func TestWaiter_CallWith_NumericOperator(t *testing.T) {
+	withWaiterFields(t, "180", "5")
+	invoker := &sequenceInvoker{bodies: []string{
+		`{"TotalCount":1}`,
+		`{"TotalCount":"3"}`,
+	}}
+	waiter := &Waiter{expr: "TotalCount", to: "3", op: ">="}
+	_, err := waiter.CallWith(invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, invoker.calls)
+
+	invoker = &sequenceInvoker{bodies: []string{`{"TotalCount":"many"}`}}
+	_, err = waiter.CallWith(invoker)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--waiter op=>= needs a numeric result, got 'many'", err.Error())
+
+	waiter.op = "~"
+	_, err = waiter.CallWith(invoker)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--waiter op=~ must be one of ==, !=, >, >=, <, <=", err.Error())
+}
+
+func TestWaiter_CallWith_IgnoreErrors(t *testing.T) {
+	withWaiterFields(t, "180", "5")
+	notFound := sdkerrors.NewServerError(404, `{"Code":"InvalidInstanceId.NotFound","Message":"not found"}`, "")
+	invoker := &sequenceInvoker{
+		bodies: []string{"", `{"Status":"Running"}`},
+		errs:   []error{notFound, nil},
+	}
+	waiter := &Waiter{expr: "Status", to: "Running", ignoreErrors: []string{"*.NotFound"}}
+	_, err := waiter.CallWith(invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, invoker.calls)
+
+	invoker = &sequenceInvoker{
+		bodies: []string{"", `{"Status":"Running"}`},
+		errs:   []error{notFound, nil},
+	}
+	waiter.ignoreErrors = nil
+	_, err = waiter.CallWith(invoker)
+	assert.Equal(t, notFound, err)
+}
+
+func TestWaiter_CallWith_ExponentialBackoff(t *testing.T) {
+	withWaiterFields(t, "3600", "2")
+	var slept []time.Duration
+	waiterSleep = func(d time.Duration) { slept = append(slept, d) }
+	WaiterFlag.Fields[7].SetAssigned(true)
+	WaiterFlag.Fields[7].SetValue("10")
+	defer WaiterFlag.Fields[7].SetAssigned(false)
+
+	invoker := &sequenceInvoker{bodies: []string{
+		`{"Status":"Pending"}`,
+		`{"Status":"Pending"}`,
+		`{"Status":"Pending"}`,
+		`{"Status":"Pending"}`,
+		`{"Status":"Running"}`,
+	}}
+	waiter := &Waiter{expr: "Status", to: "Running", backoff: "exponential"}
+	_, err := waiter.CallWith(invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}, slept)
+
+	waiter.backoff = "fixed"
+	_, err = waiter.CallWith(invoker)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--waiter timeout=3600 must between 1-600 (seconds)", err.Error())
+
+	waiter.backoff = "linear"
+	_, err = waiter.CallWith(invoker)
+	assert.NotNil(t, err)
+	assert.Equal(t, "--waiter backoff=linear must be fixed or exponential", err.Error())
+}
+
+func TestGetWaiter_RepeatedValues(t *testing.T) {
+	fields := append([]cli.Field(nil), WaiterFlag.Fields...)
+	defer func() {
+		WaiterFlag.Fields = fields
+		WaiterFlag.SetAssigned(false)
+	}()
+	ctx := cli.NewCommandContext(new(bytes.Buffer), new(bytes.Buffer))
+	AddFlags(ctx.Flags())
+	parser := cli.NewParser([]string{"--waiter", "expr=Tags", "to=a,b", "to=c", "fail=x,y"}, ctx)
+	_, err := parser.ReadAll()
+	assert.Nil(t, err)
+
+	waiter := GetWaiter()
+	assert.Equal(t, []string{"a,b", "c"}, waiter.accepted())
+	assert.Equal(t, "a,b' or 'c", waiter.to)
+	assert.Equal(t, []string{"x,y"}, waiter.fails)
+	ok, err := waiter.isAccepted("a,b")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = waiter.isAccepted("a")
+	assert.False(t, ok)
+}
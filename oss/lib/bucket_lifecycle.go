@@ -18,12 +18,13 @@ var specChineseBucketLifeCycle = SpecText{
 	ossutil lifecycle --method put oss://bucket local_xml_file [options]
     ossutil lifecycle --method get oss://bucket [local_file] [options]
     ossutil lifecycle --method delete oss://bucket [options]
+    ossutil lifecycle --simulate oss://bucket[/prefix] [local_xml_file] [--simulate-date date] [--all-versions] [--output-format json] [options]
 `,
 	detailHelpText: ` 
     lifecycle命令通过设置method选项值为put、get、delete,可以设置、查询或者删除bucket的lifecycle配置
 
 用法:
-    该命令有三种用法:
+    该命令有四种用法:
 	
     1) ossutil lifecycle --method put oss://bucket local_xml_file [options]
         这个命令从配置文件local_xml_file中读取lifecycle配置，然后设置bucket的lifecycle规则
@@ -54,6 +55,19 @@ var specChineseBucketLifeCycle = SpecText{
 	
     3) ossutil lifecycle --method delete oss://bucket [options]
         这个命令删除bucket的lifecycle配置
+
+    4) ossutil lifecycle --simulate oss://bucket[/prefix] [local_xml_file] [--simulate-date date] [--all-versions] [--output-format json] [options]
+        这个命令模拟lifecycle规则，不修改bucket。如果输入参数local_xml_file，模拟该文件中的规则，
+        否则模拟bucket当前的规则。命令列举bucket（或prefix下）的object和分片上传，输出在
+        --simulate-date（默认为当前时间）时每个规则转换存储类型、删除的object数量和字节数，以及
+        取消的分片上传数量和已上传分片的字节数，最后按存储类型汇总规则生效前后的数量和字节数，
+        便于评估存储费用的变化。
+        一个object匹配多个规则时，每个规则分别统计；汇总时删除优先于转换，转换到最冷的存储类型。
+        bucket开启或暂停了版本控制（或指定--all-versions）时列举所有版本：非当前版本按
+        NoncurrentVersionTransition和NoncurrentVersionExpiration统计，当前版本过期后成为非当前
+        版本，仍计入汇总；ExpiredObjectDeleteMarker删除只剩下删除标记的object。
+        规则带有标签时，需要查询每个object的标签。基于最后访问时间（IsAccessTime）的转换按最后
+        修改时间模拟。--output-format json时以JSON输出结果。
 `,
 	sampleText: ` 
     1) 设置bucket的lifecycle配置
@@ -67,6 +81,12 @@ var specChineseBucketLifeCycle = SpecText{
 	
     4) 删除bucket的lifecycle配置
        ossutil lifecycle --method delete oss://bucket
+
+    5) 模拟bucket当前的lifecycle规则在2025-01-01的效果
+       ossutil lifecycle --simulate oss://bucket --simulate-date 2025-01-01
+
+    6) 应用本地的lifecycle配置前，模拟它对logs/下所有版本的效果，以JSON输出
+       ossutil lifecycle --simulate oss://bucket/logs/ local_xml_file --all-versions --output-format json
 `,
 }
 
@@ -79,13 +99,14 @@ var specEnglishBucketLifeCycle = SpecText{
 	ossutil lifecycle --method put oss://bucket local_xml_file [options]
     ossutil lifecycle --method get oss://bucket [local_xml_file] [options]
     ossutil lifecycle --method delete oss://bucket [options]
+    ossutil lifecycle --simulate oss://bucket[/prefix] [local_xml_file] [--simulate-date date] [--all-versions] [--output-format json] [options]
 `,
 	detailHelpText: ` 
     lifecycle command can set, get and delete the lifecycle configuration of the oss bucket by
     set method option value to put, get, delete
 
 Usage:
-    There are three usages for this command:
+    There are four usages for this command:
 	
     1) ossutil lifecycle --method put oss://bucket local_xml_file [options]
         The command sets the lifecycle configuration of bucket from local file local_xml_file
@@ -118,6 +139,26 @@ Usage:
 	
     3) ossutil lifecycle --method delete oss://bucket [options]
        The command deletes the lifecycle configuration of bucket
+
+    4) ossutil lifecycle --simulate oss://bucket[/prefix] [local_xml_file] [--simulate-date date] [--all-versions] [--output-format json] [options]
+       The command simulates the lifecycle rules, the bucket is not changed. If you input 
+       parameter local_xml_file, the rules of the file are simulated, else the current rules 
+       of the bucket. The command lists the objects and the multipart uploads of the bucket(or 
+       under the prefix), and reports the count and the bytes of the objects each rule would 
+       transition or expire, and the multipart uploads it would abort with the bytes of their 
+       parts, on --simulate-date(the current time by default). At last the count and the bytes 
+       of every storage class before and after the rules are summarized, for the change of the 
+       storage cost.
+       Every rule is counted on its own if an object matches more than one, in the summary the 
+       expiration wins over the transitions, and the coldest storage class wins.
+       If versioning of the bucket is enabled or suspended(or with --all-versions), all the 
+       versions are listed: the noncurrent versions are counted by 
+       NoncurrentVersionTransition and NoncurrentVersionExpiration, a current version expired 
+       becomes a noncurrent one and stays in the summary; ExpiredObjectDeleteMarker removes the 
+       delete markers which are the only versions left.
+       The tags of every object are got if the rules have tags. The transitions by the last 
+       access time(IsAccessTime) are simulated by the last modified time. With --output-format 
+       json the result is printed as JSON.
 `,
 	sampleText: ` 
     1) put bucket lifecycle
@@ -131,6 +172,12 @@ Usage:
 	
     4) delete lifecycle configuration
        ossutil lifecycle --method delete oss://bucket
+
+    5) simulate the current lifecycle rules of the bucket on 2025-01-01
+       ossutil lifecycle --simulate oss://bucket --simulate-date 2025-01-01
+
+    6) simulate a local lifecycle configuration on all the versions under logs/ before putting it, print as JSON
+       ossutil lifecycle --simulate oss://bucket/logs/ local_xml_file --all-versions --output-format json
 `,
 }
 
@@ -180,6 +227,11 @@ var bucketLifeCycleCommand = BucketLifeCycleCommand{
 			OptionCloudBoxID,
 			OptionForcePathStyle,
 			OptionInsecure,
+			OptionSimulate,
+			OptionSimulateDate,
+			OptionAllversions,
+			OptionOutputFormat,
+			OptionRetryTimes,
 		},
 	},
 }
@@ -200,13 +252,18 @@ func (blc *BucketLifeCycleCommand) Init(args []string, options OptionMapType) er
 
 // RunCommand simulate inheritance, and polymorphism
 func (blc *BucketLifeCycleCommand) RunCommand() error {
+	simulate, _ := GetBool(OptionSimulate, blc.command.options)
 	strMethod, _ := GetString(OptionMethod, blc.command.options)
-	if strMethod == "" {
+	if simulate {
+		if strMethod != "" {
+			return fmt.Errorf("--simulate and --method can't be both exist")
+		}
+	} else if strMethod == "" {
 		return fmt.Errorf("--method value is empty")
 	}
 
 	strMethod = strings.ToLower(strMethod)
-	if strMethod != "put" && strMethod != "get" && strMethod != "delete" {
+	if !simulate && strMethod != "put" && strMethod != "get" && strMethod != "delete" {
 		return fmt.Errorf("--method value is not in the optional value:put|get|delete")
 	}
 
@@ -217,6 +274,10 @@ func (blc *BucketLifeCycleCommand) RunCommand() error {
 
 	blc.blOption.bucketName = srcBucketUrL.bucket
 
+	if simulate {
+		return blc.SimulateBucketLifecycle(os.Stdout, *srcBucketUrL)
+	}
+
 	if strMethod == "put" {
 		err = blc.PutBucketLifecycle()
 	} else if strMethod == "get" {
@@ -232,39 +293,42 @@ func (blc *BucketLifeCycleCommand) PutBucketLifecycle() error {
 		return fmt.Errorf("put bucket lifecycle need at least 2 parameters,the local xml file is empty")
 	}
 
-	xmlFile := blc.command.args[1]
-	fileInfo, err := os.Stat(xmlFile)
+	xmlBody, err := readLifecycleXmlFile(blc.command.args[1])
 	if err != nil {
 		return err
 	}
 
+	// put bucket lifecycle
+	client, err := blc.command.ossClient(blc.blOption.bucketName)
+	if err != nil {
+		return err
+	}
+
+	options := []oss.Option{oss.AllowSameActionOverLap(true)}
+	return client.SetBucketLifecycleXml(blc.blOption.bucketName, string(xmlBody), options...)
+}
+
+func readLifecycleXmlFile(xmlFile string) ([]byte, error) {
+	fileInfo, err := os.Stat(xmlFile)
+	if err != nil {
+		return nil, err
+	}
+
 	if fileInfo.IsDir() {
-		return fmt.Errorf("%s is dir,not the expected file", xmlFile)
+		return nil, fmt.Errorf("%s is dir,not the expected file", xmlFile)
 	}
 
 	if fileInfo.Size() == 0 {
-		return fmt.Errorf("%s is empty file", xmlFile)
+		return nil, fmt.Errorf("%s is empty file", xmlFile)
 	}
 
 	// parsing the xml file
 	file, err := os.Open(xmlFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
-	xmlBody, err := ioutil.ReadAll(file)
-	if err != nil {
-		return err
-	}
-
-	// put bucket lifecycle
-	client, err := blc.command.ossClient(blc.blOption.bucketName)
-	if err != nil {
-		return err
-	}
-
-	options := []oss.Option{oss.AllowSameActionOverLap(true)}
-	return client.SetBucketLifecycleXml(blc.blOption.bucketName, string(xmlBody), options...)
+	return ioutil.ReadAll(file)
 }
 
 func (blc *BucketLifeCycleCommand) confirm(str string) bool {
@@ -0,0 +1,671 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// storageClassRank orders the storage classes from the warmest to the
+// coldest, a transition only goes to a colder one.
+var storageClassRank = map[string]int{
+	string(oss.StorageStandard):        0,
+	string(oss.StorageIA):              1,
+	string(oss.StorageArchive):         2,
+	string(oss.StorageColdArchive):     3,
+	string(oss.StorageDeepColdArchive): 4,
+}
+
+func colderStorageClass(class, than string) bool {
+	rank, ok := storageClassRank[class]
+	if !ok {
+		return false
+	}
+	thanRank, ok := storageClassRank[than]
+	return !ok || rank > thanRank
+}
+
+// lifecycleCount is the number and the bytes of objects, versions or
+// multipart uploads.
+type lifecycleCount struct {
+	Count int64 `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+func (c *lifecycleCount) add(size int64) {
+	c.Count++
+	c.Size += size
+}
+
+// lifecycleRuleStat is what a rule would do on the simulate date, the
+// transitions are by the target storage class.
+type lifecycleRuleStat struct {
+	ID                   string                     `json:"id"`
+	Prefix               string                     `json:"prefix"`
+	Status               string                     `json:"status"`
+	Transition           map[string]*lifecycleCount `json:"transition"`
+	Expiration           lifecycleCount             `json:"expiration"`
+	NoncurrentTransition map[string]*lifecycleCount `json:"noncurrentTransition"`
+	NoncurrentExpiration lifecycleCount             `json:"noncurrentExpiration"`
+	ExpiredDeleteMarkers int64                      `json:"expiredDeleteMarkers"`
+	AbortMultipartUpload lifecycleCount             `json:"abortMultipartUpload"`
+}
+
+// lifecycleClassStat is a storage class before and after all the rules.
+type lifecycleClassStat struct {
+	StorageClass string         `json:"storageClass"`
+	Before       lifecycleCount `json:"before"`
+	After        lifecycleCount `json:"after"`
+}
+
+// lifecycleSimulateResult is printed by lifecycle --simulate. Deleted is
+// the objects and versions removed, Aborted the multipart uploads aborted.
+type lifecycleSimulateResult struct {
+	Bucket         string                `json:"bucket"`
+	Prefix         string                `json:"prefix"`
+	Date           string                `json:"date"`
+	Rules          []*lifecycleRuleStat  `json:"rules"`
+	StorageClasses []*lifecycleClassStat `json:"storageClasses"`
+	Deleted        lifecycleCount        `json:"deleted"`
+	Aborted        lifecycleCount        `json:"aborted"`
+}
+
+// simulateRule is a rule with its dates parsed.
+type simulateRule struct {
+	rule            oss.LifecycleRule
+	stat            *lifecycleRuleStat
+	expireBefore    time.Time
+	transitionDates []time.Time
+	abortBefore     time.Time
+}
+
+// lifecycleObject is an object, or a version of it. noncurrentSince is when
+// the next version was created, zero for the current version.
+type lifecycleObject struct {
+	key             string
+	versionId       string
+	size            int64
+	storageClass    string
+	lastModified    time.Time
+	noncurrentSince time.Time
+}
+
+// lifecycleSimulation applies the rules to the objects, versions and
+// multipart uploads listed, as they would be on date.
+type lifecycleSimulation struct {
+	date      time.Time
+	versioned bool
+	rules     []*simulateRule
+	needTags  bool
+	tagsOf    func(key, versionId string) (map[string]string, error)
+	classes   map[string]*lifecycleClassStat
+	deleted   lifecycleCount
+	aborted   lifecycleCount
+}
+
+// parseLifecycleDate parses the dates of rules, and --simulate-date which can
+// be a day as well.
+func parseLifecycleDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func newLifecycleSimulation(rules []oss.LifecycleRule, date time.Time, versioned bool) (*lifecycleSimulation, error) {
+	s := &lifecycleSimulation{date: date, versioned: versioned, classes: map[string]*lifecycleClassStat{}}
+	for _, rule := range rules {
+		r := &simulateRule{rule: rule, stat: &lifecycleRuleStat{
+			ID:                   rule.ID,
+			Prefix:               rule.Prefix,
+			Status:               rule.Status,
+			Transition:           map[string]*lifecycleCount{},
+			NoncurrentTransition: map[string]*lifecycleCount{},
+		}}
+		var err error
+		if exp := rule.Expiration; exp != nil {
+			if exp.CreatedBeforeDate != "" {
+				r.expireBefore, err = parseLifecycleDate(exp.CreatedBeforeDate)
+			} else if exp.Date != "" {
+				r.expireBefore, err = parseLifecycleDate(exp.Date)
+			}
+		}
+		for _, transition := range rule.Transitions {
+			var before time.Time
+			if err == nil && transition.CreatedBeforeDate != "" {
+				before, err = parseLifecycleDate(transition.CreatedBeforeDate)
+			}
+			r.transitionDates = append(r.transitionDates, before)
+		}
+		if abort := rule.AbortMultipartUpload; err == nil && abort != nil && abort.CreatedBeforeDate != "" {
+			r.abortBefore, err = parseLifecycleDate(abort.CreatedBeforeDate)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid date of lifecycle rule %s: %s", rule.ID, err.Error())
+		}
+
+		if len(rule.Tags) > 0 {
+			s.needTags = true
+		}
+		if rule.Filter != nil {
+			for _, not := range rule.Filter.Not {
+				if not.Tag != nil {
+					s.needTags = true
+				}
+			}
+		}
+		s.rules = append(s.rules, r)
+	}
+	return s, nil
+}
+
+// hasAbortMultipartUpload tells whether the multipart uploads need listing.
+func (s *lifecycleSimulation) hasAbortMultipartUpload() bool {
+	for _, r := range s.rules {
+		if r.rule.Status == "Enabled" && r.rule.AbortMultipartUpload != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// due tells whether the action of a rule happens on the simulate date, days
+// after since, or at once if since is before the created before date.
+func (s *lifecycleSimulation) due(since time.Time, days int, before time.Time) bool {
+	if days > 0 {
+		return !s.date.Before(since.AddDate(0, 0, days))
+	}
+	return !before.IsZero() && since.Before(before)
+}
+
+// matchesKey checks the prefix and the excluded prefixes of a rule.
+func (r *simulateRule) matchesKey(key string) bool {
+	if r.rule.Status != "Enabled" || !strings.HasPrefix(key, r.rule.Prefix) {
+		return false
+	}
+	if r.rule.Filter != nil {
+		for _, not := range r.rule.Filter.Not {
+			if not.Tag == nil && strings.HasPrefix(key, not.Prefix) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matches checks the size and the tags of the object as well, the tags are
+// got once for all the rules.
+func (s *lifecycleSimulation) matches(r *simulateRule, object lifecycleObject, tags *map[string]string) (bool, error) {
+	if !r.matchesKey(object.key) {
+		return false, nil
+	}
+	filter := r.rule.Filter
+	if filter != nil {
+		if filter.ObjectSizeGreaterThan != nil && object.size <= *filter.ObjectSizeGreaterThan {
+			return false, nil
+		}
+		if filter.ObjectSizeLessThan != nil && object.size >= *filter.ObjectSizeLessThan {
+			return false, nil
+		}
+	}
+
+	if *tags == nil && s.needTags && s.tagsOf != nil {
+		values, err := s.tagsOf(object.key, object.versionId)
+		if err != nil {
+			return false, err
+		}
+		*tags = values
+		if *tags == nil {
+			*tags = map[string]string{}
+		}
+	}
+	for _, tag := range r.rule.Tags {
+		if value, ok := (*tags)[tag.Key]; !ok || value != tag.Value {
+			return false, nil
+		}
+	}
+	if filter != nil {
+		for _, not := range filter.Not {
+			if not.Tag != nil && strings.HasPrefix(object.key, not.Prefix) {
+				if value, ok := (*tags)[not.Tag.Key]; ok && value == not.Tag.Value {
+					return false, nil
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *lifecycleSimulation) class(storageClass string) *lifecycleClassStat {
+	stat, ok := s.classes[storageClass]
+	if !ok {
+		stat = &lifecycleClassStat{StorageClass: storageClass}
+		s.classes[storageClass] = stat
+	}
+	return stat
+}
+
+func addLifecycleCount(counts map[string]*lifecycleCount, storageClass string, size int64) {
+	count, ok := counts[storageClass]
+	if !ok {
+		count = &lifecycleCount{}
+		counts[storageClass] = count
+	}
+	count.add(size)
+}
+
+// addObject applies every rule to the object on its own, and all of them to
+// it for the storage classes after: an expiration wins over the transitions,
+// and the coldest transition wins over the others.
+func (s *lifecycleSimulation) addObject(object lifecycleObject) error {
+	if object.storageClass == "" {
+		object.storageClass = string(oss.StorageStandard)
+	}
+	s.class(object.storageClass).Before.add(object.size)
+
+	var tags map[string]string
+	expired := false
+	finalClass := object.storageClass
+	for _, r := range s.rules {
+		ok, err := s.matches(r, object, &tags)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		current := object.noncurrentSince.IsZero()
+		if current {
+			if exp := r.rule.Expiration; exp != nil && s.due(object.lastModified, exp.Days, r.expireBefore) {
+				r.stat.Expiration.add(object.size)
+				expired = true
+				continue
+			}
+		} else if exp := r.rule.NonVersionExpiration; exp != nil && s.due(object.noncurrentSince, exp.NoncurrentDays, time.Time{}) {
+			r.stat.NoncurrentExpiration.add(object.size)
+			expired = true
+			continue
+		}
+
+		class := ""
+		if current {
+			for i, transition := range r.rule.Transitions {
+				target := string(transition.StorageClass)
+				if colderStorageClass(target, object.storageClass) && colderStorageClass(target, class) &&
+					s.due(object.lastModified, transition.Days, r.transitionDates[i]) {
+					class = target
+				}
+			}
+			if class != "" {
+				addLifecycleCount(r.stat.Transition, class, object.size)
+			}
+		} else {
+			for _, transition := range r.rule.NonVersionTransitions {
+				target := string(transition.StorageClass)
+				if colderStorageClass(target, object.storageClass) && colderStorageClass(target, class) &&
+					s.due(object.noncurrentSince, transition.NoncurrentDays, time.Time{}) {
+					class = target
+				}
+			}
+			if class != "" {
+				addLifecycleCount(r.stat.NoncurrentTransition, class, object.size)
+			}
+		}
+		if class != "" && colderStorageClass(class, finalClass) {
+			finalClass = class
+		}
+	}
+
+	// the current version expired in a versioned bucket is kept as a
+	// noncurrent version, behind a delete marker
+	if expired && (!s.versioned || !object.noncurrentSince.IsZero()) {
+		s.deleted.add(object.size)
+		return nil
+	}
+	s.class(finalClass).After.add(object.size)
+	return nil
+}
+
+// addDeleteMarker counts a delete marker which is the only version left of
+// its object, removed by ExpiredObjectDeleteMarker.
+func (s *lifecycleSimulation) addDeleteMarker(key string) {
+	removed := false
+	for _, r := range s.rules {
+		exp := r.rule.Expiration
+		if exp != nil && exp.ExpiredObjectDeleteMarker != nil && *exp.ExpiredObjectDeleteMarker && r.matchesKey(key) {
+			r.stat.ExpiredDeleteMarkers++
+			removed = true
+		}
+	}
+	if removed {
+		s.deleted.add(0)
+	}
+}
+
+// addUpload applies AbortMultipartUpload to a multipart upload, size is the
+// bytes of the parts uploaded.
+func (s *lifecycleSimulation) addUpload(key string, initiated time.Time, size int64) {
+	aborted := false
+	for _, r := range s.rules {
+		abort := r.rule.AbortMultipartUpload
+		if abort != nil && r.matchesKey(key) && s.due(initiated, abort.Days, r.abortBefore) {
+			r.stat.AbortMultipartUpload.add(size)
+			aborted = true
+		}
+	}
+	if aborted {
+		s.aborted.add(size)
+	}
+}
+
+func (s *lifecycleSimulation) result(bucketName, prefix string) lifecycleSimulateResult {
+	result := lifecycleSimulateResult{
+		Bucket:  bucketName,
+		Prefix:  prefix,
+		Date:    formatJSONTime(s.date),
+		Rules:   []*lifecycleRuleStat{},
+		Deleted: s.deleted,
+		Aborted: s.aborted,
+	}
+	for _, r := range s.rules {
+		result.Rules = append(result.Rules, r.stat)
+	}
+	for _, stat := range s.classes {
+		result.StorageClasses = append(result.StorageClasses, stat)
+	}
+	sort.Slice(result.StorageClasses, func(i, j int) bool {
+		return colderStorageClass(result.StorageClasses[j].StorageClass, result.StorageClasses[i].StorageClass)
+	})
+	return result
+}
+
+func sortedCountKeys(counts map[string]*lifecycleCount) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return colderStorageClass(keys[j], keys[i]) })
+	return keys
+}
+
+func printLifecycleSimulateResult(out io.Writer, result lifecycleSimulateResult) {
+	fmt.Fprintf(out, "simulate lifecycle of %s at %s\n", CloudURLToString(result.Bucket, result.Prefix), result.Date)
+	for _, rule := range result.Rules {
+		fmt.Fprintf(out, "\nrule: %s(%s), prefix: %s\n", rule.ID, rule.Status, rule.Prefix)
+		lines := 0
+		for _, class := range sortedCountKeys(rule.Transition) {
+			count := rule.Transition[class]
+			fmt.Fprintf(out, "    transition to %s: %d objects, %s bytes\n", class, count.Count, getSizeString(count.Size))
+			lines++
+		}
+		if rule.Expiration.Count > 0 {
+			fmt.Fprintf(out, "    expiration: %d objects, %s bytes\n", rule.Expiration.Count, getSizeString(rule.Expiration.Size))
+			lines++
+		}
+		for _, class := range sortedCountKeys(rule.NoncurrentTransition) {
+			count := rule.NoncurrentTransition[class]
+			fmt.Fprintf(out, "    noncurrent version transition to %s: %d versions, %s bytes\n", class, count.Count, getSizeString(count.Size))
+			lines++
+		}
+		if rule.NoncurrentExpiration.Count > 0 {
+			fmt.Fprintf(out, "    noncurrent version expiration: %d versions, %s bytes\n", rule.NoncurrentExpiration.Count, getSizeString(rule.NoncurrentExpiration.Size))
+			lines++
+		}
+		if rule.ExpiredDeleteMarkers > 0 {
+			fmt.Fprintf(out, "    expired delete markers: %d\n", rule.ExpiredDeleteMarkers)
+			lines++
+		}
+		if rule.AbortMultipartUpload.Count > 0 {
+			fmt.Fprintf(out, "    abort multipart upload: %d uploads, %s bytes\n", rule.AbortMultipartUpload.Count, getSizeString(rule.AbortMultipartUpload.Size))
+			lines++
+		}
+		if lines == 0 {
+			fmt.Fprintf(out, "    nothing to do\n")
+		}
+	}
+
+	fmt.Fprintf(out, "\n%-20s\t%-30s\t%s\n", "storage class", "before(count/bytes)", "after(count/bytes)")
+	fmt.Fprintf(out, "----------------------------------------------------------------------\n")
+	for _, class := range result.StorageClasses {
+		fmt.Fprintf(out, "%-20s\t%-30s\t%s\n", class.StorageClass,
+			strconv.FormatInt(class.Before.Count, 10)+"/"+getSizeString(class.Before.Size),
+			strconv.FormatInt(class.After.Count, 10)+"/"+getSizeString(class.After.Size))
+	}
+	fmt.Fprintf(out, "----------------------------------------------------------------------\n")
+	fmt.Fprintf(out, "deleted: %d, %s bytes\n", result.Deleted.Count, getSizeString(result.Deleted.Size))
+	fmt.Fprintf(out, "aborted multipart uploads: %d, %s bytes\n", result.Aborted.Count, getSizeString(result.Aborted.Size))
+}
+
+// SimulateBucketLifecycle reports what the rules of the local xml file, or
+// the ones of the bucket, would do to the objects on --simulate-date.
+func (blc *BucketLifeCycleCommand) SimulateBucketLifecycle(out io.Writer, cloudURL CloudURL) error {
+	date := time.Now().UTC()
+	if value, _ := GetString(OptionSimulateDate, blc.command.options); value != "" {
+		var err error
+		if date, err = parseLifecycleDate(value); err != nil {
+			return fmt.Errorf("invalid --simulate-date %s, the format is 2006-01-02 or 2006-01-02T15:04:05Z", value)
+		}
+	}
+
+	bucket, err := blc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+	var xmlBody []byte
+	if len(blc.command.args) >= 2 {
+		if xmlBody, err = readLifecycleXmlFile(blc.command.args[1]); err != nil {
+			return err
+		}
+	} else {
+		body, err := bucket.Client.GetBucketLifecycleXml(cloudURL.bucket)
+		if err != nil {
+			return err
+		}
+		xmlBody = []byte(body)
+	}
+	var config oss.LifecycleConfiguration
+	if err := xml.Unmarshal(xmlBody, &config); err != nil {
+		return fmt.Errorf("invalid lifecycle configuration: %s", err.Error())
+	}
+
+	allVersions, _ := GetBool(OptionAllversions, blc.command.options)
+	if !allVersions {
+		versioning, err := bucket.Client.GetBucketVersioning(cloudURL.bucket)
+		if err != nil {
+			return fmt.Errorf("get versioning of bucket %s error: %s, pass --all-versions if the bucket has versions", cloudURL.bucket, err.Error())
+		}
+		allVersions = hasObjectVersions(versioning.Status)
+	}
+	sim, err := newLifecycleSimulation(config.Rules, date, allVersions)
+	if err != nil {
+		return err
+	}
+	sim.tagsOf = func(key, versionId string) (map[string]string, error) {
+		var options []oss.Option
+		if versionId != "" {
+			options = append(options, oss.VersionId(versionId))
+		}
+		result, err := bucket.GetObjectTagging(key, options...)
+		if err != nil {
+			return nil, ObjectError{err, bucket.BucketName, key}
+		}
+		tags := map[string]string{}
+		for _, tag := range result.Tags {
+			tags[tag.Key] = tag.Value
+		}
+		return tags, nil
+	}
+
+	if allVersions {
+		err = blc.simulateObjectVersions(bucket, cloudURL.object, sim)
+	} else {
+		err = blc.simulateObjects(bucket, cloudURL.object, sim)
+	}
+	if err == nil && sim.hasAbortMultipartUpload() {
+		err = blc.simulateUploads(bucket, cloudURL.object, sim)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := sim.result(cloudURL.bucket, cloudURL.object)
+	if format := getOutputFormat(blc.command.options); format != "" {
+		printJSON(out, format, result)
+	} else {
+		printLifecycleSimulateResult(out, result)
+	}
+	return nil
+}
+
+// hasObjectVersions tells whether a bucket with the versioning status may
+// keep noncurrent versions and delete markers, a suspended bucket keeps the
+// ones made while it was enabled.
+func hasObjectVersions(status string) bool {
+	return status == string(oss.VersionEnabled) || status == string(oss.VersionSuspended)
+}
+
+func (blc *BucketLifeCycleCommand) simulateObjects(bucket *oss.Bucket, prefix string, sim *lifecycleSimulation) error {
+	marker := ""
+	for {
+		lor, err := blc.command.ossListObjectsRetry(bucket, oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(1000))
+		if err != nil {
+			return err
+		}
+		for _, object := range lor.Objects {
+			err := sim.addObject(lifecycleObject{
+				key:          object.Key,
+				size:         object.Size,
+				storageClass: object.StorageClass,
+				lastModified: object.LastModified,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !lor.IsTruncated {
+			return nil
+		}
+		marker = lor.NextMarker
+	}
+}
+
+// lifecycleVersion is a version or a delete marker listed.
+type lifecycleVersion struct {
+	lifecycleObject
+	isLatest     bool
+	deleteMarker bool
+}
+
+// simulateObjectVersions walks the versions of every object from the newest
+// one, a version becomes noncurrent when the one before it is created.
+func (blc *BucketLifeCycleCommand) simulateObjectVersions(bucket *oss.Bucket, prefix string, sim *lifecycleSimulation) error {
+	keyMarker, versionIdMarker := "", ""
+	var newer *lifecycleVersion
+	var marker *lifecycleVersion // the latest delete marker, removed if alone
+	for {
+		lor, err := blc.command.ossListObjectVersionsRetry(bucket, oss.Prefix(prefix), oss.KeyMarker(keyMarker),
+			oss.VersionIdMarker(versionIdMarker), oss.MaxKeys(1000))
+		if err != nil {
+			return err
+		}
+
+		versions := make([]lifecycleVersion, 0, len(lor.ObjectVersions)+len(lor.ObjectDeleteMarkers))
+		for _, v := range lor.ObjectVersions {
+			versions = append(versions, lifecycleVersion{lifecycleObject: lifecycleObject{
+				key: v.Key, versionId: v.VersionId, size: v.Size, storageClass: v.StorageClass, lastModified: v.LastModified,
+			}, isLatest: v.IsLatest})
+		}
+		for _, v := range lor.ObjectDeleteMarkers {
+			versions = append(versions, lifecycleVersion{lifecycleObject: lifecycleObject{
+				key: v.Key, versionId: v.VersionId, lastModified: v.LastModified,
+			}, isLatest: v.IsLatest, deleteMarker: true})
+		}
+		sort.SliceStable(versions, func(i, j int) bool {
+			if versions[i].key != versions[j].key {
+				return versions[i].key < versions[j].key
+			}
+			if versions[i].isLatest != versions[j].isLatest {
+				return versions[i].isLatest
+			}
+			return versions[i].lastModified.After(versions[j].lastModified)
+		})
+
+		for i := range versions {
+			v := versions[i]
+			if newer == nil || newer.key != v.key {
+				if marker != nil {
+					sim.addDeleteMarker(marker.key)
+				}
+				marker, newer = nil, nil
+			} else {
+				marker = nil
+				v.noncurrentSince = newer.lastModified
+			}
+			if v.deleteMarker {
+				if v.isLatest {
+					marker = &v
+				}
+			} else if err := sim.addObject(v.lifecycleObject); err != nil {
+				return err
+			}
+			newer = &v
+		}
+
+		if !lor.IsTruncated {
+			break
+		}
+		keyMarker, versionIdMarker = lor.NextKeyMarker, lor.NextVersionIdMarker
+	}
+	if marker != nil {
+		sim.addDeleteMarker(marker.key)
+	}
+	return nil
+}
+
+func (blc *BucketLifeCycleCommand) simulateUploads(bucket *oss.Bucket, prefix string, sim *lifecycleSimulation) error {
+	keyMarker, uploadIdMarker := "", ""
+	for {
+		lmr, err := blc.command.ossListMultipartUploadsRetry(bucket, oss.Prefix(prefix), oss.KeyMarker(keyMarker),
+			oss.UploadIDMarker(uploadIdMarker), oss.MaxUploads(1000))
+		if err != nil {
+			return err
+		}
+		for _, upload := range lmr.Uploads {
+			size, err := blc.uploadedPartsSize(bucket, upload)
+			if err != nil {
+				return err
+			}
+			sim.addUpload(upload.Key, upload.Initiated, size)
+		}
+		if !lmr.IsTruncated {
+			return nil
+		}
+		keyMarker, uploadIdMarker = lmr.NextKeyMarker, lmr.NextUploadIDMarker
+	}
+}
+
+func (blc *BucketLifeCycleCommand) uploadedPartsSize(bucket *oss.Bucket, upload oss.UncompletedUpload) (int64, error) {
+	imur := oss.InitiateMultipartUploadResult{Bucket: bucket.BucketName, Key: upload.Key, UploadID: upload.UploadID}
+	var size int64
+	partNumberMarker := 0
+	for {
+		lpr, err := bucket.ListUploadedParts(imur, oss.MaxParts(1000), oss.PartNumberMarker(partNumberMarker))
+		if err != nil {
+			return 0, ObjectError{err, bucket.BucketName, upload.Key}
+		}
+		for _, part := range lpr.UploadedParts {
+			size += int64(part.Size)
+		}
+		if !lpr.IsTruncated {
+			return size, nil
+		}
+		partNumberMarker, _ = strconv.Atoi(lpr.NextPartNumberMarker)
+	}
+}
@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseLifecycleRules(t *testing.T, body string) []oss.LifecycleRule {
+	var config oss.LifecycleConfiguration
+	require.NoError(t, xml.Unmarshal([]byte(body), &config))
+	return config.Rules
+}
+
+func TestParseLifecycleDate(t *testing.T) {
+	date, err := parseLifecycleDate("2024-06-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), date)
+	date, err = parseLifecycleDate("2024-06-01T12:00:00.000Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), date)
+	_, err = parseLifecycleDate("06/01/2024")
+	assert.Error(t, err)
+
+	_, err = newLifecycleSimulation(parseLifecycleRules(t, `<LifecycleConfiguration><Rule><ID>r</ID><Prefix></Prefix><Status>Enabled</Status>
+<Transition><CreatedBeforeDate>bad</CreatedBeforeDate><StorageClass>IA</StorageClass></Transition></Rule></LifecycleConfiguration>`), time.Now(), false)
+	assert.Contains(t, err.Error(), "invalid date of lifecycle rule r")
+}
+
+func TestLifecycleSimulationObjects(t *testing.T) {
+	rules := parseLifecycleRules(t, `<LifecycleConfiguration>
+  <Rule><ID>logs</ID><Prefix>logs/</Prefix><Status>Enabled</Status>
+    <Expiration><Days>365</Days></Expiration>
+    <Transition><Days>30</Days><StorageClass>IA</StorageClass></Transition>
+    <Transition><Days>90</Days><StorageClass>Archive</StorageClass></Transition>
+    <Filter><Not><Prefix>logs/keep/</Prefix></Not></Filter>
+  </Rule>
+  <Rule><ID>tmp</ID><Prefix>tmp/</Prefix><Status>Enabled</Status>
+    <Expiration><CreatedBeforeDate>2024-01-01T00:00:00.000Z</CreatedBeforeDate></Expiration>
+    <Filter><ObjectSizeGreaterThan>100</ObjectSizeGreaterThan></Filter>
+  </Rule>
+  <Rule><ID>tagged</ID><Prefix></Prefix><Status>Enabled</Status><Tag><Key>cold</Key><Value>yes</Value></Tag>
+    <Transition><Days>1</Days><StorageClass>ColdArchive</StorageClass></Transition>
+  </Rule>
+  <Rule><ID>disabled</ID><Prefix></Prefix><Status>Disabled</Status>
+    <Expiration><Days>1</Days></Expiration>
+  </Rule>
+</LifecycleConfiguration>`)
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sim, err := newLifecycleSimulation(rules, date, false)
+	require.NoError(t, err)
+	tagged := map[string]bool{"logs/b.log": true, "data/c": true}
+	sim.tagsOf = func(key, versionId string) (map[string]string, error) {
+		if tagged[key] {
+			return map[string]string{"cold": "yes"}, nil
+		}
+		return nil, nil
+	}
+
+	days := func(n int) time.Time { return date.AddDate(0, 0, -n) }
+	for _, object := range []lifecycleObject{
+		{key: "logs/a.log", size: 10, lastModified: days(40)},
+		{key: "logs/b.log", size: 20, lastModified: days(100)},
+		{key: "logs/c.log", size: 30, lastModified: days(400)},
+		{key: "logs/d.log", size: 40, lastModified: days(100), storageClass: "Archive"},
+		{key: "logs/keep/e.log", size: 50, lastModified: days(400)},
+		{key: "tmp/small", size: 60, lastModified: days(400)},
+		{key: "tmp/big", size: 200, lastModified: days(400)},
+		{key: "tmp/new", size: 200, lastModified: days(1)},
+		{key: "data/c", size: 70, lastModified: days(2), storageClass: "IA"},
+	} {
+		require.NoError(t, sim.addObject(object))
+	}
+	sim.addUpload("logs/upload", days(10), 100)
+
+	result := sim.result("bucket", "")
+	require.Len(t, result.Rules, 4)
+	logs := result.Rules[0]
+	assert.Equal(t, lifecycleCount{1, 10}, *logs.Transition["IA"])
+	assert.Equal(t, lifecycleCount{1, 20}, *logs.Transition["Archive"])
+	assert.Equal(t, lifecycleCount{1, 30}, logs.Expiration)
+	assert.Equal(t, lifecycleCount{}, logs.AbortMultipartUpload)
+	assert.Equal(t, lifecycleCount{1, 200}, result.Rules[1].Expiration)
+	assert.Equal(t, lifecycleCount{2, 90}, *result.Rules[2].Transition["ColdArchive"])
+	assert.Equal(t, lifecycleCount{}, result.Rules[3].Expiration)
+
+	classes := map[string]lifecycleClassStat{}
+	var order []string
+	for _, class := range result.StorageClasses {
+		classes[class.StorageClass] = *class
+		order = append(order, class.StorageClass)
+	}
+	assert.Equal(t, []string{"Standard", "IA", "Archive", "ColdArchive"}, order)
+	assert.Equal(t, lifecycleCount{7, 570}, classes["Standard"].Before)
+	assert.Equal(t, lifecycleCount{3, 310}, classes["Standard"].After)
+	assert.Equal(t, lifecycleCount{1, 70}, classes["IA"].Before)
+	assert.Equal(t, lifecycleCount{1, 10}, classes["IA"].After)
+	assert.Equal(t, lifecycleCount{1, 40}, classes["Archive"].After)
+	assert.Equal(t, lifecycleCount{2, 90}, classes["ColdArchive"].After)
+	assert.Equal(t, lifecycleCount{2, 230}, result.Deleted)
+
+	out := new(bytes.Buffer)
+	printLifecycleSimulateResult(out, result)
+	assert.Contains(t, out.String(), "simulate lifecycle of oss://bucket at 2024-06-01T00:00:00Z")
+	assert.Contains(t, out.String(), "rule: logs(Enabled), prefix: logs/\n    transition to IA: 1 objects, 10 bytes\n    transition to Archive: 1 objects, 20 bytes\n    expiration: 1 objects, 30 bytes\n")
+	assert.Contains(t, out.String(), "rule: disabled(Disabled), prefix: \n    nothing to do\n")
+	assert.Contains(t, out.String(), "deleted: 2, 230 bytes\n")
+}
+
+func TestLifecycleSimulationVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Has("versions") && query.Get("key-marker") == "":
+			fmt.Fprint(w, `<ListVersionsResult><Name>bucket</Name><IsTruncated>true</IsTruncated>
+<NextKeyMarker>a</NextKeyMarker><NextVersionIdMarker>v2</NextVersionIdMarker>
+<DeleteMarker><Key>a</Key><VersionId>v3</VersionId><IsLatest>true</IsLatest><LastModified>2024-05-01T00:00:00.000Z</LastModified></DeleteMarker>
+<Version><Key>a</Key><VersionId>v2</VersionId><IsLatest>false</IsLatest><LastModified>2024-03-01T00:00:00.000Z</LastModified><Size>10</Size><StorageClass>Standard</StorageClass></Version>
+</ListVersionsResult>`)
+		case query.Has("versions"):
+			fmt.Fprint(w, `<ListVersionsResult><Name>bucket</Name><IsTruncated>false</IsTruncated>
+<Version><Key>a</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><LastModified>2024-01-01T00:00:00.000Z</LastModified><Size>20</Size><StorageClass>Standard</StorageClass></Version>
+<DeleteMarker><Key>b</Key><VersionId>v1</VersionId><IsLatest>true</IsLatest><LastModified>2024-01-01T00:00:00.000Z</LastModified></DeleteMarker>
+<Version><Key>c</Key><VersionId>v1</VersionId><IsLatest>true</IsLatest><LastModified>2024-01-01T00:00:00.000Z</LastModified><Size>30</Size><StorageClass>Standard</StorageClass></Version>
+</ListVersionsResult>`)
+		case query.Has("uploads"):
+			fmt.Fprint(w, `<ListMultipartUploadsResult><Bucket>bucket</Bucket><IsTruncated>false</IsTruncated>
+<Upload><Key>u</Key><UploadId>id1</UploadId><Initiated>2024-01-01T00:00:00.000Z</Initiated></Upload>
+<Upload><Key>u</Key><UploadId>id2</UploadId><Initiated>2024-05-31T00:00:00.000Z</Initiated></Upload>
+</ListMultipartUploadsResult>`)
+		case query.Get("uploadId") != "":
+			fmt.Fprint(w, `<ListPartsResult><Bucket>bucket</Bucket><Key>u</Key><IsTruncated>false</IsTruncated>
+<Part><PartNumber>1</PartNumber><Size>100</Size></Part><Part><PartNumber>2</PartNumber><Size>50</Size></Part>
+</ListPartsResult>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client, err := oss.New(server.URL, "ak", "sk", oss.ForcePathStyle(true))
+	require.NoError(t, err)
+	bucket, err := client.Bucket("bucket")
+	require.NoError(t, err)
+
+	rules := parseLifecycleRules(t, `<LifecycleConfiguration>
+  <Rule><ID>versions</ID><Prefix></Prefix><Status>Enabled</Status>
+    <Expiration><Days>100</Days><ExpiredObjectDeleteMarker>true</ExpiredObjectDeleteMarker></Expiration>
+    <NoncurrentVersionTransition><NoncurrentDays>30</NoncurrentDays><StorageClass>IA</StorageClass></NoncurrentVersionTransition>
+    <NoncurrentVersionExpiration><NoncurrentDays>60</NoncurrentDays></NoncurrentVersionExpiration>
+    <AbortMultipartUpload><Days>7</Days></AbortMultipartUpload>
+  </Rule>
+</LifecycleConfiguration>`)
+	sim, err := newLifecycleSimulation(rules, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), true)
+	require.NoError(t, err)
+	require.True(t, sim.hasAbortMultipartUpload())
+
+	var blc BucketLifeCycleCommand
+	blc.command.options = OptionMapType{}
+	require.NoError(t, blc.simulateObjectVersions(bucket, "", sim))
+	require.NoError(t, blc.simulateUploads(bucket, "", sim))
+
+	// a/v2 is noncurrent since 2024-05-01, a/v1 since 2024-03-01, b is a
+	// lone delete marker, c expires and stays as a noncurrent version
+	stat := sim.result("bucket", "").Rules[0]
+	assert.Equal(t, lifecycleCount{1, 10}, *stat.NoncurrentTransition["IA"])
+	assert.Equal(t, lifecycleCount{1, 20}, stat.NoncurrentExpiration)
+	assert.Equal(t, int64(1), stat.ExpiredDeleteMarkers)
+	assert.Equal(t, lifecycleCount{1, 30}, stat.Expiration)
+	assert.Equal(t, lifecycleCount{1, 150}, stat.AbortMultipartUpload)
+
+	out := new(bytes.Buffer)
+	printJSON(out, OutputFormatJSON, sim.result("bucket", ""))
+	var result lifecycleSimulateResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, lifecycleCount{2, 20}, result.Deleted)
+	assert.Equal(t, lifecycleCount{1, 150}, result.Aborted)
+	require.Len(t, result.StorageClasses, 2)
+	assert.Equal(t, lifecycleClassStat{"Standard", lifecycleCount{3, 60}, lifecycleCount{1, 30}}, *result.StorageClasses[0])
+	assert.Equal(t, lifecycleClassStat{"IA", lifecycleCount{}, lifecycleCount{1, 10}}, *result.StorageClasses[1])
+}
+
+func TestHasObjectVersions(t *testing.T) {
+	assert.True(t, hasObjectVersions(string(oss.VersionEnabled)))
+	assert.True(t, hasObjectVersions(string(oss.VersionSuspended)))
+	assert.False(t, hasObjectVersions(""))
+}
@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// checksumSnapshotPrefix keys the local file checksums cached in the
+// snapshot db, apart from the lastModifiedTime records of cp.
+const checksumSnapshotPrefix = "checksum" + SnapshotConnector
+
+// contentDigest describes the content of a local file or an object. crc64
+// and md5 are empty when unknown, md5 is base64 encoded like Content-MD5.
+type contentDigest struct {
+	size  int64
+	crc64 string
+	md5   string
+}
+
+func objectDigest(props http.Header) (contentDigest, error) {
+	size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return contentDigest{}, err
+	}
+	return contentDigest{
+		size:  size,
+		crc64: props.Get(oss.HTTPHeaderOssCRC64),
+		md5:   props.Get(oss.HTTPHeaderContentMD5),
+	}, nil
+}
+
+// sameContent compares the size, then crc64, then Content-MD5. Content that
+// can not be compared, e.g. a multipart object without crc64, differs.
+func sameContent(src, dest contentDigest) bool {
+	if src.size != dest.size {
+		return false
+	}
+	if src.crc64 != "" && dest.crc64 != "" {
+		return src.crc64 == dest.crc64
+	}
+	if src.md5 != "" && dest.md5 != "" {
+		return src.md5 == dest.md5
+	}
+	return false
+}
+
+func computeFileDigest(filePath string, withMD5 bool) (contentDigest, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return contentDigest{}, err
+	}
+	defer f.Close()
+
+	crc64Ins := crc64.New(crc64.MakeTable(crc64.ECMA))
+	var md5Ins hash.Hash
+	var w io.Writer = crc64Ins
+	if withMD5 {
+		md5Ins = md5.New()
+		w = io.MultiWriter(crc64Ins, md5Ins)
+	}
+	size, err := io.Copy(w, f)
+	if err != nil {
+		return contentDigest{}, err
+	}
+	digest := contentDigest{size: size, crc64: strconv.FormatUint(crc64Ins.Sum64(), 10)}
+	if md5Ins != nil {
+		digest.md5 = base64.StdEncoding.EncodeToString(md5Ins.Sum(nil))
+	}
+	return digest, nil
+}
+
+// formatDigestSnapshot and parseDigestSnapshot encode a cached checksum as
+// size:mtime:crc64:md5, the cache is valid while size and mtime are.
+func formatDigestSnapshot(stamp string, digest contentDigest) string {
+	return stamp + ":" + digest.crc64 + ":" + digest.md5
+}
+
+func parseDigestSnapshot(value, stamp string) (contentDigest, bool) {
+	if !strings.HasPrefix(value, stamp+":") {
+		return contentDigest{}, false
+	}
+	fields := strings.SplitN(strings.TrimPrefix(value, stamp+":"), ":", 2)
+	if len(fields) != 2 {
+		return contentDigest{}, false
+	}
+	return contentDigest{crc64: fields[0], md5: fields[1]}, true
+}
+
+func (cc *CopyCommand) compareContent() bool {
+	return cc.cpOption.checksum || cc.cpOption.sizeOnly
+}
+
+func (cc *CopyCommand) sameContentReason() string {
+	if cc.cpOption.sizeOnly {
+		return skipReasonSameSize
+	}
+	return skipReasonSameContent
+}
+
+// localDigest computes the checksums of a local file, the -j routines hash
+// files in parallel. With --snapshot-path the result is cached, so files are
+// only read again when their size or lastModifiedTime change.
+func (cc *CopyCommand) localDigest(filePath string, f os.FileInfo, withMD5 bool) (contentDigest, error) {
+	absPath, _ := filepath.Abs(filePath)
+	key := []byte(checksumSnapshotPrefix + absPath)
+	stamp := fmt.Sprintf("%d:%d", f.Size(), f.ModTime().UnixNano())
+	if cc.cpOption.snapshotldb != nil {
+		if value, err := cc.cpOption.snapshotldb.Get(key, nil); err == nil {
+			if digest, ok := parseDigestSnapshot(string(value), stamp); ok && (!withMD5 || digest.md5 != "") {
+				digest.size = f.Size()
+				return digest, nil
+			}
+		}
+	}
+
+	digest, err := computeFileDigest(filePath, withMD5)
+	if err != nil {
+		return digest, err
+	}
+	if cc.cpOption.snapshotldb != nil {
+		if err := cc.cpOption.snapshotldb.Put(key, []byte(formatDigestSnapshot(stamp, digest)), nil); err != nil {
+			return digest, fmt.Errorf("dump snapshot error: %s", err.Error())
+		}
+	}
+	return digest, nil
+}
+
+// sameFileContent reports whether the local file has the content of the
+// object described by props. Directories match empty directory objects.
+func (cc *CopyCommand) sameFileContent(filePath string, props http.Header) (bool, error) {
+	remote, err := objectDigest(props)
+	if err != nil {
+		return false, nil
+	}
+	f, err := os.Stat(filePath)
+	if err != nil {
+		return false, nil
+	}
+	if f.IsDir() {
+		return remote.size == 0, nil
+	}
+	if f.Size() != remote.size || cc.cpOption.sizeOnly {
+		return f.Size() == remote.size, nil
+	}
+
+	// Content-MD5 is only needed for objects uploaded before OSS had crc64
+	local, err := cc.localDigest(filePath, f, remote.crc64 == "")
+	if err != nil {
+		return false, err
+	}
+	return sameContent(local, remote), nil
+}
+
+func (cc *CopyCommand) skipDownloadByContent(bucket *oss.Bucket, object, fileName string, size int64) bool {
+	f, err := os.Stat(fileName)
+	if err != nil {
+		return false
+	}
+	// the listed size is enough for --size-only, no need to stat the object
+	if cc.cpOption.sizeOnly && size >= 0 && !f.IsDir() {
+		return f.Size() == size
+	}
+
+	statOptions := cc.cpOption.payerOptions
+	if cc.cpOption.versionId != "" {
+		statOptions = append(statOptions, oss.VersionId(cc.cpOption.versionId))
+	}
+	props, err := cc.command.ossGetObjectStatRetry(bucket, object, statOptions...)
+	if err != nil {
+		return false
+	}
+	same, err := cc.sameFileContent(fileName, props)
+	if err != nil {
+		LogError("checksum %s error: %s\n", fileName, err.Error())
+		return false
+	}
+	return same
+}
+
+// sameObjectContent compares two objects by the checksums OSS keeps, no data
+// is read. size is the source size, the destination is only checked when it
+// is the same.
+func (cc *CopyCommand) sameObjectContent(srcBucket *oss.Bucket, srcObject string, destBucket *oss.Bucket, destObject string, size int64) (bool, error) {
+	props, err := cc.command.ossGetObjectStatRetry(destBucket, destObject, cc.cpOption.payerOptions...)
+	if err != nil {
+		return false, nil
+	}
+	dest, err := objectDigest(props)
+	if err != nil || dest.size != size {
+		return false, nil
+	}
+	if cc.cpOption.sizeOnly {
+		return true, nil
+	}
+
+	statOptions := cc.cpOption.payerOptions
+	if cc.cpOption.versionId != "" {
+		statOptions = append(statOptions, oss.VersionId(cc.cpOption.versionId))
+	}
+	if props, err = cc.command.ossGetObjectStatRetry(srcBucket, srcObject, statOptions...); err != nil {
+		return false, err
+	}
+	src, err := objectDigest(props)
+	if err != nil {
+		return false, err
+	}
+	return sameContent(src, dest), nil
+}
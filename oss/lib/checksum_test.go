@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestSameContent(t *testing.T) {
+	a := contentDigest{size: 3, crc64: "1", md5: "m"}
+	assert.True(t, sameContent(a, a))
+	assert.False(t, sameContent(a, contentDigest{size: 4, crc64: "1"}))
+	assert.False(t, sameContent(a, contentDigest{size: 3, crc64: "2", md5: "m"}))
+	// no crc64 on one side, Content-MD5 decides
+	assert.True(t, sameContent(a, contentDigest{size: 3, md5: "m"}))
+	assert.False(t, sameContent(a, contentDigest{size: 3, md5: "x"}))
+	// nothing to compare
+	assert.False(t, sameContent(contentDigest{size: 3}, contentDigest{size: 3}))
+}
+
+func TestObjectDigest(t *testing.T) {
+	props := http.Header{}
+	_, err := objectDigest(props)
+	assert.Error(t, err)
+
+	props.Set("Content-Length", "15")
+	props.Set("X-Oss-Hash-Crc64ecma", "1")
+	props.Set("Content-MD5", "m")
+	d, err := objectDigest(props)
+	require.NoError(t, err)
+	assert.Equal(t, contentDigest{size: 15, crc64: "1", md5: "m"}, d)
+}
+
+func TestComputeFileDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("this is content"), 0600))
+
+	d, err := computeFileDigest(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), d.size)
+	assert.Equal(t, "2863152195715871371", d.crc64)
+	assert.Equal(t, "", d.md5)
+
+	d, err = computeFileDigest(path, true)
+	require.NoError(t, err)
+	assert.Equal(t, "t/zvf+dF8qlVYP9fVQ47jw==", d.md5)
+
+	_, err = computeFileDigest(filepath.Join(t.TempDir(), "missing"), false)
+	assert.Error(t, err)
+}
+
+func TestDigestSnapshot(t *testing.T) {
+	value := formatDigestSnapshot("15:100", contentDigest{crc64: "1", md5: "a+b/c=="})
+	d, ok := parseDigestSnapshot(value, "15:100")
+	assert.True(t, ok)
+	assert.Equal(t, contentDigest{crc64: "1", md5: "a+b/c=="}, d)
+
+	_, ok = parseDigestSnapshot(value, "15:101")
+	assert.False(t, ok)
+	_, ok = parseDigestSnapshot("15:100:1", "15:100")
+	assert.False(t, ok)
+}
+
+func TestLocalDigestCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("this is content"), 0600))
+	db, err := leveldb.OpenFile(filepath.Join(dir, "snapshot"), nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var cc CopyCommand
+	cc.cpOption.snapshotldb = db
+	f, err := os.Stat(path)
+	require.NoError(t, err)
+	d, err := cc.localDigest(path, f, false)
+	require.NoError(t, err)
+	assert.Equal(t, "2863152195715871371", d.crc64)
+
+	// the cached value is used while size and mtime do not change
+	require.NoError(t, os.WriteFile(path, []byte("this is CONTENT"), 0600))
+	require.NoError(t, os.Chtimes(path, f.ModTime(), f.ModTime()))
+	d, err = cc.localDigest(path, f, false)
+	require.NoError(t, err)
+	assert.Equal(t, "2863152195715871371", d.crc64)
+	assert.Equal(t, int64(15), d.size)
+
+	// a cache without md5 is not enough when md5 is needed
+	d, err = cc.localDigest(path, f, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, "2863152195715871371", d.crc64)
+	assert.NotEmpty(t, d.md5)
+}
+
+func TestSameFileContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("this is content"), 0600))
+	props := http.Header{}
+	props.Set("Content-Length", "15")
+	props.Set("X-Oss-Hash-Crc64ecma", "2863152195715871371")
+
+	var cc CopyCommand
+	same, err := cc.sameFileContent(path, props)
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	props.Set("X-Oss-Hash-Crc64ecma", "1")
+	same, _ = cc.sameFileContent(path, props)
+	assert.False(t, same)
+
+	cc.cpOption.sizeOnly = true
+	same, _ = cc.sameFileContent(path, props)
+	assert.True(t, same)
+
+	props.Set("Content-Length", "0")
+	same, _ = cc.sameFileContent(path, props)
+	assert.False(t, same)
+	same, _ = cc.sameFileContent(dir, props)
+	assert.True(t, same)
+	same, _ = cc.sameFileContent(filepath.Join(dir, "missing"), props)
+	assert.False(t, same)
+}
@@ -0,0 +1,490 @@
+package lib
+
+import (
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	osscrypto "github.com/aliyun/aliyun-oss-go-sdk/oss/crypto"
+)
+
+// aesCtrAlignLen is the block size of AES/CTR, the IV of a part is counted
+// from its offset in blocks.
+const aesCtrAlignLen = 16
+
+// clientEncryption encrypts the uploads and decrypts the downloads on the
+// client, in the format of the client-side encryption of the OSS SDKs: the
+// data is encrypted by AES/CTR with a random data key, the data key and IV
+// are wrapped by an RSA master key and kept in the object meta.
+type clientEncryption struct {
+	builder    osscrypto.ContentCipherBuilder
+	canDecrypt bool
+}
+
+// loadClientEncryption reads the RSA master key from a PEM file. A private
+// key encrypts and decrypts, a public key only encrypts.
+func loadClientEncryption(keyFile string) (*clientEncryption, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey, privateKey string
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var pub *rsa.PublicKey
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid encryption key file %s: %s", keyFile, err.Error())
+			}
+			privateKey, pub = string(pem.EncodeToMemory(block)), &key.PublicKey
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid encryption key file %s: %s", keyFile, err.Error())
+			}
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("invalid encryption key file %s: not an RSA key", keyFile)
+			}
+			privateKey, pub = string(pem.EncodeToMemory(block)), &rsaKey.PublicKey
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid encryption key file %s: %s", keyFile, err.Error())
+			}
+			if _, ok := key.(*rsa.PublicKey); !ok {
+				return nil, fmt.Errorf("invalid encryption key file %s: not an RSA key", keyFile)
+			}
+			publicKey = string(pem.EncodeToMemory(block))
+		case "RSA PUBLIC KEY":
+			if _, err := x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+				return nil, fmt.Errorf("invalid encryption key file %s: %s", keyFile, err.Error())
+			}
+			publicKey = string(pem.EncodeToMemory(block))
+		}
+
+		// the public key of a private key is not always in the file
+		if pub != nil && publicKey == "" {
+			der, err := x509.MarshalPKIXPublicKey(pub)
+			if err != nil {
+				return nil, err
+			}
+			publicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		}
+	}
+	if publicKey == "" {
+		return nil, fmt.Errorf("invalid encryption key file %s: no RSA key in PEM format", keyFile)
+	}
+
+	master, err := osscrypto.CreateMasterRsa(nil, publicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &clientEncryption{builder: osscrypto.CreateAesCtrCipher(master), canDecrypt: privateKey != ""}, nil
+}
+
+func (ce *clientEncryption) cryptoBucket(bucket *oss.Bucket) *osscrypto.CryptoBucket {
+	cryptoBucket, _ := osscrypto.GetCryptoBucket(&bucket.Client, bucket.BucketName, ce.builder)
+	return cryptoBucket
+}
+
+// contentCipher returns the cipher to decrypt the object of the meta with,
+// nil if the object is not encrypted.
+func (ce *clientEncryption) contentCipher(header http.Header) (osscrypto.ContentCipher, error) {
+	key := header.Get(oss.HTTPHeaderOssMetaPrefix + osscrypto.OssClientSideEncryptionKey)
+	if key == "" {
+		return nil, nil
+	}
+	envelope := osscrypto.Envelope{
+		MatDesc: header.Get(oss.HTTPHeaderOssMetaPrefix + osscrypto.OssClientSideEncryptionMatDesc),
+		WrapAlg: header.Get(oss.HTTPHeaderOssMetaPrefix + osscrypto.OssClientSideEncryptionWrapAlg),
+		CEKAlg:  header.Get(oss.HTTPHeaderOssMetaPrefix + osscrypto.OssClientSideEncryptionCekAlg),
+	}
+	if envelope.CEKAlg != osscrypto.AesCtrAlgorithm {
+		return nil, fmt.Errorf("not supported content algorithm %s", envelope.CEKAlg)
+	}
+	if envelope.WrapAlg != osscrypto.RsaCryptoWrap {
+		return nil, fmt.Errorf("not supported key wrap algorithm %s", envelope.WrapAlg)
+	}
+	return ce.cipherOfWrappedKey(key, header.Get(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionStart))
+}
+
+// cipherOfWrappedKey unwraps the base64 data key and IV of the object meta.
+func (ce *clientEncryption) cipherOfWrappedKey(key, iv string) (osscrypto.ContentCipher, error) {
+	if !ce.canDecrypt {
+		return nil, fmt.Errorf("the encryption key file has no private key to decrypt with")
+	}
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	decodedIV, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, err
+	}
+	return ce.builder.ContentCipherEnv(osscrypto.Envelope{
+		IV:        string(decodedIV),
+		CipherKey: string(decodedKey),
+		WrapAlg:   osscrypto.RsaCryptoWrap,
+		CEKAlg:    osscrypto.AesCtrAlgorithm,
+	})
+}
+
+// alignPartSize rounds partSize up to the block size of the cipher.
+func alignPartSize(partSize int64, alignLen int64) int64 {
+	return (partSize + alignLen - 1) / alignLen * alignLen
+}
+
+// encryptCheckpointPath keeps the checkpoints apart from the ones of the sdk
+// for the same source and destination.
+func encryptCheckpointPath(cpDir, src, dest string) string {
+	sum := md5.Sum([]byte(src + SnapshotConnector + dest))
+	return filepath.Join(cpDir, fmt.Sprintf("encrypt_%x.cp", sum))
+}
+
+func loadEncryptCheckpoint(path string, v interface{}) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func saveEncryptCheckpoint(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// runParts runs fn for parts with routines at most, and returns the first
+// error.
+func runParts(parts []int, routines int, fn func(part int) error) error {
+	if routines < 1 {
+		routines = 1
+	}
+	chParts := make(chan int, len(parts))
+	for _, part := range parts {
+		chParts <- part
+	}
+	close(chParts)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var rerr error
+	for i := 0; i < routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range chParts {
+				if err := fn(part); err != nil {
+					once.Do(func() { rerr = err })
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return rerr
+}
+
+func publishStarted(listener oss.ProgressListener, consumed, total int64) {
+	if listener != nil {
+		listener.ProgressChanged(&oss.ProgressEvent{ConsumedBytes: consumed, TotalBytes: total, EventType: oss.TransferStartedEvent})
+	}
+}
+
+// encryptUploadCheckpoint is the checkpoint of an encrypted multipart upload.
+// The data key is kept wrapped, as in the object meta, so only the private
+// key resumes the upload.
+type encryptUploadCheckpoint struct {
+	FilePath string         `json:"filePath"`
+	FileSize int64          `json:"fileSize"`
+	ModTime  int64          `json:"modTime"`
+	Bucket   string         `json:"bucket"`
+	Object   string         `json:"object"`
+	UploadID string         `json:"uploadId"`
+	PartSize int64          `json:"partSize"`
+	Key      string         `json:"key"`
+	IV       string         `json:"iv"`
+	Parts    map[int]string `json:"parts"`
+	mu       sync.Mutex     `json:"-"`
+}
+
+func (cc *CopyCommand) encryptPutObjectFromFile(bucket *oss.Bucket, objectName, filePath string, options ...oss.Option) error {
+	f, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	// kept as the unencrypted content length in the meta
+	options = append(append([]oss.Option{}, options...), oss.ContentLength(f.Size()))
+	return cc.cpOption.encryption.cryptoBucket(bucket).PutObjectFromFile(objectName, filePath, options...)
+}
+
+// encryptUploadFile uploads a big file by encrypted multipart upload. The
+// parts done are kept in a checkpoint in --checkpoint-dir to resume from.
+func (cc *CopyCommand) encryptUploadFile(bucket *oss.Bucket, objectName, filePath string, partSize int64, options ...oss.Option) error {
+	ce := cc.cpOption.encryption
+	cryptoBucket := ce.cryptoBucket(bucket)
+	f, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	listener := oss.GetProgressListener(options)
+	_, routines := cc.preparePartOption(f.Size())
+	partSize = alignPartSize(partSize, aesCtrAlignLen)
+
+	absPath, _ := filepath.Abs(filePath)
+	cpPath := encryptCheckpointPath(cc.cpOption.cpDir, absPath, CloudURLToString(bucket.BucketName, objectName))
+	cp := &encryptUploadCheckpoint{}
+	cryptoContext := osscrypto.PartCryptoContext{DataSize: f.Size(), PartSize: partSize}
+	if loadEncryptCheckpoint(cpPath, cp) && cp.FilePath == absPath && cp.FileSize == f.Size() &&
+		cp.ModTime == f.ModTime().Unix() && cp.Bucket == bucket.BucketName && cp.Object == objectName &&
+		cp.PartSize == partSize && ce.canDecrypt {
+		if cryptoContext.ContentCipher, err = ce.cipherOfWrappedKey(cp.Key, cp.IV); err != nil {
+			return err
+		}
+	} else {
+		initOptions := oss.AddContentType(options, filePath, objectName)
+		imur, err := cryptoBucket.InitiateMultipartUpload(objectName, &cryptoContext, initOptions...)
+		if err != nil {
+			return err
+		}
+		cipherData := cryptoContext.ContentCipher.GetCipherData()
+		cp = &encryptUploadCheckpoint{
+			FilePath: absPath,
+			FileSize: f.Size(),
+			ModTime:  f.ModTime().Unix(),
+			Bucket:   bucket.BucketName,
+			Object:   objectName,
+			UploadID: imur.UploadID,
+			PartSize: partSize,
+			Key:      base64.StdEncoding.EncodeToString(cipherData.EncryptedKey),
+			IV:       base64.StdEncoding.EncodeToString(cipherData.EncryptedIV),
+			Parts:    map[int]string{},
+		}
+		if err := saveEncryptCheckpoint(cpPath, cp); err != nil {
+			return err
+		}
+	}
+	imur := oss.InitiateMultipartUploadResult{Bucket: cp.Bucket, Key: cp.Object, UploadID: cp.UploadID}
+
+	partNum := int((f.Size()-1)/partSize + 1)
+	var todo []int
+	var done int64
+	for part := 1; part <= partNum; part++ {
+		if _, ok := cp.Parts[part]; ok {
+			done += partLength(part, partSize, f.Size())
+		} else {
+			todo = append(todo, part)
+		}
+	}
+	publishStarted(listener, done, f.Size())
+
+	partOptions := append([]oss.Option{}, cc.cpOption.payerOptions...)
+	if listener != nil {
+		partOptions = append(partOptions, oss.Progress(listener))
+	}
+	err = runParts(todo, routines, func(part int) error {
+		start := int64(part-1) * partSize
+		uploadPart, err := cryptoBucket.UploadPartFromFile(imur, filePath, start, partLength(part, partSize, f.Size()),
+			part, cryptoContext, partOptions...)
+		if err != nil {
+			return err
+		}
+		cp.mu.Lock()
+		defer cp.mu.Unlock()
+		cp.Parts[part] = uploadPart.ETag
+		return saveEncryptCheckpoint(cpPath, cp)
+	})
+	if err != nil {
+		return err
+	}
+
+	var parts []oss.UploadPart
+	for part, etag := range cp.Parts {
+		parts = append(parts, oss.UploadPart{PartNumber: part, ETag: etag})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	if _, err := cryptoBucket.CompleteMultipartUpload(imur, parts, cc.cpOption.payerOptions...); err != nil {
+		return err
+	}
+	os.Remove(cpPath)
+	return nil
+}
+
+func partLength(part int, partSize, size int64) int64 {
+	start := int64(part-1) * partSize
+	if start+partSize > size {
+		return size - start
+	}
+	return partSize
+}
+
+// encryptDownloadCheckpoint is the checkpoint of a download by parts, the
+// parts are written to the temp file at their offsets.
+type encryptDownloadCheckpoint struct {
+	FilePath string       `json:"filePath"`
+	Bucket   string       `json:"bucket"`
+	Object   string       `json:"object"`
+	ETag     string       `json:"etag"`
+	Size     int64        `json:"size"`
+	PartSize int64        `json:"partSize"`
+	Parts    map[int]bool `json:"parts"`
+	mu       sync.Mutex   `json:"-"`
+}
+
+// encryptDownloadFile downloads an object by parts and decrypts them, an
+// object that is not encrypted is downloaded as it is. With resume, the
+// parts done are kept in a checkpoint in --checkpoint-dir to resume from.
+func (cc *CopyCommand) encryptDownloadFile(bucket *oss.Bucket, objectName, filePath string, resume bool, options ...oss.Option) error {
+	listener := oss.GetProgressListener(options)
+	props, err := bucket.GetObjectDetailedMeta(objectName, cc.cpOption.options...)
+	if err != nil {
+		return err
+	}
+	cipher, err := cc.cpOption.encryption.contentCipher(props)
+	if err != nil {
+		return fmt.Errorf("decrypt %s error: %s", CloudURLToString(bucket.BucketName, objectName), err.Error())
+	}
+	size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	partSize, routines := size, 1
+	if resume {
+		partSize, routines = cc.preparePartOption(size)
+		partSize = alignPartSize(partSize, aesCtrAlignLen)
+	}
+	if partSize <= 0 {
+		partSize = 1
+	}
+
+	absPath, _ := filepath.Abs(filePath)
+	cpPath := encryptCheckpointPath(cc.cpOption.cpDir, CloudURLToString(bucket.BucketName, objectName), absPath)
+	tempFilePath := filePath + oss.TempFileSuffix
+	cp := &encryptDownloadCheckpoint{}
+	flag := os.O_CREATE | os.O_WRONLY
+	if !resume || !loadEncryptCheckpoint(cpPath, cp) || cp.FilePath != absPath || cp.Bucket != bucket.BucketName ||
+		cp.Object != objectName || cp.ETag != props.Get(oss.HTTPHeaderEtag) || cp.Size != size || cp.PartSize != partSize {
+		cp = &encryptDownloadCheckpoint{
+			FilePath: absPath,
+			Bucket:   bucket.BucketName,
+			Object:   objectName,
+			ETag:     props.Get(oss.HTTPHeaderEtag),
+			Size:     size,
+			PartSize: partSize,
+			Parts:    map[int]bool{},
+		}
+		flag |= os.O_TRUNC
+	} else if _, err := os.Stat(tempFilePath); err != nil {
+		cp.Parts = map[int]bool{}
+	}
+	fd, err := os.OpenFile(tempFilePath, flag, oss.FilePermMode)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	partNum := int((size-1)/partSize + 1)
+	var todo []int
+	var done int64
+	for part := 1; part <= partNum; part++ {
+		if cp.Parts[part] {
+			done += partLength(part, partSize, size)
+		} else {
+			todo = append(todo, part)
+		}
+	}
+	publishStarted(listener, done, size)
+
+	err = runParts(todo, routines, func(part int) error {
+		start := int64(part-1) * partSize
+		length := partLength(part, partSize, size)
+		body, err := bucket.GetObject(objectName, append(append([]oss.Option{}, options...), oss.Range(start, start+length-1))...)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		var reader io.Reader = body
+		if cipher != nil {
+			cipherData := cipher.GetCipherData().Clone()
+			cipherData.SeekIV(uint64(start))
+			partCipher, _ := cipher.Clone(cipherData)
+			if reader, err = partCipher.DecryptContent(body); err != nil {
+				return err
+			}
+		}
+		n, err := io.Copy(io.NewOffsetWriter(fd, start), reader)
+		if err != nil {
+			return err
+		}
+		if n != length {
+			return fmt.Errorf("download %s part %d error: got %d bytes, expected %d", objectName, part, n, length)
+		}
+		if !resume {
+			return nil
+		}
+		cp.mu.Lock()
+		defer cp.mu.Unlock()
+		cp.Parts[part] = true
+		return saveEncryptCheckpoint(cpPath, cp)
+	})
+	if err != nil {
+		return err
+	}
+	if err := fd.Truncate(size); err != nil {
+		return err
+	}
+	fd.Close()
+	if err := os.Rename(tempFilePath, filePath); err != nil {
+		return err
+	}
+	os.Remove(cpPath)
+	return nil
+}
+
+func (cc *CopyCommand) checkEncryptionOptions(opType operationType) error {
+	if cc.cpOption.encryption == nil {
+		return nil
+	}
+	if opType == operationTypeCopy {
+		msg := fmt.Sprintf("option --encryption-key-file only supports upload and download")
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.checksum {
+		// the crc64 of the object is the one of the encrypted data
+		msg := fmt.Sprintf("option --encryption-key-file can't be used with option --checksum")
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.vrange != "" {
+		msg := fmt.Sprintf("option --encryption-key-file can't be used with option --range")
+		return CommandError{cc.command.name, msg}
+	}
+	if opType == operationTypeGet && !cc.cpOption.encryption.canDecrypt {
+		msg := fmt.Sprintf("download needs a private key in the file of option --encryption-key-file")
+		return CommandError{cc.command.name, msg}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	osscrypto "github.com/aliyun/aliyun-oss-go-sdk/oss/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEncryptionKeyFile(t *testing.T, blocks ...*pem.Block) string {
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func newEncryptionKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestLoadClientEncryption(t *testing.T) {
+	key := newEncryptionKey(t)
+
+	ce, err := loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	require.NoError(t, err)
+	assert.True(t, ce.canDecrypt)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	ce, err = loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	require.NoError(t, err)
+	assert.True(t, ce.canDecrypt)
+
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	ce, err = loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	require.NoError(t, err)
+	assert.False(t, ce.canDecrypt)
+
+	ce, err = loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)}))
+	require.NoError(t, err)
+	assert.False(t, ce.canDecrypt)
+
+	_, err = loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")}))
+	assert.Contains(t, err.Error(), "no RSA key in PEM format")
+
+	_, err = loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("x")}))
+	assert.Contains(t, err.Error(), "invalid encryption key file")
+
+	_, err = loadClientEncryption(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+}
+
+// encryptedMeta encrypts data as an upload does, and returns the object meta
+// with the wrapped data key and IV.
+func encryptedMeta(t *testing.T, ce *clientEncryption, data []byte) ([]byte, http.Header) {
+	cipher, err := ce.builder.ContentCipher()
+	require.NoError(t, err)
+	reader, err := cipher.EncryptContent(bytes.NewReader(data))
+	require.NoError(t, err)
+	encrypted, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+
+	cd := cipher.GetCipherData()
+	header := http.Header{}
+	header.Set(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionKey, base64.StdEncoding.EncodeToString(cd.EncryptedKey))
+	header.Set(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionStart, base64.StdEncoding.EncodeToString(cd.EncryptedIV))
+	header.Set(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionCekAlg, cd.CEKAlgorithm)
+	header.Set(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionWrapAlg, cd.WrapAlgorithm)
+	return encrypted, header
+}
+
+func TestClientEncryptionContentCipher(t *testing.T) {
+	key := newEncryptionKey(t)
+	ce, err := loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	require.NoError(t, err)
+
+	data := make([]byte, 1000)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	encrypted, header := encryptedMeta(t, ce, data)
+	assert.NotEqual(t, data, encrypted)
+
+	cipher, err := ce.contentCipher(http.Header{})
+	assert.NoError(t, err)
+	assert.Nil(t, cipher)
+
+	cipher, err = ce.contentCipher(header)
+	require.NoError(t, err)
+
+	// every part is decrypted from its offset, as a ranged download does
+	partSize := alignPartSize(300, aesCtrAlignLen)
+	assert.Equal(t, int64(304), partSize)
+	var decrypted []byte
+	for start := int64(0); start < int64(len(data)); start += partSize {
+		end := start + partLength(int(start/partSize)+1, partSize, int64(len(data)))
+		cd := cipher.GetCipherData().Clone()
+		cd.SeekIV(uint64(start))
+		partCipher, err := cipher.Clone(cd)
+		require.NoError(t, err)
+		reader, err := partCipher.DecryptContent(bytes.NewReader(encrypted[start:end]))
+		require.NoError(t, err)
+		part, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		decrypted = append(decrypted, part...)
+	}
+	assert.Equal(t, data, decrypted)
+
+	unsupported := http.Header{}
+	for k, v := range header {
+		unsupported[k] = v
+	}
+	unsupported.Set(oss.HTTPHeaderOssMetaPrefix+osscrypto.OssClientSideEncryptionCekAlg, "AES/GCM/NoPadding")
+	_, err = ce.contentCipher(unsupported)
+	assert.Contains(t, err.Error(), "not supported content algorithm")
+
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicOnly, err := loadClientEncryption(writeEncryptionKeyFile(t, &pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	require.NoError(t, err)
+	_, err = publicOnly.contentCipher(header)
+	assert.Contains(t, err.Error(), "no private key")
+}
+
+func TestEncryptCheckpoint(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cp")
+	path := encryptCheckpointPath(dir, "/data/a.txt", "oss://bucket/a.txt")
+	assert.NotEqual(t, path, encryptCheckpointPath(dir, "oss://bucket/a.txt", "/data/a.txt"))
+
+	var cp encryptUploadCheckpoint
+	assert.False(t, loadEncryptCheckpoint(path, &cp))
+
+	saved := &encryptUploadCheckpoint{FilePath: "/data/a.txt", UploadID: "id", PartSize: 1024, Parts: map[int]string{1: "etag1", 3: "etag3"}}
+	require.NoError(t, saveEncryptCheckpoint(path, saved))
+	require.True(t, loadEncryptCheckpoint(path, &cp))
+	assert.Equal(t, saved.UploadID, cp.UploadID)
+	assert.Equal(t, saved.Parts, cp.Parts)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+	assert.False(t, loadEncryptCheckpoint(path, &cp))
+	os.Remove(path)
+}
+
+func TestRunParts(t *testing.T) {
+	var mu sync.Mutex
+	var done []int
+	err := runParts([]int{1, 2, 3, 4, 5}, 3, func(part int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		done = append(done, part)
+		return nil
+	})
+	assert.NoError(t, err)
+	sort.Ints(done)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, done)
+
+	err = runParts([]int{1, 2, 3}, 0, func(part int) error {
+		if part == 2 {
+			return fmt.Errorf("part %d failed", part)
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "part 2 failed")
+
+	assert.Equal(t, int64(100), partLength(1, 100, 250))
+	assert.Equal(t, int64(50), partLength(3, 100, 250))
+}
+
+func TestCheckEncryptionOptions(t *testing.T) {
+	var cc CopyCommand
+	assert.NoError(t, cc.checkEncryptionOptions(operationTypeCopy))
+
+	cc.cpOption.encryption = &clientEncryption{canDecrypt: true}
+	assert.NoError(t, cc.checkEncryptionOptions(operationTypePut))
+	assert.NoError(t, cc.checkEncryptionOptions(operationTypeGet))
+	assert.Contains(t, cc.checkEncryptionOptions(operationTypeCopy).Error(), "only supports upload and download")
+
+	cc.cpOption.checksum = true
+	assert.Contains(t, cc.checkEncryptionOptions(operationTypePut).Error(), "--checksum")
+	cc.cpOption.checksum = false
+
+	cc.cpOption.vrange = "0-9"
+	assert.Contains(t, cc.checkEncryptionOptions(operationTypeGet).Error(), "--range")
+	cc.cpOption.vrange = ""
+
+	cc.cpOption.encryption.canDecrypt = false
+	assert.NoError(t, cc.checkEncryptionOptions(operationTypePut))
+	assert.Contains(t, cc.checkEncryptionOptions(operationTypeGet).Error(), "private key")
+}
@@ -122,7 +122,8 @@ func (cm *CommandManager) RunCommand(commandName string, args []string, options
 			return false, err
 		}
 		group := reflect.ValueOf(cmd).Elem().FieldByName("command").FieldByName("group").String()
-		return group == GroupTypeNormalCommand, nil
+		// the elapsed time would break the JSON of --output-format
+		return group == GroupTypeNormalCommand && getOutputFormat(options) == "", nil
 	}
 	return false, fmt.Errorf("no such command: \"%s\", please try \"help\" for more information", commandName)
 }
@@ -108,6 +108,23 @@ const (
 	OptionForcePathStyle             = "forcePathStyle"
 	OptionRuntime                    = "runtime"
 	OptionInsecure                   = "insecure"
+	OptionChecksum                   = "checksum"
+	OptionSizeOnly                   = "sizeOnly"
+	OptionDryRun                     = "dryRun"
+	OptionWatch                      = "watch"
+	OptionFilesFrom                  = "filesFrom"
+	OptionOutputFormat               = "outputFormat"
+	OptionPrefixDepth                = "prefixDepth"
+	OptionEncryptionKeyFile          = "encryptionKeyFile"
+	OptionWait                       = "wait"
+	OptionThenDownload               = "thenDownload"
+	OptionPostPolicy                 = "postPolicy"
+	OptionContentLengthRange         = "contentLengthRange"
+	OptionContentType                = "contentType"
+	OptionSimulate                   = "simulate"
+	OptionSimulateDate               = "simulateDate"
+	OptionReportJSON                 = "reportJSON"
+	OptionMetricsFile                = "metricsFile"
 )
 
 // the elements show in stat object
@@ -171,7 +188,11 @@ const (
 	MaxInt64                int64  = int64(MaxUint64 >> 1)
 	ReportPrefix                   = "ossutil_report_"
 	ReportSuffix                   = ".report"
+	ResultPrefix                   = "ossutil_result_"
+	ResultSuffix                   = ".jsonl"
 	DefaultOutputDir               = "ossutil_output"
+	OutputFormatJSON               = "json"
+	OutputFormatJSONL              = "jsonl"
 	CheckpointDir                  = ".ossutil_checkpoint"
 	CheckpointSep                  = "---"
 	SnapshotConnector              = "==>"
@@ -31,6 +31,18 @@ const (
 	opCopy            = "copy"
 )
 
+// why a file or object is skipped, shown in the --dry-run plan
+const (
+	skipReasonTimeRange    = "out of --start-time and --end-time"
+	skipReasonSnapshot     = "unchanged since the snapshot"
+	skipReasonNotNewer     = "destination is not older"
+	skipReasonSameContent  = "same size and checksum"
+	skipReasonSameSize     = "same size"
+	skipReasonNotConfirmed = "overwrite not confirmed"
+	skipReasonDirObject    = "directory object disabled"
+	skipReasonIsDir        = "destination is a directory"
+)
+
 /*
  * Put same type variables together to make them 64bits alignment to avoid
  * atomic.AddInt64() panic
@@ -48,9 +60,16 @@ type copyOptionType struct {
 	routines          int64
 	reporter          *Reporter
 	snapshotldb       *leveldb.DB
+	plan              *dryRunPlan
+	encryption        *clientEncryption
+	transferReport    *transferReport
+	manifest          []manifestItem
+	itemOptions       map[string][]oss.Option
 	recursive         bool
 	force             bool
 	update            bool
+	checksum          bool
+	sizeOnly          bool
 	ctnu              bool
 	payerOptions      []oss.Option
 	partitionInfo     string
@@ -152,15 +171,15 @@ var specChineseCopy = SpecText{
 	paramText: "src_url dest_url [options]",
 
 	syntaxText: ` 
-    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester] [--version-id versionId]
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester] [--version-id versionId]
+    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester] [--version-id versionId]
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--report-json=file] [--metrics-file=file] [--payer requester] [--version-id versionId]
 `,
 
 	detailHelpText: ` 
     该命令允许：从本地文件系统上传文件到oss，从oss下载object到本地文件系统，在oss
     上进行object拷贝。分别对应下述三种操作：
-        ossutil cp file_url oss://bucket[/prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--snapshot-path=sdir] [--payer requester]
+        ossutil cp file_url oss://bucket[/prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--payer requester]
         ossutil cp oss://bucket[/prefix] file_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--range=x-y] [--payer requester]
         ossutil cp oss://src_bucket[/src_prefix] oss://dest_bucket[/dest_prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--payer requester]
 
@@ -276,6 +295,66 @@ var specChineseCopy = SpecText{
     否指定了，在目标文件存在时，ossutil都不会提示，直接采取上述策略。
     该选项可用于当批量拷贝失败时，重传时跳过已经成功的文件。实现增量上传。
 
+--checksum选项
+
+    如果指定了该选项，ossutil根据内容而不是lastModifiedTime判断是否跳过上传、下载、拷贝：
+    目标文件（或object）存在，且大小和crc64都与源相同时跳过。object没有crc64值时（例如在oss
+    支持crc64之前上传的object）比较Content-MD5，两者都没有时不跳过。上传和下载需要读取本地文
+    件计算crc64，多个文件由--jobs个任务并发计算；同时指定--snapshot-path时，ossutil会在快照中
+    缓存计算结果，文件的大小和lastModifiedTime不变时不再重新读取。拷贝只比较oss上记录的值，不
+    读取数据。该选项适用于git checkout或从备份恢复后，文件内容未变而lastModifiedTime变化的场景。
+
+--size-only选项
+
+    与--checksum类似，但只比较大小，不计算crc64，适用于大批量文件的快速增量同步。
+
+    --checksum和--size-only不能同时指定，指定其中之一时--update的时间比较不再生效，也不会询问
+    用户是否替换。
+
+--dry-run选项
+
+    只列出将要执行的上传、下载、拷贝和跳过，不传输任何数据。ossutil使用与实际执行相同的文件
+    列表、--include/--exclude、--start-time/--end-time和增量策略，每个文件或object输出一行JSON：
+        {"action":"upload","source":"dir/a.txt","destination":"oss://bucket/a.txt","size":38}
+        {"action":"skip","source":"dir/b.txt","destination":"oss://bucket/b.txt","size":118,"reason":"destination is not older"}
+    最后一行为各操作的数量和大小汇总。指定该选项时不显示进度，也不会询问用户是否替换。
+
+--files-from选项
+
+    只拷贝清单文件中列出的文件或object，不遍历源端目录或prefix，不需要指定-r。清单每行一项，
+    可以是相对源端的key（上传时为源目录下的相对路径），也可以是JSON对象：
+        {"src":"2024/a.txt","dest":"archive/a.txt","meta":"Cache-Control:no-cache","acl":"private"}
+    src和dest分别相对源端和目的端（作为目录），dest缺省与src相同，meta和acl的格式同--meta和
+    --acl选项（下载时不能指定），覆盖命令行上的值。每项的结果（status为ok、skip或error，error
+    包含错误信息；因出错提前结束而未处理的项为pending）写入--output-dir下的新文件
+    ossutil_result_时间.jsonl，同一秒内的多次运行会加上序号。将结果清单再次作为--files-from
+    输入时，ok和skip的项会被跳过，只重试失败和未处理的项。该选项不能与--include、--exclude和
+    --snapshot-path同时使用。
+
+--encryption-key-file选项
+
+    客户端加密，上传时在本地加密文件内容，下载时在本地解密，OSS上只保存密文。选项的值为PEM
+    格式的RSA主密钥文件，可以是私钥（PKCS#1或PKCS#8）或公钥：私钥可用于上传和下载，公钥只能
+    用于上传。每个文件使用随机生成的数据密钥以AES/CTR加密，数据密钥和IV经RSA主密钥加密后存入
+    object的x-oss-meta-client-side-encryption-*，与OSS SDK的客户端加密（RSA主密钥）格式相同，
+    可以互相加密和解密。下载时没有该meta的object按原样下载。大文件的分片上传和分片下载同样支
+    持断点续传，断点信息保存在--checkpoint-dir下，续传上传需要私钥。该选项不支持oss间拷贝，不
+    能与--checksum和--range同时使用。
+
+--report-json、--metrics-file选项
+
+    --report-json将每个文件或object的传输结果写入指定文件，每行一个JSON（JSON lines），type为
+    object，包含op、src、dest、status（ok、skip或error）、size（字节数，未知时为-1）、startTime、
+    durationMs、retries（重试次数）、errorCode（OSS的错误码，网络错误为NetworkError，其他错误为
+    ClientError）和error。最后一行type为summary，是本次运行的汇总：成功、跳过和失败的数量，传输
+    的字节数，重试次数，总吞吐量，以及每个object吞吐量的p50、p90和p99（byte/s），和按错误码统计
+    的失败数量。指定任一选项时，结束后同样在屏幕上输出汇总。
+    --metrics-file将汇总以Prometheus文本格式写入指定文件（如ossutil_transfer_objects、
+    ossutil_transfer_bytes、ossutil_transfer_object_throughput_bytes_per_second、
+    ossutil_transfer_success），可放在node exporter的textfile collector目录下，用于监控告警。文件
+    先写入临时文件再重命名，不会被读到一半。sync --watch每一轮同步都会重写这两个文件。这两个选项
+    不能与--dry-run同时使用。
+
 --snapshot-path选项
 
     该选项用于在某些场景下加速增量上传批量文件（目前，下载和拷贝不支持该选项）。此场景为：
@@ -675,9 +754,9 @@ var specEnglishCopy = SpecText{
 	paramText: "src_url dest_url [options]",
 
 	syntaxText: ` 
-    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil cp file_url cloud_url  [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--checksum|--size-only] [--dry-run] [--files-from=manifest] [--only-current-dir] [--output-dir=odir] [--disable-ignore-error] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--report-json=file] [--metrics-file=file] [--payer requester]
 `,
 
 	detailHelpText: ` 
@@ -686,7 +765,7 @@ var specEnglishCopy = SpecText{
     2. Download object from oss to local file system
     3. Copy objects between oss
     Which matches with the following three kinds of operations:
-        ossutil cp file_url oss://bucket[/prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--snapshot-path=sdir] [--payer requester]
+        ossutil cp file_url oss://bucket[/prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--payer requester]
         ossutil cp oss://bucket[/prefix] file_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--range=x-y] [--payer requester]
         ossutil cp oss://src_bucket[/src_prefix] oss://dest_bucket[/dest_prefix] [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=file] [--payer requester]
 
@@ -826,6 +905,81 @@ Incremental Upload/Download/Copy:
     specified or not.
     The option can be used when batch copy failed, skip the succeed files in retry.
 
+--checksum option
+
+    Decide whether to skip the upload, download or copy by content instead of lastModifiedTime: 
+    the file(or object) is skipped when the destination exists with the same size and crc64. When 
+    the object has no crc64(e.g. it was uploaded before oss supported crc64), Content-MD5 is 
+    compared, and when neither is available, the file is copied. Upload and download read the 
+    local file to calculate crc64, --jobs files are hashed in parallel; with --snapshot-path the 
+    result is cached in the snapshot and the file is only read again when its size or 
+    lastModifiedTime change. Copy only compares the values kept by oss and reads no data. The 
+    option suits a directory whose lastModifiedTime changed but content did not, e.g. after git 
+    checkout or a restore from backup.
+
+--size-only option
+
+    Like --checksum, but only the size is compared and no crc64 is calculated, for fast 
+    incremental sync of lots of files.
+
+    --checksum and --size-only can't be both specified. With either of them the time comparison 
+    of --update does not apply, and ossutil does not ask whether to replace the file.
+
+--dry-run option
+
+    Only list the uploads, downloads, copies and skips that would be done, without transferring 
+    any data. ossutil uses the same file list, --include/--exclude, --start-time/--end-time and 
+    incremental policies as a real run, and prints a line of JSON for every file or object:
+        {"action":"upload","source":"dir/a.txt","destination":"oss://bucket/a.txt","size":38}
+        {"action":"skip","source":"dir/b.txt","destination":"oss://bucket/b.txt","size":118,"reason":"destination is not older"}
+    The last line sums up the count and size of every action. With the option no progress is 
+    shown and ossutil does not ask whether to replace files.
+
+--files-from option
+
+    Only copy the files or objects listed in the manifest file, instead of walking the source 
+    directory or prefix, -r is not needed. Every line of the manifest is an item, either a key 
+    relative to the source(a path relative to the source directory for upload), or a JSON object:
+        {"src":"2024/a.txt","dest":"archive/a.txt","meta":"Cache-Control:no-cache","acl":"private"}
+    src and dest are relative to the source and the destination(as directories), dest defaults 
+    to src, meta and acl have the format of --meta and --acl(not allowed for download) and 
+    override them. The result of every item(status ok, skip or error, with the message in error,
+    or pending for the items not processed as an error ended the run) is written to a new file
+    ossutil_result_time.jsonl in --output-dir, numbered when several runs start in the same
+    second. When a result manifest is fed to --files-from again, the ok and skip items are left
+    out, so only the failed and pending items are retried. 
+    The option can't be used with --include, --exclude or --snapshot-path.
+
+--encryption-key-file option
+
+    Client-side encryption, the content of files is encrypted locally on upload and decrypted 
+    locally on download, oss only keeps the ciphertext. The value of the option is an RSA master 
+    key file in PEM format, a private key(PKCS#1 or PKCS#8) or a public key: a private key works 
+    for upload and download, a public key for upload only. Every file is encrypted by AES/CTR with 
+    a random data key, the data key and IV wrapped by the RSA master key are kept in the 
+    x-oss-meta-client-side-encryption-* meta of the object, in the format of the client-side 
+    encryption(RSA master key) of OSS SDKs, so they encrypt and decrypt for each other. Objects 
+    without the meta are downloaded as they are on download. Multipart upload and download of big 
+    files resume as before, with the checkpoints in --checkpoint-dir, resuming an upload needs the 
+    private key. The option is not supported for copy between oss, and can't be used with 
+    --checksum or --range.
+
+--report-json, --metrics-file option
+
+    --report-json writes the outcome of every file or object to the file, a JSON per line(JSON 
+    lines) of type object, with op, src, dest, status(ok, skip or error), size(in bytes, -1 if 
+    unknown), startTime, durationMs, retries, errorCode(the error code of oss, NetworkError for 
+    network errors, ClientError for the others) and error. The last line of type summary sums the 
+    run up: the count of ok, skipped and failed, the bytes transferred, the retries, the overall 
+    throughput, the p50, p90 and p99 of the throughput of every object(byte/s), and the failures 
+    by error code. With either option the summary is printed on the screen at the end as well.
+    --metrics-file writes the summary to the file in the Prometheus text format(e.g. 
+    ossutil_transfer_objects, ossutil_transfer_bytes, 
+    ossutil_transfer_object_throughput_bytes_per_second, ossutil_transfer_success), put it in the 
+    directory of the textfile collector of the node exporter to alert on. The file is written to 
+    a temporary file and renamed, so it's never read half written. sync --watch rewrites both 
+    files every round. The options can't be used with --dry-run.
+
 --snapshot-path option
 
     This option is used to accelerate the incremental upload of batch files in certain scenarios(
@@ -1318,6 +1472,13 @@ var copyCommand = CopyCommand{
 			OptionStartTime,
 			OptionEndTime,
 			OptionInsecure,
+			OptionChecksum,
+			OptionSizeOnly,
+			OptionDryRun,
+			OptionFilesFrom,
+			OptionEncryptionKeyFile,
+			OptionReportJSON,
+			OptionMetricsFile,
 		},
 	},
 }
@@ -1341,11 +1502,14 @@ func (cc *CopyCommand) RunCommand() error {
 	cc.cpOption.recursive, _ = GetBool(OptionRecursion, cc.command.options)
 	cc.cpOption.force, _ = GetBool(OptionForce, cc.command.options)
 	cc.cpOption.update, _ = GetBool(OptionUpdate, cc.command.options)
+	cc.cpOption.checksum, _ = GetBool(OptionChecksum, cc.command.options)
+	cc.cpOption.sizeOnly, _ = GetBool(OptionSizeOnly, cc.command.options)
 	cc.cpOption.threshold, _ = GetInt(OptionBigFileThreshold, cc.command.options)
 	cc.cpOption.cpDir, _ = GetString(OptionCheckpointDir, cc.command.options)
 	cc.cpOption.routines, _ = GetInt(OptionRoutines, cc.command.options)
+	filesFrom, _ := GetString(OptionFilesFrom, cc.command.options)
 	cc.cpOption.ctnu = false
-	if cc.cpOption.recursive {
+	if cc.cpOption.recursive || filesFrom != "" {
 		disableIgnoreError, _ := GetBool(OptionDisableIgnoreError, cc.command.options)
 		cc.cpOption.ctnu = !disableIgnoreError
 	}
@@ -1363,17 +1527,35 @@ func (cc *CopyCommand) RunCommand() error {
 	cc.cpOption.onlyCurrentDir, _ = GetBool(OptionOnlyCurrentDir, cc.command.options)
 	cc.cpOption.disableDirObject, _ = GetBool(OptionDisableDirObject, cc.command.options)
 	cc.cpOption.disableAllSymlink, _ = GetBool(OptionDisableAllSymlink, cc.command.options)
+	if dryRun, _ := GetBool(OptionDryRun, cc.command.options); dryRun {
+		cc.cpOption.plan = newDryRunPlan(os.Stdout)
+	}
+	if keyFile, _ := GetString(OptionEncryptionKeyFile, cc.command.options); keyFile != "" {
+		encryption, err := loadClientEncryption(keyFile)
+		if err != nil {
+			return err
+		}
+		cc.cpOption.encryption = encryption
+	}
 
 	if cc.cpOption.enableSymlinkDir && cc.cpOption.disableAllSymlink {
 		return fmt.Errorf("--enable-symlink-dir and --disable-all-symlink can't be both exist")
 	}
 
+	if cc.cpOption.checksum && cc.cpOption.sizeOnly {
+		return fmt.Errorf("--checksum and --size-only can't be both exist")
+	}
+
 	var res bool
 	res, cc.cpOption.filters = getFilter(os.Args)
 	if !res {
 		return fmt.Errorf("--include or --exclude does not support format containing dir info")
 	}
 
+	if filesFrom != "" && len(cc.cpOption.filters) > 0 {
+		return fmt.Errorf("--files-from and --include or --exclude can't be both exist")
+	}
+
 	if !cc.cpOption.recursive && len(cc.cpOption.filters) > 0 {
 		return fmt.Errorf("--include or --exclude only work with --recursive")
 	}
@@ -1406,6 +1588,9 @@ func (cc *CopyCommand) RunCommand() error {
 	if err := cc.checkCopyOptions(opType); err != nil {
 		return err
 	}
+	if filesFrom != "" && cc.cpOption.snapshotPath != "" {
+		return fmt.Errorf("--files-from and --snapshot-path can't be both exist")
+	}
 
 	cc.cpOption.options = []oss.Option{}
 	if cc.cpOption.meta != "" {
@@ -1457,8 +1642,15 @@ func (cc *CopyCommand) RunCommand() error {
 		cc.cpOption.payerOptions = append(cc.cpOption.payerOptions, oss.RequestPayer(oss.PayerType(payer)))
 	}
 
+	cc.cpOption.manifest = nil
+	if filesFrom != "" {
+		if cc.cpOption.manifest, err = cc.loadManifest(filesFrom, opType, destURL); err != nil {
+			return err
+		}
+	}
+
 	// init reporter
-	if cc.cpOption.reporter, err = GetReporter(cc.cpOption.recursive, outputDir, commandLine); err != nil {
+	if cc.cpOption.reporter, err = GetReporter(cc.cpOption.recursive || filesFrom != "", outputDir, commandLine); err != nil {
 		return err
 	}
 
@@ -1503,18 +1695,37 @@ func (cc *CopyCommand) RunCommand() error {
 		cc.cpOption.partitionCount = 0
 	}
 
+	reportJSON, _ := GetString(OptionReportJSON, cc.command.options)
+	metricsFile, _ := GetString(OptionMetricsFile, cc.command.options)
+	cc.cpOption.transferReport = nil
+	if reportJSON != "" || metricsFile != "" {
+		if cc.cpOption.plan != nil {
+			return fmt.Errorf("--report-json and --metrics-file can't be used with --dry-run")
+		}
+		if cc.cpOption.transferReport, err = newTransferReport(opType, reportJSON, metricsFile); err != nil {
+			return err
+		}
+	}
+
 	cc.monitor.init(opType)
 	cc.cpOption.opType = opType
+	if cc.cpOption.plan != nil {
+		// stdout carries the plan, no progress bar
+		cc.monitor.finish = true
+	}
 
 	chProgressSignal = make(chan chProgressSignalType, 10)
 	go cc.progressBar()
 
 	startT := time.Now().UnixNano() / 1000 / 1000
-	switch opType {
-	case operationTypePut:
+	switch {
+	case filesFrom != "":
+		LogInfo("begin transferManifest\n")
+		err = cc.transferManifest(srcURLList[0], destURL, opType, outputDir)
+	case opType == operationTypePut:
 		LogInfo("begin uploadFiles\n")
 		err = cc.uploadFiles(srcURLList, destURL.(CloudURL))
-	case operationTypeGet:
+	case opType == operationTypeGet:
 		LogInfo("begin downloadFiles\n")
 		err = cc.downloadFiles(srcURLList[0].(CloudURL), destURL.(FileURL))
 	default:
@@ -1522,12 +1733,22 @@ func (cc *CopyCommand) RunCommand() error {
 		err = cc.copyFiles(srcURLList[0].(CloudURL), destURL.(CloudURL))
 	}
 	endT := time.Now().UnixNano() / 1000 / 1000
-	if endT-startT > 0 {
+	if endT-startT > 0 && cc.cpOption.plan == nil {
 		averSpeed := (cc.monitor.transferSize / (endT - startT)) * 1000
 		fmt.Printf("\naverage speed %d(byte/s)\n", averSpeed)
 		LogInfo("average speed %d(byte/s)\n", averSpeed)
 	}
 
+	if cc.cpOption.plan != nil && !cc.cpOption.bSyncCommand {
+		cc.cpOption.plan.printSummary()
+	}
+
+	if cc.cpOption.transferReport != nil {
+		if errR := cc.cpOption.transferReport.close(os.Stdout); errR != nil && err == nil {
+			err = errR
+		}
+	}
+
 	cc.cpOption.reporter.Clear()
 	ckFiles, _ := ioutil.ReadDir(cc.cpOption.cpDir)
 	if err == nil && len(ckFiles) == 0 {
@@ -1616,7 +1837,7 @@ func (cc *CopyCommand) checkCopyOptions(opType operationType) error {
 			return CommandError{cc.command.name, msg}
 		}
 	}
-	return nil
+	return cc.checkEncryptionOptions(opType)
 }
 
 func (cc *CopyCommand) progressBar() {
@@ -2050,6 +2271,11 @@ func (cc *CopyCommand) uploadFileWithReport(bucket *oss.Bucket, destURL CloudURL
 		}
 	}
 
+	if cc.cpOption.transferReport != nil {
+		objectName := cc.makeObjectName(destURL, file)
+		filePath := filepath.Join(file.dir, file.filePath)
+		cc.recordTransfer(filePath, CloudURLToString(bucket.BucketName, objectName), objectName, startT, skip, err, fileSize(filePath))
+	}
 	cc.updateMonitor(skip, err, isDir, size)
 	cc.report(msg, err)
 	return err
@@ -2082,7 +2308,15 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	srct := f.ModTime().Unix()
 	absPath, _ := filepath.Abs(filePath)
 	spath := cc.formatSnapshotKey(absPath, destURL.bucket, objectName)
-	if skip, rerr = cc.skipUpload(spath, bucket, objectName, destURL, srct); rerr != nil || skip {
+	destObject := CloudURLToString(bucket.BucketName, objectName)
+	reason, err := cc.skipUpload(spath, filePath, bucket, objectName, destURL, srct)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if reason != "" {
+		skip = true
+		cc.planSkip(filePath, destObject, size, reason)
 		return
 	}
 
@@ -2090,6 +2324,11 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	if f.IsDir() {
 		isDir = true
 		if cc.cpOption.disableDirObject {
+			skip = true
+			cc.planSkip(filePath, destObject, size, skipReasonDirObject)
+			return
+		}
+		if cc.planTransfer(opUpload, filePath, destObject, 0) {
 			skip = true
 			return
 		}
@@ -2100,11 +2339,16 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 		return
 	}
 
+	if cc.planTransfer(opUpload, filePath, destObject, size) {
+		skip = true
+		return
+	}
+
 	size = 0
 	//decide whether to use resume upload
 	if f.Size() < cc.cpOption.threshold {
 		var listener *OssProgressListener = &OssProgressListener{&cc.monitor, 0, 0, false}
-		options := cc.cpOption.options
+		options := cc.objectOptions(bucket.BucketName, objectName)
 		options = append(options, oss.Progress(listener))
 		rerr = cc.ossUploadFileRetry(bucket, objectName, filePath, options...)
 		if err := cc.updateSnapshot(rerr, spath, srct); err != nil {
@@ -2121,7 +2365,7 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	LogInfo("multipart upload,file:%s,file size:%d,partSize:%d,routin count:%d\n",
 		filePath, f.Size(), partSize, rt)
 	cp := oss.CheckpointDir(true, cc.cpOption.cpDir)
-	options := cc.cpOption.options
+	options := cc.objectOptions(bucket.BucketName, objectName)
 	options = append(options, oss.Routines(rt), cp, oss.Progress(listener))
 	rerr = cc.ossResumeUploadRetry(bucket, objectName, filePath, partSize, options...)
 	if err := cc.updateSnapshot(rerr, spath, srct); err != nil {
@@ -2141,41 +2385,52 @@ func (cc *CopyCommand) makeObjectName(destURL CloudURL, file fileInfoType) strin
 	return destURL.object
 }
 
-func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName string, destURL CloudURL, srcModifiedTime int64) (bool, error) {
+func (cc *CopyCommand) skipUpload(spath, filePath string, bucket *oss.Bucket, objectName string, destURL CloudURL, srcModifiedTime int64) (string, error) {
 	if cc.cpOption.startTime > 0 && srcModifiedTime < cc.cpOption.startTime {
-		return true, nil
+		return skipReasonTimeRange, nil
 	}
 
 	if cc.cpOption.endTime > 0 && srcModifiedTime > cc.cpOption.endTime {
-		return true, nil
+		return skipReasonTimeRange, nil
 	}
 
-	if cc.cpOption.snapshotPath != "" || cc.cpOption.update {
+	if cc.cpOption.snapshotPath != "" || cc.cpOption.update || cc.compareContent() {
 		if cc.cpOption.snapshotPath != "" {
 			tstr, err := cc.cpOption.snapshotldb.Get([]byte(spath), nil)
 			if err == nil {
 				t, _ := strconv.ParseInt(string(tstr), 10, 64)
 				if t == srcModifiedTime {
-					return true, nil
+					return skipReasonSnapshot, nil
 				}
 			}
 		}
+		if cc.compareContent() {
+			props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, cc.cpOption.payerOptions...)
+			if err != nil {
+				return "", nil
+			}
+			same, err := cc.sameFileContent(filePath, props)
+			if err != nil || !same {
+				return "", err
+			}
+			return cc.sameContentReason(), nil
+		}
 		if cc.cpOption.update {
 			if props, err := cc.command.ossGetObjectStatRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
 				destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 				if err == nil && destt.Unix() >= srcModifiedTime {
-					return true, nil
+					return skipReasonNotNewer, nil
 				}
 			}
 		}
-	} else if !cc.cpOption.force {
+	} else if !cc.cpOption.force && cc.cpOption.plan == nil {
 		if _, err := cc.command.ossGetObjectMetaRetry(bucket, objectName, cc.cpOption.payerOptions...); err == nil {
 			if !cc.confirm(CloudURLToString(destURL.bucket, objectName)) {
-				return true, nil
+				return skipReasonNotConfirmed, nil
 			}
 		}
 	}
-	return false, nil
+	return "", nil
 }
 
 func (cc *CopyCommand) formatSnapshotKey(absPath, bucket, object string) string {
@@ -2198,6 +2453,7 @@ func (cc *CopyCommand) ossPutObjectRetry(bucket *oss.Bucket, objectName string,
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:put object:%s.\n", i-1, objectName)
@@ -2222,6 +2478,7 @@ func (cc *CopyCommand) ossUploadFileRetry(bucket *oss.Bucket, objectName string,
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:upload file:%s\n", i-1, filePath)
@@ -2229,7 +2486,12 @@ func (cc *CopyCommand) ossUploadFileRetry(bucket *oss.Bucket, objectName string,
 		}
 
 		startT := time.Now()
-		err := bucket.PutObjectFromFile(objectName, filePath, options...)
+		var err error
+		if cc.cpOption.encryption != nil {
+			err = cc.encryptPutObjectFromFile(bucket, objectName, filePath, options...)
+		} else {
+			err = bucket.PutObjectFromFile(objectName, filePath, options...)
+		}
 		cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
 
 		if err == nil {
@@ -2303,13 +2565,19 @@ func (cc *CopyCommand) ossResumeUploadRetry(bucket *oss.Bucket, objectName strin
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d,multipart upload file:%s.\n", i-1, filePath)
 			}
 		}
 		startT := time.Now()
-		err := bucket.UploadFile(objectName, filePath, partSize, options...)
+		var err error
+		if cc.cpOption.encryption != nil {
+			err = cc.encryptUploadFile(bucket, objectName, filePath, partSize, options...)
+		} else {
+			err = bucket.UploadFile(objectName, filePath, partSize, options...)
+		}
 		cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
 
 		if err == nil {
@@ -2466,7 +2734,7 @@ func (cc *CopyCommand) downloadSingleFileWithReport(bucket *oss.Bucket, objectIn
 	} else if skip {
 		LogInfo("download skip:%s\n", objectInfo.relativeKey)
 	} else {
-		if realSize < 0 && logLevel >= oss.Info {
+		if realSize < 0 && (logLevel >= oss.Info || cc.cpOption.transferReport != nil) {
 			fileName := cc.makeFileName(objectInfo.relativeKey, filePath)
 			fileInfo, errF := os.Stat(fileName)
 			if errF == nil && !fileInfo.IsDir() {
@@ -2483,6 +2751,11 @@ func (cc *CopyCommand) downloadSingleFileWithReport(bucket *oss.Bucket, objectIn
 		cc.updateSnapshot(nil, CloudURLToString(bucket.BucketName, objectKey), objectInfo.lastModified.Unix())
 	}
 
+	if cc.cpOption.transferReport != nil {
+		objectKey := objectInfo.prefix + objectInfo.relativeKey
+		fileName := cc.makeFileName(objectInfo.relativeKey, filePath)
+		cc.recordTransfer(CloudURLToString(bucket.BucketName, objectKey), fileName, objectKey, startT, skip, err, realSize)
+	}
 	cc.updateMonitor(skip, err, false, size)
 	cc.report(msg, err)
 	return err
@@ -2516,7 +2789,12 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 	}
 
 	rsize := cc.getRangeSize(size)
-	if cc.skipDownload(fileName, srct, CloudURLToString(bucket.BucketName, object)) {
+	srcObject := CloudURLToString(bucket.BucketName, object)
+	if reason := cc.skipDownload(bucket, object, fileName, size, srct); reason != "" {
+		cc.planSkip(srcObject, fileName, rsize, reason)
+		return true, nil, rsize, msg
+	}
+	if cc.planTransfer(opDownload, srcObject, fileName, rsize) {
 		return true, nil, rsize, msg
 	}
 
@@ -2558,42 +2836,52 @@ func (cc *CopyCommand) makeFileName(relativeObject, filePath string) string {
 	return filePath
 }
 
-func (cc *CopyCommand) skipDownload(fileName string, srcModifiedTime time.Time, object string) bool {
+func (cc *CopyCommand) skipDownload(bucket *oss.Bucket, object, fileName string, size int64, srcModifiedTime time.Time) string {
 	if cc.cpOption.startTime > 0 && srcModifiedTime.Unix() < cc.cpOption.startTime {
-		return true
+		return skipReasonTimeRange
 	}
 
 	if cc.cpOption.endTime > 0 && srcModifiedTime.Unix() > cc.cpOption.endTime {
-		return true
+		return skipReasonTimeRange
 	}
 
-	if cc.cpOption.snapshotPath != "" || cc.cpOption.update {
+	if cc.cpOption.snapshotPath != "" || cc.cpOption.update || cc.compareContent() {
 		if cc.cpOption.snapshotPath != "" {
-			tstr, err := cc.cpOption.snapshotldb.Get([]byte(object), nil)
+			tstr, err := cc.cpOption.snapshotldb.Get([]byte(CloudURLToString(bucket.BucketName, object)), nil)
 			if err == nil {
 				t, _ := strconv.ParseInt(string(tstr), 10, 64)
 				if t == srcModifiedTime.Unix() {
-					return true
+					return skipReasonSnapshot
 				}
 			}
 		}
 
+		if cc.compareContent() {
+			if cc.skipDownloadByContent(bucket, object, fileName, size) {
+				return cc.sameContentReason()
+			}
+			return ""
+		}
+
 		if f, err := os.Stat(fileName); err == nil {
 			destt := f.ModTime()
 			if destt.Unix() >= srcModifiedTime.Unix() {
-				return true
+				return skipReasonNotNewer
 			}
 		}
 	} else {
 		if !cc.cpOption.force {
 			if fileInfo, err := os.Stat(fileName); err == nil {
-				if fileInfo.IsDir() || !cc.confirm(fileName) {
-					return true
+				if fileInfo.IsDir() {
+					return skipReasonIsDir
+				}
+				if cc.cpOption.plan == nil && !cc.confirm(fileName) {
+					return skipReasonNotConfirmed
 				}
 			}
 		}
 	}
-	return false
+	return ""
 }
 
 func (cc *CopyCommand) createParentDirectory(fileName string) error {
@@ -2609,6 +2897,7 @@ func (cc *CopyCommand) ossDownloadFileRetry(bucket *oss.Bucket, objectName, file
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:get object to file:%s.\n", i-1, fileName)
@@ -2616,7 +2905,12 @@ func (cc *CopyCommand) ossDownloadFileRetry(bucket *oss.Bucket, objectName, file
 		}
 
 		startT := time.Now()
-		err := bucket.GetObjectToFile(objectName, fileName, options...)
+		var err error
+		if cc.cpOption.encryption != nil {
+			err = cc.encryptDownloadFile(bucket, objectName, fileName, false, options...)
+		} else {
+			err = bucket.GetObjectToFile(objectName, fileName, options...)
+		}
 		cost := time.Now().UnixNano()/1000/1000 - startT.UnixNano()/1000/1000
 
 		if err == nil {
@@ -2639,13 +2933,19 @@ func (cc *CopyCommand) ossResumeDownloadRetry(bucket *oss.Bucket, objectName str
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d:mulitpart download file:%s.\n", i-1, objectName)
 			}
 		}
 
-		err := bucket.DownloadFile(objectName, filePath, partSize, options...)
+		var err error
+		if cc.cpOption.encryption != nil {
+			err = cc.encryptDownloadFile(bucket, objectName, filePath, true, options...)
+		} else {
+			err = bucket.DownloadFile(objectName, filePath, partSize, options...)
+		}
 		if err == nil {
 			return cc.truncateFile(filePath, size)
 		}
@@ -2972,7 +3272,14 @@ func (cc *CopyCommand) checkCopyFileArgs(srcURL, destURL CloudURL) error {
 }
 
 func (cc *CopyCommand) copySingleFileWithReport(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) error {
+	startT := time.Now()
 	skip, err, size, msg := cc.copySingleFile(bucket, objectInfo, srcURL, destURL)
+	if cc.cpOption.transferReport != nil {
+		srcObject := objectInfo.prefix + objectInfo.relativeKey
+		destObject := cc.makeCopyObjectName(objectInfo.relativeKey, destURL.object)
+		cc.recordTransfer(CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject), srcObject,
+			startT, skip, err, cc.copiedSize(bucket, srcObject, objectInfo.size, size, skip || err != nil))
+	}
 	cc.updateMonitor(skip, err, false, size)
 	cc.report(msg, err)
 	return err
@@ -3007,8 +3314,14 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 		}
 	}
 
-	if skip, err := cc.skipCopy(destURL, destObject, srct); err != nil || skip {
-		return skip, err, size, msg
+	if reason, err := cc.skipCopy(bucket, srcObject, destURL, destObject, size, srct); err != nil || reason != "" {
+		if reason != "" {
+			cc.planSkip(CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject), size, reason)
+		}
+		return reason != "", err, size, msg
+	}
+	if cc.planTransfer(opCopy, CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject), size) {
+		return true, nil, size, msg
 	}
 
 	if size < cc.cpOption.threshold {
@@ -3018,11 +3331,30 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 	var listener *OssResumeProgressListener = &OssResumeProgressListener{&cc.monitor, 0, 0, false, false}
 	partSize, rt := cc.preparePartOption(size)
 	cp := oss.CheckpointDir(true, cc.cpOption.cpDir)
-	options := cc.cpOption.options
+	options := cc.objectOptions(destURL.bucket, destObject)
 	options = append(options, oss.Routines(rt), cp, oss.Progress(listener), oss.MetadataDirective(oss.MetaReplace))
 	return false, cc.ossResumeCopyRetry(srcURL.bucket, srcObject, destURL.bucket, destObject, partSize, options...), 0, msg
 }
 
+// copiedSize is the size of the object copied for the transfer report, it
+// is got again if the object is copied by multipart, or not got at all.
+func (cc *CopyCommand) copiedSize(bucket *oss.Bucket, object string, size, copied int64, failed bool) int64 {
+	if size >= 0 || failed {
+		return size
+	}
+	if copied > 0 {
+		return copied
+	}
+	props, err := cc.command.ossGetObjectStatRetry(bucket, object, cc.cpOption.payerOptions...)
+	if err != nil {
+		return -1
+	}
+	if size, err = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64); err != nil {
+		return -1
+	}
+	return size
+}
+
 func (cc *CopyCommand) makeCopyObjectName(srcRelativeObject, destObject string) string {
 	if destObject == "" || strings.HasSuffix(destObject, "/") {
 		return destObject + srcRelativeObject
@@ -3030,46 +3362,53 @@ func (cc *CopyCommand) makeCopyObjectName(srcRelativeObject, destObject string)
 	return destObject
 }
 
-func (cc *CopyCommand) skipCopy(destURL CloudURL, destObject string, srct time.Time) (bool, error) {
+func (cc *CopyCommand) skipCopy(srcBucket *oss.Bucket, srcObject string, destURL CloudURL, destObject string, size int64, srct time.Time) (string, error) {
 	if cc.cpOption.startTime > 0 && srct.Unix() < cc.cpOption.startTime {
-		return true, nil
+		return skipReasonTimeRange, nil
 	}
 
 	if cc.cpOption.endTime > 0 && srct.Unix() > cc.cpOption.endTime {
-		return true, nil
+		return skipReasonTimeRange, nil
 	}
 
 	destBucket, err := cc.command.ossBucket(destURL.bucket)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	if cc.cpOption.update {
+	if cc.compareContent() {
+		same, err := cc.sameObjectContent(srcBucket, srcObject, destBucket, destObject, size)
+		if err != nil || !same {
+			return "", err
+		}
+		return cc.sameContentReason(), nil
+	} else if cc.cpOption.update {
 		if props, err := cc.command.ossGetObjectStatRetry(destBucket, destObject, cc.cpOption.payerOptions...); err == nil {
 			destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 			if err == nil && destt.Unix() >= srct.Unix() {
-				return true, nil
+				return skipReasonNotNewer, nil
 			}
 		}
 	} else {
-		if !cc.cpOption.force {
+		if !cc.cpOption.force && cc.cpOption.plan == nil {
 			if _, err := cc.command.ossGetObjectMetaRetry(destBucket, destObject, cc.cpOption.payerOptions...); err == nil {
 				if !cc.confirm(CloudURLToString(destURL.bucket, destObject)) {
-					return true, nil
+					return skipReasonNotConfirmed, nil
 				}
 			}
 		}
 	}
-	return false, nil
+	return "", nil
 }
 
 func (cc *CopyCommand) ossCopyObjectRetry(bucket *oss.Bucket, objectName, destBucketName, destObjectName string) error {
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
-	options := cc.cpOption.options
+	options := cc.objectOptions(destBucketName, destObjectName)
 	options = append(options, oss.MetadataDirective(oss.MetaReplace))
 	options = append(options, oss.TaggingDirective(oss.TaggingReplace))
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d,copy object:%s.\n", i-1, objectName)
@@ -3097,6 +3436,7 @@ func (cc *CopyCommand) ossResumeCopyRetry(bucketName, objectName, destBucketName
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		if i > 1 {
+			cc.addRetry(objectName)
 			time.Sleep(time.Duration(3) * time.Second)
 			if int64(i) >= retryTimes {
 				fmt.Printf("\nretry count:%d, resume copy object:%s.\n", i-1, objectName)
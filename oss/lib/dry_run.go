@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	planSkip   = "skip"
+	planDelete = "delete"
+)
+
+// planEntry is one line of the --dry-run plan. Action is upload, download,
+// copy, skip or delete.
+type planEntry struct {
+	Action      string `json:"action"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Size        int64  `json:"size"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type planTotal struct {
+	Count int64 `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+// dryRunPlan prints what cp, sync or rm would do as JSON lines, and a
+// summary line with the count and size of every action at the end.
+type dryRunPlan struct {
+	mu     sync.Mutex
+	out    io.Writer
+	totals map[string]*planTotal
+}
+
+func newDryRunPlan(out io.Writer) *dryRunPlan {
+	return &dryRunPlan{out: out, totals: map[string]*planTotal{}}
+}
+
+func (p *dryRunPlan) add(entry planEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, _ := json.Marshal(entry)
+	fmt.Fprintln(p.out, string(data))
+	total, ok := p.totals[entry.Action]
+	if !ok {
+		total = &planTotal{}
+		p.totals[entry.Action] = total
+	}
+	total.Count++
+	total.Size += entry.Size
+}
+
+func (p *dryRunPlan) printSummary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, _ := json.Marshal(map[string]interface{}{"summary": p.totals})
+	fmt.Fprintln(p.out, string(data))
+}
+
+func (cc *CopyCommand) planSkip(src, dest string, size int64, reason string) {
+	if cc.cpOption.plan != nil {
+		cc.cpOption.plan.add(planEntry{Action: planSkip, Source: src, Destination: dest, Size: size, Reason: reason})
+	}
+}
+
+// planTransfer adds the transfer to the plan of --dry-run, and reports
+// whether it must be left out.
+func (cc *CopyCommand) planTransfer(action, src, dest string, size int64) bool {
+	if cc.cpOption.plan == nil {
+		return false
+	}
+	cc.cpOption.plan.add(planEntry{Action: action, Source: src, Destination: dest, Size: size})
+	return true
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readPlan(t *testing.T, out *bytes.Buffer) ([]planEntry, map[string]planTotal) {
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.NotEmpty(t, lines)
+	var entries []planEntry
+	for _, line := range lines[:len(lines)-1] {
+		var entry planEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	var summary struct {
+		Summary map[string]planTotal `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+	return entries, summary.Summary
+}
+
+func TestDryRunPlan(t *testing.T) {
+	out := new(bytes.Buffer)
+	var cc CopyCommand
+	assert.False(t, cc.planTransfer(opUpload, "a.txt", "oss://b/a.txt", 3))
+	cc.planSkip("a.txt", "oss://b/a.txt", 3, skipReasonSnapshot)
+	assert.Empty(t, out.String())
+
+	cc.cpOption.plan = newDryRunPlan(out)
+	assert.True(t, cc.planTransfer(opUpload, "a.txt", "oss://b/a.txt", 3))
+	assert.True(t, cc.planTransfer(opUpload, "c.txt", "oss://b/c.txt", 4))
+	cc.planSkip("b.txt", "oss://b/b.txt", 5, skipReasonSnapshot)
+	cc.cpOption.plan.printSummary()
+
+	entries, summary := readPlan(t, out)
+	assert.Equal(t, []planEntry{
+		{Action: "upload", Source: "a.txt", Destination: "oss://b/a.txt", Size: 3},
+		{Action: "upload", Source: "c.txt", Destination: "oss://b/c.txt", Size: 4},
+		{Action: "skip", Source: "b.txt", Destination: "oss://b/b.txt", Size: 5, Reason: skipReasonSnapshot},
+	}, entries)
+	assert.Equal(t, map[string]planTotal{"upload": {Count: 2, Size: 7}, "skip": {Count: 1, Size: 5}}, summary)
+}
+
+func TestSkipDownloadDryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("abc"), 0600))
+
+	var cc CopyCommand
+	cc.cpOption.plan = newDryRunPlan(new(bytes.Buffer))
+	now := time.Now()
+	// an existing file would be confirmed, the plan does not ask
+	assert.Equal(t, "", cc.skipDownload(nil, "a.txt", path, 3, now))
+	assert.Equal(t, skipReasonIsDir, cc.skipDownload(nil, "a.txt", dir, 3, now))
+
+	cc.cpOption.startTime = now.Unix() + 10
+	assert.Equal(t, skipReasonTimeRange, cc.skipDownload(nil, "a.txt", path, 3, now))
+	cc.cpOption.startTime = 0
+
+	cc.cpOption.update = true
+	assert.Equal(t, skipReasonNotNewer, cc.skipDownload(nil, "a.txt", path, 3, now.Add(-time.Hour)))
+	assert.Equal(t, "", cc.skipDownload(nil, "a.txt", path, 3, now.Add(time.Hour)))
+
+	cc.cpOption.update = false
+	cc.cpOption.sizeOnly = true
+	assert.Equal(t, skipReasonSameSize, cc.skipDownload(nil, "a.txt", path, 3, now))
+	assert.Equal(t, "", cc.skipDownload(nil, "a.txt", path, 4, now))
+}
+
+func TestSyncPlanExtraKeys(t *testing.T) {
+	origin := copyCommand.cpOption.plan
+	defer func() { copyCommand.cpOption.plan = origin }()
+	out := new(bytes.Buffer)
+	copyCommand.cpOption.plan = newDryRunPlan(out)
+
+	sc := SyncCommand{objectSizes: map[string]int64{"p/b.txt": 7}}
+	sc.syncOption.dryRun = true
+	keys := map[string]string{"b.txt": "p/", "a.txt": "p/"}
+	require.NoError(t, sc.planExtraKeys(keys, CloudURL{bucket: "bucket", object: "p/"}))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.txt"), []byte("abc"), 0600))
+	sc.syncOption.backupDir = "backup" + string(os.PathSeparator)
+	keys = map[string]string{"c.txt": "", "sub" + string(os.PathSeparator): ""}
+	require.NoError(t, sc.planExtraKeys(keys, FileURL{urlStr: dir + string(os.PathSeparator)}))
+	sc.printPlanSummary()
+
+	entries, summary := readPlan(t, out)
+	require.Len(t, entries, 3)
+	assert.Equal(t, planEntry{Action: "delete", Destination: "oss://bucket/p/a.txt", Reason: "not in source"}, entries[0])
+	assert.Equal(t, planEntry{Action: "delete", Destination: "oss://bucket/p/b.txt", Size: 7, Reason: "not in source"}, entries[1])
+	assert.Equal(t, int64(3), entries[2].Size)
+	assert.True(t, strings.HasSuffix(entries[2].Destination, "c.txt"))
+	assert.Equal(t, "not in source, moved to backup"+string(os.PathSeparator)+"c.txt", entries[2].Reason)
+	assert.Equal(t, planTotal{Count: 3, Size: 10}, summary["delete"])
+}
+
+func TestPlanRemoveObjectsNeedsRecursive(t *testing.T) {
+	var rc RemoveCommand
+	rc.rmOption.typeSet = objectType
+	err := rc.planRemoveObjects(nil, CloudURL{bucket: "bucket", object: "a"}, newDryRunPlan(new(bytes.Buffer)))
+	assert.EqualError(t, err, "--dry-run only supports removing objects with --recursive")
+}
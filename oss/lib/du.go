@@ -2,7 +2,10 @@ package lib
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,6 +31,20 @@ var specChineseDu = SpecText{
 
     1) ossutil du oss://bucket[/prefix] [options]
       查询bucket或者指定前缀(目录)所占存储空间大小
+
+--prefix-depth选项
+
+    按object名在指定前缀之后的前若干级目录分别统计object数量和大小，例如前缀为logs/且
+    --prefix-depth为1时，oss://bucket/logs/2023/a.log和oss://bucket/logs/2024/b.log分别
+    计入logs/2023/和logs/2024/。不够该级数的object计入其所在的目录。未完成上传的块不按目
+    录统计。
+
+--output-format选项
+
+    指定为json时，ossutil将统计结果输出为一个JSON对象，包括objectCount、objectSize、partCount、
+    partSize、totalSize，以及按存储类型（storageClasses）和按目录（prefixes）的数量和大小。
+    指定为jsonl时，每个存储类型和目录输出一行，type为storageClass或prefix，最后一行type为total。
+    大小均以字节为单位，不受--block-size影响。
 `,
 
 	sampleText: ` 
@@ -42,6 +59,9 @@ var specChineseDu = SpecText{
     
     4) 统计结果以KB为单位显示, 支持MB, GB, TB
        ossutil du oss://bucket/prefix --block-size KB
+
+    5) 按第一级目录统计, 以JSON格式输出
+       ossutil du oss://bucket/prefix --prefix-depth 1 --output-format json
 `,
 }
 
@@ -63,6 +83,21 @@ Usages：
 
     1) ossutil du oss://bucket[/prefix] [options]
        Gets the bucket or the specified prefix(directory) storage size
+
+--prefix-depth option
+
+    Sums up the count and size of objects by the leading directories of their names after the 
+    prefix as well, e.g., with --prefix-depth 1 and the prefix logs/, oss://bucket/logs/2023/a.log 
+    and oss://bucket/logs/2024/b.log are counted in logs/2023/ and logs/2024/. Objects not that 
+    deep are counted in the directory they are in. Uncompleted parts are not counted by directory.
+
+--output-format option
+
+    If the option is json, ossutil prints the result as a JSON object, with the objectCount, 
+    objectSize, partCount, partSize, totalSize, and the count and size by storage class
+    (storageClasses) and by directory(prefixes). If it is jsonl, every storage class and 
+    directory is a line of type storageClass or prefix, and the last line is of type total. 
+    The sizes are in bytes, whatever --block-size is.
 `,
 
 	sampleText: ` 
@@ -77,6 +112,9 @@ Usages：
 
     4) The du results are displayed in KB block size, Support MB, GB, TB
        ossutil du oss://bucket/prefix --block-size KB
+
+    5) sum up by the first level of directories, and print the result as JSON
+       ossutil du oss://bucket/prefix --prefix-depth 1 --output-format json
 `,
 }
 
@@ -98,6 +136,35 @@ type duSizeOptionType struct {
 	mutex            sync.Mutex
 	displayUnit      string
 	blockSize        int64
+	prefixDepth      int64
+	prefixCountMap   map[string]int64
+	prefixSizeMap    map[string]int64
+	outputFormat     string
+}
+
+// duTotal is the count and size of the objects of a storage class, or under
+// a prefix, printed by --output-format.
+type duTotal struct {
+	Type         string `json:"type,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Prefix       string `json:"prefix,omitempty"`
+	Count        int64  `json:"count"`
+	Size         int64  `json:"size"`
+}
+
+// duSummary is the result of du printed by --output-format. For jsonl the
+// storage classes and prefixes are lines of their own before it.
+type duSummary struct {
+	Type           string    `json:"type,omitempty"`
+	Bucket         string    `json:"bucket"`
+	Prefix         string    `json:"prefix"`
+	ObjectCount    int64     `json:"objectCount"`
+	ObjectSize     int64     `json:"objectSize"`
+	PartCount      int64     `json:"partCount"`
+	PartSize       int64     `json:"partSize"`
+	TotalSize      int64     `json:"totalSize"`
+	StorageClasses []duTotal `json:"storageClasses,omitempty"`
+	Prefixes       []duTotal `json:"prefixes,omitempty"`
 }
 
 type DuCommand struct {
@@ -128,6 +195,8 @@ var duSizeCommand = DuCommand{
 			OptionAllversions,
 			OptionPassword,
 			OptionBlockSize,
+			OptionPrefixDepth,
+			OptionOutputFormat,
 			OptionMode,
 			OptionECSRoleName,
 			OptionTokenTimeout,
@@ -171,6 +240,8 @@ func (duc *DuCommand) RunCommand() error {
 	duc.duOption.sumObjectSize = 0
 	duc.duOption.totalPartCount = 0
 	duc.duOption.sumPartSize = 0
+	duc.duOption.prefixCountMap = make(map[string]int64)
+	duc.duOption.prefixSizeMap = make(map[string]int64)
 
 	blockSizeMap := make(map[string]int64)
 	blockSizeMap["byte"] = 1
@@ -206,6 +277,8 @@ func (duc *DuCommand) RunCommand() error {
 	}
 	duc.duOption.displayUnit = strBlockSize
 	duc.duOption.blockSize = blockSizeMap[strBlockSize]
+	duc.duOption.prefixDepth, _ = GetInt(OptionPrefixDepth, duc.command.options)
+	duc.duOption.outputFormat = getOutputFormat(duc.command.options)
 
 	duc.duOption.bucketName = srcBucketUrL.bucket
 	duc.duOption.object = srcBucketUrL.object
@@ -226,6 +299,14 @@ func (duc *DuCommand) RunCommand() error {
 		return err
 	}
 
+	if duc.duOption.outputFormat != "" {
+		if err = duc.GetAllPartSize(bucket); err != nil {
+			return err
+		}
+		duc.printJSON(os.Stdout)
+		return nil
+	}
+
 	printHeader := false
 	for k, v := range duc.duOption.countTypeMap {
 		if !printHeader {
@@ -242,6 +323,7 @@ func (duc *DuCommand) RunCommand() error {
 		fmt.Printf("----------------------------------------------------------\n")
 	}
 	fmt.Printf("%-20s%-20d\t%-23s%d\n", "total object count:", duc.duOption.totalObjectCount, "total object sum size:", duc.duOption.sumObjectSize)
+	duc.printPrefixes()
 
 	//second:get all part size
 	err = duc.GetAllPartSize(bucket)
@@ -275,19 +357,11 @@ func (duc *DuCommand) getAllObjectSize(bucket *oss.Bucket) error {
 			return err
 		}
 
-		duc.duOption.totalObjectCount += int64(len(lor.Objects))
 		for _, object := range lor.Objects {
-			duc.duOption.sumObjectSize += object.Size
-			if _, ok := duc.duOption.countTypeMap[object.StorageClass]; ok {
-				duc.duOption.countTypeMap[object.StorageClass]++
-				duc.duOption.sizeTypeMap[object.StorageClass] += object.Size
-			} else {
-				duc.duOption.countTypeMap[object.StorageClass] = 1
-				duc.duOption.sizeTypeMap[object.StorageClass] = object.Size
-			}
+			duc.addObject(object.Key, object.StorageClass, object.Size)
 		}
 
-		fmt.Printf("\robject count:%d\tobject sum size:%d", duc.duOption.totalObjectCount, duc.duOption.sumObjectSize)
+		duc.progress("\robject count:%d\tobject sum size:%d", duc.duOption.totalObjectCount, duc.duOption.sumObjectSize)
 
 		pre = oss.Prefix(lor.Prefix)
 		marker = oss.Marker(lor.NextMarker)
@@ -303,6 +377,104 @@ func (duc *DuCommand) getAllObjectSize(bucket *oss.Bucket) error {
 	return nil
 }
 
+// addObject counts an object, or an object version, by its storage class
+// and, with --prefix-depth, by the leading directories of its name.
+func (duc *DuCommand) addObject(key, storageClass string, size int64) {
+	duc.duOption.totalObjectCount++
+	duc.duOption.sumObjectSize += size
+	duc.duOption.countTypeMap[storageClass]++
+	duc.duOption.sizeTypeMap[storageClass] += size
+	if duc.duOption.prefixDepth > 0 {
+		prefix := duPrefix(duc.duOption.object, key, duc.duOption.prefixDepth)
+		duc.duOption.prefixCountMap[prefix]++
+		duc.duOption.prefixSizeMap[prefix] += size
+	}
+}
+
+// duPrefix is key up to the depth-th "/" after the du prefix, or up to its
+// last one if there are not that many.
+func duPrefix(prefix, key string, depth int64) string {
+	end := len(prefix)
+	for i := int64(0); i < depth; i++ {
+		index := strings.Index(key[end:], "/")
+		if index < 0 {
+			break
+		}
+		end += index + 1
+	}
+	return key[:end]
+}
+
+func (duc *DuCommand) progress(format string, args ...interface{}) {
+	if duc.duOption.outputFormat == "" {
+		fmt.Printf(format, args...)
+	}
+}
+
+func (duc *DuCommand) printPrefixes() {
+	if duc.duOption.prefixDepth <= 0 || len(duc.duOption.prefixCountMap) == 0 {
+		return
+	}
+	fmt.Printf("\n%-20s\t%-30s\t%s\n", "object count", "sum size(byte)", "prefix")
+	fmt.Printf("----------------------------------------------------------\n")
+	for _, prefix := range sortedKeys(duc.duOption.prefixCountMap) {
+		fmt.Printf("%-20d\t%-30d\t%s\n", duc.duOption.prefixCountMap[prefix], duc.duOption.prefixSizeMap[prefix], CloudURLToString(duc.duOption.bucketName, prefix))
+	}
+	fmt.Printf("----------------------------------------------------------\n")
+}
+
+// printJSON prints the sizes in bytes, --block-size is for the text only.
+func (duc *DuCommand) printJSON(out io.Writer) {
+	summary := duSummary{
+		Bucket:      duc.duOption.bucketName,
+		Prefix:      duc.duOption.object,
+		ObjectCount: duc.duOption.totalObjectCount,
+		ObjectSize:  duc.duOption.sumObjectSize,
+		PartCount:   duc.duOption.totalPartCount,
+		PartSize:    duc.duOption.sumPartSize,
+		TotalSize:   duc.duOption.sumObjectSize + duc.duOption.sumPartSize,
+	}
+	for _, storageClass := range sortedKeys(duc.duOption.countTypeMap) {
+		summary.StorageClasses = append(summary.StorageClasses, duTotal{
+			StorageClass: storageClass,
+			Count:        duc.duOption.countTypeMap[storageClass],
+			Size:         duc.duOption.sizeTypeMap[storageClass],
+		})
+	}
+	for _, prefix := range sortedKeys(duc.duOption.prefixCountMap) {
+		summary.Prefixes = append(summary.Prefixes, duTotal{
+			Prefix: prefix,
+			Count:  duc.duOption.prefixCountMap[prefix],
+			Size:   duc.duOption.prefixSizeMap[prefix],
+		})
+	}
+
+	if duc.duOption.outputFormat != OutputFormatJSONL {
+		printJSON(out, duc.duOption.outputFormat, summary)
+		return
+	}
+	for _, total := range summary.StorageClasses {
+		total.Type = "storageClass"
+		printJSON(out, OutputFormatJSONL, total)
+	}
+	for _, total := range summary.Prefixes {
+		total.Type = "prefix"
+		printJSON(out, OutputFormatJSONL, total)
+	}
+	summary.Type = "total"
+	summary.StorageClasses, summary.Prefixes = nil, nil
+	printJSON(out, OutputFormatJSONL, summary)
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (duc *DuCommand) getAllObjectVersionsSize(bucket *oss.Bucket) error {
 	// Delete Object Versions and DeleteMarks
 	pre := oss.Prefix(duc.duOption.object)
@@ -318,18 +490,10 @@ func (duc *DuCommand) getAllObjectVersionsSize(bucket *oss.Bucket) error {
 		if err != nil {
 			return err
 		}
-		duc.duOption.totalObjectCount += int64(len(lor.ObjectVersions))
 		for _, object := range lor.ObjectVersions {
-			duc.duOption.sumObjectSize += object.Size
-			if _, ok := duc.duOption.countTypeMap[object.StorageClass]; ok {
-				duc.duOption.countTypeMap[object.StorageClass]++
-				duc.duOption.sizeTypeMap[object.StorageClass] += object.Size
-			} else {
-				duc.duOption.countTypeMap[object.StorageClass] = 1
-				duc.duOption.sizeTypeMap[object.StorageClass] = object.Size
-			}
+			duc.addObject(object.Key, object.StorageClass, object.Size)
 		}
-		fmt.Printf("\robject count:%d\tobject sum size:%d", duc.duOption.totalObjectCount, duc.duOption.sumObjectSize)
+		duc.progress("\robject count:%d\tobject sum size:%d", duc.duOption.totalObjectCount, duc.duOption.sumObjectSize)
 		keyMarker = oss.KeyMarker(lor.NextKeyMarker)
 		versionIdMarker := oss.VersionIdMarker(lor.NextVersionIdMarker)
 		listOptions = []oss.Option{pre, keyMarker, versionIdMarker, oss.MaxKeys(1000)}
@@ -397,7 +561,7 @@ func (duc *DuCommand) statPartSize(bucket *oss.Bucket, object MultiPartObject) e
 			for _, v := range lpRes.UploadedParts {
 				duc.duOption.sumPartSize += int64(v.Size)
 			}
-			fmt.Printf("\rpart count:%d\tpart sum size:%d", duc.duOption.totalPartCount, duc.duOption.sumPartSize)
+			duc.progress("\rpart count:%d\tpart sum size:%d", duc.duOption.totalPartCount, duc.duOption.sumPartSize)
 			duc.duOption.mutex.Unlock()
 		}
 
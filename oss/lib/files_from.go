@@ -0,0 +1,420 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const (
+	manifestStatusOK      = "ok"
+	manifestStatusSkip    = "skip"
+	manifestStatusError   = "error"
+	manifestStatusPending = "pending"
+)
+
+// manifestItem is one line of the --files-from manifest, either a plain key
+// or a JSON object. Src and Dest are relative to the source and destination
+// url, Dest defaults to Src. Status and Error are set in the result manifest.
+type manifestItem struct {
+	Src    string `json:"src"`
+	Dest   string `json:"dest,omitempty"`
+	Meta   string `json:"meta,omitempty"`
+	ACL    string `json:"acl,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (item manifestItem) dest() string {
+	if item.Dest != "" {
+		return item.Dest
+	}
+	return item.Src
+}
+
+// readManifest reads the items of a manifest. The items a result manifest
+// reports as done are left out, so it can be fed back to retry the failures.
+func readManifest(path string) ([]manifestItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []manifestItem
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		item := manifestItem{Src: text}
+		if strings.HasPrefix(strings.TrimSpace(text), "{") {
+			item = manifestItem{}
+			if err := json.Unmarshal([]byte(text), &item); err != nil {
+				return nil, fmt.Errorf("invalid manifest line %d: %s", line, err.Error())
+			}
+		}
+		if item.Src == "" {
+			return nil, fmt.Errorf("invalid manifest line %d: src is empty", line)
+		}
+		if item.Status == manifestStatusOK || item.Status == manifestStatusSkip {
+			continue
+		}
+		item.Status, item.Error = "", ""
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// manifestResult writes the result manifest, the items with their status.
+// done tells the items recorded by their index in the manifest.
+type manifestResult struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	done    map[int]bool
+	failed  int64
+	pending int64
+}
+
+// newManifestResult creates a new result file, runs started in the same
+// second get a numbered name instead of overwriting each other.
+func newManifestResult(outputDir string) (*manifestResult, error) {
+	if outputDir == "" {
+		outputDir = DefaultOutputDir
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+	base := outputDir + string(os.PathSeparator) + ResultPrefix + time.Now().Format("20060102_150405")
+	for n := 1; ; n++ {
+		path := base + ResultSuffix
+		if n > 1 {
+			path = fmt.Sprintf("%s_%d%s", base, n, ResultSuffix)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0664)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Create result file error: %s", err.Error())
+		}
+		return &manifestResult{path: path, f: f, done: map[int]bool{}}, nil
+	}
+}
+
+func (r *manifestResult) record(index int, item manifestItem, skip bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item.Status = manifestStatusOK
+	if err != nil {
+		item.Status = manifestStatusError
+		item.Error = err.Error()
+		r.failed++
+	} else if skip {
+		item.Status = manifestStatusSkip
+	}
+	r.write(index, item)
+}
+
+// recordPending records the items of manifest never transferred, because
+// the run stopped at an error, so feeding the result back retries them too.
+func (r *manifestResult) recordPending(manifest []manifestItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, item := range manifest {
+		if r.done[i] {
+			continue
+		}
+		item.Status = manifestStatusPending
+		r.pending++
+		r.write(i, item)
+	}
+}
+
+func (r *manifestResult) write(index int, item manifestItem) {
+	r.done[index] = true
+	data, _ := json.Marshal(item)
+	fmt.Fprintln(r.f, string(data))
+}
+
+func (r *manifestResult) close() {
+	r.f.Close()
+}
+
+// manifestTransfer is where the items of a manifest are transferred from and
+// to. srcDir is the local source directory, destDir the local destination
+// directory, srcURL and destURL the oss ones, with a prefix ending in "/".
+type manifestTransfer struct {
+	opType  operationType
+	bucket  *oss.Bucket
+	srcDir  string
+	destDir string
+	srcURL  CloudURL
+	destURL CloudURL
+	result  *manifestResult
+}
+
+func manifestPrefix(object string) string {
+	if object != "" && !strings.HasSuffix(object, "/") {
+		return object + "/"
+	}
+	return object
+}
+
+// loadManifest reads --files-from, the per item --meta and --acl are checked
+// and kept by destination object.
+func (cc *CopyCommand) loadManifest(path string, opType operationType, destURL StorageURLer) ([]manifestItem, error) {
+	items, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.cpOption.itemOptions = map[string][]oss.Option{}
+	for _, item := range items {
+		if item.Meta == "" && item.ACL == "" {
+			continue
+		}
+		if opType == operationTypeGet {
+			return nil, fmt.Errorf("invalid manifest item %s: no need to set meta or acl for download", item.Src)
+		}
+		var options []oss.Option
+		if item.Meta != "" {
+			headers, err := cc.command.parseHeaders(item.Meta, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest item %s: %s", item.Src, err.Error())
+			}
+			if options, err = cc.command.getOSSOptions(headerOptionMap, headers); err != nil {
+				return nil, fmt.Errorf("invalid manifest item %s: %s", item.Src, err.Error())
+			}
+		}
+		if item.ACL != "" {
+			acl, err := cc.command.checkACL(item.ACL, objectACL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest item %s: %s", item.Src, err.Error())
+			}
+			options = append(options, oss.ObjectACL(acl))
+		}
+		cloudURL := destURL.(CloudURL)
+		object := manifestPrefix(cloudURL.object) + filepath.ToSlash(item.dest())
+		cc.cpOption.itemOptions[CloudURLToString(cloudURL.bucket, object)] = options
+	}
+	return items, nil
+}
+
+// objectOptions are the options to write object with, the --meta and --acl
+// of its manifest item come after the ones of the command.
+func (cc *CopyCommand) objectOptions(bucket, object string) []oss.Option {
+	options := cc.cpOption.options
+	if itemOptions, ok := cc.cpOption.itemOptions[CloudURLToString(bucket, object)]; ok {
+		options = append(append([]oss.Option{}, options...), itemOptions...)
+	}
+	return options
+}
+
+func (cc *CopyCommand) transferManifest(srcURL, destURL StorageURLer, opType operationType, outputDir string) error {
+	t := &manifestTransfer{opType: opType}
+	var err error
+	switch opType {
+	case operationTypePut:
+		f, errF := os.Stat(srcURL.ToString())
+		if errF != nil {
+			return errF
+		}
+		if !f.IsDir() {
+			return fmt.Errorf("--files-from needs a directory as the source, %s is not", srcURL.ToString())
+		}
+		t.srcDir = srcURL.ToString()
+		t.destURL = destURL.(CloudURL)
+		if err := t.destURL.checkObjectPrefix(); err != nil {
+			return err
+		}
+		t.bucket, err = cc.command.ossBucket(t.destURL.bucket)
+	case operationTypeGet:
+		t.srcURL = srcURL.(CloudURL)
+		t.destDir = destURL.ToString()
+		if !strings.HasSuffix(t.destDir, "/") && !strings.HasSuffix(t.destDir, "\\") {
+			t.destDir += string(os.PathSeparator)
+		}
+		if err := os.MkdirAll(t.destDir, 0755); err != nil {
+			return err
+		}
+		t.bucket, err = cc.command.ossBucket(t.srcURL.bucket)
+	default:
+		t.srcURL = srcURL.(CloudURL)
+		t.destURL = destURL.(CloudURL)
+		if err := t.destURL.checkObjectPrefix(); err != nil {
+			return err
+		}
+		t.bucket, err = cc.command.ossBucket(t.srcURL.bucket)
+	}
+	if err != nil {
+		return err
+	}
+	t.srcURL.object = manifestPrefix(t.srcURL.object)
+	t.destURL.object = manifestPrefix(t.destURL.object)
+
+	if cc.cpOption.plan == nil {
+		if t.result, err = newManifestResult(outputDir); err != nil {
+			return err
+		}
+	}
+	return cc.runManifest(t)
+}
+
+// runManifest transfers the items of the manifest. When an error ends the
+// run early, the items in flight are finished and the ones left are recorded
+// as pending before the result manifest is closed.
+func (cc *CopyCommand) runManifest(t *manifestTransfer) error {
+	chItems := make(chan int, ChannelBuf)
+	chError := make(chan error, cc.cpOption.routines)
+	chListError := make(chan error, 1)
+	chStop := make(chan struct{})
+	go cc.manifestStatistic(t)
+	go func() {
+		defer close(chItems)
+		for i := range cc.cpOption.manifest {
+			select {
+			case chItems <- i:
+			case <-chStop:
+				return
+			}
+		}
+		chListError <- nil
+	}()
+	var wg sync.WaitGroup
+	for i := 0; int64(i) < cc.cpOption.routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cc.manifestConsumer(t, chItems, chError, chStop)
+		}()
+	}
+
+	err := cc.waitRoutinueComplete(chError, chListError, opUpload)
+	close(chStop)
+	wg.Wait()
+	if t.result != nil {
+		t.result.recordPending(cc.cpOption.manifest)
+		t.result.close()
+		fmt.Printf("\nresult manifest: %s, failed items: %d, pending items: %d\n", t.result.path, t.result.failed, t.result.pending)
+	}
+	return err
+}
+
+func (cc *CopyCommand) manifestStatistic(t *manifestTransfer) {
+	for _, item := range cc.cpOption.manifest {
+		if t.opType != operationTypePut {
+			cc.monitor.updateScanNum(1)
+			continue
+		}
+		if f, err := os.Stat(filepath.Join(t.srcDir, filepath.FromSlash(item.Src))); err == nil && !f.IsDir() {
+			cc.monitor.updateScanSizeNum(f.Size(), 1)
+		} else {
+			cc.monitor.updateScanNum(1)
+		}
+	}
+	cc.monitor.setScanEnd()
+	freshProgress()
+}
+
+// manifestConsumer transfers the items by their index in the manifest until
+// there are no more, or chStop is closed.
+func (cc *CopyCommand) manifestConsumer(t *manifestTransfer, chItems <-chan int, chError chan<- error, chStop <-chan struct{}) {
+	for i := range chItems {
+		select {
+		case <-chStop:
+			return
+		default:
+		}
+		item := cc.cpOption.manifest[i]
+		skip, err := cc.transferManifestItem(t, item)
+		if t.result != nil {
+			t.result.record(i, item, skip, err)
+		}
+		if err != nil {
+			chError <- err
+			if !cc.cpOption.ctnu {
+				return
+			}
+		}
+	}
+
+	chError <- nil
+}
+
+// transferManifestItem uploads, downloads or copies one item to the
+// destination it names, instead of to the destination url plus its key.
+func (cc *CopyCommand) transferManifestItem(t *manifestTransfer, item manifestItem) (bool, error) {
+	var skip, isDir bool
+	var err error
+	var size int64
+	var msg string
+	var src, dest, retryKey string
+	startT := time.Now()
+	switch t.opType {
+	case operationTypePut:
+		file := fileInfoType{filepath.FromSlash(item.Src), t.srcDir}
+		destURL := t.destURL
+		destURL.object += filepath.ToSlash(item.dest())
+		src, dest, retryKey = filepath.Join(file.dir, file.filePath), CloudURLToString(destURL.bucket, destURL.object), destURL.object
+		if f, errF := os.Stat(filepath.Join(file.dir, file.filePath)); errF == nil && f.IsDir() {
+			err = fmt.Errorf("%s is a directory, list the files in it instead", item.Src)
+			msg = fmt.Sprintf("%s %s to %s", opUpload, item.Src, CloudURLToString(destURL.bucket, destURL.object))
+			break
+		}
+		skip, err, isDir, size, msg = cc.uploadFile(t.bucket, destURL, file)
+	case operationTypeGet:
+		objectInfo := objectInfoType{t.srcURL.object, item.Src, -1, time.Now()}
+		retryKey = t.srcURL.object + item.Src
+		src, dest = CloudURLToString(t.bucket.BucketName, retryKey), t.destDir+filepath.FromSlash(item.dest())
+		skip, err, size, msg = cc.downloadSingleFile(t.bucket, objectInfo, dest)
+	default:
+		objectInfo := objectInfoType{t.srcURL.object, item.Src, -1, time.Now()}
+		destURL := t.destURL
+		destURL.object += item.dest()
+		retryKey = t.srcURL.object + item.Src
+		src, dest = CloudURLToString(t.srcURL.bucket, retryKey), CloudURLToString(destURL.bucket, destURL.object)
+		skip, err, size, msg = cc.copySingleFile(t.bucket, objectInfo, t.srcURL, destURL)
+	}
+
+	if err != nil {
+		LogError("%s error: %s\n", msg, err.Error())
+	} else if skip {
+		LogInfo("%s skip\n", msg)
+	} else {
+		LogInfo("%s success\n", msg)
+	}
+	if cc.cpOption.transferReport != nil {
+		reportSize := int64(-1)
+		switch t.opType {
+		case operationTypePut:
+			reportSize = fileSize(src)
+		case operationTypeGet:
+			if !skip && err == nil {
+				reportSize = fileSize(dest)
+			}
+		default:
+			reportSize = cc.copiedSize(t.bucket, retryKey, -1, size, skip || err != nil)
+		}
+		cc.recordTransfer(src, dest, retryKey, startT, skip, err, reportSize)
+	}
+	cc.updateMonitor(skip, err, isDir, size)
+	cc.report(msg, err)
+	return skip, err
+}
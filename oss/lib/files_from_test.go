@@ -0,0 +1,154 @@
+package lib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifest(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "manifest")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+	return path
+}
+
+func TestReadManifest(t *testing.T) {
+	path := writeManifest(t,
+		"dir/a.txt",
+		"",
+		"with space.txt\r",
+		`{"src":"b.txt","dest":"c/b.txt","meta":"Cache-Control:no-cache","acl":"private"}`,
+		`{"src":"done.txt","status":"ok"}`,
+		`{"src":"same.txt","status":"skip"}`,
+		`{"src":"failed.txt","status":"error","error":"timeout"}`)
+	items, err := readManifest(path)
+	require.NoError(t, err)
+	assert.Equal(t, []manifestItem{
+		{Src: "dir/a.txt"},
+		{Src: "with space.txt"},
+		{Src: "b.txt", Dest: "c/b.txt", Meta: "Cache-Control:no-cache", ACL: "private"},
+		{Src: "failed.txt"},
+	}, items)
+	assert.Equal(t, "dir/a.txt", items[0].dest())
+	assert.Equal(t, "c/b.txt", items[2].dest())
+
+	_, err = readManifest(writeManifest(t, "a.txt", `{"dest":"b.txt"}`))
+	assert.EqualError(t, err, "invalid manifest line 2: src is empty")
+	_, err = readManifest(writeManifest(t, `{"src":`))
+	assert.Error(t, err)
+	_, err = readManifest(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestManifestResultRetry(t *testing.T) {
+	result, err := newManifestResult(t.TempDir())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(filepath.Base(result.path), ResultPrefix))
+	manifest := []manifestItem{{Src: "a.txt"}, {Src: "b.txt"}, {Src: "c.txt", Dest: "d.txt"}, {Src: "e.txt"}}
+	result.record(0, manifest[0], false, nil)
+	result.record(1, manifest[1], true, nil)
+	result.record(2, manifest[2], false, errors.New("timeout"))
+	result.recordPending(manifest)
+	result.close()
+	assert.Equal(t, int64(1), result.failed)
+	assert.Equal(t, int64(1), result.pending)
+
+	data, err := os.ReadFile(result.path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"src":"a.txt","status":"ok"}
+{"src":"b.txt","status":"skip"}
+{"src":"c.txt","dest":"d.txt","status":"error","error":"timeout"}
+{"src":"e.txt","status":"pending"}
+`, string(data))
+
+	// fed back, the failure and the item never processed are left
+	items, err := readManifest(result.path)
+	require.NoError(t, err)
+	assert.Equal(t, []manifestItem{{Src: "c.txt", Dest: "d.txt"}, {Src: "e.txt"}}, items)
+}
+
+func TestManifestResultUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	first, err := newManifestResult(dir)
+	require.NoError(t, err)
+	defer first.close()
+	second, err := newManifestResult(dir)
+	require.NoError(t, err)
+	defer second.close()
+	assert.NotEqual(t, first.path, second.path)
+}
+
+func TestRunManifestStopsAtErrorAndRecordsPending(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0755))
+	}
+
+	// no progress bar is running
+	origin := signalNum
+	signalNum = -1
+	defer func() { signalNum = origin }()
+
+	var cc CopyCommand
+	cc.monitor.init(operationTypePut)
+	cc.cpOption.routines = 1
+	cc.cpOption.manifest = []manifestItem{{Src: "a"}, {Src: "b"}, {Src: "c"}, {Src: "d"}}
+	result, err := newManifestResult(t.TempDir())
+	require.NoError(t, err)
+	transfer := &manifestTransfer{opType: operationTypePut, srcDir: dir, destURL: CloudURL{bucket: "bucket", object: "p/"}, result: result}
+
+	err = cc.runManifest(transfer)
+	assert.EqualError(t, err, "a is a directory, list the files in it instead")
+
+	items, err := readManifest(result.path)
+	require.NoError(t, err)
+	assert.Equal(t, cc.cpOption.manifest, items)
+	assert.Equal(t, int64(1), result.failed)
+	assert.Equal(t, int64(3), result.pending)
+}
+
+func TestLoadManifestItemOptions(t *testing.T) {
+	var cc CopyCommand
+	cc.cpOption.options = []oss.Option{oss.ObjectACL(oss.ACLPublicRead)}
+	path := writeManifest(t,
+		"a.txt",
+		`{"src":"b.txt","dest":"c/b.txt","meta":"Cache-Control:no-cache","acl":"private"}`)
+
+	items, err := cc.loadManifest(path, operationTypePut, CloudURL{bucket: "bucket", object: "p"})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Len(t, cc.objectOptions("bucket", "p/a.txt"), 1)
+	assert.Len(t, cc.objectOptions("bucket", "p/c/b.txt"), 3)
+	// the options of the command are not changed
+	assert.Len(t, cc.cpOption.options, 1)
+
+	_, err = cc.loadManifest(path, operationTypeGet, FileURL{urlStr: t.TempDir()})
+	assert.EqualError(t, err, "invalid manifest item b.txt: no need to set meta or acl for download")
+
+	_, err = cc.loadManifest(writeManifest(t, `{"src":"a.txt","acl":"nope"}`), operationTypePut, CloudURL{bucket: "bucket"})
+	assert.Error(t, err)
+}
+
+func TestTransferManifestItemDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	// no progress bar is running
+	origin := signalNum
+	signalNum = -1
+	defer func() { signalNum = origin }()
+
+	var cc CopyCommand
+	cc.monitor.init(operationTypePut)
+	transfer := &manifestTransfer{opType: operationTypePut, srcDir: dir, destURL: CloudURL{bucket: "bucket", object: "p/"}}
+	skip, err := cc.transferManifestItem(transfer, manifestItem{Src: "sub"})
+	assert.False(t, skip)
+	assert.EqualError(t, err, "sub is a directory, list the files in it instead")
+	assert.Equal(t, int64(1), cc.monitor.errNum)
+}
@@ -15,7 +15,7 @@ var specChineseList = SpecText{
 	paramText: "[cloud_url] [options]",
 
 	syntaxText: ` 
-    ossutil ls [oss://bucket[/prefix]] [-s] [-d] [-m] [--limited-num num] [--marker marker] [--upload-id-marker umarker] [--payer requester] [--include include-pattern] [--exclude exclude-pattern]  [--version-id-marker id_marker] [--all-versions]  [-c file] 
+    ossutil ls [oss://bucket[/prefix]] [-s] [-d] [-m] [--limited-num num] [--marker marker] [--upload-id-marker umarker] [--payer requester] [--include include-pattern] [--exclude exclude-pattern]  [--version-id-marker id_marker] [--all-versions] [--output-format json|jsonl] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -43,6 +43,14 @@ var specChineseList = SpecText{
 
     --include和--exclude可以出现多次。当多个规则出现时，这些规则按从左往右的顺序应用
 
+--output-format选项
+
+    指定为json时，ossutil将列举结果输出为一个JSON数组，指定为jsonl时每行输出一个JSON对象，
+    不再输出表头和数量统计，便于用jq等工具处理。每项的type为bucket、object、version、
+    deleteMarker、prefix或upload，并包含url、key、size、etag、storageClass、lastModified
+    （UTC，RFC3339格式）、versionId、isLatest、restoreStatus（in-progress或restored）、
+    restoreExpiry、uploadId等信息。该选项忽略--short-format选项。
+
 用法：
 
     该命令有两种用法：
@@ -176,6 +184,10 @@ var specChineseList = SpecText{
         Object Number is: 2
 
     15) ossutil ls oss://bucket --all-versions
+
+    16) ossutil ls oss://bucket/dir/ --output-format jsonl
+        {"type":"object","url":"oss://bucket/dir/a.txt","bucket":"bucket","key":"dir/a.txt","size":1030,"etag":"4A902D176BE0EE4224BC196BBB8CCC69","storageClass":"Standard","lastModified":"2019-05-30T06:23:51Z"}
+        {"type":"object","url":"oss://bucket/dir/b.txt","bucket":"bucket","key":"dir/b.txt","size":1030,"etag":"4A902D176BE0EE4224BC196BBB8CCC69","storageClass":"Archive","lastModified":"2019-05-30T06:24:05Z","restoreStatus":"restored","restoreExpiry":"2019-06-01T06:30:00Z"}
 `,
 }
 
@@ -186,7 +198,7 @@ var specEnglishList = SpecText{
 	paramText: "[cloud_url] [options]",
 
 	syntaxText: ` 
-    ossutil ls [oss://bucket[/prefix]] [-s] [-d] [-m] [--limited-num num] [--marker marker] [--upload-id-marker umarker] [--payer requester] [--include include-pattern] [--exclude exclude-pattern]  [--version-id-marker id_marker] [--all-versions]  [-c file] 
+    ossutil ls [oss://bucket[/prefix]] [-s] [-d] [-m] [--limited-num num] [--marker marker] [--upload-id-marker umarker] [--payer requester] [--include include-pattern] [--exclude exclude-pattern]  [--version-id-marker id_marker] [--all-versions] [--output-format json|jsonl] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -222,6 +234,15 @@ var specEnglishList = SpecText{
     When there are multi filters, the rule is the filters that appear later in the command take precedence
     over filters that appear earlier in the command
 
+--output-format option
+
+    If the option is json, ossutil prints the result as a JSON array, if it is jsonl, a JSON 
+    object per line, without the headers and the numbers, to be processed by tools like jq. 
+    The type of every entry is bucket, object, version, deleteMarker, prefix or upload, with 
+    the url, key, size, etag, storageClass, lastModified(UTC, in RFC3339), versionId, isLatest, 
+    restoreStatus(in-progress or restored), restoreExpiry and uploadId of it. The option 
+    ignores --short-format.
+
 Usage:
 
     There are two usages:
@@ -357,6 +378,10 @@ Usage:
         2019-05-30 14:24:05 +0800 CST         1030      Standard   4A902D176BE0EE4224BC196BBB8CCC69      oss://bucket/test.mp4
         Object Number is: 2
     15) ossutil ls oss://bucket[/prefix] --all-versions
+
+    16) ossutil ls oss://bucket/dir/ --output-format jsonl
+        {"type":"object","url":"oss://bucket/dir/a.txt","bucket":"bucket","key":"dir/a.txt","size":1030,"etag":"4A902D176BE0EE4224BC196BBB8CCC69","storageClass":"Standard","lastModified":"2019-05-30T06:23:51Z"}
+        {"type":"object","url":"oss://bucket/dir/b.txt","bucket":"bucket","key":"dir/b.txt","size":1030,"etag":"4A902D176BE0EE4224BC196BBB8CCC69","storageClass":"Archive","lastModified":"2019-05-30T06:24:05Z","restoreStatus":"restored","restoreExpiry":"2019-06-01T06:30:00Z"}
 `,
 }
 
@@ -365,6 +390,7 @@ type ListCommand struct {
 	command     Command
 	payerOption oss.Option
 	filters     []filterOptionType
+	output      *jsonWriter
 }
 
 var listCommand = ListCommand{
@@ -400,6 +426,7 @@ var listCommand = ListCommand{
 			OptionExclude,
 			OptionAllversions,
 			OptionVersionIdMarker,
+			OptionOutputFormat,
 			OptionPassword,
 			OptionMode,
 			OptionECSRoleName,
@@ -489,6 +516,9 @@ func (lc *ListCommand) listBuckets(prefix string) error {
 	if err != nil {
 		return err
 	}
+	if lc.output = newJSONWriter(os.Stdout, getOutputFormat(lc.command.options)); lc.output != nil {
+		defer lc.output.close()
+	}
 
 	// list all buckets
 	pre := oss.Prefix(prefix)
@@ -501,14 +531,16 @@ func (lc *ListCommand) listBuckets(prefix string) error {
 		}
 		pre = oss.Prefix(lbr.Prefix)
 		marker = oss.Marker(lbr.NextMarker)
-		if num == 0 && !shortFormat && len(lbr.Buckets) > 0 {
+		if num == 0 && !shortFormat && lc.output == nil && len(lbr.Buckets) > 0 {
 			fmt.Printf("%-30s %20s%s%12s%s%s\n", "CreationTime", "Region", FormatTAB, "StorageClass", FormatTAB, "BucketName")
 		}
 		for _, bucket := range lbr.Buckets {
 			if limitedNum >= 0 && num >= limitedNum {
 				break
 			}
-			if !shortFormat {
+			if lc.output != nil {
+				lc.output.write(bucketEntry(bucket))
+			} else if !shortFormat {
 				fmt.Printf("%-30s %20s%s%12s%s%s\n", utcToLocalTime(bucket.CreationDate), bucket.Location, FormatTAB, bucket.StorageClass, FormatTAB, CloudURLToString(bucket.Name, ""))
 			} else {
 				fmt.Println(CloudURLToString(bucket.Name, ""))
@@ -519,7 +551,9 @@ func (lc *ListCommand) listBuckets(prefix string) error {
 			break
 		}
 	}
-	fmt.Printf("Bucket Number is: %d\n", num)
+	if lc.output == nil {
+		fmt.Printf("Bucket Number is: %d\n", num)
+	}
 	return nil
 }
 
@@ -545,6 +579,9 @@ func (lc *ListCommand) listFiles(cloudURL CloudURL) error {
 	if err != nil {
 		return err
 	}
+	if lc.output = newJSONWriter(os.Stdout, getOutputFormat(lc.command.options)); lc.output != nil {
+		defer lc.output.close()
+	}
 
 	shortFormat, _ := GetBool(OptionShortFormat, lc.command.options)
 	directory, _ := GetBool(OptionDirectory, lc.command.options)
@@ -619,6 +656,9 @@ func (lc *ListCommand) listObjects(bucket *oss.Bucket, cloudURL CloudURL, shortF
 		}
 	}
 
+	if lc.output != nil {
+		return num, nil
+	}
 	if !directory {
 		fmt.Printf("Object Number is: %d\n", num)
 	} else {
@@ -670,6 +710,9 @@ func (lc *ListCommand) listObjectVersions(bucket *oss.Bucket, cloudURL CloudURL,
 		}
 	}
 
+	if lc.output != nil {
+		return num, nil
+	}
 	if !directory {
 		fmt.Printf("Object Number is: %d\n", num)
 	} else {
@@ -679,7 +722,7 @@ func (lc *ListCommand) listObjectVersions(bucket *oss.Bucket, cloudURL CloudURL,
 }
 
 func (lc *ListCommand) displayObjectsResult(lor oss.ListObjectsResult, bucket string, shortFormat bool, directory bool, i int64, limitedNum *int64) int64 {
-	if i == 0 && !shortFormat && !directory && len(lor.Objects) > 0 {
+	if i == 0 && !shortFormat && !directory && lc.output == nil && len(lor.Objects) > 0 {
 		fmt.Printf("%-30s%12s%s%12s%s%-36s%s%s\n", "LastModifiedTime", "Size(B)", "  ", "StorageClass", "   ", "ETAG", "  ", "ObjectName")
 	}
 
@@ -695,7 +738,7 @@ func (lc *ListCommand) displayObjectsResult(lor oss.ListObjectsResult, bucket st
 }
 
 func (lc *ListCommand) displayObjectVersionsResult(lor oss.ListObjectVersionsResult, bucket string, shortFormat bool, directory bool, i int64, limitedNum *int64) int64 {
-	if i == 0 && (len(lor.ObjectDeleteMarkers) > 0 || len(lor.ObjectVersions) > 0) {
+	if i == 0 && lc.output == nil && (len(lor.ObjectDeleteMarkers) > 0 || len(lor.ObjectVersions) > 0) {
 		if directory {
 			fmt.Printf("%-6s%s%-30s%12s%s%12s%s%-36s%s%-66s%s%-10s%s%-13s%s%s\n", "COMMON-PREFIX", "  ", "LastModifiedTime", "Size(B)", "  ", "StorageClass", "  ", "ETAG", "  ", "VERSIONID", "  ", "IS-LATEST", "  ", "DELETE-MARKER", "  ", "ObjectName")
 		} else {
@@ -724,7 +767,9 @@ func (lc *ListCommand) showObjects(lor oss.ListObjectsResult, bucket string, sho
 			continue
 		}
 
-		if !shortFormat {
+		if lc.output != nil {
+			lc.output.write(objectEntry(bucket, object))
+		} else if !shortFormat {
 			fmt.Printf("%-30s%12d%s%12s%s%-36s%s%s\n", utcToLocalTime(object.LastModified), object.Size, "  ", object.StorageClass, "   ", strings.Trim(object.ETag, "\""), "  ", CloudURLToString(bucket, object.Key))
 		} else {
 			fmt.Printf("%s\n", CloudURLToString(bucket, object.Key))
@@ -748,7 +793,9 @@ func (lc *ListCommand) showObjectVersions(lor oss.ListObjectVersionsResult, buck
 		}
 
 		//COMMON-PREFIX LastModifiedTime  Size(B)  StorageClass  ETAG VERSIONID  IS-LATEST  DELETE-MARKER  ObjectName
-		if directory {
+		if lc.output != nil {
+			lc.output.write(deleteMarkerEntry(bucket, object))
+		} else if directory {
 			fmt.Printf("%-13t%s%-30s%12d%s%12s%s%-36s%s%-66s%s%-10t%s%-13t%s%s\n",
 				false, "  ",
 				utcToLocalTime(object.LastModified),
@@ -785,7 +832,9 @@ func (lc *ListCommand) showObjectVersions(lor oss.ListObjectVersionsResult, buck
 		}
 
 		//COMMON-PREFIX LastModifiedTime  Size(B)  StorageClass  ETAG VERSIONID  IS-LATEST  DELETE-MARKER  ObjectName
-		if directory {
+		if lc.output != nil {
+			lc.output.write(objectVersionEntry(bucket, object))
+		} else if directory {
 			fmt.Printf("%-13t%s%-30s%12d%s%12s%s%-36s%s%-66s%s%-10t%s%-13t%s%s\n",
 				false, "  ",
 				utcToLocalTime(object.LastModified),
@@ -827,7 +876,11 @@ func (lc *ListCommand) showDirectories(lor oss.ListObjectsResult, bucket string,
 			continue
 		}
 
-		fmt.Printf("%s\n", CloudURLToString(bucket, prefix))
+		if lc.output != nil {
+			lc.output.write(prefixEntry(bucket, prefix))
+		} else {
+			fmt.Printf("%s\n", CloudURLToString(bucket, prefix))
+		}
 		*limitedNum--
 		num++
 	}
@@ -846,15 +899,19 @@ func (lc *ListCommand) showDirectoriesVersion(lor oss.ListObjectVersionsResult,
 			continue
 		}
 
-		fmt.Printf("%-13t%s%-30s%12s%s%12s%s%-36s%s%-66s%s%-10s%s%-13s%s%s\n",
-			true, "  ",
-			"", "", "  ",
-			"", "  ",
-			"", "  ",
-			"", "  ",
-			"", "  ",
-			"", "  ",
-			CloudURLToString(bucket, prefix))
+		if lc.output != nil {
+			lc.output.write(prefixEntry(bucket, prefix))
+		} else {
+			fmt.Printf("%-13t%s%-30s%12s%s%12s%s%-36s%s%-66s%s%-10s%s%-13s%s%s\n",
+				true, "  ",
+				"", "", "  ",
+				"", "  ",
+				"", "  ",
+				"", "  ",
+				"", "  ",
+				"", "  ",
+				CloudURLToString(bucket, prefix))
+		}
 
 		*limitedNum--
 		num++
@@ -903,7 +960,9 @@ func (lc *ListCommand) listMultipartUploads(bucket *oss.Bucket, cloudURL CloudUR
 			break
 		}
 	}
-	fmt.Printf("UploadID Number is: %d\n", multipartNum)
+	if lc.output == nil {
+		fmt.Printf("UploadID Number is: %d\n", multipartNum)
+	}
 	return multipartNum, nil
 }
 
@@ -912,7 +971,7 @@ func (lc *ListCommand) displayMultipartUploadsResult(lmr oss.ListMultipartUpload
 		shortFormat = true
 	}
 
-	if i == 0 && len(lmr.Uploads) > 0 {
+	if i == 0 && lc.output == nil && len(lmr.Uploads) > 0 {
 		if shortFormat {
 			fmt.Printf("%-32s%s%s\n", "UploadID", FormatTAB, "ObjectName")
 		} else {
@@ -936,7 +995,9 @@ func (lc *ListCommand) showMultipartUploads(lmr oss.ListMultipartUploadResult, b
 			continue
 		}
 
-		if shortFormat {
+		if lc.output != nil {
+			lc.output.write(uploadEntry(bucket, upload))
+		} else if shortFormat {
 			fmt.Printf("%-32s%s%s\n", upload.UploadID, FormatTAB, CloudURLToString(bucket, upload.Key))
 		} else {
 			fmt.Printf("%-30s%s%-32s%s%s\n", utcToLocalTime(upload.Initiated), FormatTAB, upload.UploadID, FormatTAB, CloudURLToString(bucket, upload.Key))
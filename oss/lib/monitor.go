@@ -667,3 +667,88 @@ func (m *CPMonitor) getPrecent(snap *CPMonitorSnap) float64 {
 	}
 	return 0
 }
+
+// For restore --wait
+type RestoreWaitMonitorSnap struct {
+	restoredNum   int64
+	downloadedNum int64
+	errNum        int64
+	pendingNum    int64
+}
+
+/*
+ * Put same type variables together to make them 64bits alignment to avoid
+ * atomic.AddInt64() panic
+ * Please guarantee the alignment if you add new filed
+ */
+type RestoreWaitMonitor struct {
+	totalNum      int64
+	restoredNum   int64
+	downloadedNum int64
+	errNum        int64
+	download      bool
+	finish        bool
+	_             uint32 //Add padding to make sure the next data 64bits alignment
+}
+
+func (m *RestoreWaitMonitor) init(totalNum int64, download bool) {
+	m.totalNum = totalNum
+	m.restoredNum = 0
+	m.downloadedNum = 0
+	m.errNum = 0
+	m.download = download
+	m.finish = false
+}
+
+func (m *RestoreWaitMonitor) updateRestoredNum(num int64) {
+	atomic.AddInt64(&m.restoredNum, num)
+}
+
+func (m *RestoreWaitMonitor) updateDownloadedNum(num int64) {
+	atomic.AddInt64(&m.downloadedNum, num)
+}
+
+func (m *RestoreWaitMonitor) updateErrNum(num int64) {
+	atomic.AddInt64(&m.errNum, num)
+}
+
+func (m *RestoreWaitMonitor) getSnapshot() *RestoreWaitMonitorSnap {
+	var snap RestoreWaitMonitorSnap
+	snap.restoredNum = atomic.LoadInt64(&m.restoredNum)
+	snap.downloadedNum = atomic.LoadInt64(&m.downloadedNum)
+	snap.errNum = atomic.LoadInt64(&m.errNum)
+	snap.pendingNum = m.totalNum - snap.restoredNum - snap.errNum
+	return &snap
+}
+
+func (m *RestoreWaitMonitor) progressBar(finish bool, exitStat int) string {
+	if m.finish {
+		return ""
+	}
+	m.finish = m.finish || finish
+	snap := m.getSnapshot()
+	if !finish {
+		return getClearStr(fmt.Sprintf("Total %d objects. %s, In progress %d objects%s.", m.totalNum, m.getOKInfo(snap), snap.pendingNum, m.getErrInfo(snap)))
+	}
+	if exitStat != normalExit {
+		return getClearStr(fmt.Sprintf("Total %d objects. %s, when error happens.\n", m.totalNum, m.getOKInfo(snap)))
+	}
+	if snap.errNum == 0 {
+		return getClearStr(fmt.Sprintf("Succeed: Total %d objects. %s.\n", m.totalNum, m.getOKInfo(snap)))
+	}
+	return getClearStr(fmt.Sprintf("FinishWithError: Total %d objects. %s%s.\n", m.totalNum, m.getOKInfo(snap), m.getErrInfo(snap)))
+}
+
+func (m *RestoreWaitMonitor) getOKInfo(snap *RestoreWaitMonitorSnap) string {
+	if m.download {
+		return fmt.Sprintf("Restored %d objects, Downloaded %d objects", snap.restoredNum, snap.downloadedNum)
+	}
+	return fmt.Sprintf("Restored %d objects", snap.restoredNum)
+}
+
+func (m *RestoreWaitMonitor) getErrInfo(snap *RestoreWaitMonitorSnap) string {
+	if snap.errNum != 0 {
+		return fmt.Sprintf(", Error %d objects", snap.errNum)
+	}
+	return ""
+}
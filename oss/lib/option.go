@@ -62,6 +62,57 @@ var OptionMap = map[string]Option{
 	OptionForce:  Option{"-f", "--force", "", OptionTypeFlagTrue, "", "", "强制操作，不进行询问提示。", "operate silently without asking user to confirm the operation."},
 	OptionUpdate: Option{"-u", "--update", "", OptionTypeFlagTrue, "", "", "更新操作", "update"},
 	OptionDelete: Option{"", "--delete", "", OptionTypeFlagTrue, "", "", "删除操作", "delete"},
+	OptionChecksum: Option{"", "--checksum", "", OptionTypeFlagTrue, "", "",
+		"根据内容判断是否跳过上传、下载或拷贝：大小相同且crc64（缺失时比较Content-MD5）相同的文件或object不再传输，不比较lastModifiedTime。",
+		"decide whether to skip upload, download or copy by content: files or objects with the same size and crc64(Content-MD5 when crc64 is missing) are not transferred, lastModifiedTime is not compared."},
+	OptionSizeOnly: Option{"", "--size-only", "", OptionTypeFlagTrue, "", "",
+		"只根据大小判断是否跳过上传、下载或拷贝，大小相同的文件或object不再传输。",
+		"decide whether to skip upload, download or copy by size only, files or objects with the same size are not transferred."},
+	OptionDryRun: Option{"", "--dry-run", "", OptionTypeFlagTrue, "", "",
+		"只输出将要执行的操作，不上传、下载、拷贝或删除。每个文件或object输出一行JSON（action为upload、download、copy、skip或delete，并包含大小和跳过原因），最后一行为各操作的数量和大小汇总。",
+		"only print what would be done, without uploading, downloading, copying or deleting anything. Every file or object is a line of JSON(action upload, download, copy, skip or delete, with the size and the reason of skips), the last line sums up the count and size of every action."},
+	OptionWatch: Option{"", "--watch", "", OptionTypeFlagTrue, "", "",
+		"同步完成后继续监听本地目录，将新增或修改的文件增量上传，指定--delete时同时删除本地已删除文件对应的object，直到被中断（Ctrl+C）。",
+		"after the sync, keep watching the local directory and upload the files created or changed, with --delete the objects of deleted files are deleted too, until interrupted(Ctrl+C)."},
+	OptionFilesFrom: Option{"", "--files-from", "", OptionTypeString, "", "",
+		"只拷贝清单文件中列出的文件或object，清单每行一个相对源端的key，或一个JSON对象（src、dest及可选的meta、acl）。每项的结果写入--output-dir下的结果清单。",
+		"only copy the files or objects listed in the manifest file, a key relative to the source per line, or a JSON object(src, dest and optional meta, acl). The result of every item is written to a result manifest in --output-dir."},
+	OptionOutputFormat: Option{"", "--output-format", "", OptionTypeAlternative, fmt.Sprintf("%s/%s", OutputFormatJSON, OutputFormatJSONL), "",
+		"以JSON格式输出结果，取值为json或jsonl。json输出一个JSON数组（stat和du输出一个JSON对象），jsonl每行输出一个JSON对象，便于用jq等工具处理。",
+		"print the result as JSON, the value can be json or jsonl. json prints a JSON array(a JSON object for stat and du), jsonl prints a JSON object per line, to be processed by tools like jq."},
+	OptionPrefixDepth: Option{"", "--prefix-depth", "", OptionTypeInt64, "1", "",
+		"du命令按object名的前若干级目录分别统计数量和大小，取值为目录的级数。",
+		"the du command sums up the count and size of objects by the leading directories of their names as well, the value is the number of directory levels."},
+	OptionEncryptionKeyFile: Option{"", "--encryption-key-file", "", OptionTypeString, "", "",
+		"客户端加密使用的RSA主密钥文件（PEM格式）。上传时在本地用随机数据密钥以AES/CTR加密文件内容，数据密钥经主密钥加密后存入object的meta，与OSS SDK的客户端加密兼容；下载时解密。私钥可用于上传和下载，公钥只能用于上传。",
+		"the RSA master key file(PEM) of client-side encryption. The content of files is encrypted locally by AES/CTR with a random data key on upload, the data key wrapped by the master key is kept in the object meta, compatible with the client-side encryption of OSS SDKs, and decrypted on download. A private key works for upload and download, a public key for upload only."},
+	OptionWait: Option{"", "--wait", "", OptionTypeFlagTrue, "", "",
+		"发起恢复后等待object变为可读状态，按递增的间隔查询x-oss-restore状态并显示进度。",
+		"after the restore requests, wait for the objects to become readable, checking the x-oss-restore status at growing intervals and showing the progress."},
+	OptionThenDownload: Option{"", "--then-download", "", OptionTypeString, "", "",
+		"等待恢复（隐含--wait），每个object变为可读后立即下载到指定的本地目录。中断后再次执行相同命令时，已下载的object会被跳过，大文件从断点继续下载。",
+		"wait for the restore(implies --wait), and download every object to the local directory as soon as it becomes readable. When the same command is run again after an interruption, the objects downloaded are skipped, and big files resume from their checkpoints."},
+	OptionPostPolicy: Option{"", "--post-policy", "", OptionTypeFlagTrue, "", "",
+		"sign命令生成浏览器表单上传（PostObject）的policy和签名，而不是签名url。签名版本由--sign-version决定，支持v1和v4。",
+		"the sign command generates the policy and signature of browser form uploads(PostObject) instead of a signed url. The signature version follows --sign-version, v1 and v4 are supported."},
+	OptionContentLengthRange: Option{"", "--content-length-range", "", OptionTypeString, "", "",
+		"限制表单上传文件的大小范围，格式为min-max（单位为字节），只用于--post-policy。",
+		"the range of the size of files uploaded by the form, in the format of min-max(in bytes), only for --post-policy."},
+	OptionContentType: Option{"", "--content-type", "", OptionTypeString, "", "",
+		"限制表单上传文件的Content-Type，以/结尾时（如image/）为前缀匹配，只用于--post-policy。",
+		"the Content-Type of files uploaded by the form, a value ending with /(e.g. image/) matches as a prefix, only for --post-policy."},
+	OptionSimulate: Option{"", "--simulate", "", OptionTypeFlagTrue, "", "",
+		"模拟lifecycle规则，统计在--simulate-date时各规则转换存储类型、删除的object和取消的分片上传，不修改bucket。",
+		"simulate the lifecycle rules, report the objects transitioned or expired and the multipart uploads aborted by each rule on --simulate-date, the bucket is not changed."},
+	OptionSimulateDate: Option{"", "--simulate-date", "", OptionTypeString, "", "",
+		"--simulate模拟的日期，格式为2006-01-02或2006-01-02T15:04:05Z，默认为当前时间。",
+		"the date --simulate simulates at, in the format 2006-01-02 or 2006-01-02T15:04:05Z, the current time by default."},
+	OptionReportJSON: Option{"", "--report-json", "", OptionTypeString, "", "",
+		"将每个文件或object的传输结果以JSON lines格式写入指定文件，包含结果、字节数、耗时、重试次数和错误码，最后一行为汇总。",
+		"write the outcome of every file or object to the file as JSON lines, with the status, bytes, duration, retries and error code, the last line is the summary."},
+	OptionMetricsFile: Option{"", "--metrics-file", "", OptionTypeString, "", "",
+		"传输结束后将汇总以Prometheus文本格式写入指定文件，用于node exporter的textfile collector。",
+		"write the summary to the file in the Prometheus text format when the transfer ends, for the textfile collector of the node exporter."},
 	OptionOutputDir: Option{"", "--output-dir", DefaultOutputDir, OptionTypeString, "", "",
 		fmt.Sprintf("指定输出文件所在的目录，输出文件目前包含：cp命令批量拷贝文件出错时所产生的report文件（关于report文件更多信息，请参考cp命令帮助）。默认值为：当前目录下的%s目录。", DefaultOutputDir),
 		fmt.Sprintf("The option specify the directory to place output file in, output file contains: report file generated by cp command when error happens of batch copy operation(for more information about report file, see help of cp command). The default value of the option is: %s directory in current directory.", DefaultOutputDir)},
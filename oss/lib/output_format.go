@@ -0,0 +1,288 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const (
+	restoreInProgress = "in-progress"
+	restoreRestored   = "restored"
+)
+
+// listEntry is an object, object version, delete marker, directory, multipart
+// upload or bucket printed by --output-format, Type tells them apart.
+type listEntry struct {
+	Type          string `json:"type"`
+	URL           string `json:"url"`
+	Bucket        string `json:"bucket"`
+	Key           string `json:"key,omitempty"`
+	Size          *int64 `json:"size,omitempty"`
+	ETag          string `json:"etag,omitempty"`
+	StorageClass  string `json:"storageClass,omitempty"`
+	LastModified  string `json:"lastModified,omitempty"`
+	VersionID     string `json:"versionId,omitempty"`
+	IsLatest      *bool  `json:"isLatest,omitempty"`
+	RestoreStatus string `json:"restoreStatus,omitempty"`
+	RestoreExpiry string `json:"restoreExpiry,omitempty"`
+	UploadID      string `json:"uploadId,omitempty"`
+	Initiated     string `json:"initiated,omitempty"`
+	Region        string `json:"region,omitempty"`
+	CreationTime  string `json:"creationTime,omitempty"`
+}
+
+// objectStatEntry is the stat of an object printed by --output-format,
+// Headers are the response headers as they are.
+type objectStatEntry struct {
+	listEntry
+	ACL     string            `json:"acl"`
+	Owner   string            `json:"owner"`
+	Headers map[string]string `json:"headers"`
+}
+
+// bucketStatEntry is the stat of a bucket printed by --output-format.
+type bucketStatEntry struct {
+	Name                   string `json:"name"`
+	Location               string `json:"location"`
+	CreationTime           string `json:"creationTime"`
+	ExtranetEndpoint       string `json:"extranetEndpoint"`
+	IntranetEndpoint       string `json:"intranetEndpoint"`
+	ACL                    string `json:"acl"`
+	Owner                  string `json:"owner"`
+	StorageClass           string `json:"storageClass"`
+	RedundancyType         string `json:"redundancyType,omitempty"`
+	SSEAlgorithm           string `json:"sseAlgorithm,omitempty"`
+	KMSMasterKeyID         string `json:"kmsMasterKeyId,omitempty"`
+	KMSDataEncryption      string `json:"kmsDataEncryption,omitempty"`
+	TransferAcceleration   string `json:"transferAcceleration"`
+	CrossRegionReplication string `json:"crossRegionReplication"`
+	AccessMonitor          string `json:"accessMonitor,omitempty"`
+}
+
+// jsonWriter prints entries one by one, as the elements of a JSON array, or
+// as JSON lines.
+type jsonWriter struct {
+	out    io.Writer
+	format string
+	count  int64
+}
+
+// getOutputFormat returns json, jsonl, or empty for the columns of text.
+func getOutputFormat(options OptionMapType) string {
+	format, _ := GetString(OptionOutputFormat, options)
+	return strings.ToLower(format)
+}
+
+// newJSONWriter returns nil if format is empty, the text is printed as
+// before then.
+func newJSONWriter(out io.Writer, format string) *jsonWriter {
+	if format == "" {
+		return nil
+	}
+	return &jsonWriter{out: out, format: format}
+}
+
+func (w *jsonWriter) write(v interface{}) {
+	if w.format == OutputFormatJSONL {
+		fmt.Fprintf(w.out, "%s\n", marshalJSON(v, false, ""))
+	} else if w.count == 0 {
+		fmt.Fprintf(w.out, "[\n  %s", marshalJSON(v, true, "  "))
+	} else {
+		fmt.Fprintf(w.out, ",\n  %s", marshalJSON(v, true, "  "))
+	}
+	w.count++
+}
+
+// close ends the JSON array, an empty one if nothing is written.
+func (w *jsonWriter) close() {
+	if w.format == OutputFormatJSONL {
+		return
+	}
+	if w.count == 0 {
+		fmt.Fprintf(w.out, "[]\n")
+	} else {
+		fmt.Fprintf(w.out, "\n]\n")
+	}
+}
+
+// printJSON prints v alone, indented for json, on a line for jsonl.
+func printJSON(out io.Writer, format string, v interface{}) {
+	fmt.Fprintf(out, "%s\n", marshalJSON(v, format != OutputFormatJSONL, ""))
+}
+
+// marshalJSON keeps the characters of object names like & and < as they are.
+// If indented, prefix is put before every line but the first.
+func marshalJSON(v interface{}, indent bool, prefix string) []byte {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if indent {
+		encoder.SetIndent(prefix, "  ")
+	}
+	encoder.Encode(v)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+func formatJSONTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseRestoreInfo parses the RestoreInfo of a listed object or the
+// x-oss-restore header, e.g. ongoing-request="false", expiry-date="Sun, 16
+// Apr 2017 08:12:33 GMT", into in-progress or restored and the expiry time.
+func parseRestoreInfo(info string) (string, string) {
+	if info == "" {
+		return "", ""
+	}
+	status, expiry := "", ""
+	for _, item := range strings.Split(info, "\",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(kv[1]), "\"")
+		switch strings.ToLower(kv[0]) {
+		case "ongoing-request":
+			if value == "true" {
+				status = restoreInProgress
+			} else {
+				status = restoreRestored
+			}
+		case "expiry-date":
+			expiry = value
+			if t, err := time.Parse(http.TimeFormat, value); err == nil {
+				expiry = formatJSONTime(t)
+			}
+		}
+	}
+	return status, expiry
+}
+
+func objectEntry(bucket string, object oss.ObjectProperties) listEntry {
+	size := object.Size
+	entry := listEntry{
+		Type:         "object",
+		URL:          CloudURLToString(bucket, object.Key),
+		Bucket:       bucket,
+		Key:          object.Key,
+		Size:         &size,
+		ETag:         strings.Trim(object.ETag, "\""),
+		StorageClass: object.StorageClass,
+		LastModified: formatJSONTime(object.LastModified),
+	}
+	entry.RestoreStatus, entry.RestoreExpiry = parseRestoreInfo(object.RestoreInfo)
+	return entry
+}
+
+func objectVersionEntry(bucket string, object oss.ObjectVersionProperties) listEntry {
+	size, isLatest := object.Size, object.IsLatest
+	entry := listEntry{
+		Type:         "version",
+		URL:          CloudURLToString(bucket, object.Key),
+		Bucket:       bucket,
+		Key:          object.Key,
+		Size:         &size,
+		ETag:         strings.Trim(object.ETag, "\""),
+		StorageClass: object.StorageClass,
+		LastModified: formatJSONTime(object.LastModified),
+		VersionID:    object.VersionId,
+		IsLatest:     &isLatest,
+	}
+	entry.RestoreStatus, entry.RestoreExpiry = parseRestoreInfo(object.RestoreInfo)
+	return entry
+}
+
+func deleteMarkerEntry(bucket string, object oss.ObjectDeleteMarkerProperties) listEntry {
+	isLatest := object.IsLatest
+	return listEntry{
+		Type:         "deleteMarker",
+		URL:          CloudURLToString(bucket, object.Key),
+		Bucket:       bucket,
+		Key:          object.Key,
+		LastModified: formatJSONTime(object.LastModified),
+		VersionID:    object.VersionId,
+		IsLatest:     &isLatest,
+	}
+}
+
+func prefixEntry(bucket, prefix string) listEntry {
+	return listEntry{Type: "prefix", URL: CloudURLToString(bucket, prefix), Bucket: bucket, Key: prefix}
+}
+
+func uploadEntry(bucket string, upload oss.UncompletedUpload) listEntry {
+	return listEntry{
+		Type:      "upload",
+		URL:       CloudURLToString(bucket, upload.Key),
+		Bucket:    bucket,
+		Key:       upload.Key,
+		UploadID:  upload.UploadID,
+		Initiated: formatJSONTime(upload.Initiated),
+	}
+}
+
+func bucketEntry(bucket oss.BucketProperties) listEntry {
+	return listEntry{
+		Type:         "bucket",
+		URL:          CloudURLToString(bucket.Name, ""),
+		Bucket:       bucket.Name,
+		StorageClass: bucket.StorageClass,
+		Region:       bucket.Location,
+		CreationTime: formatJSONTime(bucket.CreationDate),
+	}
+}
+
+func newObjectStatEntry(cloudURL CloudURL, props http.Header, headers map[string]string, goar oss.GetObjectACLResult) objectStatEntry {
+	entry := objectStatEntry{
+		listEntry: listEntry{
+			Type:         "object",
+			URL:          CloudURLToString(cloudURL.bucket, cloudURL.object),
+			Bucket:       cloudURL.bucket,
+			Key:          cloudURL.object,
+			ETag:         strings.Trim(props.Get(oss.HTTPHeaderEtag), "\""),
+			StorageClass: props.Get(oss.HTTPHeaderOssStorageClass),
+			VersionID:    oss.GetVersionId(props),
+		},
+		ACL:     goar.ACL,
+		Owner:   goar.Owner.ID,
+		Headers: headers,
+	}
+	if size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64); err == nil {
+		entry.Size = &size
+	}
+	if lm, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err == nil {
+		entry.LastModified = formatJSONTime(lm)
+	}
+	entry.RestoreStatus, entry.RestoreExpiry = parseRestoreInfo(props.Get("x-oss-restore"))
+	return entry
+}
+
+func newBucketStatEntry(info oss.BucketInfo) bucketStatEntry {
+	return bucketStatEntry{
+		Name:                   info.Name,
+		Location:               info.Location,
+		CreationTime:           formatJSONTime(info.CreationDate),
+		ExtranetEndpoint:       info.ExtranetEndpoint,
+		IntranetEndpoint:       info.IntranetEndpoint,
+		ACL:                    info.ACL,
+		Owner:                  info.Owner.ID,
+		StorageClass:           info.StorageClass,
+		RedundancyType:         info.RedundancyType,
+		SSEAlgorithm:           info.SseRule.SSEAlgorithm,
+		KMSMasterKeyID:         info.SseRule.KMSMasterKeyID,
+		KMSDataEncryption:      info.SseRule.KMSDataEncryption,
+		TransferAcceleration:   info.TransferAcceleration,
+		CrossRegionReplication: info.CrossRegionReplication,
+		AccessMonitor:          info.AccessMonitor,
+	}
+}
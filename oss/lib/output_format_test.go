@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONWriter(t *testing.T) {
+	assert.Nil(t, newJSONWriter(new(bytes.Buffer), ""))
+
+	out := new(bytes.Buffer)
+	w := newJSONWriter(out, OutputFormatJSON)
+	w.close()
+	assert.Equal(t, "[]\n", out.String())
+
+	out.Reset()
+	w = newJSONWriter(out, OutputFormatJSON)
+	w.write(prefixEntry("bucket", "a&b/"))
+	w.write(prefixEntry("bucket", "c/"))
+	w.close()
+	var entries []listEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+	assert.Equal(t, []listEntry{prefixEntry("bucket", "a&b/"), prefixEntry("bucket", "c/")}, entries)
+	assert.Contains(t, out.String(), "\n  {\n    \"type\": \"prefix\",")
+	assert.Contains(t, out.String(), "oss://bucket/a&b/")
+
+	out.Reset()
+	w = newJSONWriter(out, OutputFormatJSONL)
+	w.write(prefixEntry("bucket", "a/"))
+	w.write(prefixEntry("bucket", "b/"))
+	w.close()
+	assert.Equal(t, `{"type":"prefix","url":"oss://bucket/a/","bucket":"bucket","key":"a/"}
+{"type":"prefix","url":"oss://bucket/b/","bucket":"bucket","key":"b/"}
+`, out.String())
+}
+
+func TestParseRestoreInfo(t *testing.T) {
+	status, expiry := parseRestoreInfo("")
+	assert.Equal(t, "", status)
+	assert.Equal(t, "", expiry)
+
+	status, expiry = parseRestoreInfo(`ongoing-request="true"`)
+	assert.Equal(t, restoreInProgress, status)
+	assert.Equal(t, "", expiry)
+
+	status, expiry = parseRestoreInfo(`ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`)
+	assert.Equal(t, restoreRestored, status)
+	assert.Equal(t, "2017-04-16T08:12:33Z", expiry)
+}
+
+func TestListEntries(t *testing.T) {
+	modified := time.Date(2019, 5, 30, 14, 23, 51, 0, time.FixedZone("CST", 8*3600))
+	entry := objectEntry("bucket", oss.ObjectProperties{
+		Key:          "dir/a.txt",
+		Size:         0,
+		ETag:         `"4A902D176BE0EE4224BC196BBB8CCC69"`,
+		StorageClass: "Archive",
+		LastModified: modified,
+		RestoreInfo:  `ongoing-request="true"`,
+	})
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"object","url":"oss://bucket/dir/a.txt","bucket":"bucket","key":"dir/a.txt","size":0,
+		"etag":"4A902D176BE0EE4224BC196BBB8CCC69","storageClass":"Archive","lastModified":"2019-05-30T06:23:51Z",
+		"restoreStatus":"in-progress"}`, string(data))
+
+	data, err = json.Marshal(objectVersionEntry("bucket", oss.ObjectVersionProperties{Key: "a", Size: 3, VersionId: "v1", LastModified: modified}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"version","url":"oss://bucket/a","bucket":"bucket","key":"a","size":3,
+		"lastModified":"2019-05-30T06:23:51Z","versionId":"v1","isLatest":false}`, string(data))
+
+	data, err = json.Marshal(deleteMarkerEntry("bucket", oss.ObjectDeleteMarkerProperties{Key: "a", VersionId: "v2", IsLatest: true}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"deleteMarker","url":"oss://bucket/a","bucket":"bucket","key":"a","versionId":"v2","isLatest":true}`, string(data))
+}
+
+func TestObjectStatEntry(t *testing.T) {
+	props := http.Header{}
+	props.Set("Content-Length", "3")
+	props.Set("Etag", `"ABC"`)
+	props.Set("Last-Modified", "Sun, 16 Apr 2017 08:12:33 GMT")
+	props.Set("X-Oss-Storage-Class", "Archive")
+	props.Set("X-Oss-Version-Id", "v1")
+	props.Set("X-Oss-Restore", `ongoing-request="false", expiry-date="Mon, 17 Apr 2017 08:12:33 GMT"`)
+	var goar oss.GetObjectACLResult
+	goar.ACL = "private"
+	goar.Owner.ID = "owner"
+
+	entry := newObjectStatEntry(CloudURL{bucket: "bucket", object: "a"}, props, map[string]string{"Etag": `"ABC"`}, goar)
+	size := int64(3)
+	assert.Equal(t, &size, entry.Size)
+	assert.Equal(t, "ABC", entry.ETag)
+	assert.Equal(t, "2017-04-16T08:12:33Z", entry.LastModified)
+	assert.Equal(t, "Archive", entry.StorageClass)
+	assert.Equal(t, "v1", entry.VersionID)
+	assert.Equal(t, restoreRestored, entry.RestoreStatus)
+	assert.Equal(t, "2017-04-17T08:12:33Z", entry.RestoreExpiry)
+
+	out := new(bytes.Buffer)
+	printJSON(out, OutputFormatJSONL, entry)
+	assert.Equal(t, `{"type":"object","url":"oss://bucket/a","bucket":"bucket","key":"a","size":3,"etag":"ABC",`+
+		`"storageClass":"Archive","lastModified":"2017-04-16T08:12:33Z","versionId":"v1","restoreStatus":"restored",`+
+		`"restoreExpiry":"2017-04-17T08:12:33Z","acl":"private","owner":"owner","headers":{"Etag":"\"ABC\""}}`+"\n", out.String())
+}
+
+func TestListShowObjectsJSON(t *testing.T) {
+	out := new(bytes.Buffer)
+	var lc ListCommand
+	lc.output = newJSONWriter(out, OutputFormatJSONL)
+	limitedNum := int64(2)
+	lor := oss.ListObjectsResult{
+		Objects:        []oss.ObjectProperties{{Key: "a"}, {Key: "b"}, {Key: "c"}},
+		CommonPrefixes: []string{"d/"},
+	}
+	assert.Equal(t, int64(2), lc.displayObjectsResult(lor, "bucket", false, false, 0, &limitedNum))
+	assert.Equal(t, `{"type":"object","url":"oss://bucket/a","bucket":"bucket","key":"a","size":0}
+{"type":"object","url":"oss://bucket/b","bucket":"bucket","key":"b","size":0}
+`, out.String())
+
+	out.Reset()
+	limitedNum = -1
+	assert.Equal(t, int64(4), lc.displayObjectsResult(lor, "bucket", true, true, 0, &limitedNum))
+	assert.Contains(t, out.String(), `{"type":"prefix","url":"oss://bucket/d/","bucket":"bucket","key":"d/"}`)
+}
+
+func TestDuPrefix(t *testing.T) {
+	assert.Equal(t, "logs/2023/", duPrefix("logs/", "logs/2023/01/a.log", 1))
+	assert.Equal(t, "logs/2023/01/", duPrefix("logs/", "logs/2023/01/a.log", 2))
+	assert.Equal(t, "logs/2023/01/", duPrefix("logs/", "logs/2023/01/a.log", 5))
+	assert.Equal(t, "logs/", duPrefix("logs/", "logs/a.log", 1))
+	assert.Equal(t, "logs-2023/", duPrefix("logs", "logs-2023/a.log", 1))
+	assert.Equal(t, "", duPrefix("", "a.log", 1))
+}
+
+func TestDuPrintJSON(t *testing.T) {
+	var duc DuCommand
+	duc.duOption.countTypeMap = map[string]int64{}
+	duc.duOption.sizeTypeMap = map[string]int64{}
+	duc.duOption.prefixCountMap = map[string]int64{}
+	duc.duOption.prefixSizeMap = map[string]int64{}
+	duc.duOption.bucketName = "bucket"
+	duc.duOption.object = "logs/"
+	duc.duOption.prefixDepth = 1
+	duc.addObject("logs/2023/a.log", "Standard", 3)
+	duc.addObject("logs/2023/b.log", "IA", 4)
+	duc.addObject("logs/2024/c.log", "Standard", 5)
+	duc.duOption.totalPartCount = 1
+	duc.duOption.sumPartSize = 10
+
+	out := new(bytes.Buffer)
+	duc.duOption.outputFormat = OutputFormatJSON
+	duc.printJSON(out)
+	var summary duSummary
+	require.NoError(t, json.Unmarshal(out.Bytes(), &summary))
+	assert.Equal(t, duSummary{
+		Bucket:      "bucket",
+		Prefix:      "logs/",
+		ObjectCount: 3,
+		ObjectSize:  12,
+		PartCount:   1,
+		PartSize:    10,
+		TotalSize:   22,
+		StorageClasses: []duTotal{
+			{StorageClass: "IA", Count: 1, Size: 4},
+			{StorageClass: "Standard", Count: 2, Size: 8},
+		},
+		Prefixes: []duTotal{
+			{Prefix: "logs/2023/", Count: 2, Size: 7},
+			{Prefix: "logs/2024/", Count: 1, Size: 5},
+		},
+	}, summary)
+
+	out.Reset()
+	duc.duOption.outputFormat = OutputFormatJSONL
+	duc.printJSON(out)
+	assert.Equal(t, `{"type":"storageClass","storageClass":"IA","count":1,"size":4}
+{"type":"storageClass","storageClass":"Standard","count":2,"size":8}
+{"type":"prefix","prefix":"logs/2023/","count":2,"size":7}
+{"type":"prefix","prefix":"logs/2024/","count":1,"size":5}
+{"type":"total","bucket":"bucket","prefix":"logs/","objectCount":3,"objectSize":12,"partCount":1,"partSize":10,"totalSize":22}
+`, out.String())
+}
@@ -0,0 +1,168 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const (
+	postSignatureVersionV4 = "OSS4-HMAC-SHA256"
+	postV4DateFormat       = "20060102T150405Z"
+)
+
+// postPolicyConfig is what the policy of a browser PostObject form allows:
+// the keys with keyPrefix, the sizes in [minSize, maxSize] if maxSize is not
+// negative, and contentType, or the content types with contentType as the
+// prefix if it ends with "/".
+type postPolicyConfig struct {
+	keyPrefix   string
+	minSize     int64
+	maxSize     int64
+	contentType string
+	expiration  time.Time
+}
+
+// postPolicyForm is the url and the fields of a PostObject form, the file
+// field comes after the fields.
+type postPolicyForm struct {
+	URL        string            `json:"url"`
+	Expiration string            `json:"expiration"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// parseContentLengthRange parses min-max of --content-length-range.
+func parseContentLengthRange(value string) (int64, int64, error) {
+	if value == "" {
+		return 0, -1, nil
+	}
+	sli := strings.SplitN(value, "-", 2)
+	if len(sli) != 2 {
+		return 0, 0, fmt.Errorf("invalid --content-length-range %s, the format is min-max", value)
+	}
+	min, err := strconv.ParseInt(strings.TrimSpace(sli[0]), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --content-length-range %s, the format is min-max", value)
+	}
+	max, err := strconv.ParseInt(strings.TrimSpace(sli[1]), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --content-length-range %s, the format is min-max", value)
+	}
+	if min < 0 || min > max {
+		return 0, 0, fmt.Errorf("invalid --content-length-range %s, min must be in 0-max", value)
+	}
+	return min, max, nil
+}
+
+// postObjectURL is the url of the bucket the form is posted to.
+func postObjectURL(config *oss.Config, bucketName string) (string, error) {
+	endpoint := config.Endpoint
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if config.IsCname {
+		return fmt.Sprintf("%s://%s/", u.Scheme, u.Host), nil
+	}
+	if config.IsPathStyle || net.ParseIP(u.Hostname()) != nil {
+		return fmt.Sprintf("%s://%s/%s/", u.Scheme, u.Host, bucketName), nil
+	}
+	return fmt.Sprintf("%s://%s.%s/", u.Scheme, bucketName, u.Host), nil
+}
+
+// newPostPolicyForm signs the policy with the credentials of config, by the
+// V4 signature if config signs V4 requests, else by the V1 signature.
+func newPostPolicyForm(config *oss.Config, bucketName string, policy postPolicyConfig, now time.Time) (*postPolicyForm, error) {
+	formURL, err := postObjectURL(config, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	credentials := config.GetCredentials()
+	fields := map[string]string{"key": policy.keyPrefix + "${filename}"}
+
+	conditions := []interface{}{
+		map[string]string{"bucket": bucketName},
+		[]interface{}{"starts-with", "$key", policy.keyPrefix},
+	}
+	if policy.maxSize >= 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", policy.minSize, policy.maxSize})
+	}
+	if strings.HasSuffix(policy.contentType, "/") {
+		conditions = append(conditions, []interface{}{"starts-with", "$content-type", policy.contentType})
+	} else if policy.contentType != "" {
+		conditions = append(conditions, []interface{}{"eq", "$content-type", policy.contentType})
+		fields["content-type"] = policy.contentType
+	}
+	if token := credentials.GetSecurityToken(); token != "" {
+		conditions = append(conditions, map[string]string{"x-oss-security-token": token})
+		fields["x-oss-security-token"] = token
+	}
+
+	v4 := config.AuthVersion == oss.AuthV4
+	var credential, date string
+	if v4 {
+		if config.Region == "" {
+			return nil, fmt.Errorf("the V4 signature of post policy needs the region, please set --region")
+		}
+		date = now.UTC().Format(postV4DateFormat)
+		credential = fmt.Sprintf("%s/%s/%s/%s/aliyun_v4_request", credentials.GetAccessKeyID(), date[:8], config.Region, config.GetSignProduct())
+		conditions = append(conditions,
+			map[string]string{"x-oss-signature-version": postSignatureVersionV4},
+			map[string]string{"x-oss-credential": credential},
+			map[string]string{"x-oss-date": date})
+	}
+
+	expiration := policy.expiration.UTC().Format("2006-01-02T15:04:05.000Z")
+	data, err := json.Marshal(map[string]interface{}{"expiration": expiration, "conditions": conditions})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(data)
+	fields["policy"] = encodedPolicy
+
+	if v4 {
+		fields["x-oss-signature-version"] = postSignatureVersionV4
+		fields["x-oss-credential"] = credential
+		fields["x-oss-date"] = date
+		fields["x-oss-signature"] = postSignatureV4(credentials.GetAccessKeySecret(), date[:8], config.Region, config.GetSignProduct(), encodedPolicy)
+	} else {
+		fields["OSSAccessKeyId"] = credentials.GetAccessKeyID()
+		fields["Signature"] = postSignatureV1(credentials.GetAccessKeySecret(), encodedPolicy)
+	}
+	return &postPolicyForm{URL: formURL, Expiration: expiration, Fields: fields}, nil
+}
+
+// postSignatureV1 signs the base64 policy with the secret.
+func postSignatureV1(secret, encodedPolicy string) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write([]byte(encodedPolicy))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// postSignatureV4 signs the base64 policy with the signing key derived from
+// the secret, the date, the region and the product.
+func postSignatureV4(secret, day, region, product, encodedPolicy string) string {
+	key := []byte("aliyun_v4" + secret)
+	for _, v := range []string{day, region, product, "aliyun_v4_request"} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(v))
+		key = h.Sum(nil)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(encodedPolicy))
+	return hex.EncodeToString(h.Sum(nil))
+}
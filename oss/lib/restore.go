@@ -28,7 +28,7 @@ var specChineseRestore = SpecText{
 	paramText: "cloud_url [local_xml_file] [options]",
 
 	syntaxText: ` 
-    ossutil restore cloud_url [local_xml_file] [--encoding-type url] [-r] [-f] [--output-dir=odir] [--version-id versionId] [--payer requester] [-c file] [--object-file file] [--snapshot-path dir] [--disable-ignore-error]
+    ossutil restore cloud_url [local_xml_file] [--encoding-type url] [-r] [-f] [--output-dir=odir] [--version-id versionId] [--payer requester] [-c file] [--object-file file] [--snapshot-path dir] [--disable-ignore-error] [--wait] [--then-download dir] [--checkpoint-dir=cdir]
 `,
 
 	detailHelpText: ` 
@@ -83,6 +83,19 @@ var specChineseRestore = SpecText{
     object1
     object2
     object3
+
+--wait, --then-download
+
+    指定--wait时，ossutil在发起恢复后等待成功发起恢复的object变为可读状态：查询每个object的
+    x-oss-restore状态，查询间隔从30秒开始逐次加倍，最长10分钟，并显示已恢复、恢复中和出错的
+    object数量。解冻状态的object和非冷冻存储类型的object视为可读，冷冻状态但没有恢复中的object
+    视为出错。
+
+    指定--then-download dir时（隐含--wait），每个object变为可读后立即下载到本地目录dir，本地
+    文件名与cp命令下载时相同（去掉url中最后一个"/"及之前的部分），同名文件会被覆盖。已下载的
+    object记录在--checkpoint-dir下的状态文件中，大文件的分片下载同样在该目录下记录断点；恢复可能
+    持续数小时，中断后再次执行相同的命令，已下载的object不会再次恢复和下载，大文件从断点继续下
+    载。全部成功后删除状态文件。
 `,
 
 	sampleText: ` 
@@ -94,6 +107,8 @@ var specChineseRestore = SpecText{
     6) ossutil restore oss://bucket-restore/object-prefix -r -f local_xml_file
     7) ossutil restore oss://bucket-restore --object-file file -f local_xml_file
     8) ossutil restore oss://bucket-restore --object-file file --snapshot-path dir -f local_xml_file
+    9) ossutil restore oss://bucket-restore/object-store --wait
+    10) ossutil restore oss://bucket-restore/object-prefix -r -f --then-download ./restored
 `,
 }
 
@@ -104,7 +119,7 @@ var specEnglishRestore = SpecText{
 	paramText: "cloud_url [local_xml_file] [options]",
 
 	syntaxText: ` 
-    ossutil restore cloud_url [local_xml_file] [--encoding-type url] [-r] [-f] [--output-dir=odir] [--version-id versionId] [--payer requester] [-c file] [--object-file file] [--snapshot-path dir] [--disable-ignore-error]
+    ossutil restore cloud_url [local_xml_file] [--encoding-type url] [-r] [-f] [--output-dir=odir] [--version-id versionId] [--payer requester] [-c file] [--object-file file] [--snapshot-path dir] [--disable-ignore-error] [--wait] [--then-download dir] [--checkpoint-dir=cdir]
 `,
 
 	detailHelpText: ` 
@@ -165,6 +180,22 @@ Usage:
     object1
     object2
     object3
+
+--wait, --then-download
+
+    With --wait, ossutil waits for the objects restore requested successfully to become readable 
+    after the restore requests: the x-oss-restore status of every object is checked at an interval 
+    starting from 30 seconds and doubled every time, at most 10 minutes, and the number of objects 
+    restored, in progress and failed is shown. Restored objects and objects not in a frozen storage 
+    class are readable, frozen objects not being restored are failures.
+
+    With --then-download dir(implies --wait), every object is downloaded to the local directory dir 
+    as soon as it becomes readable, the file names are the ones of cp download(the part of the url 
+    up to its last "/" is left out), existing files are overwritten. The objects downloaded are 
+    recorded in a state file in --checkpoint-dir, where the multipart downloads of big files keep 
+    their checkpoints too. A restore can take hours, when the same command is run again after an 
+    interruption, the objects downloaded are neither restored nor downloaded again, and big files 
+    resume from their checkpoints. The state file is removed when all succeed.
 `,
 
 	sampleText: ` 
@@ -176,6 +207,8 @@ Usage:
     6) ossutil restore oss://bucket-restore/object-prefix -r -f local_xml_file
     7) ossutil restore oss://bucket-restore --object-file file -f local_xml_file
     8) ossutil restore oss://bucket-restore --object-file file --snapshot-path dir -f local_xml_file
+    9) ossutil restore oss://bucket-restore/object-store --wait
+    10) ossutil restore oss://bucket-restore/object-prefix -r -f --then-download ./restored
 `,
 }
 
@@ -190,6 +223,7 @@ type RestoreCommand struct {
 	configXml     string
 	hasObjFile    bool
 	objFilePath   string
+	waiter        *restoreWaiter
 }
 
 var restoreCommand = RestoreCommand{
@@ -239,6 +273,9 @@ var restoreCommand = RestoreCommand{
 			OptionCloudBoxID,
 			OptionForcePathStyle,
 			OptionInsecure,
+			OptionWait,
+			OptionThenDownload,
+			OptionCheckpointDir,
 		},
 	},
 }
@@ -292,6 +329,14 @@ func (rc *RestoreCommand) RunCommand() error {
 	if err = rc.checkOptions(cloudURL, recursive, force, versionid, objFileXml); err != nil {
 		return err
 	}
+	wait, _ := GetBool(OptionWait, rc.command.options)
+	thenDownload, _ := GetString(OptionThenDownload, rc.command.options)
+	if wait || thenDownload != "" {
+		cpDir, _ := GetString(OptionCheckpointDir, rc.command.options)
+		if rc.waiter, err = newRestoreWaiter(cloudURL, thenDownload, cpDir); err != nil {
+			return err
+		}
+	}
 	bucket, err := rc.command.ossBucket(cloudURL.bucket)
 	if err != nil {
 		return err
@@ -317,7 +362,11 @@ func (rc *RestoreCommand) RunCommand() error {
 		return rc.batchRestoreObjects(bucket, cloudURL, recursive)
 	} else {
 		if !recursive {
-			return rc.ossRestoreObject(bucket, cloudURL.object, versionid, false)
+			if err := rc.ossRestoreObject(bucket, cloudURL.object, versionid, false); err != nil || rc.waiter == nil {
+				return err
+			}
+			rc.waiter.add(cloudURL.object, versionid)
+			return rc.waitRestore(bucket)
 		}
 
 		return rc.batchRestoreObjects(bucket, cloudURL, recursive)
@@ -439,9 +488,14 @@ func (rc *RestoreCommand) batchRestoreObjects(bucket *oss.Bucket, cloudURL Cloud
 	defer rc.reOption.reporter.Clear()
 
 	if rc.hasObjFile {
-		return rc.restoreObjectsFromFile(bucket, cloudURL, rc.objFilePath)
+		err = rc.restoreObjectsFromFile(bucket, cloudURL, rc.objFilePath)
+	} else {
+		err = rc.restoreObjects(bucket, cloudURL)
 	}
-	return rc.restoreObjects(bucket, cloudURL)
+	if err != nil || rc.waiter == nil {
+		return err
+	}
+	return rc.waitRestore(bucket)
 }
 
 func (rc *RestoreCommand) restoreObjects(bucket *oss.Bucket, cloudURL CloudURL) error {
@@ -543,7 +597,13 @@ func (rc *RestoreCommand) restoreConsumer(bucket *oss.Bucket, cloudURL CloudURL,
 }
 
 func (rc *RestoreCommand) restoreObjectWithReport(bucket *oss.Bucket, object string) error {
-	err := rc.ossRestoreObject(bucket, object, "", true)
+	var err error
+	if rc.waiter != nil && rc.waiter.downloaded(object) {
+		rc.updateSkip(1)
+		LogInfo("restore obj skip: %s, downloaded already\n", object)
+	} else if err = rc.ossRestoreObject(bucket, object, "", true); err == nil && rc.waiter != nil {
+		rc.waiter.add(object, "")
+	}
 	rc.command.updateMonitor(err, &rc.monitor)
 	msg := fmt.Sprintf("restore %s", CloudURLToString(bucket.BucketName, object))
 	rc.command.report(msg, err, &rc.reOption)
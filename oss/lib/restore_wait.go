@@ -0,0 +1,295 @@
+package lib
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// restoreWaitInterval is the first interval to check the restore status of
+// objects at, it doubles every round up to restoreWaitMaxInterval.
+var (
+	restoreWaitInterval    = 30 * time.Second
+	restoreWaitMaxInterval = 10 * time.Minute
+)
+
+type restoreWaitObject struct {
+	key       string
+	versionId string
+}
+
+// restoreWaitState is kept in --checkpoint-dir for --then-download. The
+// objects downloaded are neither restored nor downloaded again when the
+// command is run again after an interruption.
+type restoreWaitState struct {
+	Downloaded map[string]string `json:"downloaded"`
+}
+
+// restoreWaiter waits for the objects restore requested to become readable,
+// and downloads them one by one as they do if downloadDir is set.
+type restoreWaiter struct {
+	monitor     RestoreWaitMonitor //Put first for atomic op on some fileds
+	cloudURL    CloudURL
+	downloadDir string
+	cpDir       string
+	statePath   string
+	state       restoreWaitState
+	objects     []restoreWaitObject
+	mu          sync.Mutex
+}
+
+func newRestoreWaiter(cloudURL CloudURL, downloadDir, cpDir string) (*restoreWaiter, error) {
+	w := &restoreWaiter{cloudURL: cloudURL, cpDir: cpDir, state: restoreWaitState{Downloaded: map[string]string{}}}
+	if downloadDir == "" {
+		return w, nil
+	}
+
+	absDir, err := filepath.Abs(downloadDir)
+	if err != nil {
+		return nil, err
+	}
+	if f, err := os.Stat(absDir); err == nil && !f.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory, --then-download needs a directory", downloadDir)
+	}
+	w.downloadDir = absDir
+	sum := md5.Sum([]byte(cloudURL.ToString() + SnapshotConnector + absDir))
+	w.statePath = filepath.Join(cpDir, fmt.Sprintf("restore_%x.json", sum))
+
+	data, err := ioutil.ReadFile(w.statePath)
+	if err != nil {
+		return w, nil
+	}
+	if err := json.Unmarshal(data, &w.state); err != nil {
+		return nil, fmt.Errorf("invalid restore state file %s: %s", w.statePath, err.Error())
+	}
+	if w.state.Downloaded == nil {
+		w.state.Downloaded = map[string]string{}
+	}
+	return w, nil
+}
+
+func (w *restoreWaiter) add(key, versionId string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.objects = append(w.objects, restoreWaitObject{key, versionId})
+}
+
+func (w *restoreWaiter) downloaded(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.state.Downloaded[key]
+	return ok
+}
+
+func (w *restoreWaiter) markDownloaded(key, etag string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state.Downloaded[key] = etag
+	data, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(w.cpDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.statePath, data, 0600)
+}
+
+// clear removes the state when all objects are downloaded, and the
+// checkpoint directory if nothing else is left in it.
+func (w *restoreWaiter) clear() {
+	if w.statePath == "" {
+		return
+	}
+	os.Remove(w.statePath)
+	os.Remove(w.cpDir)
+}
+
+// fileName keeps the object name after the last "/" of the cloud url, as cp
+// does for download.
+func (w *restoreWaiter) fileName(key string) string {
+	relativeKey := key
+	if index := strings.LastIndex(w.cloudURL.object, "/"); index > 0 {
+		relativeKey = key[index+1:]
+	}
+	return filepath.Join(w.downloadDir, relativeKey)
+}
+
+func isArchiveStorageClass(storageClass string) bool {
+	switch oss.StorageClassType(storageClass) {
+	case oss.StorageArchive, oss.StorageColdArchive, oss.StorageDeepColdArchive:
+		return true
+	}
+	return false
+}
+
+// waitRestore checks the restore status of the objects until all of them are
+// readable, with the interval doubled every round.
+func (rc *RestoreCommand) waitRestore(bucket *oss.Bucket) error {
+	w := rc.waiter
+	w.monitor.init(int64(len(w.objects)), w.downloadDir != "")
+	routines, _ := GetInt(OptionRoutines, rc.command.options)
+
+	var ferr error
+	pending := w.objects
+	interval := restoreWaitInterval
+	for {
+		var err error
+		pending, err = rc.checkRestoredObjects(bucket, pending, routines)
+		if err != nil {
+			ferr = err
+			if !rc.reOption.ctnu {
+				fmt.Printf(w.monitor.progressBar(true, errExit))
+				return err
+			}
+		}
+		fmt.Printf(w.monitor.progressBar(false, normalExit))
+		if len(pending) == 0 {
+			break
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > restoreWaitMaxInterval {
+			interval = restoreWaitMaxInterval
+		}
+	}
+	fmt.Printf(w.monitor.progressBar(true, normalExit))
+
+	if ferr == nil {
+		w.clear()
+	}
+	if ferr != nil && rc.reOption.ctnu {
+		return nil
+	}
+	return ferr
+}
+
+// checkRestoredObjects returns the objects still being restored.
+func (rc *RestoreCommand) checkRestoredObjects(bucket *oss.Bucket, objects []restoreWaitObject, routines int64) ([]restoreWaitObject, error) {
+	chObjects := make(chan restoreWaitObject, len(objects))
+	for _, object := range objects {
+		chObjects <- object
+	}
+	close(chObjects)
+
+	var mu sync.Mutex
+	var pending []restoreWaitObject
+	var ferr error
+	var wg sync.WaitGroup
+	for i := 0; int64(i) < routines || i == 0; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range chObjects {
+				done, err := rc.checkRestoredObject(bucket, object)
+				mu.Lock()
+				if err != nil {
+					ferr = err
+				} else if !done {
+					pending = append(pending, object)
+				}
+				if done {
+					fmt.Printf(rc.waiter.monitor.progressBar(false, normalExit))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return pending, ferr
+}
+
+// checkRestoredObject returns whether the object is done, restored or failed.
+func (rc *RestoreCommand) checkRestoredObject(bucket *oss.Bucket, object restoreWaitObject) (bool, error) {
+	w := rc.waiter
+	var options []oss.Option
+	if object.versionId != "" {
+		options = append(options, oss.VersionId(object.versionId))
+	}
+	options = append(options, rc.commonOptions...)
+
+	props, err := rc.command.ossGetObjectStatRetry(bucket, object.key, options...)
+	if err == nil {
+		status, _ := parseRestoreInfo(props.Get("x-oss-restore"))
+		if status == restoreInProgress {
+			return false, nil
+		}
+		if status == "" && isArchiveStorageClass(props.Get(oss.HTTPHeaderOssStorageClass)) {
+			err = ObjectError{fmt.Errorf("the object is not being restored"), bucket.BucketName, object.key}
+		} else if w.downloadDir != "" {
+			err = rc.downloadRestoredObject(bucket, object.key, props, options...)
+		}
+	}
+
+	if err != nil {
+		w.monitor.updateErrNum(1)
+		if rc.reOption.reporter != nil {
+			rc.command.report(fmt.Sprintf("wait restore %s", CloudURLToString(bucket.BucketName, object.key)), err, &rc.reOption)
+		}
+		return true, err
+	}
+	w.monitor.updateRestoredNum(1)
+	if w.downloadDir != "" {
+		w.monitor.updateDownloadedNum(1)
+	}
+	return true, nil
+}
+
+func (rc *RestoreCommand) downloadRestoredObject(bucket *oss.Bucket, object string, props http.Header, options ...oss.Option) error {
+	w := rc.waiter
+	fileName := w.fileName(object)
+	if strings.HasSuffix(object, "/") {
+		return os.MkdirAll(fileName, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+
+	size, _ := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err := rc.ossDownloadRestoredRetry(bucket, object, fileName, size, options...); err != nil {
+		return err
+	}
+	LogInfo("download restored object %s to %s\n", CloudURLToString(bucket.BucketName, object), fileName)
+	return w.markDownloaded(object, props.Get(oss.HTTPHeaderEtag))
+}
+
+// ossDownloadRestoredRetry downloads big objects by resumable download, so
+// an interrupted download continues from the checkpoint in --checkpoint-dir.
+func (rc *RestoreCommand) ossDownloadRestoredRetry(bucket *oss.Bucket, object, fileName string, size int64, options ...oss.Option) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, rc.command.options)
+	partSize, partNum := copyCommand.calcPartSize(size)
+	routines := 4
+	if partNum < int64(routines) {
+		routines = int(partNum)
+	}
+
+	for i := 1; ; i++ {
+		var err error
+		if size < DefaultBigFileThreshold {
+			err = bucket.GetObjectToFile(object, fileName, options...)
+		} else {
+			downloadOptions := append(append([]oss.Option{}, options...), oss.Routines(routines), oss.CheckpointDir(true, rc.waiter.cpDir))
+			err = bucket.DownloadFile(object, fileName, partSize, downloadOptions...)
+		}
+		if err == nil {
+			return nil
+		}
+		LogError("try count:%d,download restored object error %s,error:%s\n", i, object, err.Error())
+
+		// http 4XX error no need to retry
+		// only network error or internal error need to retry
+		serviceError, noNeedRetry := err.(oss.ServiceError)
+		if int64(i) >= retryTimes || (noNeedRetry && serviceError.StatusCode < 500) {
+			return ObjectError{err, bucket.BucketName, object}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRestoreWaitServer serves HEAD and GET of objects, an object is being
+// restored for its first heads times.
+func newRestoreWaitServer(t *testing.T, objects map[string]string, storageClass map[string]string, heads map[string]int) *httptest.Server {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		data, ok := objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", `"`+key+`"`)
+		w.Header().Set(oss.HTTPHeaderOssStorageClass, storageClass[key])
+		if r.Method == http.MethodHead {
+			mu.Lock()
+			if heads[key] > 0 {
+				heads[key]--
+				w.Header().Set("x-oss-restore", `ongoing-request="true"`)
+			} else if _, ok := heads[key]; ok {
+				w.Header().Set("x-oss-restore", `ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`)
+			}
+			mu.Unlock()
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRestoreWaitBucket(t *testing.T, endpoint string) *oss.Bucket {
+	client, err := oss.New(endpoint, "ak", "sk", oss.ForcePathStyle(true))
+	require.NoError(t, err)
+	bucket, err := client.Bucket("bucket")
+	require.NoError(t, err)
+	return bucket
+}
+
+func TestRestoreWaitThenDownload(t *testing.T) {
+	interval := restoreWaitInterval
+	restoreWaitInterval = time.Millisecond
+	defer func() { restoreWaitInterval = interval }()
+
+	objects := map[string]string{"dir/a.txt": "aaa", "dir/sub/b.txt": "bbbb", "dir/c.txt": "c", "dir/d.txt": "standard"}
+	storageClass := map[string]string{"dir/a.txt": "Archive", "dir/sub/b.txt": "ColdArchive", "dir/c.txt": "Archive", "dir/d.txt": "Standard"}
+	heads := map[string]int{"dir/a.txt": 2, "dir/sub/b.txt": 0}
+	bucket := newRestoreWaitBucket(t, newRestoreWaitServer(t, objects, storageClass, heads).URL)
+
+	downloadDir := filepath.Join(t.TempDir(), "download")
+	cpDir := filepath.Join(t.TempDir(), "cp")
+	var rc RestoreCommand
+	rc.reOption.ctnu = true
+	var err error
+	rc.waiter, err = newRestoreWaiter(CloudURL{bucket: "bucket", object: "dir/"}, downloadDir, cpDir)
+	require.NoError(t, err)
+	for _, key := range []string{"dir/a.txt", "dir/sub/b.txt", "dir/c.txt", "dir/d.txt", "dir/missing.txt"} {
+		rc.waiter.add(key, "")
+	}
+
+	// c.txt is archived without being restored, missing.txt does not exist
+	assert.NoError(t, rc.waitRestore(bucket))
+	assert.Equal(t, int64(3), rc.waiter.monitor.restoredNum)
+	assert.Equal(t, int64(3), rc.waiter.monitor.downloadedNum)
+	assert.Equal(t, int64(2), rc.waiter.monitor.errNum)
+	assert.Equal(t, 0, heads["dir/a.txt"])
+
+	for file, data := range map[string]string{"a.txt": "aaa", "sub/b.txt": "bbbb", "d.txt": "standard"} {
+		got, err := ioutil.ReadFile(filepath.Join(downloadDir, file))
+		require.NoError(t, err)
+		assert.Equal(t, data, string(got))
+	}
+	_, err = os.Stat(filepath.Join(downloadDir, "c.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the state is kept for the failures, the objects downloaded are skipped
+	// when run again
+	waiter, err := newRestoreWaiter(CloudURL{bucket: "bucket", object: "dir/"}, downloadDir, cpDir)
+	require.NoError(t, err)
+	assert.True(t, waiter.downloaded("dir/a.txt"))
+	assert.True(t, waiter.downloaded("dir/sub/b.txt"))
+	assert.False(t, waiter.downloaded("dir/c.txt"))
+}
+
+func TestRestoreWaitSingleObject(t *testing.T) {
+	objects := map[string]string{"dir/a.txt": "aaa", "dir/c.txt": "c"}
+	storageClass := map[string]string{"dir/a.txt": "Archive", "dir/c.txt": "Archive"}
+	heads := map[string]int{"dir/a.txt": 0}
+	bucket := newRestoreWaitBucket(t, newRestoreWaitServer(t, objects, storageClass, heads).URL)
+
+	var rc RestoreCommand
+	var err error
+	cpDir := filepath.Join(t.TempDir(), "cp")
+	rc.waiter, err = newRestoreWaiter(CloudURL{bucket: "bucket", object: "dir/a.txt"}, "", cpDir)
+	require.NoError(t, err)
+	rc.waiter.add("dir/a.txt", "")
+	assert.NoError(t, rc.waitRestore(bucket))
+	assert.Equal(t, int64(1), rc.waiter.monitor.restoredNum)
+	assert.Equal(t, int64(0), rc.waiter.monitor.downloadedNum)
+
+	downloadDir := t.TempDir()
+	rc.waiter, err = newRestoreWaiter(CloudURL{bucket: "bucket", object: "dir/a.txt"}, downloadDir, cpDir)
+	require.NoError(t, err)
+	rc.waiter.add("dir/a.txt", "")
+	assert.NoError(t, rc.waitRestore(bucket))
+	got, err := ioutil.ReadFile(filepath.Join(downloadDir, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", string(got))
+	_, err = os.Stat(cpDir)
+	assert.True(t, os.IsNotExist(err))
+
+	rc.waiter, err = newRestoreWaiter(CloudURL{bucket: "bucket", object: "dir/c.txt"}, "", cpDir)
+	require.NoError(t, err)
+	rc.waiter.add("dir/c.txt", "")
+	err = rc.waitRestore(bucket)
+	assert.Contains(t, err.Error(), "the object is not being restored")
+}
+
+func TestNewRestoreWaiter(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("x"), 0600))
+	_, err := newRestoreWaiter(CloudURL{bucket: "bucket"}, file, dir)
+	assert.Contains(t, err.Error(), "is not a directory")
+
+	w, err := newRestoreWaiter(CloudURL{bucket: "bucket", object: "a/b/"}, dir, dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "c/d.txt"), w.fileName("a/b/c/d.txt"))
+	require.NoError(t, ioutil.WriteFile(w.statePath, []byte("{"), 0600))
+	_, err = newRestoreWaiter(CloudURL{bucket: "bucket", object: "a/b/"}, dir, dir)
+	assert.Contains(t, err.Error(), "invalid restore state file")
+
+	w, err = newRestoreWaiter(CloudURL{bucket: "bucket"}, dir, dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a/b.txt"), w.fileName("a/b.txt"))
+
+	assert.True(t, isArchiveStorageClass("DeepColdArchive"))
+	assert.False(t, isArchiveStorageClass("IA"))
+}
+
+func TestRestoreWaitMonitor(t *testing.T) {
+	var m RestoreWaitMonitor
+	m.init(3, true)
+	m.updateRestoredNum(1)
+	m.updateDownloadedNum(1)
+	assert.Contains(t, m.progressBar(false, normalExit), "Total 3 objects. Restored 1 objects, Downloaded 1 objects, In progress 2 objects.")
+	m.updateErrNum(1)
+	assert.Contains(t, m.progressBar(false, normalExit), "In progress 1 objects, Error 1 objects.")
+	assert.Contains(t, m.progressBar(true, normalExit), "FinishWithError: Total 3 objects. Restored 1 objects, Downloaded 1 objects, Error 1 objects.\n")
+	assert.Equal(t, "", m.progressBar(true, normalExit))
+
+	m.init(2, false)
+	m.updateRestoredNum(2)
+	assert.Contains(t, m.progressBar(true, normalExit), "Succeed: Total 2 objects. Restored 2 objects.\n")
+}
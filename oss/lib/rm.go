@@ -18,6 +18,7 @@ type removeOptionType struct {
 	recursive bool
 	force     bool
 	typeSet   int64
+	dryRun    bool
 
 	//version
 	versionId   string
@@ -31,7 +32,7 @@ var specChineseRemove = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil rm oss://bucket[/prefix] [-r] [-b] [-m] [-a] [-f]  [--include include-pattern] [--exclude exclude-pattern]  [--version-id versionId | --all-versions] [--payer requester] [-c file]
+    ossutil rm oss://bucket[/prefix] [-r] [-b] [-m] [-a] [-f] [--dry-run]  [--include include-pattern] [--exclude exclude-pattern]  [--version-id versionId | --all-versions] [--payer requester] [-c file]
 `,
 
 	detailHelpText: ` 
@@ -61,6 +62,11 @@ var specChineseRemove = SpecText{
     命令断点续传失败（报错：NoSuchUpload），这种时候如果想要重新上传整个文件，请删除
     checkpoint目录中相应的文件。
 
+--dry-run选项
+
+    与-r一起使用，列出将被删除的objects（包括--include和--exclude的筛选），不执行删除。
+    每个object输出一行JSON，最后一行为数量和大小的汇总，可以在CI等无法交互确认的场景中检查。
+
 --include和--exclude选项
 
     可以指定该选项以指定规则筛选要操作的文件/object
@@ -137,6 +143,7 @@ var specChineseRemove = SpecText{
     ossutil rm oss://bucket2 -a -r -b -f
     ossutil rm oss://bucket2/%e4%b8%ad%e6%96%87 --encoding-type url
     ossutil rm oss://bucket1/objdir -r --include "*.jpg" --include "*.png" --exclude "*.avi" --exclude "*.mp4"
+    ossutil rm oss://bucket1/objdir -r --dry-run
     ossutil rm oss://bucket1/obj1 --version-id versionId
     ossutil rm oss://bucket1/obj1 --all-versions
     ossutil rm oss://bucket1/objdir -r  --all-versions
@@ -152,7 +159,7 @@ var specEnglishRemove = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil rm oss://bucket[/prefix] [-r] [-b] [-m] [-a] [-f]  [--include include-pattern] [--exclude exclude-pattern]  [--version-id versionId | --all-versions] [--payer requester] [-c file]
+    ossutil rm oss://bucket[/prefix] [-r] [-b] [-m] [-a] [-f] [--dry-run]  [--include include-pattern] [--exclude exclude-pattern]  [--version-id versionId | --all-versions] [--payer requester] [-c file]
 `,
 
 	detailHelpText: ` 
@@ -189,6 +196,12 @@ var specEnglishRemove = SpecText{
     the next time(Error: NoSuchUpload). If you want to reupload/download/copy the entire file 
     again, please remove the checkpoint file in checkpoint directory. 
 
+--dry-run option
+
+    Use with -r to list the objects that would be removed(after --include and --exclude), 
+    without removing anything. Every object is a line of JSON, the last line sums up the count 
+    and size, so the plan can be checked where no one can confirm, e.g. in CI.
+
 --include and --exclude option:
 
     These parameters perform pattern matching to either exclude or include a particular file or object
@@ -280,6 +293,7 @@ Usage:
     ossutil rm oss://bucket2 -a -r -b -f
     ossutil rm oss://bucket2/%e4%b8%ad%e6%96%87 --encoding-type url
     ossutil rm oss://bucket1/objdir -r --include "*.jpg" --include "*.png" --exclude "*.avi" --exclude "*.mp4"
+    ossutil rm oss://bucket1/objdir -r --dry-run
     ossutil rm oss://bucket1/obj1 --version-id versionId
     ossutil rm oss://bucket1/obj1 --all-versions
     ossutil rm oss://bucket1/objdir -r  --all-versions
@@ -345,6 +359,7 @@ var removeCommand = RemoveCommand{
 			OptionCloudBoxID,
 			OptionForcePathStyle,
 			OptionInsecure,
+			OptionDryRun,
 		},
 	},
 }
@@ -405,6 +420,10 @@ func (rc *RemoveCommand) RunCommand() error {
 		return fmt.Errorf("--include or --exclude only work with --recursive")
 	}
 
+	if rc.rmOption.dryRun {
+		return rc.planRemoveObjects(bucket, cloudURL, newDryRunPlan(os.Stdout))
+	}
+
 	// confirm remove objects/multiparts/allTypes before statistic
 	if !rc.confirmRemoveObject(cloudURL) {
 		return nil
@@ -429,6 +448,7 @@ func (rc *RemoveCommand) assembleOption(cloudURL CloudURL) error {
 	toBucket, _ := GetBool(OptionBucket, rc.command.options)
 	rc.rmOption.versionId, _ = GetString(OptionVersionId, rc.command.options)
 	rc.rmOption.allVersions, _ = GetBool(OptionAllversions, rc.command.options)
+	rc.rmOption.dryRun, _ = GetBool(OptionDryRun, rc.command.options)
 
 	if err := rc.checkOption(cloudURL, isMultipart, isAllType, toBucket); err != nil {
 		return err
@@ -753,6 +773,38 @@ func (rc *RemoveCommand) batchDeleteObjects(bucket *oss.Bucket, cloudURL CloudUR
 	return nil
 }
 
+// planRemoveObjects lists the objects batchDeleteObjects would delete for
+// --dry-run, with the same prefix and filters.
+func (rc *RemoveCommand) planRemoveObjects(bucket *oss.Bucket, cloudURL CloudURL, plan *dryRunPlan) error {
+	if !rc.rmOption.recursive || rc.rmOption.typeSet != objectType || rc.rmOption.allVersions {
+		return fmt.Errorf("--dry-run only supports removing objects with --recursive")
+	}
+
+	pre := oss.Prefix(cloudURL.object)
+	marker := oss.Marker("")
+	for {
+		listOptions := append(rc.commonOptions, marker, pre, oss.MaxKeys(1000))
+		lor, err := rc.command.ossListObjectsRetry(bucket, listOptions...)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range lor.Objects {
+			if doesSingleObjectMatchPatterns(object.Key, rc.filters) {
+				plan.add(planEntry{Action: planDelete, Destination: CloudURLToString(bucket.BucketName, object.Key), Size: object.Size})
+			}
+		}
+
+		pre = oss.Prefix(lor.Prefix)
+		marker = oss.Marker(lor.NextMarker)
+		if !lor.IsTruncated {
+			break
+		}
+	}
+	plan.printSummary()
+	return nil
+}
+
 func (rc *RemoveCommand) ossBatchDeleteObjectsRetry(bucket *oss.Bucket, objects []string) (int, error) {
 	retryTimes, _ := GetInt(OptionRetryTimes, rc.command.options)
 	num := len(objects)
@@ -2,6 +2,7 @@ package lib
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -15,6 +16,8 @@ var specChineseSignurl = SpecText{
 
 	syntaxText: ` 
     ossutil sign cloud_url [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    ossutil sign cloud_url -r|--object-file file [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    ossutil sign cloud_url --post-policy [--timeout t] [--content-length-range min-max] [--content-type type]
 `,
 
 	detailHelpText: ` 
@@ -24,7 +27,29 @@ var specChineseSignurl = SpecText{
 
 用法：
 
-    ossutil sign oss://bucket/object [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    1) ossutil sign oss://bucket/object [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+        签名单个object，输出签名url。
+
+    2) ossutil sign oss://bucket[/prefix] -r [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+       ossutil sign oss://bucket --object-file file [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+        批量签名，-r签名所有前缀匹配的object，--object-file签名文件中列出的object（每行一个
+    object名，--encoding-type url时为url编码的object名）。每个object输出一行JSON，便于分享大
+    量object：
+        {"key":"dir/a.txt","url":"https://bucket.oss-cn-hangzhou.aliyuncs.com/dir%2Fa.txt?...","expiration":"2023-12-03T13:00:00Z"}
+    不支持--version-id。
+
+    3) ossutil sign oss://bucket[/prefix] --post-policy [--timeout t] [--content-length-range min-max] [--content-type type]
+        生成浏览器表单直传（PostObject）所需的policy和签名，以JSON输出表单的url和字段：
+        {
+          "url": "https://bucket.oss-cn-hangzhou.aliyuncs.com/",
+          "expiration": "2023-12-03T13:00:00.000Z",
+          "fields": {"key": "prefix${filename}", "policy": "...", "OSSAccessKeyId": "...", "Signature": "..."}
+        }
+    表单包含fields中的所有字段，最后是名为file的文件字段。policy限制object名以prefix开头，
+    --timeout为policy的有效时间；--content-length-range限制文件大小（字节），--content-type
+    限制文件的Content-Type，以/结尾时为前缀匹配。使用STS临时凭证时包含x-oss-security-token。
+    --sign-version v4时使用V4签名（需要--region），字段为x-oss-signature-version、
+    x-oss-credential、x-oss-date和x-oss-signature，否则使用V1签名。
 `,
 
 	sampleText: ` 
@@ -51,6 +76,14 @@ var specChineseSignurl = SpecText{
 
     ossutil sign oss://bucket1/object1.jpg  --query-param x-oss-process:image/resize,m_fixed,w_100,h_100/rotate,90
         生成处理过的图片 oss://bucket1/dir/object1.jpg的签名url 
+    ossutil sign oss://bucket1/dir/ -r --timeout 86400
+        签名oss://bucket1/dir/下的所有object，每个object输出一行JSON，超时时间1天
+
+    ossutil sign oss://bucket1 --object-file objects.txt
+        签名objects.txt中列出的object，每个object输出一行JSON
+
+    ossutil sign oss://bucket1/uploads/ --post-policy --timeout 3600 --content-length-range 1-10485760 --content-type image/
+        生成表单上传到oss://bucket1/uploads/的policy和签名，只允许10MB以内的图片
 `,
 }
 
@@ -62,6 +95,8 @@ var specEnglishSignurl = SpecText{
 
 	syntaxText: ` 
     ossutil sign cloud_url [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    ossutil sign cloud_url -r|--object-file file [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    ossutil sign cloud_url --post-policy [--timeout t] [--content-length-range min-max] [--content-type type]
 `,
 
 	detailHelpText: ` 
@@ -77,7 +112,32 @@ var specEnglishSignurl = SpecText{
 
 Usage:
 
-    ossutil sign oss://bucket/object [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+    1) ossutil sign oss://bucket/object [--timeout t] [--version-id versionId] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+        Sign an object, and print the signed url.
+
+    2) ossutil sign oss://bucket[/prefix] -r [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+       ossutil sign oss://bucket --object-file file [--timeout t] [--trafic-limit limitSpeed] [--disable-encode-slash] [--payer requester] [--query-param key:value]
+        Sign in batch, -r signs all the objects with the prefix, --object-file signs the objects 
+    listed in the file(an object name per line, url encoded with --encoding-type url). A line of 
+    JSON is printed for every object, to share lots of objects:
+        {"key":"dir/a.txt","url":"https://bucket.oss-cn-hangzhou.aliyuncs.com/dir%2Fa.txt?...","expiration":"2023-12-03T13:00:00Z"}
+    --version-id is not supported.
+
+    3) ossutil sign oss://bucket[/prefix] --post-policy [--timeout t] [--content-length-range min-max] [--content-type type]
+        Generate the policy and signature of browser form uploads(PostObject), and print the url 
+    and the fields of the form as JSON:
+        {
+          "url": "https://bucket.oss-cn-hangzhou.aliyuncs.com/",
+          "expiration": "2023-12-03T13:00:00.000Z",
+          "fields": {"key": "prefix${filename}", "policy": "...", "OSSAccessKeyId": "...", "Signature": "..."}
+        }
+    The form has all the fields, followed by the file field named file. The policy allows the 
+    object names starting with prefix, --timeout is the time the policy is valid for; 
+    --content-length-range limits the size of files(in bytes), --content-type limits the 
+    Content-Type of files, a value ending with / matches as a prefix. x-oss-security-token is 
+    included for STS credentials. With --sign-version v4 the V4 signature is used(--region is 
+    needed), with the fields x-oss-signature-version, x-oss-credential, x-oss-date and 
+    x-oss-signature, else the V1 signature.
 `,
 
 	sampleText: ` 
@@ -104,6 +164,14 @@ Usage:
 
     ossutil sign oss://bucket1/object1.jpg  --query-param x-oss-process:image/resize,m_fixed,w_100,h_100/rotate,90
 		Generate the signature of processed picture oss://bucket1/dir/object1.jpg
+    ossutil sign oss://bucket1/dir/ -r --timeout 86400
+        Sign all the objects in oss://bucket1/dir/, a line of JSON for every object, with expire time 1 day
+
+    ossutil sign oss://bucket1 --object-file objects.txt
+        Sign the objects listed in objects.txt, a line of JSON for every object
+
+    ossutil sign oss://bucket1/uploads/ --post-policy --timeout 3600 --content-length-range 1-10485760 --content-type image/
+        Generate the policy and signature of form uploads to oss://bucket1/uploads/, for images up to 10MB only
 `,
 }
 
@@ -151,6 +219,12 @@ var signURLCommand = SignurlCommand{
 			OptionCloudBoxID,
 			OptionForcePathStyle,
 			OptionInsecure,
+			OptionRecursion,
+			OptionObjectFile,
+			OptionRetryTimes,
+			OptionPostPolicy,
+			OptionContentLengthRange,
+			OptionContentType,
 		},
 	},
 }
@@ -172,10 +246,22 @@ func (sc *SignurlCommand) Init(args []string, options OptionMapType) error {
 // RunCommand simulate inheritance, and polymorphism
 func (sc *SignurlCommand) RunCommand() error {
 	encodingType, _ := GetString(OptionEncodingType, sc.command.options)
-	cloudURL, err := ObjectURLFromString(sc.command.args[0], encodingType)
+	recursive, _ := GetBool(OptionRecursion, sc.command.options)
+	objectFile, _ := GetString(OptionObjectFile, sc.command.options)
+	postPolicy, _ := GetBool(OptionPostPolicy, sc.command.options)
+	var cloudURL CloudURL
+	var err error
+	if recursive || objectFile != "" || postPolicy {
+		cloudURL, err = CloudURLFromString(sc.command.args[0], encodingType)
+	} else {
+		cloudURL, err = ObjectURLFromString(sc.command.args[0], encodingType)
+	}
 	if err != nil {
 		return err
 	}
+	if err := sc.checkOptions(cloudURL, recursive, objectFile, postPolicy); err != nil {
+		return err
+	}
 
 	timeout, _ := GetInt(OptionTimeout, sc.command.options)
 	versionId, _ := GetString(OptionVersionId, sc.command.options)
@@ -195,6 +281,10 @@ func (sc *SignurlCommand) RunCommand() error {
 		return err
 	}
 
+	if postPolicy {
+		return sc.signPostPolicy(os.Stdout, bucket, cloudURL, timeout)
+	}
+
 	var options []oss.Option
 	if len(versionId) > 0 {
 		options = append(options, oss.VersionId(versionId))
@@ -215,6 +305,14 @@ func (sc *SignurlCommand) RunCommand() error {
 		}
 	}
 
+	if recursive || objectFile != "" {
+		var listOptions []oss.Option
+		if payer != "" {
+			listOptions = append(listOptions, oss.RequestPayer(oss.PayerType(payer)))
+		}
+		return sc.batchSign(os.Stdout, bucket, cloudURL, objectFile, timeout, listOptions, options...)
+	}
+
 	str, err := sc.ossSign(bucket, cloudURL.object, timeout, options...)
 	if err != nil {
 		return err
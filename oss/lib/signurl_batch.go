@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// signEntry is a line of the JSON lines batch sign prints.
+type signEntry struct {
+	Key        string `json:"key"`
+	URL        string `json:"url"`
+	Expiration string `json:"expiration"`
+}
+
+func (sc *SignurlCommand) checkOptions(cloudURL CloudURL, recursive bool, objectFile string, postPolicy bool) error {
+	if cloudURL.bucket == "" {
+		return fmt.Errorf("invalid cloud url: %s, miss bucket", cloudURL.urlStr)
+	}
+	if recursive && objectFile != "" {
+		return fmt.Errorf("--recursive and --object-file can't be both exist")
+	}
+	if objectFile != "" && cloudURL.object != "" {
+		return fmt.Errorf("the first arg of `ossutil sign` only support oss://bucket when set option --object-file")
+	}
+
+	versionId, _ := GetString(OptionVersionId, sc.command.options)
+	if (recursive || objectFile != "") && versionId != "" {
+		return fmt.Errorf("sign with --recursive or --object-file does not support the --version-id=%s argument", versionId)
+	}
+
+	contentLengthRange, _ := GetString(OptionContentLengthRange, sc.command.options)
+	contentType, _ := GetString(OptionContentType, sc.command.options)
+	if !postPolicy {
+		if contentLengthRange != "" || contentType != "" {
+			return fmt.Errorf("--content-length-range and --content-type only work with --post-policy")
+		}
+		return nil
+	}
+	if recursive || objectFile != "" {
+		return fmt.Errorf("--post-policy can't be used with --recursive or --object-file")
+	}
+	query, _ := GetStrings(OptionQueryParam, sc.command.options)
+	if _, err := GetInt(OptionTrafficLimit, sc.command.options); err == nil || versionId != "" || len(query) > 0 {
+		return fmt.Errorf("--post-policy can't be used with --version-id, --trafic-limit or --query-param")
+	}
+	_, _, err := parseContentLengthRange(contentLengthRange)
+	return err
+}
+
+// batchSign signs the objects with the prefix of cloudURL, or the ones in
+// objectFile, and prints a line of JSON for every object.
+func (sc *SignurlCommand) batchSign(out io.Writer, bucket *oss.Bucket, cloudURL CloudURL, objectFile string, timeout int64, listOptions []oss.Option, options ...oss.Option) error {
+	chObjects := make(chan string, ChannelBuf)
+	chError := make(chan error, 1)
+	if objectFile != "" {
+		go sc.objectFileProducer(objectFile, chObjects, chError)
+	} else {
+		go sc.command.objectProducer(bucket, cloudURL, chObjects, chError, []filterOptionType{}, listOptions...)
+	}
+
+	expiration := formatJSONTime(time.Now().Add(time.Duration(timeout) * time.Second))
+	for object := range chObjects {
+		str, err := sc.ossSign(bucket, object, timeout, options...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s\n", marshalJSON(signEntry{Key: object, URL: str, Expiration: expiration}, false, ""))
+	}
+	return <-chError
+}
+
+// objectFileProducer reads the object names of --object-file, one per line,
+// url encoded with --encoding-type url.
+func (sc *SignurlCommand) objectFileProducer(objectFile string, chObjects chan<- string, chError chan<- error) {
+	defer close(chObjects)
+	file, err := os.Open(objectFile)
+	if err != nil {
+		chError <- err
+		return
+	}
+	defer file.Close()
+
+	encodingType, _ := GetString(OptionEncodingType, sc.command.options)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		object := strings.TrimSpace(scanner.Text())
+		if object == "" {
+			continue
+		}
+		if encodingType == URLEncodingType {
+			oldObject := object
+			if object, err = url.QueryUnescape(oldObject); err != nil {
+				chError <- fmt.Errorf("invalid object url: %s, object name is not url encoded, %s", oldObject, err.Error())
+				return
+			}
+		}
+		chObjects <- object
+	}
+	chError <- scanner.Err()
+}
+
+// signPostPolicy prints the url and the fields of a browser PostObject form
+// for the keys with the prefix of cloudURL.
+func (sc *SignurlCommand) signPostPolicy(out io.Writer, bucket *oss.Bucket, cloudURL CloudURL, timeout int64) error {
+	contentLengthRange, _ := GetString(OptionContentLengthRange, sc.command.options)
+	contentType, _ := GetString(OptionContentType, sc.command.options)
+	minSize, maxSize, err := parseContentLengthRange(contentLengthRange)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	policy := postPolicyConfig{
+		keyPrefix:   cloudURL.object,
+		minSize:     minSize,
+		maxSize:     maxSize,
+		contentType: contentType,
+		expiration:  now.Add(time.Duration(timeout) * time.Second),
+	}
+	form, err := newPostPolicyForm(bucket.Client.Config, bucket.BucketName, policy, now)
+	if err != nil {
+		return err
+	}
+	printJSON(out, OutputFormatJSON, form)
+	return nil
+}
@@ -3,6 +3,7 @@ package lib
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -17,7 +18,7 @@ var specChineseStat = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil stat oss://bucket[/object] [--encoding-type url] [--version-id versionId] [--payer requester] [-c file] 
+    ossutil stat oss://bucket[/object] [--encoding-type url] [--version-id versionId] [--payer requester] [--output-format json|jsonl] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -35,6 +36,13 @@ var specChineseStat = SpecText{
     2) ossutil stat oss://bucket/object [--encoding-type url] [--version-id versionId]
         ossutil显示指定object的元信息，包括文件大小，最新更新时间，etag，文件类型，acl，文
     件的自定义meta等信息。
+
+--output-format选项
+
+    指定为json时，ossutil将描述信息输出为一个格式化的JSON对象，指定为jsonl时输出为一行JSON。
+    object的信息包括key、size、etag、storageClass、lastModified（UTC，RFC3339格式）、
+    versionId、restoreStatus（in-progress或restored）、restoreExpiry、acl、owner，以及
+    headers中原样的响应头。
 `,
 
 	sampleText: ` 
@@ -43,6 +51,7 @@ var specChineseStat = SpecText{
     ossutil stat oss://bucket1/object --version-id versionId
     ossutil stat oss://bucket1/%e4%b8%ad%e6%96%87 --encoding-type url
     ossutil stat oss://bucket1/object --payer requester
+    ossutil stat oss://bucket1/object --output-format json
 `,
 }
 
@@ -53,7 +62,7 @@ var specEnglishStat = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil stat oss://bucket[/object] [--encoding-type url]  [--version-id versionId] [--payer requester] [-c file] 
+    ossutil stat oss://bucket[/object] [--encoding-type url]  [--version-id versionId] [--payer requester] [--output-format json|jsonl] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -71,6 +80,13 @@ Usage：
     2) ossutil stat oss://bucket/object [--encoding-type url] [--version-id versionId]
         ossutil display object meta info, include file size, last modify time, etag, content-type, 
     user meta etc.
+
+--output-format option
+
+    If the option is json, ossutil prints the meta information as an indented JSON object, 
+    if it is jsonl, as a line of JSON. The information of an object includes the key, size, 
+    etag, storageClass, lastModified(UTC, in RFC3339), versionId, restoreStatus(in-progress 
+    or restored), restoreExpiry, acl, owner, and the response headers as they are in headers.
 `,
 
 	sampleText: ` 
@@ -79,6 +95,7 @@ Usage：
     ossutil stat oss://bucket1/object --version-id versionId  
     ossutil stat oss://bucket1/%e4%b8%ad%e6%96%87 --encoding-type url
     ossutil stat oss://bucket1/object --payer requester
+    ossutil stat oss://bucket1/object --output-format json
 `,
 }
 
@@ -87,6 +104,7 @@ type StatCommand struct {
 	command       Command
 	versionId     string
 	commonOptions []oss.Option
+	outputFormat  string
 }
 
 var statCommand = StatCommand{
@@ -100,6 +118,7 @@ var statCommand = StatCommand{
 		group:       GroupTypeNormalCommand,
 		validOptionNames: []string{
 			OptionEncodingType,
+			OptionOutputFormat,
 			OptionConfigFile,
 			OptionEndpoint,
 			OptionAccessKeyID,
@@ -149,6 +168,7 @@ func (sc *StatCommand) Init(args []string, options OptionMapType) error {
 // RunCommand simulate inheritance, and polymorphism
 func (sc *StatCommand) RunCommand() error {
 	sc.versionId, _ = GetString(OptionVersionId, sc.command.options)
+	sc.outputFormat = getOutputFormat(sc.command.options)
 	encodingType, _ := GetString(OptionEncodingType, sc.command.options)
 	cloudURL, err := CloudURLFromString(sc.command.args[0], encodingType)
 	if err != nil {
@@ -184,6 +204,10 @@ func (sc *StatCommand) bucketStat(bucket *oss.Bucket, cloudURL CloudURL) error {
 	if err != nil {
 		return err
 	}
+	if sc.outputFormat != "" {
+		printJSON(os.Stdout, sc.outputFormat, newBucketStatEntry(gbar.BucketInfo))
+		return nil
+	}
 
 	fmt.Printf("%-22s: %s\n", StatName, gbar.BucketInfo.Name)
 	fmt.Printf("%-22s: %s\n", StatLocation, gbar.BucketInfo.Location)
@@ -265,6 +289,11 @@ func (sc *StatCommand) objectStat(bucket *oss.Bucket, cloudURL CloudURL) error {
 		}
 	}
 
+	if sc.outputFormat != "" {
+		printJSON(os.Stdout, sc.outputFormat, newObjectStatEntry(cloudURL, props, attrMap, goar))
+		return nil
+	}
+
 	sortNames = append(sortNames, "Owner")
 	sortNames = append(sortNames, "ACL")
 	attrMap[StatOwner] = goar.Owner.ID
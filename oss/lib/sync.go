@@ -32,6 +32,8 @@ type syncOptionType struct {
 	disableAllSymlink bool
 	cpDir             string
 	removeCount       int
+	dryRun            bool
+	watch             bool
 
 	filters      []filterOptionType
 	payerOptions []oss.Option
@@ -44,9 +46,9 @@ var specChineseSync = SpecText{
 	paramText: "src dest [options]",
 
 	syntaxText: ` 
-    ossutil sync local_dir cloud_url [-f] [-u] [--delete] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil sync cloud_url local_dir [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil sync cloud_url cloud_url [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil sync local_dir cloud_url [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--watch] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil sync cloud_url local_dir [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil sync cloud_url cloud_url [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
 `,
 
 	detailHelpText: ` 
@@ -69,6 +71,26 @@ var specChineseSync = SpecText{
 --backup-dir
     该选项表示用于备份目的端文件的目录, 不能是目的端目录的子目录,如果输入了--delete, 该选项必须输入
 
+--checksum, --size-only
+    根据大小和crc64（或只根据大小）判断目的端是否需要更新, 不比较lastModifiedTime, 详见cp命令帮助
+
+--dry-run
+    只输出同步计划, 不传输、删除或移走任何文件. 除cp命令的upload、download、copy和skip外,
+    --delete将删除或移走的object或文件输出为delete, 详见cp命令帮助
+
+--watch
+    只支持从本地目录同步到oss. 同步完成后继续监听本地目录(Linux上使用inotify), 将新增或修改的
+    文件增量上传; 输入--delete时, 本地删除的文件或目录对应的object也会被删除. 短时间内的多次修改
+    会在文件静止2秒后合并上传, 持续修改时最多等待30秒. 监听出错(如inotify队列溢出)时会重新完整同步
+    一次目录. --include、--exclude和--only-current-dir同样生效. 该选项会一直运行
+    直到被中断(Ctrl+C), 删除object时不会询问确认, 不支持--snapshot-path和--dry-run
+
+--encryption-key-file
+    客户端加密, 上传时在本地加密, 下载时在本地解密, 与OSS SDK的客户端加密格式相同, 详见cp命令帮助
+
+--report-json, --metrics-file
+    将每个文件或object的传输结果以JSON lines写入--report-json, 最后一行为汇总; 将汇总以Prometheus
+    文本格式写入--metrics-file, 详见cp命令帮助. --watch时每一轮同步都会重写这两个文件
   
     其他选项说明、用法和cp命令相同
 `,
@@ -201,9 +223,9 @@ var specEnglishSync = SpecText{
 	paramText: "src dest [options]",
 
 	syntaxText: ` 
-    ossutil sync local_dir cloud_url [-f] [-u] [--delete] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--payer requester]
-    ossutil sync cloud_url local_dir [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--payer requester]
-    ossutil sync cloud_url cloud_url [-f] [-u] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
+    ossutil sync local_dir cloud_url [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--watch] [--backup-dir] [--enable-symlink-dir] [--disable-all-symlink] [--disable-ignore-error] [--only-current-dir] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil sync cloud_url local_dir [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--encryption-key-file=keyfile] [--report-json=file] [--metrics-file=file] [--payer requester]
+    ossutil sync cloud_url cloud_url [-f] [-u] [--checksum|--size-only] [--dry-run] [--delete] [--backup-dir] [--only-current-dir] [--disable-ignore-error] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--payer requester]
 `,
 
 	detailHelpText: ` 
@@ -232,6 +254,33 @@ var specEnglishSync = SpecText{
     It cannot be a subdirectory of the destination directory. 
     If you enter --delete, this option must be entered
 
+--checksum, --size-only
+    Decide whether the destination needs update by size and crc64(or by size only) instead of 
+    lastModifiedTime, see the help of cp command
+
+--dry-run
+    Only print the plan of the sync, without transferring, deleting or removing anything. Besides 
+    the upload, download, copy and skip lines of cp command, the objects or files --delete would 
+    delete or remove are printed as delete, see the help of cp command
+
+--watch
+    Only supported when syncing a local directory to oss. After the sync, keep watching the local
+    directory(with inotify on Linux) and upload the files created or changed; with --delete, the
+    objects of the files or directories deleted locally are deleted too. Bursts of changes are
+    uploaded together once the files are quiet for 2 seconds, or after 30 seconds of constant
+    changes. After a watch error(e.g. the inotify queue overflowed) the whole directory is synced
+    again. --include, --exclude and --only-current-dir apply as well. The command runs until interrupted(Ctrl+C), it never asks to
+    confirm deletions, --snapshot-path and --dry-run are not supported
+
+--encryption-key-file
+    Client-side encryption, encrypt locally on upload and decrypt locally on download, in the 
+    format of the client-side encryption of OSS SDKs, see the help of cp command
+
+--report-json, --metrics-file
+    Write the outcome of every file or object to --report-json as JSON lines, the last line is the
+    summary; write the summary to --metrics-file in the Prometheus text format, see the help of cp
+    command. With --watch both files are rewritten every round
+
     Other options descriptions and usage are the same as the cp command
 `,
 
@@ -361,6 +410,9 @@ var specEnglishSync = SpecText{
 type SyncCommand struct {
 	command    Command
 	syncOption syncOptionType
+
+	// sizes of the listed objects by key, for the --dry-run plan
+	objectSizes map[string]int64
 }
 
 var syncCommand = SyncCommand{
@@ -377,6 +429,12 @@ var syncCommand = SyncCommand{
 			//OptionRecursion,
 			OptionForce,
 			OptionUpdate,
+			OptionChecksum,
+			OptionSizeOnly,
+			OptionDryRun,
+			OptionEncryptionKeyFile,
+			OptionReportJSON,
+			OptionMetricsFile,
 			OptionContinue,
 			OptionOutputDir,
 			OptionBigFileThreshold,
@@ -434,6 +492,7 @@ var syncCommand = SyncCommand{
 			// The following options are only supported by sc command, not supported by cp command
 			OptionDelete,
 			OptionBackupDir,
+			OptionWatch,
 			OptionInsecure,
 		},
 	},
@@ -460,6 +519,7 @@ func (sc *SyncCommand) Init(args []string, options OptionMapType) error {
 	bakupOptions[OptionRecursion] = &recursive
 	delete(bakupOptions, OptionDelete)
 	delete(bakupOptions, OptionBackupDir)
+	delete(bakupOptions, OptionWatch)
 
 	copyCommand.cpOption.bSyncCommand = true
 	err := (&copyCommand).Init(args, bakupOptions)
@@ -481,6 +541,8 @@ func (sc *SyncCommand) RunCommand() error {
 	sc.syncOption.disableDirObject, _ = GetBool(OptionDisableDirObject, sc.command.options)
 	sc.syncOption.disableAllSymlink, _ = GetBool(OptionDisableAllSymlink, sc.command.options)
 	sc.syncOption.force, _ = GetBool(OptionForce, sc.command.options)
+	sc.syncOption.dryRun, _ = GetBool(OptionDryRun, sc.command.options)
+	sc.syncOption.watch, _ = GetBool(OptionWatch, sc.command.options)
 
 	// check point dir
 	sc.syncOption.cpDir, _ = GetString(OptionCheckpointDir, sc.command.options)
@@ -529,8 +591,23 @@ func (sc *SyncCommand) RunCommand() error {
 		}
 	}
 
+	if sc.syncOption.watch {
+		if err = sc.checkWatchOptions(srcURL, destURL); err != nil {
+			return err
+		}
+	}
+
+	if err = sc.syncOnce(srcURL, destURL); err != nil || !sc.syncOption.watch {
+		return err
+	}
+	return sc.watchAndSync(srcURL, destURL)
+}
+
+func (sc *SyncCommand) syncOnce(srcURL, destURL StorageURLer) error {
 	if !sc.syncOption.bDelete {
-		return copyCommand.RunCommand()
+		err := copyCommand.RunCommand()
+		sc.printPlanSummary()
+		return err
 	}
 
 	// sync command add '/' afert cloud prefix
@@ -540,7 +617,7 @@ func (sc *SyncCommand) RunCommand() error {
 
 	// check backup dir
 	if destURL.IsFileURL() {
-		err = sc.CheckDestBackupDir(destURL)
+		err := sc.CheckDestBackupDir(destURL)
 		if err != nil {
 			return err
 		}
@@ -548,8 +625,10 @@ func (sc *SyncCommand) RunCommand() error {
 	opType := sc.getCommandType(srcURL, destURL)
 
 	// get file list or object key list
+	sc.objectSizes = make(map[string]int64)
 	srcKeys := make(map[string]string)
 	destKeys := make(map[string]string)
+	var err error
 	if srcURL.IsFileURL() {
 		err = sc.GetLocalFileKeys(srcURL, srcKeys)
 	} else {
@@ -582,6 +661,15 @@ func (sc *SyncCommand) RunCommand() error {
 		}
 	}
 
+	if sc.syncOption.dryRun {
+		err = copyCommand.RunCommand()
+		if err == nil {
+			err = sc.planExtraKeys(destKeys, destURL)
+		}
+		sc.printPlanSummary()
+		return err
+	}
+
 	if destURL.IsFileURL() {
 		fmt.Printf("\nfile(directory) will be removed count:%d\n", len(destKeys))
 	} else {
@@ -771,20 +859,20 @@ func (sc *SyncCommand) GetFileList(strPath string, chFiles chan<- fileInfoType,
 
 func (sc *SyncCommand) ReadLocalFileKeys(chFiles <-chan fileInfoType, chFinish chan<- error, keys map[string]string) {
 	totalCount := 0
-	fmt.Printf("\n")
+	sc.printf("\n")
 	for fileInfo := range chFiles {
 		if copyCommand.filterFile(fileInfo, sc.syncOption.cpDir) { // exclude checkpoint files
 			totalCount++
-			fmt.Printf("\rtotal file(directory) count:%d", totalCount)
+			sc.printf("\rtotal file(directory) count:%d", totalCount)
 			keys[fileInfo.filePath] = ""
 			if len(keys) > MaxSyncNumbers {
-				fmt.Printf("\n")
+				sc.printf("\n")
 				chFinish <- fmt.Errorf("over max sync numbers %d", MaxSyncNumbers)
 				break
 			}
 		}
 	}
-	fmt.Printf("\rtotal file(directory) count:%d", totalCount)
+	sc.printf("\rtotal file(directory) count:%d", totalCount)
 	chFinish <- nil
 }
 
@@ -857,6 +945,9 @@ func (sc *SyncCommand) CheckDestBackupDir(sUrl StorageURLer) error {
 	// create bacup dir
 	f, err = os.Stat(sc.syncOption.backupDir)
 	if err != nil {
+		if sc.syncOption.dryRun {
+			return nil
+		}
 		if err := os.MkdirAll(sc.syncOption.backupDir, 0755); err != nil {
 			return err
 		}
@@ -897,18 +988,21 @@ func (sc *SyncCommand) GetOssKeyList(bucket *oss.Bucket, sURL StorageURLer, chOb
 
 func (sc *SyncCommand) ReadOssKeys(keys map[string]string, sURL StorageURLer, chObjects <-chan objectInfoType, chFinish chan<- error) {
 	totalCount := 0
-	fmt.Printf("\n")
+	sc.printf("\n")
 	for objectInfo := range chObjects {
 		totalCount++
-		fmt.Printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
+		sc.printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
 		keys[objectInfo.relativeKey] = objectInfo.prefix
+		if sc.objectSizes != nil {
+			sc.objectSizes[objectInfo.prefix+objectInfo.relativeKey] = objectInfo.size
+		}
 		if len(keys) > MaxSyncNumbers {
-			fmt.Printf("\n")
+			sc.printf("\n")
 			chFinish <- fmt.Errorf("over max sync numbers %d", MaxSyncNumbers)
 			break
 		}
 	}
-	fmt.Printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
+	sc.printf("\r%s,total oss object count:%d", sURL.ToString(), totalCount)
 	chFinish <- nil
 }
 
@@ -981,3 +1075,62 @@ func (sc *SyncCommand) moveFileToPath(srcName, destName string) error {
 		return nil
 	}
 }
+
+// printf prints the progress of listing, stdout carries the plan with --dry-run
+func (sc *SyncCommand) printf(format string, a ...interface{}) {
+	if !sc.syncOption.dryRun {
+		fmt.Printf(format, a...)
+	}
+}
+
+func (sc *SyncCommand) printPlanSummary() {
+	if sc.syncOption.dryRun && copyCommand.cpOption.plan != nil {
+		copyCommand.cpOption.plan.printSummary()
+	}
+}
+
+// planExtraKeys adds what --delete would remove to the --dry-run plan.
+// Local directories are left out, they are removed when they end up empty.
+func (sc *SyncCommand) planExtraKeys(keys map[string]string, sUrl StorageURLer) error {
+	plan := copyCommand.cpOption.plan
+	var sortList []string
+	for k := range keys {
+		sortList = append(sortList, k)
+	}
+	sort.Strings(sortList)
+
+	if sUrl.IsCloudURL() {
+		bucketName := sUrl.(CloudURL).bucket
+		for _, k := range sortList {
+			object := keys[k] + k
+			plan.add(planEntry{
+				Action:      planDelete,
+				Destination: CloudURLToString(bucketName, object),
+				Size:        sc.objectSizes[object],
+				Reason:      "not in source",
+			})
+		}
+		return nil
+	}
+
+	absDirName, err := sc.GetAbsPath(sUrl.ToString())
+	if err != nil {
+		return err
+	}
+	for _, k := range sortList {
+		if strings.HasSuffix(k, string(os.PathSeparator)) {
+			continue
+		}
+		var size int64
+		if f, err := os.Stat(absDirName + k); err == nil {
+			size = f.Size()
+		}
+		plan.add(planEntry{
+			Action:      planDelete,
+			Destination: absDirName + k,
+			Size:        size,
+			Reason:      "not in source, moved to " + sc.syncOption.backupDir + k,
+		})
+	}
+	return nil
+}
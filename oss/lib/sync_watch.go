@@ -0,0 +1,423 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watched directory must be quiet before the
+// changes collected are synced, so a burst of writes is uploaded once.
+// watchMaxDelay bounds the wait, a directory that is never quiet, e.g. with a
+// log file appended all the time, is still synced that often.
+var (
+	watchDebounce = 2 * time.Second
+	watchMaxDelay = 30 * time.Second
+)
+
+// syncWatcher uploads the files changed under srcDir to destURL, and deletes
+// the objects of the removed ones with --delete, after the initial sync.
+type syncWatcher struct {
+	sc      *SyncCommand
+	watcher *fsnotify.Watcher
+	bucket  *oss.Bucket
+	destURL CloudURL
+	srcDir  string
+
+	// dirs are the watched directories, to tell a removed directory from a
+	// removed file. changes are the paths changed since the last flush,
+	// relative to srcDir, the first of them collected at firstChange.
+	dirs        map[string]bool
+	changes     map[string]bool
+	firstChange time.Time
+
+	// rescan is set after a watcher error, e.g. the inotify queue overflowed,
+	// as changes may have been lost the next flush syncs the whole directory
+	// again with resync.
+	rescan bool
+	resync func() error
+}
+
+func (sc *SyncCommand) checkWatchOptions(srcURL, destURL StorageURLer) error {
+	if !srcURL.IsFileURL() || !destURL.IsCloudURL() {
+		return fmt.Errorf("--watch only supports syncing a local directory to oss")
+	}
+	if sc.syncOption.dryRun {
+		return fmt.Errorf("--watch and --dry-run can't be both exist")
+	}
+	// the snapshot db is closed once the initial sync is done
+	if snapshotPath, _ := GetString(OptionSnapshotPath, sc.command.options); snapshotPath != "" {
+		return fmt.Errorf("--watch and --snapshot-path can't be both exist")
+	}
+	return nil
+}
+
+// watchAndSync keeps syncing srcURL to destURL until interrupted.
+func (sc *SyncCommand) watchAndSync(srcURL, destURL StorageURLer) error {
+	cloudURL := sc.adjustCloudUrl(destURL).(CloudURL)
+	bucket, err := sc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+	srcDir, err := filepath.Abs(srcURL.ToString())
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	w := newSyncWatcher(sc, srcDir)
+	w.watcher = watcher
+	w.bucket = bucket
+	w.destURL = cloudURL
+	w.resync = func() error {
+		return sc.syncOnce(srcURL, destURL)
+	}
+	if err := w.watchDir(srcDir); err != nil {
+		return err
+	}
+
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(chSignal)
+
+	fmt.Printf("\nwatching %s for changes, press Ctrl+C to stop\n", srcURL.ToString())
+	LogInfo("begin watching %s\n", srcDir)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	resetDebounce := func() {
+		if !debounce.Stop() {
+			select {
+			case <-debounce.C:
+			default:
+			}
+		}
+		debounce.Reset(w.flushDelay(time.Now()))
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.collect(event) {
+				resetDebounce()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// e.g. the inotify queue overflowed, some changes are lost
+			fmt.Printf("watch %s error: %s, the directory will be synced again\n", srcDir, err.Error())
+			LogError("watch %s error: %s\n", srcDir, err.Error())
+			w.markRescan(time.Now())
+			resetDebounce()
+		case <-debounce.C:
+			w.flush()
+		case <-chSignal:
+			fmt.Printf("\nstop watching %s\n", srcURL.ToString())
+			LogInfo("stop watching %s\n", srcDir)
+			return nil
+		}
+	}
+}
+
+func newSyncWatcher(sc *SyncCommand, srcDir string) *syncWatcher {
+	return &syncWatcher{
+		sc:      sc,
+		srcDir:  srcDir,
+		dirs:    map[string]bool{},
+		changes: map[string]bool{},
+	}
+}
+
+// watchDir watches dir and, unless --only-current-dir, its subdirectories.
+func (w *syncWatcher) watchDir(dir string) error {
+	return filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			// removed while walking
+			return nil
+		}
+		if !f.IsDir() || !copyCommand.filterPath(path, w.sc.syncOption.cpDir) {
+			return nil
+		}
+		if path != w.srcDir && w.sc.syncOption.onlyCurrentDir {
+			return filepath.SkipDir
+		}
+		if w.watcher != nil {
+			if err := w.watcher.Add(path); err != nil {
+				return fmt.Errorf("watch %s error: %s", path, err.Error())
+			}
+		}
+		w.dirs[path] = true
+		return nil
+	})
+}
+
+// collect records the path of event, and reports whether it is a change to
+// sync.
+func (w *syncWatcher) collect(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	rel, err := filepath.Rel(w.srcDir, event.Name)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return false
+	}
+	if w.sc.syncOption.onlyCurrentDir && strings.Contains(rel, string(os.PathSeparator)) {
+		return false
+	}
+	if !copyCommand.filterPath(event.Name, w.sc.syncOption.cpDir) {
+		return false
+	}
+
+	// watch new directories at once, files created in them before the watch
+	// starts are found when the directory is listed on flush
+	if event.Op&fsnotify.Create != 0 {
+		if f, err := os.Stat(event.Name); err == nil && f.IsDir() && !w.dirs[event.Name] {
+			if err := w.watchDir(event.Name); err != nil {
+				LogError("%s\n", err.Error())
+			}
+		}
+	}
+	if len(w.changes) == 0 && !w.rescan {
+		w.firstChange = time.Now()
+	}
+	w.changes[rel] = true
+	return true
+}
+
+// markRescan asks the next flush to sync the whole directory again.
+func (w *syncWatcher) markRescan(now time.Time) {
+	if len(w.changes) == 0 && !w.rescan {
+		w.firstChange = now
+	}
+	w.rescan = true
+}
+
+// flushDelay is how long to wait for more changes, watchDebounce but no
+// later than watchMaxDelay after the first change pending.
+func (w *syncWatcher) flushDelay(now time.Time) time.Duration {
+	delay := watchDebounce
+	if left := w.firstChange.Add(watchMaxDelay).Sub(now); left < delay {
+		delay = left
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// pendingChanges returns the changed paths in order, leaving out those under
+// a changed directory, which is listed anyway.
+func (w *syncWatcher) pendingChanges() []string {
+	var paths []string
+	for rel := range w.changes {
+		paths = append(paths, rel)
+	}
+	w.changes = map[string]bool{}
+	sort.Strings(paths)
+
+	var result []string
+	parent := ""
+	for _, rel := range paths {
+		if parent != "" && strings.HasPrefix(rel, parent) {
+			continue
+		}
+		result = append(result, rel)
+		parent = ""
+		if f, err := os.Stat(filepath.Join(w.srcDir, rel)); err == nil && f.IsDir() {
+			parent = rel + string(os.PathSeparator)
+		} else if err != nil && w.dirs[filepath.Join(w.srcDir, rel)] {
+			parent = rel + string(os.PathSeparator)
+		}
+	}
+	return result
+}
+
+// flush syncs the changes collected, or the whole directory after a watcher
+// error. Errors are printed, watching goes on.
+func (w *syncWatcher) flush() {
+	// the checkpoint dir is removed after every successful run
+	os.MkdirAll(copyCommand.cpOption.cpDir, 0755)
+
+	if w.rescan {
+		w.rescan = false
+		w.changes = map[string]bool{}
+		// watch the directories created while events were lost
+		w.dirs = map[string]bool{}
+		if err := w.watchDir(w.srcDir); err != nil {
+			LogError("%s\n", err.Error())
+		}
+		if err := w.resync(); err != nil {
+			w.printError(fmt.Sprintf("sync %s", w.srcDir), err)
+		}
+		return
+	}
+
+	for _, rel := range w.pendingChanges() {
+		path := filepath.Join(w.srcDir, rel)
+		f, err := os.Stat(path)
+		if err == nil {
+			if f.IsDir() {
+				// --only-current-dir leaves out directories
+				if !w.sc.syncOption.onlyCurrentDir {
+					w.uploadDir(rel)
+				}
+			} else {
+				w.uploadFile(fileInfoType{rel, w.srcDir})
+			}
+			continue
+		}
+		if !os.IsNotExist(err) {
+			w.printError(fmt.Sprintf("stat %s", path), err)
+			continue
+		}
+		wasDir := w.dirs[path]
+		w.forgetDir(path)
+		if w.sc.syncOption.bDelete {
+			w.deleteRemoved(rel, wasDir)
+		}
+	}
+}
+
+func (w *syncWatcher) uploadDir(rel string) {
+	dpath := filepath.Join(w.srcDir, rel) + string(os.PathSeparator)
+	chFiles := make(chan fileInfoType, ChannelBuf)
+	chListError := make(chan error, 1)
+	go func() {
+		chListError <- getFileListCommon(dpath, chFiles, w.sc.syncOption.onlyCurrentDir,
+			w.sc.syncOption.disableAllSymlink, w.sc.syncOption.enableSymlinkDir, w.sc.syncOption.filters)
+	}()
+
+	w.uploadFile(fileInfoType{rel + string(os.PathSeparator), w.srcDir})
+	for file := range chFiles {
+		// dirs are listed with a trailing separator, which Join drops
+		w.uploadFile(fileInfoType{rel + string(os.PathSeparator) + file.filePath, w.srcDir})
+	}
+	if err := <-chListError; err != nil {
+		w.printError(fmt.Sprintf("list %s", dpath), err)
+	}
+}
+
+func (w *syncWatcher) uploadFile(file fileInfoType) {
+	isDirPath := strings.HasSuffix(file.filePath, string(os.PathSeparator))
+	if !isDirPath && !doesSingleFileMatchPatterns(file.filePath, w.sc.syncOption.filters) {
+		return
+	}
+	if !copyCommand.filterFile(file, w.sc.syncOption.cpDir) {
+		return
+	}
+	if w.sc.syncOption.disableAllSymlink && !isDirPath {
+		if f, err := os.Lstat(filepath.Join(file.dir, file.filePath)); err == nil && f.Mode()&os.ModeSymlink != 0 {
+			return
+		}
+	}
+
+	skip, err, _, _, msg := copyCommand.uploadFile(w.bucket, w.destURL, file)
+	if err != nil {
+		w.printError(msg, err)
+	} else if skip {
+		LogInfo("upload file skip:%s\n", file.filePath)
+	} else {
+		fmt.Printf("%s\n", msg)
+		LogInfo("%s\n", msg)
+	}
+}
+
+// forgetDir stops tracking dir and its subdirectories, the watches of
+// removed directories are gone with them.
+func (w *syncWatcher) forgetDir(dir string) {
+	prefix := dir + string(os.PathSeparator)
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(w.dirs, path)
+		}
+	}
+}
+
+// watchObjectName is the object of the local path rel, like makeObjectName.
+func (w *syncWatcher) watchObjectName(rel string) string {
+	return w.destURL.object + strings.Replace(rel, string(os.PathSeparator), "/", -1)
+}
+
+func (w *syncWatcher) deleteRemoved(rel string, wasDir bool) {
+	if !wasDir {
+		object := w.watchObjectName(rel)
+		if doesSingleFileMatchPatterns(rel, w.sc.syncOption.filters) && w.objectExists(object) {
+			w.deleteObjects([]string{object})
+		}
+		return
+	}
+
+	prefix := w.watchObjectName(rel) + "/"
+	chObjects := make(chan objectInfoType, ChannelBuf)
+	chListError := make(chan error, 1)
+	go func() {
+		chListError <- getObjectListCommon(w.bucket, CloudURL{bucket: w.destURL.bucket, object: prefix}, chObjects,
+			false, w.sc.syncOption.filters, w.sc.syncOption.payerOptions)
+	}()
+
+	var objects []string
+	for objectInfo := range chObjects {
+		objects = append(objects, objectInfo.prefix+objectInfo.relativeKey)
+	}
+	if err := <-chListError; err != nil {
+		w.printError(fmt.Sprintf("list %s", CloudURLToString(w.destURL.bucket, prefix)), err)
+		return
+	}
+	// the directory object itself is not listed
+	if w.objectExists(prefix) {
+		objects = append(objects, prefix)
+	}
+	w.deleteObjects(objects)
+}
+
+// objectExists keeps files that were never uploaded, e.g. the temporary
+// files of editors, out of the deletions printed.
+func (w *syncWatcher) objectExists(object string) bool {
+	exist, err := w.bucket.IsObjectExist(object, w.sc.syncOption.payerOptions...)
+	if err != nil {
+		w.printError(fmt.Sprintf("stat %s", CloudURLToString(w.destURL.bucket, object)), err)
+		return false
+	}
+	return exist
+}
+
+// deleteObjects deletes objects in batches, no confirmation is asked in
+// watch mode.
+func (w *syncWatcher) deleteObjects(objects []string) {
+	rmOptions := append(w.sc.syncOption.payerOptions, oss.DeleteObjectsQuiet(true))
+	for len(objects) > 0 {
+		batch := objects
+		if len(batch) > MaxBatchCount {
+			batch = batch[:MaxBatchCount]
+		}
+		objects = objects[len(batch):]
+		if err := w.sc.BatchRmObjects(w.bucket, batch, rmOptions); err != nil {
+			w.printError("delete objects", err)
+			continue
+		}
+		for _, object := range batch {
+			fmt.Printf("delete %s\n", CloudURLToString(w.destURL.bucket, object))
+		}
+	}
+}
+
+func (w *syncWatcher) printError(msg string, err error) {
+	fmt.Printf("%s error: %s\n", msg, err.Error())
+	LogError("%s error: %s\n", msg, err.Error())
+}
@@ -0,0 +1,115 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWatchOptions(t *testing.T) {
+	var sc SyncCommand
+	sc.command.options = OptionMapType{}
+	local := FileURL{urlStr: t.TempDir()}
+	cloud := CloudURL{bucket: "bucket", object: "p"}
+
+	assert.NoError(t, sc.checkWatchOptions(local, cloud))
+	assert.EqualError(t, sc.checkWatchOptions(cloud, local), "--watch only supports syncing a local directory to oss")
+	assert.EqualError(t, sc.checkWatchOptions(cloud, cloud), "--watch only supports syncing a local directory to oss")
+
+	snapshotPath := "snapshot"
+	sc.command.options[OptionSnapshotPath] = &snapshotPath
+	assert.EqualError(t, sc.checkWatchOptions(local, cloud), "--watch and --snapshot-path can't be both exist")
+
+	sc.syncOption.dryRun = true
+	assert.EqualError(t, sc.checkWatchOptions(local, cloud), "--watch and --dry-run can't be both exist")
+}
+
+func TestSyncWatcherCollect(t *testing.T) {
+	dir := t.TempDir()
+	sep := string(os.PathSeparator)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0600))
+
+	var sc SyncCommand
+	sc.syncOption.cpDir = filepath.Join(dir, CheckpointDir)
+	w := newSyncWatcher(&sc, dir)
+	require.NoError(t, w.watchDir(dir))
+	assert.Equal(t, map[string]bool{dir: true, filepath.Join(dir, "sub"): true, filepath.Join(dir, "sub", "deep"): true}, w.dirs)
+
+	assert.False(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "a.txt"), Op: fsnotify.Chmod}))
+	assert.False(t, w.collect(fsnotify.Event{Name: dir, Op: fsnotify.Write}))
+	assert.False(t, w.collect(fsnotify.Event{Name: filepath.Join(sc.syncOption.cpDir, "x.cp"), Op: fsnotify.Create}))
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "sub", "a.txt"), Op: fsnotify.Write}))
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "sub"), Op: fsnotify.Create}))
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "b.txt"), Op: fsnotify.Remove}))
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "b.txt"), Op: fsnotify.Create}))
+
+	// files under a changed directory are uploaded with it
+	assert.Equal(t, []string{"b.txt", "sub"}, w.pendingChanges())
+	assert.Empty(t, w.changes)
+
+	// a removed directory is still known by its watch
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "sub")))
+	w.collect(fsnotify.Event{Name: filepath.Join(dir, "sub", "deep"), Op: fsnotify.Remove})
+	w.collect(fsnotify.Event{Name: filepath.Join(dir, "sub"), Op: fsnotify.Remove})
+	assert.Equal(t, []string{"sub"}, w.pendingChanges())
+	w.forgetDir(filepath.Join(dir, "sub"))
+	assert.Equal(t, map[string]bool{dir: true}, w.dirs)
+
+	// --only-current-dir ignores subdirectories
+	sc.syncOption.onlyCurrentDir = true
+	assert.False(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "sub", "c.txt"), Op: fsnotify.Create}))
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "c.txt"), Op: fsnotify.Create}))
+	assert.Equal(t, []string{"c.txt"}, w.pendingChanges())
+	assert.Equal(t, "p/sub/c.txt", (&syncWatcher{destURL: CloudURL{object: "p/"}}).watchObjectName("sub"+sep+"c.txt"))
+}
+
+func TestSyncWatcherFlushDelay(t *testing.T) {
+	dir := t.TempDir()
+	var sc SyncCommand
+	w := newSyncWatcher(&sc, dir)
+	start := time.Now()
+
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "a.log"), Op: fsnotify.Write}))
+	first := w.firstChange
+	assert.False(t, first.Before(start))
+	assert.Equal(t, watchDebounce, w.flushDelay(first))
+
+	// later changes do not move the first one, the wait is bounded
+	assert.True(t, w.collect(fsnotify.Event{Name: filepath.Join(dir, "a.log"), Op: fsnotify.Write}))
+	assert.Equal(t, first, w.firstChange)
+	assert.Equal(t, time.Second, w.flushDelay(first.Add(watchMaxDelay-time.Second)))
+	assert.Equal(t, time.Duration(0), w.flushDelay(first.Add(watchMaxDelay+time.Second)))
+}
+
+func TestSyncWatcherRescanAfterError(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	var sc SyncCommand
+	sc.syncOption.cpDir = filepath.Join(t.TempDir(), CheckpointDir)
+	w := newSyncWatcher(&sc, dir)
+	resyncs := 0
+	w.resync = func() error {
+		resyncs++
+		return nil
+	}
+
+	w.collect(fsnotify.Event{Name: filepath.Join(dir, "a.txt"), Op: fsnotify.Create})
+	first := w.firstChange
+	w.markRescan(first.Add(time.Second))
+	assert.Equal(t, first, w.firstChange)
+	w.flush()
+	assert.Equal(t, 1, resyncs)
+	assert.False(t, w.rescan)
+	assert.Empty(t, w.changes)
+	assert.True(t, w.dirs[filepath.Join(dir, "sub")])
+
+	// only a watcher error resyncs the whole directory
+	w.flush()
+	assert.Equal(t, 1, resyncs)
+}
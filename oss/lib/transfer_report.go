@@ -0,0 +1,330 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// transferRecord is a line of --report-json, the outcome of an object.
+type transferRecord struct {
+	Type       string `json:"type"`
+	Op         string `json:"op"`
+	Src        string `json:"src"`
+	Dest       string `json:"dest"`
+	Status     string `json:"status"`
+	Size       int64  `json:"size"`
+	StartTime  string `json:"startTime"`
+	DurationMs int64  `json:"durationMs"`
+	Retries    int64  `json:"retries"`
+	ErrorCode  string `json:"errorCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// transferSummary is the end of a run, the last line of --report-json. The
+// throughput percentiles are of the objects transferred, in byte/s.
+type transferSummary struct {
+	Type          string           `json:"type"`
+	Op            string           `json:"op"`
+	StartTime     string           `json:"startTime"`
+	EndTime       string           `json:"endTime"`
+	DurationMs    int64            `json:"durationMs"`
+	OK            int64            `json:"ok"`
+	Skipped       int64            `json:"skipped"`
+	Failed        int64            `json:"failed"`
+	Bytes         int64            `json:"bytes"`
+	Retries       int64            `json:"retries"`
+	Throughput    int64            `json:"throughput"`
+	ThroughputP50 int64            `json:"throughputP50"`
+	ThroughputP90 int64            `json:"throughputP90"`
+	ThroughputP99 int64            `json:"throughputP99"`
+	ErrorCodes    map[string]int64 `json:"errorCodes,omitempty"`
+}
+
+// transferReport collects the outcome of every object cp and sync transfer
+// for --report-json and --metrics-file.
+type transferReport struct {
+	mu          sync.Mutex
+	op          string
+	start       time.Time
+	out         *os.File
+	path        string
+	metricsPath string
+	retries     map[string]int64
+	speeds      []float64
+	summary     transferSummary
+}
+
+func transferOpName(opType operationType) string {
+	switch opType {
+	case operationTypePut:
+		return opUpload
+	case operationTypeGet:
+		return opDownload
+	}
+	return opCopy
+}
+
+func newTransferReport(opType operationType, path, metricsPath string) (*transferReport, error) {
+	r := &transferReport{
+		op:          transferOpName(opType),
+		start:       time.Now(),
+		path:        path,
+		metricsPath: metricsPath,
+		retries:     map[string]int64{},
+	}
+	r.summary = transferSummary{Type: "summary", Op: r.op, ErrorCodes: map[string]int64{}}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+		if err != nil {
+			return nil, fmt.Errorf("Create report json file error: %s", err.Error())
+		}
+		r.out = f
+	}
+	return r, nil
+}
+
+// addRetry counts a retry of the object the retry functions of cp transfer,
+// the object uploaded to, or downloaded or copied from.
+func (r *transferReport) addRetry(object string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries[object]++
+}
+
+// transferErrorCode is the error code of OSS, NetworkError or ClientError.
+func transferErrorCode(err error) string {
+	for {
+		switch e := err.(type) {
+		case ObjectError:
+			err = e.err
+		case FileError:
+			err = e.err
+		case BucketError:
+			err = e.err
+		case CopyError:
+			err = e.err
+		case oss.ServiceError:
+			return e.Code
+		case *oss.ServiceError:
+			return e.Code
+		case net.Error:
+			return "NetworkError"
+		default:
+			return "ClientError"
+		}
+	}
+}
+
+// record adds the outcome of an object, retryKey is the object its retries
+// are counted by, size is -1 if unknown.
+func (r *transferReport) record(src, dest, retryKey string, startT time.Time, skip bool, err error, size int64) {
+	if r == nil {
+		return
+	}
+	cost := time.Since(startT)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := transferRecord{
+		Type:       "object",
+		Op:         r.op,
+		Src:        src,
+		Dest:       dest,
+		Status:     manifestStatusOK,
+		Size:       size,
+		StartTime:  startT.UTC().Format(time.RFC3339Nano),
+		DurationMs: cost.Nanoseconds() / int64(time.Millisecond),
+		Retries:    r.retries[retryKey],
+	}
+	delete(r.retries, retryKey)
+	r.summary.Retries += record.Retries
+
+	if err != nil {
+		record.Status = manifestStatusError
+		record.ErrorCode = transferErrorCode(err)
+		record.Error = err.Error()
+		r.summary.Failed++
+		r.summary.ErrorCodes[record.ErrorCode]++
+	} else if skip {
+		record.Status = manifestStatusSkip
+		r.summary.Skipped++
+	} else {
+		r.summary.OK++
+		if size > 0 {
+			r.summary.Bytes += size
+			if cost > 0 {
+				r.speeds = append(r.speeds, float64(size)/cost.Seconds())
+			}
+		}
+	}
+	if r.out != nil {
+		fmt.Fprintf(r.out, "%s\n", marshalJSON(record, false, ""))
+	}
+}
+
+// percentile is the nearest rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return int64(sorted[rank])
+}
+
+// finish makes the summary at the end of the run.
+func (r *transferReport) finish(end time.Time) transferSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.summary
+	s.StartTime = r.start.UTC().Format(time.RFC3339Nano)
+	s.EndTime = end.UTC().Format(time.RFC3339Nano)
+	cost := end.Sub(r.start)
+	s.DurationMs = cost.Nanoseconds() / int64(time.Millisecond)
+	if cost > 0 {
+		s.Throughput = int64(float64(s.Bytes) / cost.Seconds())
+	}
+	speeds := append([]float64{}, r.speeds...)
+	sort.Float64s(speeds)
+	s.ThroughputP50 = percentile(speeds, 50)
+	s.ThroughputP90 = percentile(speeds, 90)
+	s.ThroughputP99 = percentile(speeds, 99)
+	if len(s.ErrorCodes) == 0 {
+		s.ErrorCodes = nil
+	}
+	return s
+}
+
+func printTransferSummary(out io.Writer, s transferSummary) {
+	fmt.Fprintf(out, "\n%s summary: ok %d, skip %d, error %d, %s bytes in %.3fs, %s(byte/s), retries %d\n",
+		s.Op, s.OK, s.Skipped, s.Failed, getSizeString(s.Bytes), float64(s.DurationMs)/1000, getSizeString(s.Throughput), s.Retries)
+	fmt.Fprintf(out, "object throughput(byte/s): p50 %s, p90 %s, p99 %s\n",
+		getSizeString(s.ThroughputP50), getSizeString(s.ThroughputP90), getSizeString(s.ThroughputP99))
+	codes := make([]string, 0, len(s.ErrorCodes))
+	for code := range s.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(out, "error %s: %d\n", code, s.ErrorCodes[code])
+	}
+}
+
+func escapeMetricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeMetrics writes the summary for the textfile collector of the
+// Prometheus node exporter. The file is replaced by a rename, so the
+// collector never reads a half written one.
+func writeMetrics(path string, s transferSummary, end time.Time) error {
+	buf := new(bytes.Buffer)
+	op := escapeMetricLabel(s.Op)
+	metric := func(name, help string) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	metric("ossutil_transfer_objects", "Objects of the last run by status.")
+	for _, v := range []struct {
+		status string
+		count  int64
+	}{{manifestStatusOK, s.OK}, {manifestStatusSkip, s.Skipped}, {manifestStatusError, s.Failed}} {
+		fmt.Fprintf(buf, "ossutil_transfer_objects{op=\"%s\",status=\"%s\"} %d\n", op, v.status, v.count)
+	}
+	metric("ossutil_transfer_errors", "Objects failed in the last run by error code.")
+	codes := make([]string, 0, len(s.ErrorCodes))
+	for code := range s.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(buf, "ossutil_transfer_errors{op=\"%s\",code=\"%s\"} %d\n", op, escapeMetricLabel(code), s.ErrorCodes[code])
+	}
+	metric("ossutil_transfer_bytes", "Bytes transferred in the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_bytes{op=\"%s\"} %d\n", op, s.Bytes)
+	metric("ossutil_transfer_retries", "Retries in the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_retries{op=\"%s\"} %d\n", op, s.Retries)
+	metric("ossutil_transfer_duration_seconds", "Duration of the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_duration_seconds{op=\"%s\"} %g\n", op, float64(s.DurationMs)/1000)
+	metric("ossutil_transfer_throughput_bytes_per_second", "Bytes per second of the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_throughput_bytes_per_second{op=\"%s\"} %d\n", op, s.Throughput)
+	metric("ossutil_transfer_object_throughput_bytes_per_second", "Percentiles of the bytes per second of the objects transferred in the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_object_throughput_bytes_per_second{op=\"%s\",quantile=\"0.5\"} %d\n", op, s.ThroughputP50)
+	fmt.Fprintf(buf, "ossutil_transfer_object_throughput_bytes_per_second{op=\"%s\",quantile=\"0.9\"} %d\n", op, s.ThroughputP90)
+	fmt.Fprintf(buf, "ossutil_transfer_object_throughput_bytes_per_second{op=\"%s\",quantile=\"0.99\"} %d\n", op, s.ThroughputP99)
+	metric("ossutil_transfer_success", "1 if no object failed in the last run.")
+	success := 1
+	if s.Failed > 0 {
+		success = 0
+	}
+	fmt.Fprintf(buf, "ossutil_transfer_success{op=\"%s\"} %d\n", op, success)
+	metric("ossutil_transfer_last_run_timestamp_seconds", "End time of the last run.")
+	fmt.Fprintf(buf, "ossutil_transfer_last_run_timestamp_seconds{op=\"%s\"} %d\n", op, end.Unix())
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf.Bytes(), 0664); err != nil {
+		return fmt.Errorf("write metrics file error: %s", err.Error())
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write metrics file error: %s", err.Error())
+	}
+	return nil
+}
+
+// close writes the summary to the report json file and the metrics file,
+// and prints it.
+func (r *transferReport) close(out io.Writer) error {
+	end := time.Now()
+	s := r.finish(end)
+	if r.out != nil {
+		fmt.Fprintf(r.out, "%s\n", marshalJSON(s, false, ""))
+		r.out.Close()
+	}
+	printTransferSummary(out, s)
+	if r.path != "" {
+		fmt.Fprintf(out, "report json: %s\n", r.path)
+	}
+	if r.metricsPath != "" {
+		if err := writeMetrics(r.metricsPath, s, end); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "metrics file: %s\n", r.metricsPath)
+	}
+	return nil
+}
+
+// recordTransfer adds the outcome of an object to the transfer report, if
+// there is one.
+func (cc *CopyCommand) recordTransfer(src, dest, retryKey string, startT time.Time, skip bool, err error, size int64) {
+	cc.cpOption.transferReport.record(src, dest, retryKey, startT, skip, err, size)
+}
+
+func (cc *CopyCommand) addRetry(object string) {
+	cc.cpOption.transferReport.addRetry(object)
+}
+
+// fileSize is the size of a local file for the transfer report, -1 if it
+// can't be got.
+func fileSize(path string) int64 {
+	if f, err := os.Stat(path); err == nil && !f.IsDir() {
+		return f.Size()
+	}
+	return -1
+}
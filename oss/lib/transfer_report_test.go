@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTransferReport(t *testing.T, path string) ([]transferRecord, transferSummary) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []transferRecord
+	var summary transferSummary
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if strings.Contains(string(line), `"type":"summary"`) {
+			require.NoError(t, json.Unmarshal(line, &summary))
+			continue
+		}
+		var record transferRecord
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+	return records, summary
+}
+
+func TestTransferErrorCode(t *testing.T) {
+	assert.Equal(t, "NoSuchKey", transferErrorCode(ObjectError{oss.ServiceError{Code: "NoSuchKey"}, "bucket", "a"}))
+	assert.Equal(t, "InternalError", transferErrorCode(FileError{oss.ServiceError{Code: "InternalError"}, "a"}))
+	assert.Equal(t, "NetworkError", transferErrorCode(ObjectError{&net.OpError{Op: "dial", Err: errors.New("refused")}, "bucket", "a"}))
+	assert.Equal(t, "ClientError", transferErrorCode(FileError{errors.New("no such file"), "a"}))
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, int64(0), percentile(nil, 50))
+	values := []float64{}
+	for i := 1; i <= 100; i++ {
+		values = append(values, float64(i))
+	}
+	assert.Equal(t, int64(50), percentile(values, 50))
+	assert.Equal(t, int64(90), percentile(values, 90))
+	assert.Equal(t, int64(99), percentile(values, 99))
+	assert.Equal(t, int64(7), percentile([]float64{7}, 99))
+}
+
+func TestTransferReport(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.jsonl")
+	metricsPath := filepath.Join(dir, "ossutil.prom")
+	r, err := newTransferReport(operationTypePut, reportPath, metricsPath)
+	require.NoError(t, err)
+
+	startT := time.Now().Add(-time.Second)
+	r.addRetry("a")
+	r.addRetry("a")
+	r.record("/data/a", "oss://bucket/a", "a", startT, false, nil, 1000)
+	r.record("/data/b", "oss://bucket/b", "b", startT, true, nil, 10)
+	r.record("/data/c", "oss://bucket/c", "c", startT, false, ObjectError{oss.ServiceError{Code: "AccessDenied"}, "bucket", "c"}, 10)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, r.close(out))
+	assert.Contains(t, out.String(), "upload summary: ok 1, skip 1, error 1, 1,000 bytes in")
+	assert.Contains(t, out.String(), "retries 2\nobject throughput(byte/s): p50 ")
+	assert.Contains(t, out.String(), "error AccessDenied: 1\n")
+	assert.Contains(t, out.String(), "report json: "+reportPath+"\n")
+
+	records, summary := readTransferReport(t, reportPath)
+	require.Len(t, records, 3)
+	assert.Equal(t, "object", records[0].Type)
+	assert.Equal(t, "upload", records[0].Op)
+	assert.Equal(t, manifestStatusOK, records[0].Status)
+	assert.Equal(t, int64(2), records[0].Retries)
+	assert.Equal(t, int64(1000), records[0].Size)
+	assert.True(t, records[0].DurationMs >= 1000)
+	assert.Equal(t, manifestStatusSkip, records[1].Status)
+	assert.Equal(t, int64(0), records[1].Retries)
+	assert.Equal(t, manifestStatusError, records[2].Status)
+	assert.Equal(t, "AccessDenied", records[2].ErrorCode)
+	assert.Contains(t, records[2].Error, "Object=c")
+
+	assert.Equal(t, "summary", summary.Type)
+	assert.Equal(t, int64(1), summary.OK)
+	assert.Equal(t, int64(1), summary.Skipped)
+	assert.Equal(t, int64(1), summary.Failed)
+	assert.Equal(t, int64(1000), summary.Bytes)
+	assert.Equal(t, int64(2), summary.Retries)
+	assert.Equal(t, map[string]int64{"AccessDenied": 1}, summary.ErrorCodes)
+	assert.True(t, summary.ThroughputP50 > 0 && summary.ThroughputP50 <= 1000)
+	assert.Equal(t, summary.ThroughputP50, summary.ThroughputP99)
+
+	metrics, err := ioutil.ReadFile(metricsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(metrics), "# HELP ossutil_transfer_objects Objects of the last run by status.\n# TYPE ossutil_transfer_objects gauge\n")
+	assert.Contains(t, string(metrics), "ossutil_transfer_objects{op=\"upload\",status=\"ok\"} 1\n")
+	assert.Contains(t, string(metrics), "ossutil_transfer_errors{op=\"upload\",code=\"AccessDenied\"} 1\n")
+	assert.Contains(t, string(metrics), "ossutil_transfer_bytes{op=\"upload\"} 1000\n")
+	assert.Contains(t, string(metrics), "ossutil_transfer_success{op=\"upload\"} 0\n")
+	_, err = os.Stat(metricsPath + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = newTransferReport(operationTypePut, filepath.Join(dir, "missing", "report.jsonl"), "")
+	assert.Contains(t, err.Error(), "Create report json file error")
+
+	// no report is kept without the options
+	var nilReport *transferReport
+	nilReport.addRetry("a")
+	nilReport.record("a", "b", "a", startT, false, nil, 1)
+}
+
+func TestTransferReportCopyCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/bucket/dir/a.txt":
+			ioutil.ReadAll(r.Body)
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket/dir/b.txt":
+			w.Write([]byte("bbbb"))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	}))
+	defer server.Close()
+	client, err := oss.New(server.URL, "ak", "sk", oss.ForcePathStyle(true))
+	require.NoError(t, err)
+	bucket, err := client.Bucket("bucket")
+	require.NoError(t, err)
+
+	// no progress bar is running
+	origin := signalNum
+	signalNum = -1
+	defer func() { signalNum = origin }()
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("aaa"), 0600))
+	reportPath := filepath.Join(dir, "report.jsonl")
+
+	var cc CopyCommand
+	cc.command.options = OptionMapType{}
+	cc.cpOption.force = true
+	cc.cpOption.threshold = DefaultBigFileThreshold
+	cc.monitor.init(operationTypePut)
+	cc.cpOption.transferReport, err = newTransferReport(operationTypePut, reportPath, "")
+	require.NoError(t, err)
+
+	assert.NoError(t, cc.uploadFileWithReport(bucket, CloudURL{bucket: "bucket", object: "dir/"}, fileInfoType{"a.txt", dir}))
+	downloadDir := filepath.Join(dir, "download") + string(os.PathSeparator)
+	assert.NoError(t, cc.downloadSingleFileWithReport(bucket, objectInfoType{"dir/", "b.txt", 4, time.Now()}, downloadDir))
+	assert.Error(t, cc.downloadSingleFileWithReport(bucket, objectInfoType{"dir/", "c.txt", 1, time.Now()}, downloadDir))
+	require.NoError(t, cc.cpOption.transferReport.close(new(bytes.Buffer)))
+
+	records, summary := readTransferReport(t, reportPath)
+	require.Len(t, records, 3)
+	assert.Equal(t, filepath.Join(dir, "a.txt"), records[0].Src)
+	assert.Equal(t, "oss://bucket/dir/a.txt", records[0].Dest)
+	assert.Equal(t, int64(3), records[0].Size)
+	assert.Equal(t, manifestStatusOK, records[0].Status)
+	assert.Equal(t, "oss://bucket/dir/b.txt", records[1].Src)
+	assert.Equal(t, downloadDir+"b.txt", records[1].Dest)
+	assert.Equal(t, int64(4), records[1].Size)
+	assert.Equal(t, manifestStatusError, records[2].Status)
+	assert.Equal(t, "NoSuchKey", records[2].ErrorCode)
+	assert.Equal(t, int64(2), summary.OK)
+	assert.Equal(t, int64(7), summary.Bytes)
+	assert.Equal(t, int64(1), summary.Failed)
+}
@@ -0,0 +1,318 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit keeps an opt-in, append-only JSON lines log of the API calls
+// and plugin runs made by the CLI.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigFileName     = "audit.json"
+	DefaultLogFileName = "audit.log"
+)
+
+const (
+	EnvEnabled = "ALIBABA_CLOUD_CLI_AUDIT_LOG_ENABLED"
+	EnvPath    = "ALIBABA_CLOUD_CLI_AUDIT_LOG_PATH"
+)
+
+type Config struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path,omitempty"`
+}
+
+func Default() *Config {
+	return &Config{}
+}
+
+func GetConfigFilePath(configDir string) string {
+	return filepath.Join(configDir, ConfigFileName)
+}
+
+// LogPath returns the configured log file, or audit.log under configDir.
+func (c *Config) LogPath(configDir string) string {
+	if c != nil && c.Path != "" {
+		return c.Path
+	}
+	return filepath.Join(configDir, DefaultLogFileName)
+}
+
+func Load(configDir string) (*Config, error) {
+	data, err := os.ReadFile(GetConfigFilePath(configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Default(), nil
+	}
+	return &c, nil
+}
+
+func Save(configDir string, c *Config) error {
+	if c == nil {
+		c = Default()
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetConfigFilePath(configDir), data, 0600)
+}
+
+func MergeFromEnv(base *Config) *Config {
+	if base == nil {
+		base = Default()
+	}
+	out := *base
+	if raw, ok := os.LookupEnv(EnvEnabled); ok {
+		if enabled, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			out.Enabled = enabled
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv(EnvPath)); raw != "" {
+		out.Path = raw
+	}
+	return &out
+}
+
+func LoadEffective(configDir string) (*Config, error) {
+	c, err := Load(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return MergeFromEnv(c), nil
+}
+
+// EventExit marks the record written when the process exits, it carries the
+// exit code of the whole run.
+const EventExit = "exit"
+
+// Entry is one line of the audit log. A call is written as soon as it
+// completes; the exit code of the run follows in a separate exit record.
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	Event      string            `json:"event,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Pid        int               `json:"pid"`
+	Profile    string            `json:"profile,omitempty"`
+	Mode       string            `json:"mode,omitempty"`
+	Product    string            `json:"product,omitempty"`
+	Api        string            `json:"api,omitempty"`
+	Method     string            `json:"method,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	Plugin     string            `json:"plugin,omitempty"`
+	Region     string            `json:"region,omitempty"`
+	Endpoint   string            `json:"endpoint,omitempty"`
+	RequestId  string            `json:"request_id,omitempty"`
+	StatusCode int               `json:"status_code,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Cached     bool              `json:"cached,omitempty"`
+	ExitCode   *int              `json:"exit_code,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+}
+
+// Log appends entries to one log file. A nil *Log is a disabled log, so
+// callers do not need to check.
+type Log struct {
+	path string
+}
+
+type runningEntry struct {
+	path  string
+	entry Entry
+	start time.Time
+}
+
+var (
+	mu sync.Mutex
+	// running holds the calls started but not ended yet, e.g. a plugin process
+	running []*runningEntry
+	// paths are the log files written by this process, each gets an exit record
+	paths    []string
+	writeErr error
+	now      = time.Now
+)
+
+// Open returns the log configured for configDir, or nil when auditing is off.
+func Open(configDir string) *Log {
+	c, err := LoadEffective(configDir)
+	if err != nil || !c.Enabled {
+		return nil
+	}
+	return &Log{path: c.LogPath(configDir)}
+}
+
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Record appends a finished call right away, so it is kept even if the
+// process is killed later. A write error is returned by Finish.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	fill(&e)
+	mu.Lock()
+	defer mu.Unlock()
+	write(l.path, e)
+}
+
+// Start begins a call that is still running, e.g. a plugin process, and
+// returns the function that ends and appends it. A call that is never ended,
+// because the process exits first, is timed and appended by Finish.
+func (l *Log) Start(e Entry) func() {
+	if l == nil {
+		return func() {}
+	}
+	fill(&e)
+	r := &runningEntry{path: l.path, entry: e, start: now()}
+	mu.Lock()
+	running = append(running, r)
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if removeRunning(r) {
+			r.entry.DurationMs = now().Sub(r.start).Milliseconds()
+			write(r.path, r.entry)
+		}
+	}
+}
+
+// Finish appends the calls still running and an exit record with exitCode to
+// every log written by this process. It returns the first write error of the
+// run. It is registered both as exit hook and on the normal return path of
+// main, only the first call writes.
+func Finish(exitCode int) error {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, r := range running {
+		r.entry.DurationMs = now().Sub(r.start).Milliseconds()
+		write(r.path, r.entry)
+	}
+	running = nil
+	exit := Entry{Time: now(), Event: EventExit, ExitCode: &exitCode}
+	fill(&exit)
+	for _, path := range paths {
+		if err := Append(path, exit); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	err := writeErr
+	paths, writeErr = nil, nil
+	return err
+}
+
+func fill(e *Entry) {
+	if e.Time.IsZero() {
+		e.Time = now()
+	}
+	e.Time = e.Time.UTC()
+	if e.User == "" {
+		e.User = currentUser()
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if e.Pid == 0 {
+		e.Pid = os.Getpid()
+	}
+}
+
+// write appends e and remembers path for the exit record, mu must be held.
+func write(path string, e Entry) {
+	if err := Append(path, e); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	for _, p := range paths {
+		if p == path {
+			return
+		}
+	}
+	paths = append(paths, path)
+}
+
+func removeRunning(r *runningEntry) bool {
+	for i, other := range running {
+		if other == r {
+			running = append(running[:i], running[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Append writes entries as JSON lines. The file is opened in append mode and
+// each batch goes out in a single write, so concurrent CLI processes do not
+// interleave lines.
+func Append(path string, entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if v := os.Getenv("USER"); v != "" {
+		return v
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var out []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestLoadDefaultWhenMissing(t *testing.T) {
+	dir := t.TempDir()
+	got, err := Load(dir)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, filepath.Join(dir, DefaultLogFileName), got.LogPath(dir))
+}
+
+func TestSaveLoadAndMergeFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, Save(dir, &Config{Enabled: true, Path: "/var/log/aliyun.log"}))
+
+	got, err := LoadEffective(dir)
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, "/var/log/aliyun.log", got.LogPath(dir))
+
+	t.Setenv(EnvEnabled, "false")
+	t.Setenv(EnvPath, "/tmp/other.log")
+	got, err = LoadEffective(dir)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, "/tmp/other.log", got.Path)
+}
+
+func TestOpenDisabledIsNoop(t *testing.T) {
+	t.Setenv(EnvEnabled, "")
+	var log *Log = Open(t.TempDir())
+	assert.Nil(t, log)
+	log.Record(Entry{Product: "ecs"})
+	log.Start(Entry{Product: "fc"})()
+	assert.Equal(t, "", log.Path())
+}
+
+func TestRecordAndFinish(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvEnabled, "true")
+	t.Setenv(EnvPath, "")
+	log := Open(dir)
+	require.NotNil(t, log)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return base }
+
+	log.Record(Entry{Product: "Ecs", Api: "DeleteInstance", StatusCode: 200, DurationMs: 12})
+	// written as soon as the call completes
+	entries := readEntries(t, log.Path())
+	require.Len(t, entries, 1)
+	assert.Equal(t, "DeleteInstance", entries[0].Api)
+	assert.Equal(t, base, entries[0].Time)
+	assert.Equal(t, int64(12), entries[0].DurationMs)
+	assert.Equal(t, os.Getpid(), entries[0].Pid)
+	assert.Nil(t, entries[0].ExitCode)
+
+	end := log.Start(Entry{Product: "fc", Plugin: "aliyun-cli-fc"})
+	now = func() time.Time { return base.Add(1500 * time.Millisecond) }
+	// never ended: the process exits while it runs
+	log.Start(Entry{Product: "fc", Api: "invoke"})
+	end()
+	end()
+	require.Len(t, readEntries(t, log.Path()), 2)
+	now = func() time.Time { return base.Add(4 * time.Second) }
+
+	require.NoError(t, Finish(3))
+	require.NoError(t, Finish(0))
+
+	entries = readEntries(t, log.Path())
+	require.Len(t, entries, 4)
+	assert.Equal(t, int64(1500), entries[1].DurationMs)
+	assert.Equal(t, "invoke", entries[2].Api)
+	assert.Equal(t, int64(2500), entries[2].DurationMs)
+	assert.Equal(t, EventExit, entries[3].Event)
+	require.NotNil(t, entries[3].ExitCode)
+	assert.Equal(t, 3, *entries[3].ExitCode)
+	assert.Equal(t, os.Getpid(), entries[3].Pid)
+
+	info, err := os.Stat(log.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestFinishWithoutEntriesWritesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, Finish(0))
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAppendKeepsExistingLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.log")
+	require.NoError(t, Append(path, Entry{Api: "A"}))
+	require.NoError(t, Append(path, Entry{Api: "B"}, Entry{Api: "C"}))
+	require.NoError(t, Append(path))
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"A", "B", "C"}, []string{entries[0].Api, entries[1].Api, entries[2].Api})
+}
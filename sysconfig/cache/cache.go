@@ -0,0 +1,242 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache keeps an opt-in on-disk cache of read-only API responses,
+// each entry valid for a configurable TTL.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ConfigFileName = "cache.json"
+	DirName        = "cache"
+	// DefaultTTLSeconds is used when the cache is enabled without a TTL.
+	DefaultTTLSeconds = 60
+)
+
+const (
+	EnvEnabled = "ALIBABA_CLOUD_CLI_CACHE_ENABLED"
+	EnvTTL     = "ALIBABA_CLOUD_CLI_CACHE_TTL"
+)
+
+var now = time.Now
+
+type Config struct {
+	Enabled    bool `json:"enabled"`
+	TTLSeconds int  `json:"ttl_seconds,omitempty"`
+}
+
+func Default() *Config {
+	return &Config{}
+}
+
+func GetConfigFilePath(configDir string) string {
+	return filepath.Join(configDir, ConfigFileName)
+}
+
+// GetDir returns the directory holding the cached responses.
+func GetDir(configDir string) string {
+	return filepath.Join(configDir, DirName)
+}
+
+// TTL returns the configured TTL, or DefaultTTLSeconds.
+func (c *Config) TTL() time.Duration {
+	if c == nil || c.TTLSeconds <= 0 {
+		return DefaultTTLSeconds * time.Second
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+func Load(configDir string) (*Config, error) {
+	data, err := os.ReadFile(GetConfigFilePath(configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Default(), nil
+	}
+	return &c, nil
+}
+
+func Save(configDir string, c *Config) error {
+	if c == nil {
+		c = Default()
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetConfigFilePath(configDir), data, 0600)
+}
+
+func MergeFromEnv(base *Config) *Config {
+	if base == nil {
+		base = Default()
+	}
+	out := *base
+	if raw, ok := os.LookupEnv(EnvEnabled); ok {
+		if enabled, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			out.Enabled = enabled
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv(EnvTTL)); raw != "" {
+		if ttl, err := strconv.Atoi(raw); err == nil && ttl > 0 {
+			out.TTLSeconds = ttl
+		}
+	}
+	return &out
+}
+
+func LoadEffective(configDir string) (*Config, error) {
+	c, err := Load(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return MergeFromEnv(c), nil
+}
+
+// Key hashes the parts identifying a request into a cache key. Callers pass
+// the parts in a fixed order, with maps already normalized.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(strconv.Itoa(len(p))))
+		h.Write([]byte{':'})
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Entry is one cached response.
+type Entry struct {
+	CreatedAt time.Time           `json:"created_at"`
+	Status    int                 `json:"status"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      string              `json:"body"`
+}
+
+// Store reads and writes the entries of one cache directory. A nil *Store is
+// a disabled cache, so callers do not need to check.
+type Store struct {
+	dir string
+	ttl time.Duration
+}
+
+// Open returns the store under configDir, or nil when the cache is disabled
+// or its config cannot be read.
+func Open(configDir string) *Store {
+	cfg, err := LoadEffective(configDir)
+	if err != nil || !cfg.Enabled {
+		return nil
+	}
+	return NewStore(GetDir(configDir), cfg.TTL())
+}
+
+func NewStore(dir string, ttl time.Duration) *Store {
+	return &Store{dir: dir, ttl: ttl}
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Get returns the entry for key if it has not expired. Expired or unreadable
+// entries are removed.
+func (s *Store) Get(key string) (*Entry, bool) {
+	if s == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || now().Sub(e.CreatedAt) >= s.ttl {
+		_ = os.Remove(s.path(key))
+		return nil, false
+	}
+	return &e, true
+}
+
+// Put saves e under key. The file is written next to its final name and
+// renamed, so concurrent readers never see a partial entry.
+func (s *Store) Put(key string, e *Entry) error {
+	if s == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	e.CreatedAt = now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// Clear removes every cached response under configDir and returns how many
+// entries were removed.
+func Clear(configDir string) (int, error) {
+	dir := GetDir(configDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		if strings.HasSuffix(name, ".json") {
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaultWhenMissing(t *testing.T) {
+	got, err := Load(t.TempDir())
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, DefaultTTLSeconds*time.Second, got.TTL())
+}
+
+func TestSaveLoadAndMergeFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, Save(dir, &Config{Enabled: true, TTLSeconds: 300}))
+
+	got, err := LoadEffective(dir)
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, 300*time.Second, got.TTL())
+
+	t.Setenv(EnvEnabled, "false")
+	t.Setenv(EnvTTL, "10")
+	got, err = LoadEffective(dir)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, 10*time.Second, got.TTL())
+
+	t.Setenv(EnvEnabled, "not-a-bool")
+	t.Setenv(EnvTTL, "-1")
+	got, err = LoadEffective(dir)
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, 300*time.Second, got.TTL())
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, Open(dir))
+
+	require.NoError(t, Save(dir, &Config{Enabled: true}))
+	s := Open(dir)
+	require.NotNil(t, s)
+	assert.Equal(t, GetDir(dir), s.dir)
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("a", "b"), Key("a", "b"))
+	assert.NotEqual(t, Key("a", "b"), Key("b", "a"))
+	// parts are length prefixed, so moving a separator changes the key
+	assert.NotEqual(t, Key("ab", "c"), Key("a", "bc"))
+	assert.Len(t, Key(), 64)
+}
+
+func TestStoreGetPut(t *testing.T) {
+	origin := now
+	defer func() { now = origin }()
+	current := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	s := NewStore(filepath.Join(t.TempDir(), DirName), time.Minute)
+	_, ok := s.Get("k")
+	assert.False(t, ok)
+
+	require.NoError(t, s.Put("k", &Entry{Status: 200, Body: `{"RequestId":"r"}`}))
+	info, err := os.Stat(s.path("k"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	e, ok := s.Get("k")
+	require.True(t, ok)
+	assert.Equal(t, 200, e.Status)
+	assert.Equal(t, `{"RequestId":"r"}`, e.Body)
+
+	current = current.Add(time.Minute)
+	_, ok = s.Get("k")
+	assert.False(t, ok)
+	_, err = os.Stat(s.path("k"))
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, os.WriteFile(s.path("bad"), []byte("{"), 0600))
+	_, ok = s.Get("bad")
+	assert.False(t, ok)
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	_, ok := s.Get("k")
+	assert.False(t, ok)
+	assert.NoError(t, s.Put("k", &Entry{}))
+}
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+	n, err := Clear(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	s := NewStore(GetDir(dir), time.Minute)
+	require.NoError(t, s.Put("a", &Entry{Status: 200}))
+	require.NoError(t, s.Put("b", &Entry{Status: 200}))
+	require.NoError(t, os.WriteFile(filepath.Join(GetDir(dir), "keep.txt"), nil, 0600))
+
+	n, err = Clear(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, ok := s.Get("a")
+	assert.False(t, ok)
+	_, err = os.Stat(filepath.Join(GetDir(dir), "keep.txt"))
+	assert.NoError(t, err)
+}
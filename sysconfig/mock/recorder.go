@@ -0,0 +1,168 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-cli/v3/sysconfig/redact"
+)
+
+// MockModeRecord is the ALIBABA_CLOUD_CLI_MOCK value that captures real runs
+// into the mock store instead of replaying it.
+const MockModeRecord = "record"
+
+const redactedValue = "******"
+
+// redactedArgPlaceholder replaces secret flag values in the recorded cmd. It is
+// a matcher wildcard, so the record still replays for any value.
+const redactedArgPlaceholder = "?"
+
+var sensitiveJSONField = regexp.MustCompile(`("([A-Za-z0-9_.-]+)"\s*:\s*")((?:[^"\\]|\\.)*)(")`)
+
+// sensitiveEnvLine matches `NAME=value` lines, also with a leading `export`,
+// `set` or PowerShell `$env:`, as printed by `configure export-credentials`.
+var sensitiveEnvLine = regexp.MustCompile(`(?m)^([ \t]*(?:export[ \t]+|set[ \t]+|\$env:)?([A-Za-z_][A-Za-z0-9_]*)[ \t]*=[ \t]*)(\S.*?)(\r?)$`)
+
+func IsRecordMode() bool {
+	return os.Getenv(EnvMockEnabled) == MockModeRecord
+}
+
+// Recorder tees the CLI output and appends it, with the normalized command and
+// exit code, to the mock store once the run finishes.
+type Recorder struct {
+	args   []string
+	path   string
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	teeOut io.Writer
+	teeErr io.Writer
+	errOut io.Writer
+	once   sync.Once
+	mu     sync.Mutex
+}
+
+func NewRecorder(opts Options) *Recorder {
+	r := &Recorder{args: opts.Args, path: opts.MockPath, errOut: opts.Stderr}
+	r.teeOut = io.MultiWriter(opts.Stdout, &lockedWriter{mu: &r.mu, w: &r.stdout})
+	r.teeErr = io.MultiWriter(opts.Stderr, &lockedWriter{mu: &r.mu, w: &r.stderr})
+	return r
+}
+
+func (r *Recorder) Stdout() io.Writer {
+	return r.teeOut
+}
+
+func (r *Recorder) Stderr() io.Writer {
+	return r.teeErr
+}
+
+// Finish saves the record. Only the first call has effect, so it is safe to
+// call from both the exit hook and the normal return path.
+func (r *Recorder) Finish(exitCode int) {
+	r.once.Do(func() {
+		if err := r.save(exitCode); err != nil {
+			writef(r.errOut, "WARNING: record mock data failed %s\n", err)
+		}
+	})
+}
+
+func (r *Recorder) save(exitCode int) error {
+	cmd := NormalizeCommand(r.args)
+	if cmd == "" {
+		return nil
+	}
+	r.mu.Lock()
+	stdout, stderr := r.stdout.String(), r.stderr.String()
+	r.mu.Unlock()
+
+	current := LoadLenient(r.path)
+	record := Record{
+		Name:     recordName(len(current)+1, cmd),
+		Cmd:      cmd,
+		ExitCode: exitCode,
+		Stdout:   RedactOutput(stdout),
+		Stderr:   RedactOutput(stderr),
+		Times:    1,
+	}
+	return Save(r.path, append(current, record))
+}
+
+func recordName(seq int, cmd string) string {
+	tokens := strings.Fields(cmd)
+	identity := tokens[:commandIdentityEnd(tokens)]
+	if len(identity) > 2 {
+		identity = identity[:2]
+	}
+	if len(identity) == 0 {
+		return fmt.Sprintf("recorded-%d", seq)
+	}
+	return fmt.Sprintf("recorded-%d-%s", seq, strings.Join(identity, "-"))
+}
+
+// NormalizeCommand turns the CLI arguments into a mock cmd rule: leading global
+// flags are dropped like the matcher does, secret flag values become a
+// wildcard and whitespace inside an argument becomes `*` so the rule stays one
+// token per argument.
+func NormalizeCommand(args []string) string {
+	args = StripLeadingGlobalFlags(args)
+	tokens := make([]string, 0, len(args))
+	redactNext := false
+	for _, arg := range args {
+		if redactNext {
+			redactNext = false
+			tokens = append(tokens, redactedArgPlaceholder)
+			continue
+		}
+		if isFlagToken(arg) {
+			name, hasInlineValue := flagNameAndInlineValue(arg[2:])
+			if redact.IsSensitiveKey(name) {
+				if hasInlineValue {
+					arg = arg[:2+len(name)+1] + redactedArgPlaceholder
+				} else {
+					redactNext = true
+				}
+			}
+		}
+		if arg == "" {
+			continue
+		}
+		tokens = append(tokens, strings.Join(strings.Fields(arg), "*"))
+	}
+	return strings.Join(tokens, " ")
+}
+
+// RedactOutput masks the values of secret JSON fields such as AccessKeySecret
+// or SecurityToken, e.g. in `sts AssumeRole` responses or `configure get`, and
+// of secret `NAME=value` lines such as `export ALIBABA_CLOUD_ACCESS_KEY_SECRET=...`.
+func RedactOutput(s string) string {
+	s = sensitiveJSONField.ReplaceAllStringFunc(s, func(m string) string {
+		sub := sensitiveJSONField.FindStringSubmatch(m)
+		if !redact.IsSensitiveKey(sub[2]) || sub[3] == "" {
+			return m
+		}
+		return sub[1] + redactedValue + sub[4]
+	})
+	return sensitiveEnvLine.ReplaceAllStringFunc(s, func(m string) string {
+		sub := sensitiveEnvLine.FindStringSubmatch(m)
+		if !redact.IsSensitiveKey(sub[2]) {
+			return m
+		}
+		return sub[1] + redactedValue + sub[4]
+	})
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,113 @@
+package mock
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeCommand(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--profile", "prod", "ecs", "DescribeRegions"}, "ecs DescribeRegions"},
+		{[]string{"ecs", "DescribeInstances", "--InstanceName", "my web"}, "ecs DescribeInstances --InstanceName my*web"},
+		{[]string{"configure", "set", "--access-key-secret", "abc", "--region", "cn-hangzhou"}, "configure set --access-key-secret ? --region cn-hangzhou"},
+		{[]string{"sts", "X", "--SecurityToken=abc"}, "sts X --SecurityToken=?"},
+		{[]string{"rds", "CreateAccount", "--AccountPassword", "hunter2"}, "rds CreateAccount --AccountPassword ?"},
+		{[]string{"ecs", "DescribeInstances", "--NextToken", "n1"}, "ecs DescribeInstances --NextToken n1"},
+		{[]string{"ecs", "X", "--Name", ""}, "ecs X --Name"},
+		{[]string{"--quiet"}, ""},
+	}
+	for _, c := range cases {
+		if got := NormalizeCommand(c.args); got != c.want {
+			t.Fatalf("NormalizeCommand(%q) = %q, want %q", c.args, got, c.want)
+		}
+	}
+
+	if !MatchCommand(NormalizeCommand([]string{"configure", "set", "--access-key-secret", "abc"}), []string{"configure", "set", "--access-key-secret", "other"}) {
+		t.Fatalf("redacted command should match any secret value")
+	}
+	if !MatchCommand(NormalizeCommand([]string{"ecs", "X", "--Name", "my web"}), []string{"ecs", "X", "--Name", "my web"}) {
+		t.Fatalf("command with spaces should match itself")
+	}
+}
+
+func TestRedactOutput(t *testing.T) {
+	in := `{"Credentials": {"AccessKeyId": "STS.id", "AccessKeySecret": "s3cr3t", "SecurityToken": "to\"ken"}, "NextToken": "n1", "access_key_secret": ""}`
+	want := `{"Credentials": {"AccessKeyId": "******", "AccessKeySecret": "******", "SecurityToken": "******"}, "NextToken": "n1", "access_key_secret": ""}`
+	if got := RedactOutput(in); got != want {
+		t.Fatalf("RedactOutput = %q, want %q", got, want)
+	}
+
+	in = `{"access_token": "a1", "oauth_access_token": "a2", "oauth_refresh_token": "r1", "AccountPassword": "hunter2", "RegionId": "cn-hangzhou"}`
+	want = `{"access_token": "******", "oauth_access_token": "******", "oauth_refresh_token": "******", "AccountPassword": "******", "RegionId": "cn-hangzhou"}`
+	if got := RedactOutput(in); got != want {
+		t.Fatalf("RedactOutput = %q, want %q", got, want)
+	}
+
+	in = "export ALIBABA_CLOUD_ACCESS_KEY_ID='id'\n" +
+		"export ALIBABA_CLOUD_ACCESS_KEY_SECRET=abc\n" +
+		"export ALIBABA_CLOUD_REGION_ID='cn-hangzhou'\n" +
+		"$env:ALIBABA_CLOUD_SECURITY_TOKEN = \"tok\"\r\n" +
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET=\n" +
+		"ALIBABA_CLOUD_REGION_ID=cn-hangzhou\n"
+	want = "export ALIBABA_CLOUD_ACCESS_KEY_ID=******\n" +
+		"export ALIBABA_CLOUD_ACCESS_KEY_SECRET=******\n" +
+		"export ALIBABA_CLOUD_REGION_ID='cn-hangzhou'\n" +
+		"$env:ALIBABA_CLOUD_SECURITY_TOKEN = ******\r\n" +
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET=\n" +
+		"ALIBABA_CLOUD_REGION_ID=cn-hangzhou\n"
+	if got := RedactOutput(in); got != want {
+		t.Fatalf("RedactOutput = %q, want %q", got, want)
+	}
+}
+
+func TestRecorderFinishAppendsRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mocks.json")
+	if err := Save(path, []Record{{Name: "existing", Cmd: "ecs *", Times: 0}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	recorder := NewRecorder(Options{
+		Args:     []string{"--profile", "prod", "sts", "AssumeRole", "--RoleArn", "acs:ram::1:role/x"},
+		Stdout:   &stdout,
+		Stderr:   &stderr,
+		MockPath: path,
+	})
+	writes(recorder.Stdout(), `{"AccessKeySecret":"s3cr3t"}`+"\n")
+	writes(recorder.Stderr(), "warn\n")
+	recorder.Finish(3)
+	recorder.Finish(0)
+
+	if stdout.String() != `{"AccessKeySecret":"s3cr3t"}`+"\n" || stderr.String() != "warn\n" {
+		t.Fatalf("output not passed through: stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+
+	records, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	got := records[1]
+	want := Record{
+		Name:     "recorded-2-sts-AssumeRole",
+		Cmd:      "sts AssumeRole --RoleArn acs:ram::1:role/x",
+		ExitCode: 3,
+		Stdout:   `{"AccessKeySecret":"******"}` + "\n",
+		Stderr:   "warn\n",
+		Times:    1,
+	}
+	if got != want {
+		t.Fatalf("record = %+v, want %+v", got, want)
+	}
+
+	index, ok := FindMatch(records, []string{"sts", "AssumeRole", "--RoleArn", "acs:ram::1:role/x"})
+	if !ok || index != 1 {
+		t.Fatalf("FindMatch = %d, %v; want recorded entry", index, ok)
+	}
+}
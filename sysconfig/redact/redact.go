@@ -0,0 +1,74 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact tells which parameter, flag, field and environment variable
+// names carry secrets, so the mock recorder, the audit log and the response
+// cache agree on what must not be written in plaintext.
+package redact
+
+import "strings"
+
+// sensitiveWords are looked for in the normalized name, so `AccessKeySecret`,
+// `--access-key-secret`, `oauth_refresh_token` and
+// `ALIBABA_CLOUD_ACCESS_KEY_SECRET` all match.
+var sensitiveWords = []string{
+	"secret",
+	"password",
+	"token",
+	"privatekey",
+	"accesskey",
+	"credential",
+	"kubeconfig",
+}
+
+// sensitiveKeys only match the whole normalized name, `SignatureMethod` or
+// `SignatureVersion` are not secrets.
+var sensitiveKeys = []string{
+	"signature",
+}
+
+// publicKeys contain a sensitive word but are pagination or idempotency
+// tokens, masking them would break --pager and hide useful values.
+var publicKeys = []string{
+	"nexttoken",
+	"clienttoken",
+	"continuationtoken",
+}
+
+// Normalize lowercases name and drops `-`, `_` and `.`.
+func Normalize(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", ".", "").Replace(name))
+}
+
+// IsSensitiveKey tells whether the value of a parameter, flag, JSON field or
+// environment variable called name must be masked.
+func IsSensitiveKey(name string) bool {
+	k := Normalize(name)
+	for _, p := range publicKeys {
+		if k == p {
+			return false
+		}
+	}
+	for _, s := range sensitiveKeys {
+		if k == s {
+			return true
+		}
+	}
+	for _, w := range sensitiveWords {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	for _, k := range []string{
+		"AccessKeySecret", "access-key-secret", "AccessKeyId", "SecurityToken", "sts_token",
+		"access_token", "oauth_access_token", "oauth_refresh_token", "AccountPassword",
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET", "ALIBABA_CLOUD_SECURITY_TOKEN", "PrivateKey",
+		"Signature", "bearer-token", "ClientSecret", "Credentials.AccessKeySecret",
+	} {
+		assert.True(t, IsSensitiveKey(k), k)
+	}
+	for _, k := range []string{
+		"RegionId", "InstanceName", "NextToken", "next-token", "ClientToken",
+		"SignatureMethod", "SignatureVersion", "ALIBABA_CLOUD_REGION_ID", "",
+	} {
+		assert.False(t, IsSensitiveKey(k), k)
+	}
+}
@@ -48,6 +48,27 @@ func IsInteractive() bool {
 	return (info.Mode() & os.ModeCharDevice) != 0
 }
 
+// CheckDenied rejects cmd before its request is built when a rule denies it
+// whatever the request parameters, so a denied call sets up no client. Rules
+// with parameter conditions and confirmation are left to CheckAndConfirm.
+func CheckDenied(policy *Policy, cmd CommandInfo) error {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+
+	if result, decided := policy.CheckPattern(cmd); decided && result.Action == ActionDeny {
+		return deniedError(cmd, result.Rule)
+	}
+	return nil
+}
+
+func deniedError(cmd CommandInfo, rule *Rule) error {
+	return fmt.Errorf(i18n.T(
+		"operation blocked by safety policy: %s %s (rule: %s)",
+		"操作被安全策略拒绝: %s %s (规则: %s)",
+	).GetMessage(), cmd.Product, displayCommand(cmd), rule.Pattern)
+}
+
 func CheckAndConfirm(ctx *cli.Context, policy *Policy, cmd CommandInfo, skipConfirm bool) error {
 	if policy == nil {
 		policy = DefaultPolicy()
@@ -56,10 +77,7 @@ func CheckAndConfirm(ctx *cli.Context, policy *Policy, cmd CommandInfo, skipConf
 	result := policy.Check(cmd)
 	switch result.Action {
 	case ActionDeny:
-		return fmt.Errorf(i18n.T(
-			"operation blocked by safety policy: %s %s (rule: %s)",
-			"操作被安全策略拒绝: %s %s (规则: %s)",
-		).GetMessage(), cmd.Product, displayCommand(cmd), result.Rule.Pattern)
+		return deniedError(cmd, result.Rule)
 	case ActionConfirm:
 		if skipConfirm {
 			// --yes or env: treat as already confirmed
@@ -0,0 +1,218 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Operator string
+
+const (
+	OpEq     Operator = "eq"
+	OpNe     Operator = "ne"
+	OpGlob   Operator = "glob"
+	OpGt     Operator = "gt"
+	OpGe     Operator = "ge"
+	OpLt     Operator = "lt"
+	OpLe     Operator = "le"
+	OpExists Operator = "exists"
+)
+
+// ParamCondition narrows a rule to invocations whose request parameter Name
+// satisfies Op against Value. Numeric operators (gt, ge, lt, le) never match a
+// parameter that is missing or not a number.
+type ParamCondition struct {
+	Name  string   `json:"name"`
+	Op    Operator `json:"op,omitempty"` // default eq
+	Value string   `json:"value,omitempty"`
+}
+
+// paramConditionSyntax maps the short form accepted by
+// `configure safety-policy add --param` to operators. Longer tokens come first
+// so that ">=" is not read as ">".
+var paramConditionSyntax = []struct {
+	token string
+	op    Operator
+}{
+	{">=", OpGe},
+	{"<=", OpLe},
+	{"!=", OpNe},
+	{"~=", OpGlob},
+	{">", OpGt},
+	{"<", OpLt},
+	{"=", OpEq},
+}
+
+// ParseParamCondition parses `Name<op>Value`, e.g. `Amount>10`,
+// `RegionId=cn-shanghai` or `InstanceType~=ecs.g7.*`. A bare `Name` means the
+// parameter must be present.
+func ParseParamCondition(s string) (ParamCondition, error) {
+	s = strings.TrimSpace(s)
+	best := -1
+	var op Operator
+	var tokenLen int
+	for _, syntax := range paramConditionSyntax {
+		if i := strings.Index(s, syntax.token); i > 0 && (best < 0 || i < best) {
+			best, op, tokenLen = i, syntax.op, len(syntax.token)
+		}
+	}
+	if best < 0 {
+		if s == "" {
+			return ParamCondition{}, fmt.Errorf("empty parameter condition")
+		}
+		return ParamCondition{Name: s, Op: OpExists}, nil
+	}
+	cond := ParamCondition{
+		Name:  strings.TrimSpace(s[:best]),
+		Op:    op,
+		Value: strings.TrimSpace(s[best+tokenLen:]),
+	}
+	if cond.isNumeric() {
+		if _, err := strconv.ParseFloat(cond.Value, 64); err != nil {
+			return ParamCondition{}, fmt.Errorf("parameter condition %q needs a numeric value", s)
+		}
+	}
+	return cond, nil
+}
+
+func (c ParamCondition) String() string {
+	switch c.Op {
+	case OpExists:
+		return c.Name
+	case "":
+		return c.Name + "=" + c.Value
+	}
+	for _, syntax := range paramConditionSyntax {
+		if syntax.op == c.Op {
+			return c.Name + syntax.token + c.Value
+		}
+	}
+	return fmt.Sprintf("%s %s %s", c.Name, c.Op, c.Value)
+}
+
+func (c ParamCondition) isNumeric() bool {
+	switch c.Op {
+	case OpGt, OpGe, OpLt, OpLe:
+		return true
+	}
+	return false
+}
+
+func (c ParamCondition) Match(params map[string]string) bool {
+	value, ok := lookupParam(params, c.Name)
+	switch c.Op {
+	case OpExists:
+		return ok
+	case OpNe:
+		return !ok || !strings.EqualFold(value, c.Value)
+	}
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case "", OpEq:
+		return strings.EqualFold(value, c.Value)
+	case OpGlob:
+		return matchPattern(c.Value, value)
+	}
+	if !c.isNumeric() {
+		return false
+	}
+	actual, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return false
+	}
+	expected, err := strconv.ParseFloat(strings.TrimSpace(c.Value), 64)
+	if err != nil {
+		return false
+	}
+	switch c.Op {
+	case OpGt:
+		return actual > expected
+	case OpGe:
+		return actual >= expected
+	case OpLt:
+		return actual < expected
+	default:
+		return actual <= expected
+	}
+}
+
+// lookupParam finds a parameter by name, case-insensitively, since users type
+// `--regionid` and `--RegionId` interchangeably.
+func lookupParam(params map[string]string, name string) (string, bool) {
+	if v, ok := params[name]; ok {
+		return v, true
+	}
+	for k, v := range params {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// matchSelector reports whether value matches one of the glob patterns; an
+// empty selector matches everything.
+func matchSelector(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchPattern(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConditions reports whether the rule is narrowed by profile, region or
+// parameters, i.e. it cannot be expressed as a plain pattern=action pair.
+func (r Rule) HasConditions() bool {
+	return len(r.Profiles) > 0 || len(r.Regions) > 0 || len(r.Params) > 0
+}
+
+func (r Rule) matchConditions(cmd CommandInfo) bool {
+	if !matchSelector(r.Profiles, cmd.Profile) {
+		return false
+	}
+	if !matchSelector(r.Regions, cmd.Region) {
+		return false
+	}
+	for _, cond := range r.Params {
+		if !cond.Match(cmd.Params) {
+			return false
+		}
+	}
+	return true
+}
+
+// DescribeConditions renders the rule conditions for `safety-policy list`.
+func (r Rule) DescribeConditions() string {
+	var parts []string
+	if len(r.Profiles) > 0 {
+		parts = append(parts, "profiles="+strings.Join(r.Profiles, ","))
+	}
+	if len(r.Regions) > 0 {
+		parts = append(parts, "regions="+strings.Join(r.Regions, ","))
+	}
+	for _, cond := range r.Params {
+		parts = append(parts, cond.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2009-present, Alibaba Cloud All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package safety
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParamCondition(t *testing.T) {
+	cases := map[string]ParamCondition{
+		"Amount>10":             {Name: "Amount", Op: OpGt, Value: "10"},
+		"Amount >= 2.5":         {Name: "Amount", Op: OpGe, Value: "2.5"},
+		"Amount<=3":             {Name: "Amount", Op: OpLe, Value: "3"},
+		"RegionId=cn-shanghai":  {Name: "RegionId", Op: OpEq, Value: "cn-shanghai"},
+		"RegionId!=cn-shanghai": {Name: "RegionId", Op: OpNe, Value: "cn-shanghai"},
+		"InstanceType~=ecs.g7*": {Name: "InstanceType", Op: OpGlob, Value: "ecs.g7*"},
+		"Force":                 {Name: "Force", Op: OpExists},
+		"Tag=a=b":               {Name: "Tag", Op: OpEq, Value: "a=b"},
+	}
+	for in, want := range cases {
+		got, err := ParseParamCondition(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseParamCondition("Amount>ten")
+	assert.EqualError(t, err, `parameter condition "Amount>ten" needs a numeric value`)
+	_, err = ParseParamCondition(" ")
+	assert.EqualError(t, err, "empty parameter condition")
+
+	c, _ := ParseParamCondition("Amount >= 2.5")
+	assert.Equal(t, "Amount>=2.5", c.String())
+}
+
+func TestParamCondition_Match(t *testing.T) {
+	params := map[string]string{"Amount": "20", "RegionId": "cn-shanghai", "InstanceType": "ecs.g7.large"}
+	cases := []struct {
+		cond string
+		want bool
+	}{
+		{"Amount>10", true},
+		{"Amount<10", false},
+		{"amount>=20", true},
+		{"Missing>1", false},
+		{"RegionId=CN-SHANGHAI", true},
+		{"RegionId!=cn-beijing", true},
+		{"Missing!=x", true},
+		{"InstanceType~=ecs.g7.*", true},
+		{"InstanceType~=ecs.c7.*", false},
+		{"RegionId", true},
+		{"Missing", false},
+	}
+	for _, c := range cases {
+		cond, err := ParseParamCondition(c.cond)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, cond.Match(params), c.cond)
+	}
+	assert.False(t, ParamCondition{Name: "RegionId", Op: OpGt, Value: "1"}.Match(params))
+}
+
+func TestPolicy_Check_Conditions(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:RunInstances", Action: ActionDeny, Profiles: []string{"prod*"}, Params: []ParamCondition{{Name: "Amount", Op: OpGt, Value: "10"}}},
+			{Pattern: "ecs:RunInstances", Action: ActionConfirm, Regions: []string{"cn-shanghai"}},
+		},
+	}
+	cmd := CommandInfo{Product: "ecs", ApiOrMethod: "RunInstances", Profile: "prod-east", Region: "cn-hangzhou", Params: map[string]string{"Amount": "50"}}
+	result := policy.Check(cmd)
+	assert.True(t, result.Matched)
+	assert.Equal(t, ActionDeny, result.Action)
+
+	cmd.Params["Amount"] = "5"
+	result = policy.Check(cmd)
+	assert.False(t, result.Matched)
+
+	cmd.Region = "cn-shanghai"
+	result = policy.Check(cmd)
+	assert.True(t, result.Matched)
+	assert.Equal(t, ActionConfirm, result.Action)
+
+	cmd.Profile = "dev"
+	cmd.Params["Amount"] = "50"
+	result = policy.Check(cmd)
+	assert.Equal(t, ActionConfirm, result.Action)
+}
+
+func TestPolicy_CheckPattern(t *testing.T) {
+	policy := &Policy{
+		Enabled: true,
+		Rules: []Rule{
+			{Pattern: "ecs:RunInstances", Action: ActionAllow, Params: []ParamCondition{{Name: "Amount", Op: OpLe, Value: "1"}}},
+			{Pattern: "ecs:*Instances", Action: ActionDeny, Profiles: []string{"prod*"}},
+			{Pattern: "ecs:Delete*", Action: ActionDeny, Regions: []string{"cn-shanghai"}},
+			{Pattern: "ecs:*", Action: ActionConfirm},
+		},
+	}
+
+	// the parameter conditions of the first rule are needed
+	_, decided := policy.CheckPattern(CommandInfo{Product: "ecs", ApiOrMethod: "RunInstances", Profile: "prod"})
+	assert.False(t, decided)
+
+	result, decided := policy.CheckPattern(CommandInfo{Product: "ecs", ApiOrMethod: "StopInstances", Profile: "prod"})
+	assert.True(t, decided)
+	assert.Equal(t, ActionDeny, result.Action)
+	assert.Equal(t, "ecs:*Instances", result.Rule.Pattern)
+
+	result, decided = policy.CheckPattern(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Region: "cn-hangzhou"})
+	assert.True(t, decided)
+	assert.Equal(t, ActionConfirm, result.Action)
+
+	result, decided = policy.CheckPattern(CommandInfo{Product: "rds", ApiOrMethod: "DeleteDBInstance"})
+	assert.True(t, decided)
+	assert.False(t, result.Matched)
+
+	cmd := CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance", Region: "cn-shanghai"}
+	require.Error(t, CheckDenied(policy, cmd))
+	assert.Contains(t, CheckDenied(policy, cmd).Error(), "ecs:Delete*")
+	assert.NoError(t, CheckDenied(policy, CommandInfo{Product: "ecs", ApiOrMethod: "RunInstances", Profile: "prod"}))
+	assert.NoError(t, CheckDenied(policy, CommandInfo{Product: "ecs", ApiOrMethod: "DescribeInstances"}))
+}
+
+func TestRule_DescribeConditions(t *testing.T) {
+	r := Rule{
+		Pattern:  "ecs:*",
+		Profiles: []string{"prod"},
+		Regions:  []string{"cn-*", "ap-*"},
+		Params:   []ParamCondition{{Name: "Amount", Op: OpGt, Value: "10"}, {Name: "Force", Op: OpExists}},
+	}
+	assert.True(t, r.HasConditions())
+	assert.Equal(t, "profiles=prod regions=cn-*,ap-* Amount>10 Force", r.DescribeConditions())
+	assert.False(t, Rule{Pattern: "ecs:*"}.HasConditions())
+}
+
+func TestSerializeRulesForEnv_SkipsConditionalRules(t *testing.T) {
+	rules := []Rule{
+		{Pattern: "*:Delete*", Action: ActionDeny},
+		{Pattern: "ecs:RunInstances", Action: ActionDeny, Profiles: []string{"prod"}},
+	}
+	assert.Equal(t, "*:Delete*=deny", serializeRulesForEnv(rules))
+}
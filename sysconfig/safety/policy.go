@@ -48,6 +48,13 @@ type Rule struct {
 	Pattern string `json:"pattern"`
 	// Action: allow, deny, confirm (or forbid)
 	Action Action `json:"action"`
+	// Profiles and Regions optionally restrict the rule to matching profile
+	// names and regions (wildcards allowed), e.g. only the "prod*" profiles.
+	Profiles []string `json:"profiles,omitempty"`
+	Regions  []string `json:"regions,omitempty"`
+	// Params are request parameter conditions that must all hold,
+	// e.g. RegionId=cn-shanghai or Amount>10.
+	Params []ParamCondition `json:"params,omitempty"`
 }
 
 type Policy struct {
@@ -86,6 +93,14 @@ type CommandInfo struct {
 	// Path is only set for REST style invocations that supply a path
 	// (e.g. `aliyun cs DELETE /clusters` -> Path = "/clusters").
 	Path string
+	// Profile and Region are the effective profile name and region of the call.
+	Profile string
+	Region  string
+	// Params are the request parameters rule conditions are matched against:
+	// those of the prepared request for API calls, including --body fields and
+	// cli defaults (e.g. `--Amount 20` -> "Amount": "20"), and the flags as
+	// typed for plugin commands and checks made before the request is built.
+	Params map[string]string
 }
 
 func (p *Policy) Check(cmd CommandInfo) CheckResult {
@@ -98,25 +113,55 @@ func (p *Policy) Check(cmd CommandInfo) CheckResult {
 	// Rules are evaluated in order; first match wins
 	for i := range p.Rules {
 		rule := &p.Rules[i]
-		if matchPattern(rule.Pattern, cmdPattern) {
-			action := rule.Action
-			if action == ActionForbid {
-				action = ActionConfirm
-			}
-			if action == "" {
-				action = ActionAllow
-			}
-			return CheckResult{
-				Action:  action,
-				Matched: true,
-				Rule:    rule,
-			}
+		if matchPattern(rule.Pattern, cmdPattern) && rule.matchConditions(cmd) {
+			return rule.result()
 		}
 	}
 
 	return CheckResult{Action: ActionAllow, Matched: false}
 }
 
+// CheckPattern is Check for a command whose request parameters are not known
+// yet. decided is false when a rule with parameter conditions matches the
+// command before the rule that decides, Check must then be called with the
+// parameters.
+func (p *Policy) CheckPattern(cmd CommandInfo) (result CheckResult, decided bool) {
+	if !p.Enabled || len(p.Rules) == 0 {
+		return CheckResult{Action: ActionAllow, Matched: false}, true
+	}
+
+	cmdPattern := buildCommandPattern(cmd)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !matchPattern(rule.Pattern, cmdPattern) {
+			continue
+		}
+		if len(rule.Params) > 0 {
+			return CheckResult{}, false
+		}
+		if rule.matchConditions(cmd) {
+			return rule.result(), true
+		}
+	}
+
+	return CheckResult{Action: ActionAllow, Matched: false}, true
+}
+
+func (r *Rule) result() CheckResult {
+	action := r.Action
+	if action == ActionForbid {
+		action = ActionConfirm
+	}
+	if action == "" {
+		action = ActionAllow
+	}
+	return CheckResult{
+		Action:  action,
+		Matched: true,
+		Rule:    r,
+	}
+}
+
 // buildCommandPattern renders the user-typed command as a single identifier:
 //
 //	product:ApiOrMethod          (two-segment commands: RPC, REST-by-ApiName, plugin)
@@ -164,9 +209,19 @@ func InferOperationFromApiName(apiName string) string {
 	if strings.HasPrefix(apiLower, "create") || strings.HasPrefix(apiLower, "add") {
 		return "create"
 	}
+	if strings.HasPrefix(apiLower, "describe") || strings.HasPrefix(apiLower, "list") || strings.HasPrefix(apiLower, "get") ||
+		strings.HasPrefix(apiLower, "query") || strings.HasPrefix(apiLower, "search") {
+		return "read"
+	}
 	return ""
 }
 
+// IsReadOnlyApiName reports whether InferOperationFromApiName takes apiName
+// for a read, e.g. DescribeInstances or ListTagResources.
+func IsReadOnlyApiName(apiName string) bool {
+	return InferOperationFromApiName(apiName) == "read"
+}
+
 const SafetyPolicyFileName = "safety-policy.json"
 
 func GetPolicyFilePath(configDir string) string {
@@ -249,6 +304,12 @@ func serializeRulesForEnv(rules []Rule) string {
 	}
 	parts := make([]string, 0, len(rules))
 	for _, r := range rules {
+		// pattern=action cannot carry profile/region/parameter conditions, and
+		// dropping them would turn e.g. a prod-only deny into a global one.
+		// Plugins can still read the full rules from EnvSafetyPolicyFile.
+		if r.HasConditions() {
+			continue
+		}
 		pat := strings.TrimSpace(r.Pattern)
 		act := strings.ToLower(strings.TrimSpace(string(r.Action)))
 		if pat == "" || act == "" {
@@ -288,17 +349,75 @@ func MergePolicyFromEnv(base *Policy) *Policy {
 	return &Policy{Enabled: enabled, Rules: rules}
 }
 
+// Overrides are the settings of a project-local configuration on top of the
+// policy file. A project comes with the code checked out, so it may only make
+// the policy stricter: it can enable the policy and add rules, which are
+// evaluated after those of the file. Its enabled:false and allow rules are
+// ignored.
+type Overrides struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// StricterRules are the rules of the overrides Apply keeps, all but the ones
+// that allow.
+func (o *Overrides) StricterRules() []Rule {
+	if o == nil {
+		return nil
+	}
+	var rules []Rule
+	for _, rule := range o.Rules {
+		if rule.Action == "" || rule.Action == ActionAllow {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Enables tells whether the overrides turn on a policy the file leaves off.
+func (o *Overrides) Enables(base *Policy) bool {
+	return o != nil && o.Enabled != nil && *o.Enabled && (base == nil || !base.Enabled)
+}
+
+func (o *Overrides) Apply(base *Policy) *Policy {
+	if base == nil {
+		base = DefaultPolicy()
+	}
+	if o == nil {
+		return base
+	}
+	stricter := o.StricterRules()
+	rules := make([]Rule, 0, len(base.Rules)+len(stricter))
+	rules = append(rules, base.Rules...)
+	rules = append(rules, stricter...)
+	return &Policy{Enabled: base.Enabled || o.Enables(base), Rules: rules}
+}
+
 func LoadEffectivePolicy(configDir string) (*Policy, error) {
+	return LoadEffectivePolicyWithOverrides(configDir, nil)
+}
+
+// LoadEffectivePolicyWithOverrides merges the policy file, the overrides and
+// the environment, in increasing precedence.
+func LoadEffectivePolicyWithOverrides(configDir string, o *Overrides) (*Policy, error) {
 	p, err := LoadPolicy(configDir)
 	if err != nil {
 		return nil, err
 	}
-	return MergePolicyFromEnv(p), nil
+	return MergePolicyFromEnv(o.Apply(p)), nil
 }
 
 const EnvSafetyPolicyFile = "ALIBABA_CLOUD_CLI_SAFETY_POLICY_FILE"
 
 func MergeSafetyPolicyPathIntoEnvs(configDir string, envs map[string]string) {
+	MergeSafetyPolicyPathIntoEnvsWithOverrides(configDir, nil, envs)
+}
+
+// MergeSafetyPolicyPathIntoEnvsWithOverrides is MergeSafetyPolicyPathIntoEnvs
+// with the overrides of a project in the serialized rules, the file at
+// EnvSafetyPolicyFile does not contain them.
+func MergeSafetyPolicyPathIntoEnvsWithOverrides(configDir string, o *Overrides, envs map[string]string) {
 	if envs == nil || configDir == "" {
 		return
 	}
@@ -308,7 +427,7 @@ func MergeSafetyPolicyPathIntoEnvs(configDir string, envs map[string]string) {
 	}
 	envs[EnvSafetyPolicyFile] = p
 
-	ef, err := LoadEffectivePolicy(configDir)
+	ef, err := LoadEffectivePolicyWithOverrides(configDir, o)
 	if err != nil {
 		return
 	}
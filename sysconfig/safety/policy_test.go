@@ -425,3 +425,61 @@ func TestLoadEffectivePolicy_AppliesEnv(t *testing.T) {
 	require.Len(t, got.Rules, 1)
 	assert.Equal(t, "ecs:Delete*", got.Rules[0].Pattern)
 }
+
+func TestLoadEffectivePolicyWithOverrides(t *testing.T) {
+	unsetEnvForTest(t, EnvSafetyPolicyEnabled)
+	unsetEnvForTest(t, EnvSafetyPolicyRules)
+	dir := t.TempDir()
+	require.NoError(t, SavePolicy(dir, &Policy{Enabled: false, Rules: []Rule{{Pattern: "*:Delete*", Action: ActionConfirm}}}))
+
+	enabled := true
+	o := &Overrides{Enabled: &enabled, Rules: []Rule{
+		{Pattern: "ecs:DeleteInstance", Action: ActionDeny},
+		{Pattern: "ecs:RunInstances", Action: ActionDeny},
+		{Pattern: "*:Delete*", Action: ActionAllow},
+	}}
+	got, err := LoadEffectivePolicyWithOverrides(dir, o)
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+	require.Len(t, got.Rules, 3)
+	// the rules of the file come first, the project only adds restrictions
+	assert.Equal(t, ActionConfirm, got.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}).Action)
+	assert.Equal(t, ActionDeny, got.Check(CommandInfo{Product: "ecs", ApiOrMethod: "RunInstances"}).Action)
+	assert.Equal(t, ActionConfirm, got.Check(CommandInfo{Product: "vpc", ApiOrMethod: "DeleteVpc"}).Action)
+
+	// the environment wins over the project
+	t.Setenv(EnvSafetyPolicyEnabled, "false")
+	got, err = LoadEffectivePolicyWithOverrides(dir, o)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+
+	envs := map[string]string{}
+	MergeSafetyPolicyPathIntoEnvsWithOverrides(dir, o, envs)
+	assert.Equal(t, "*:Delete*=confirm,ecs:DeleteInstance=deny,ecs:RunInstances=deny", envs[EnvSafetyPolicyRules])
+
+	got, err = LoadEffectivePolicyWithOverrides(dir, nil)
+	require.NoError(t, err)
+	assert.Len(t, got.Rules, 1)
+}
+
+func TestOverridesCannotWeakenPolicy(t *testing.T) {
+	disabled := false
+	base := &Policy{Enabled: true, Rules: []Rule{{Pattern: "ecs:Delete*", Action: ActionDeny}}}
+	o := &Overrides{Enabled: &disabled, Rules: []Rule{{Pattern: "ecs:*", Action: ActionAllow}, {Pattern: "ecs:Stop*"}}}
+	got := o.Apply(base)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, base.Rules, got.Rules)
+	assert.Equal(t, ActionDeny, got.Check(CommandInfo{Product: "ecs", ApiOrMethod: "DeleteInstance"}).Action)
+	assert.Empty(t, o.StricterRules())
+	assert.False(t, o.Enables(&Policy{}))
+}
+
+func TestIsReadOnlyApiName(t *testing.T) {
+	for _, name := range []string{"DescribeInstances", "ListTagResources", "GetUser", "QueryBill", "SearchResources", "describeInstances"} {
+		assert.True(t, IsReadOnlyApiName(name), name)
+		assert.Equal(t, "read", InferOperationFromApiName(name), name)
+	}
+	for _, name := range []string{"", "DeleteInstance", "CreateVpc", "ModifyInstanceAttribute", "RunInstances"} {
+		assert.False(t, IsReadOnlyApiName(name), name)
+	}
+}